@@ -8,12 +8,14 @@ require (
 	github.com/go-ldap/ldap/v3 v3.4.5
 	github.com/go-ping/ping v1.1.0
 	github.com/golang/snappy v0.0.4
+	github.com/google/btree v1.1.2
 	github.com/google/go-cmp v0.5.9
 	github.com/google/nftables v0.1.0
 	github.com/google/uuid v1.3.0
 	github.com/grpc-ecosystem/go-grpc-middleware/providers/prometheus v1.0.0-rc.0
 	github.com/grpc-ecosystem/go-grpc-middleware/v2 v2.0.0-rc.5
 	github.com/hashicorp/go-hclog v1.5.0
+	github.com/hashicorp/go-msgpack v0.5.5
 	github.com/hashicorp/golang-lru/v2 v2.0.4
 	github.com/hashicorp/raft v1.5.0
 	github.com/improbable-eng/grpc-web v0.15.0
@@ -33,6 +35,7 @@ require (
 	github.com/vishvananda/netlink v1.1.0
 	github.com/webmeshproj/api v0.2.2-0.20230814000712-1ce8010ea26d
 	github.com/webmeshproj/raft-badger v0.0.0-20230808161310-f874ad74d944
+	go.etcd.io/bbolt v1.3.7
 	golang.org/x/crypto v0.12.0
 	golang.org/x/sync v0.3.0
 	golang.org/x/sys v0.11.0
@@ -65,7 +68,6 @@ require (
 	github.com/google/flatbuffers v23.5.26+incompatible // indirect
 	github.com/gorilla/websocket v1.5.0 // indirect
 	github.com/hashicorp/go-immutable-radix v1.3.1 // indirect
-	github.com/hashicorp/go-msgpack v0.5.5 // indirect
 	github.com/hashicorp/golang-lru v1.0.2 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/josharian/native v1.1.0 // indirect
@@ -0,0 +1,169 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ctlcmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/webmeshproj/webmesh/pkg/meshdb/backups"
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+var (
+	backupDir        string
+	backupS3Endpoint string
+	backupS3Bucket   string
+	backupS3Prefix   string
+	backupS3Region   string
+	backupOutput     string
+	backupKey        string
+	backupKeyFile    string
+)
+
+func init() {
+	fl := backupCmd.PersistentFlags()
+	fl.StringVar(&backupDir, "dir", "", "Local directory containing backups")
+	fl.StringVar(&backupS3Endpoint, "s3-endpoint", "", "URL of an S3-compatible object store containing backups")
+	fl.StringVar(&backupS3Bucket, "s3-bucket", "", "Bucket containing backups")
+	fl.StringVar(&backupS3Prefix, "s3-prefix", "", "Key prefix for backups in the bucket")
+	fl.StringVar(&backupS3Region, "s3-region", "us-east-1", "Region of the bucket")
+	backupRestoreCmd.Flags().StringVar(&backupOutput, "output", "", "Output file (default: the backup name in the current directory)")
+	backupRestoreCmd.Flags().StringVar(&backupKey, "encryption-key", "", "Storage encryption key the backup was taken with")
+	backupRestoreCmd.Flags().StringVar(&backupKeyFile, "encryption-key-file", "", "File containing the storage encryption key the backup was taken with")
+	backupCmd.AddCommand(backupListCmd)
+	backupCmd.AddCommand(backupRestoreCmd)
+	rootCmd.AddCommand(backupCmd)
+}
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Manage snapshot backups taken by the mesh leader",
+	Long: `Manage snapshot backups taken by the mesh leader.
+
+Backups are read directly from their destination, so these commands work when
+the mesh is unavailable. Credentials for S3-compatible object stores are read
+from the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables.`,
+}
+
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the backups in a destination",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		dest, err := backupDestination()
+		if err != nil {
+			return err
+		}
+		list, err := backups.List(cmd.Context(), dest)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tCREATED\tSIZE\tENCRYPTED\tSHA256")
+		for _, b := range list {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%t\t%s\n", b.Name, b.CreatedAt.Format(time.RFC3339), b.Size, b.Encrypted, b.Checksum)
+		}
+		return w.Flush()
+	},
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore [NAME]",
+	Short: "Download and verify a backup for restoring a new cluster",
+	Long: `Download and verify a backup for restoring a new cluster.
+
+If NAME is not given, the latest backup is used. The backup is checked against
+its recorded checksum and written to a local file. Backups taken from a node
+with storage encryption are encrypted with its key, which must be given with
+--encryption-key or --encryption-key-file. Bootstrap a new cluster from it
+with --bootstrap.restore-snapshot.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dest, err := backupDestination()
+		if err != nil {
+			return err
+		}
+		var key []byte
+		switch {
+		case backupKeyFile != "":
+			key, err = storage.ReadEncryptionKey(backupKeyFile)
+		case backupKey != "":
+			key, err = storage.ParseEncryptionKey(backupKey)
+		}
+		if err != nil {
+			return err
+		}
+		var name string
+		if len(args) > 0 {
+			name = args[0]
+		} else {
+			latest, err := backups.Latest(cmd.Context(), dest)
+			if err != nil {
+				return err
+			}
+			name = latest.Name
+		}
+		out := backupOutput
+		if out == "" {
+			out = name
+		}
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		if err := backups.Download(cmd.Context(), dest, name, key, f); err != nil {
+			f.Close()
+			os.Remove(out)
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		cmd.Printf("Wrote backup %s to %s\n", name, out)
+		cmd.Printf("Bootstrap a new cluster from it with --bootstrap.restore-snapshot=%s\n", out)
+		return nil
+	},
+}
+
+func backupDestination() (backups.Destination, error) {
+	switch {
+	case backupDir != "" && backupS3Endpoint != "":
+		return nil, errors.New("only one of --dir or --s3-endpoint may be set")
+	case backupDir != "":
+		if _, err := os.Stat(backupDir); err != nil {
+			return nil, err
+		}
+		return backups.NewDirDestination(backupDir)
+	case backupS3Endpoint != "":
+		return backups.NewS3Destination(backups.S3Options{
+			Endpoint:        backupS3Endpoint,
+			Bucket:          backupS3Bucket,
+			Prefix:          backupS3Prefix,
+			Region:          backupS3Region,
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		})
+	default:
+		return nil, errors.New("one of --dir or --s3-endpoint must be set")
+	}
+}
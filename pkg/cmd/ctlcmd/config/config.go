@@ -38,6 +38,12 @@ import (
 
 	"github.com/webmeshproj/webmesh/pkg/plugins/builtins/basicauth"
 	"github.com/webmeshproj/webmesh/pkg/plugins/builtins/ldap"
+	"github.com/webmeshproj/webmesh/pkg/services/labels"
+	"github.com/webmeshproj/webmesh/pkg/services/leaderproxy"
+	"github.com/webmeshproj/webmesh/pkg/services/leadership"
+	"github.com/webmeshproj/webmesh/pkg/services/maintenance"
+	"github.com/webmeshproj/webmesh/pkg/services/membership"
+	"github.com/webmeshproj/webmesh/pkg/services/topology"
 	"github.com/webmeshproj/webmesh/pkg/util"
 )
 
@@ -154,6 +160,12 @@ type ClusterConfig struct {
 	ConnectTimeout Duration `yaml:"connect-timeout,omitempty" json:"connect-timeout,omitempty"`
 	// RequestTimeout is the timeout for requests to the cluster.
 	RequestTimeout Duration `yaml:"request-timeout,omitempty" json:"request-timeout,omitempty"`
+	// Consistency is the read consistency to request from the cluster. One of
+	// any, bounded-staleness, or linearizable.
+	Consistency string `yaml:"consistency,omitempty" json:"consistency,omitempty"`
+	// MaxLag is the number of entries a follower may lag behind the leader for
+	// bounded-staleness reads.
+	MaxLag uint64 `yaml:"max-lag,omitempty" json:"max-lag,omitempty"`
 }
 
 // User is the named configuration for a user.
@@ -232,6 +244,51 @@ func (c *Config) NewAdminClient() (v1.AdminClient, io.Closer, error) {
 	return v1.NewAdminClient(conn), conn, nil
 }
 
+// NewMaintenanceClient returns a new Maintenance client.
+func (c *Config) NewMaintenanceClient() (maintenance.MaintenanceClient, io.Closer, error) {
+	conn, err := c.DialCurrent()
+	if err != nil {
+		return nil, nil, err
+	}
+	return maintenance.NewMaintenanceClient(conn), conn, nil
+}
+
+// NewLabelsClient returns a new Labels client for the current context.
+func (c *Config) NewLabelsClient() (labels.LabelsClient, io.Closer, error) {
+	conn, err := c.DialCurrent()
+	if err != nil {
+		return nil, nil, err
+	}
+	return labels.NewLabelsClient(conn), conn, nil
+}
+
+// NewTopologyClient returns a new Topology client.
+func (c *Config) NewTopologyClient() (topology.TopologyClient, io.Closer, error) {
+	conn, err := c.DialCurrent()
+	if err != nil {
+		return nil, nil, err
+	}
+	return topology.NewTopologyClient(conn), conn, nil
+}
+
+// NewLeadershipClient creates a new Leadership client for the current context.
+func (c *Config) NewLeadershipClient() (leadership.LeadershipClient, io.Closer, error) {
+	conn, err := c.DialCurrent()
+	if err != nil {
+		return nil, nil, err
+	}
+	return leadership.NewLeadershipClient(conn), conn, nil
+}
+
+// NewMembershipClient returns a new Membership client for the current context.
+func (c *Config) NewMembershipClient() (membership.MembershipClient, io.Closer, error) {
+	conn, err := c.DialCurrent()
+	if err != nil {
+		return nil, nil, err
+	}
+	return membership.NewMembershipClient(conn), conn, nil
+}
+
 // DialCurrent connects to the current context.
 func (c *Config) DialCurrent() (*grpc.ClientConn, error) {
 	var opts []grpc.DialOption
@@ -260,6 +317,13 @@ func (c *Config) DialCurrent() (*grpc.ClientConn, error) {
 		opts = append(opts, grpc.WithUnaryInterceptor(RequestTimeoutUnaryClientInterceptor(timeout)))
 		opts = append(opts, grpc.WithStreamInterceptor(RequestTimeoutStreamClientInterceptor(timeout)))
 	}
+	if cluster.Consistency != "" || cluster.MaxLag > 0 {
+		consistency, err := leaderproxy.ParseConsistency(cluster.Consistency)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithChainUnaryInterceptor(ConsistencyUnaryClientInterceptor(consistency, cluster.MaxLag)))
+	}
 	ctx := context.Background()
 	if cluster.ConnectTimeout.Duration > 0 {
 		var cancel context.CancelFunc
@@ -416,6 +480,8 @@ func bindFlags(c *Config, flset *pflag.FlagSet, usrIdx, clusterIdx int) {
 	fs.BoolVar(&c.Clusters[clusterIdx].Cluster.TLSSkipVerify, "tls-skip-verify", c.Clusters[clusterIdx].Cluster.TLSSkipVerify, "Whether TLS verification should be skipped for the cluster connection")
 	fs.BoolVar(&c.Clusters[clusterIdx].Cluster.Insecure, "insecure", c.Clusters[clusterIdx].Cluster.Insecure, "Whether TLS should be disabled for the cluster connection")
 	fs.BoolVar(&c.Clusters[clusterIdx].Cluster.PreferLeader, "prefer-leader", c.Clusters[clusterIdx].Cluster.PreferLeader, "Whether to prefer the leader node for the cluster connection")
+	fs.StringVar(&c.Clusters[clusterIdx].Cluster.Consistency, "consistency", c.Clusters[clusterIdx].Cluster.Consistency, "The read consistency to request (any, bounded-staleness, or linearizable)")
+	fs.Uint64Var(&c.Clusters[clusterIdx].Cluster.MaxLag, "max-lag", c.Clusters[clusterIdx].Cluster.MaxLag, "The number of entries a follower may lag behind the leader for bounded-staleness reads")
 
 	fs.Func("certificate-authority", "The path to the CA certificate for the cluster connection", func(s string) error {
 		data, err := os.ReadFile(s)
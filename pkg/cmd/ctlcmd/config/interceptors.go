@@ -19,10 +19,13 @@ package config
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+
+	"github.com/webmeshproj/webmesh/pkg/services/leaderproxy"
 )
 
 // LeaderUnaryClientInterceptor returns a gRPC unary client interceptor that
@@ -53,6 +56,19 @@ func LeaderStreamClientInterceptor() grpc.StreamClientInterceptor {
 	}
 }
 
+// ConsistencyUnaryClientInterceptor returns a gRPC unary client interceptor
+// that adds the read consistency metadata to the outgoing context. A zero
+// maxLag leaves the server default in place.
+func ConsistencyUnaryClientInterceptor(consistency leaderproxy.Consistency, maxLag uint64) grpc.UnaryClientInterceptor {
+	kv := []string{leaderproxy.ConsistencyMeta, string(consistency)}
+	if maxLag > 0 {
+		kv = append(kv, leaderproxy.MaxLagMeta, strconv.FormatUint(maxLag, 10))
+	}
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(metadata.AppendToOutgoingContext(ctx, kv...), method, req, reply, cc, opts...)
+	}
+}
+
 // RequestTimeoutUnaryClientInterceptor returns a gRPC unary client interceptor
 // that adds a timeout to the outgoing context.
 func RequestTimeoutUnaryClientInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
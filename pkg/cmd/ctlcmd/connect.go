@@ -25,6 +25,7 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -52,6 +53,7 @@ func init() {
 	flags.BoolVar(&connectOpts.NoIPv6, "no-ipv6", false, "do not use IPv6 when joining the cluster")
 	flags.BoolVar(&connectOpts.LocalDNS, "local-dns", false, "start a local MeshDNS server")
 	flags.Uint16Var(&connectOpts.LocalDNSPort, "local-dns-port", 5353, "port to use for the local MeshDNS server")
+	flags.DurationVar(&connectOpts.LeaseTTL, "lease-ttl", 30*time.Second, "TTL of the lease keeping the node registered, 0 to disable")
 
 	flags.StringVar(&connectLogLevel, "log-level", "info", "log level to use")
 	rootCmd.AddCommand(connectCmd)
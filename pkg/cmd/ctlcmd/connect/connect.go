@@ -67,6 +67,10 @@ type Options struct {
 	LocalDNS bool
 	// LocalDNSPort is the port to use for the local MeshDNS server.
 	LocalDNSPort uint16
+	// LeaseTTL is the TTL of the lease the node's registration is
+	// attached to. The node is removed from the mesh if it stops
+	// renewing the lease. Zero disables leases.
+	LeaseTTL time.Duration
 }
 
 // Connect connects to the mesh as an ephemeral node. The context
@@ -90,6 +94,7 @@ func Connect(ctx context.Context, opts Options, stopChan chan struct{}) error {
 	storeOpts.Mesh.JoinAddress = opts.JoinServer
 	storeOpts.Mesh.NoIPv4 = opts.NoIPv4
 	storeOpts.Mesh.NoIPv6 = opts.NoIPv6
+	storeOpts.Mesh.LeaseTTL = opts.LeaseTTL
 	storeOpts.WireGuard.InterfaceName = opts.InterfaceName
 	storeOpts.WireGuard.ListenPort = int(opts.ListenPort)
 	storeOpts.WireGuard.ForceTUN = opts.ForceTUN
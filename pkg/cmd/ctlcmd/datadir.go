@@ -0,0 +1,207 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ctlcmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/webmeshproj/webmesh/pkg/raft"
+)
+
+var (
+	datadirEncryptionKeyFile string
+	datadirStorageBackend    string
+	datadirJSON              bool
+	datadirFrom              uint64
+	datadirTo                uint64
+	datadirValues            bool
+)
+
+func init() {
+	fl := debugDataDirCmd.PersistentFlags()
+	fl.StringVar(&datadirEncryptionKeyFile, "encryption-key-file", "", "File containing the key the stores are encrypted with")
+	fl.StringVar(&datadirStorageBackend, "storage-backend", "", "Backend of the raft data store (default: detected)")
+	fl.BoolVar(&datadirJSON, "json", false, "Print results as JSON")
+	debugDataDirLogsCmd.Flags().Uint64Var(&datadirFrom, "from", 0, "First log index to print (default: first index in the store)")
+	debugDataDirLogsCmd.Flags().Uint64Var(&datadirTo, "to", 0, "Last log index to print (default: last index in the store)")
+	debugDataDirKeysCmd.Flags().BoolVar(&datadirValues, "values", false, "Print the value of each key")
+	debugDataDirCmd.AddCommand(debugDataDirStateCmd)
+	debugDataDirCmd.AddCommand(debugDataDirLogsCmd)
+	debugDataDirCmd.AddCommand(debugDataDirKeysCmd)
+	debugCmd.AddCommand(debugDataDirCmd)
+}
+
+var debugDataDirCmd = &cobra.Command{
+	Use:   "datadir",
+	Short: "Inspect the raft data directory of a stopped node",
+	Long: `Inspect the raft data directory of a stopped node.
+
+The raft-store and raft-data directories are opened read-only. The node
+must not be running, since it holds a lock on the stores.`,
+}
+
+var debugDataDirStateCmd = &cobra.Command{
+	Use:   "state [DIR]",
+	Short: "Show the raft configuration, term, log indexes and snapshots",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := openDataDir(args[0])
+		if err != nil {
+			return err
+		}
+		defer dir.Close()
+		state, err := dir.State()
+		if err != nil {
+			return err
+		}
+		if datadirJSON {
+			return printJSON(cmd.OutOrStdout(), state)
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Log store:\t%s\n", state.LogStore)
+		fmt.Fprintf(w, "Current term:\t%d\n", state.CurrentTerm)
+		fmt.Fprintf(w, "Last vote:\t%s (term %d)\n", state.LastVoteCandidate, state.LastVoteTerm)
+		fmt.Fprintf(w, "First index:\t%d\n", state.FirstIndex)
+		fmt.Fprintf(w, "Last index:\t%d\n", state.LastIndex)
+		fmt.Fprintf(w, "Configuration index:\t%d\n", state.ConfigurationIndex)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "ID\tADDRESS\tSUFFRAGE")
+		for _, srv := range state.Configuration {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", srv.ID, srv.Address, srv.Suffrage)
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "SNAPSHOT\tTERM\tINDEX\tSIZE")
+		for _, snap := range state.Snapshots {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", snap.ID, snap.Term, snap.Index, snap.Size)
+		}
+		return w.Flush()
+	},
+}
+
+var debugDataDirLogsCmd = &cobra.Command{
+	Use:   "logs [DIR]",
+	Short: "Print the decoded raft logs in a range",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := openDataDir(args[0])
+		if err != nil {
+			return err
+		}
+		defer dir.Close()
+		logs, err := dir.Logs(datadirFrom, datadirTo)
+		if err != nil {
+			return err
+		}
+		if datadirJSON {
+			return printJSON(cmd.OutOrStdout(), logs)
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "INDEX\tTERM\tTYPE\tAPPENDED\tDATA")
+		for _, l := range logs {
+			var appended string
+			if !l.AppendedAt.IsZero() {
+				appended = l.AppendedAt.UTC().Format(time.RFC3339)
+			}
+			var data string
+			switch {
+			case l.Error != "":
+				data = "error: " + l.Error
+			case l.Entry != nil:
+				data = protojson.MarshalOptions{}.Format(l.Entry)
+			case l.Configuration != nil:
+				for i, srv := range l.Configuration {
+					if i > 0 {
+						data += ", "
+					}
+					data += fmt.Sprintf("%s=%s (%s)", srv.ID, srv.Address, srv.Suffrage)
+				}
+			}
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\n", l.Index, l.Term, l.Type, appended, data)
+		}
+		return w.Flush()
+	},
+}
+
+var debugDataDirKeysCmd = &cobra.Command{
+	Use:   "keys [DIR] [PREFIX]",
+	Short: "List the keys in the raft data store",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := openDataDir(args[0])
+		if err != nil {
+			return err
+		}
+		defer dir.Close()
+		st, err := dir.OpenStorage()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+		var prefix string
+		if len(args) > 1 {
+			prefix = args[1]
+		}
+		if !datadirValues && !datadirJSON {
+			keys, err := st.List(cmd.Context(), prefix)
+			if err != nil {
+				return err
+			}
+			for _, key := range keys {
+				cmd.Println(key)
+			}
+			return nil
+		}
+		values := make(map[string]string)
+		err = st.IterPrefix(cmd.Context(), prefix, func(key, value string) error {
+			values[key] = value
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if datadirJSON {
+			return printJSON(cmd.OutOrStdout(), values)
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			cmd.Printf("%s\t%s\n", key, strconv.Quote(values[key]))
+		}
+		return nil
+	},
+}
+
+func openDataDir(path string) (*raft.DataDir, error) {
+	opts := raft.NewOptions(0)
+	opts.DataDir = path
+	opts.EncryptionKeyFile = datadirEncryptionKeyFile
+	opts.StorageBackend = datadirStorageBackend
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	return raft.OpenDataDir(log, opts)
+}
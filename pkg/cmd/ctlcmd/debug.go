@@ -18,13 +18,17 @@ package ctlcmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os/exec"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
+
+	"github.com/webmeshproj/webmesh/pkg/storage"
 )
 
 var (
@@ -35,6 +39,7 @@ func init() {
 	debugCmd.AddCommand(debugGetKeyCmd)
 	debugCmd.AddCommand(debugListKeysCmd)
 	debugCmd.AddCommand(debugPprofCmd)
+	debugCmd.AddCommand(debugDBStatsCmd)
 	debugCmd.PersistentFlags().StringVar(&debugServer, "debug-server", "http://localhost:6060/debug", "Address of the debug server")
 	rootCmd.AddCommand(debugCmd)
 }
@@ -97,6 +102,29 @@ var debugPprofCmd = &cobra.Command{
 	},
 }
 
+var debugDBStatsCmd = &cobra.Command{
+	Use:   "db-stats",
+	Short: "Show the key counts and sizes of the node's data store by prefix",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		stats, err := doDebugDBStats(cmd.Context())
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PREFIX\tKEYS\tBYTES")
+		var keys int
+		var bytes int64
+		for _, s := range stats {
+			fmt.Fprintf(w, "%s\t%d\t%d\n", s.Prefix, s.Keys, s.Bytes)
+			keys += s.Keys
+			bytes += s.Bytes
+		}
+		fmt.Fprintf(w, "TOTAL\t%d\t%d\n", keys, bytes)
+		return w.Flush()
+	},
+}
+
 func completeKeys(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	resp, err := doDebugListKeys(cmd.Context(), toComplete)
 	if err != nil {
@@ -146,6 +174,30 @@ func doDebugListKeys(ctx context.Context, prefix string) ([]string, error) {
 	return strings.Split(bodyStr, "\n"), nil
 }
 
+func doDebugDBStats(ctx context.Context) ([]storage.PrefixStats, error) {
+	req, err := newDebugDBRequest(ctx, "db/stats", "")
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bad status: %s: %s", resp.Status, string(body))
+	}
+	var stats []storage.PrefixStats
+	if err := json.Unmarshal(body, &stats); err != nil {
+		return nil, fmt.Errorf("decode stats: %w", err)
+	}
+	return stats, nil
+}
+
 func newDebugDBRequest(ctx context.Context, path string, query string) (*http.Request, error) {
 	debugServer = strings.TrimSuffix(debugServer, "/")
 	path = strings.TrimPrefix(path, "/")
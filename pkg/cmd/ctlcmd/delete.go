@@ -19,6 +19,8 @@ package ctlcmd
 import (
 	"github.com/spf13/cobra"
 	v1 "github.com/webmeshproj/api/v1"
+
+	"github.com/webmeshproj/webmesh/pkg/services/topology"
 )
 
 var (
@@ -40,6 +42,7 @@ func init() {
 	cobra.CheckErr(deleteEdgesCmd.MarkFlagRequired("from"))
 	cobra.CheckErr(deleteEdgesCmd.MarkFlagRequired("to"))
 	deleteCmd.AddCommand(deleteEdgesCmd)
+	deleteCmd.AddCommand(deleteTopologyPoliciesCmd)
 
 	rootCmd.AddCommand(deleteCmd)
 }
@@ -181,3 +184,25 @@ var deleteEdgesCmd = &cobra.Command{
 		return err
 	},
 }
+
+var deleteTopologyPoliciesCmd = &cobra.Command{
+	Use:     "topologypolicies",
+	Short:   "Delete topology policies and the edges they generated from the mesh",
+	Aliases: []string{"topologypolicy", "tp"},
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, closer, err := cliConfig.NewTopologyClient()
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+		for _, arg := range args {
+			_, err = client.DeletePolicy(cmd.Context(), &topology.Policy{Name: arg})
+			if err != nil {
+				return err
+			}
+			cmd.Println("Deleted topology policy", arg)
+		}
+		return nil
+	},
+}
@@ -17,19 +17,62 @@ limitations under the License.
 package ctlcmd
 
 import (
+	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/spf13/cobra"
 	v1 "github.com/webmeshproj/api/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/webmeshproj/webmesh/pkg/services/leaderproxy"
+	"github.com/webmeshproj/webmesh/pkg/services/topology"
 )
 
+func printRevision(cmd *cobra.Command, header metadata.MD) {
+	if !getShowRevision {
+		return
+	}
+	if rev := header.Get(leaderproxy.RevisionMeta); len(rev) > 0 {
+		cmd.PrintErrln("revision:", rev[0])
+	}
+}
+
+// listContext returns the context for a list request with the page
+// requested on the command line.
+func listContext(cmd *cobra.Command) context.Context {
+	ctx := cmd.Context()
+	if getLimit > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, leaderproxy.LimitMeta, strconv.Itoa(getLimit))
+	}
+	if getContinue != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, leaderproxy.ContinueMeta, getContinue)
+	}
+	return ctx
+}
+
+func printContinue(cmd *cobra.Command, header metadata.MD) {
+	if token := header.Get(leaderproxy.ContinueMeta); len(token) > 0 {
+		cmd.PrintErrln("continue:", token[0])
+	}
+}
+
 var (
-	getEdgeFrom string
-	getEdgeTo   string
+	getEdgeFrom     string
+	getEdgeTo       string
+	getShowRevision bool
+	getLimit        int
+	getContinue     string
+	getSelector     string
 )
 
 func init() {
+	getCmd.PersistentFlags().BoolVar(&getShowRevision, "show-revision", false, "Print the revision of a single resource to stderr")
+	getCmd.PersistentFlags().IntVar(&getLimit, "limit", 0, "The maximum number of resources to list, the token for the next page is printed to stderr")
+	getCmd.PersistentFlags().StringVar(&getContinue, "continue", "", "The token returned by a previous list to continue from")
+	getNodesCmd.Flags().StringVarP(&getSelector, "selector", "l", "", "Only list nodes with labels matching the selector, e.g. app=db,env in (prod,staging). Selected nodes are listed in a single page")
 	getCmd.AddCommand(getNodesCmd)
 	getCmd.AddCommand(getGraphCmd)
 	getCmd.AddCommand(getRolesCmd)
@@ -43,6 +86,7 @@ func init() {
 	cobra.CheckErr(getEdgesCmd.RegisterFlagCompletionFunc("from", completeNodes(1)))
 	cobra.CheckErr(getEdgesCmd.RegisterFlagCompletionFunc("to", completeNodes(1)))
 	getCmd.AddCommand(getEdgesCmd)
+	getCmd.AddCommand(getTopologyPoliciesCmd)
 
 	rootCmd.AddCommand(getCmd)
 }
@@ -73,10 +117,19 @@ var getNodesCmd = &cobra.Command{
 			}
 			return encodeToStdout(cmd, resp)
 		}
-		resp, err := client.ListNodes(cmd.Context(), &emptypb.Empty{})
+		if getSelector != "" && (getLimit > 0 || getContinue != "") {
+			return fmt.Errorf("--selector cannot be combined with --limit or --continue")
+		}
+		var header metadata.MD
+		ctx := listContext(cmd)
+		if getSelector != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, leaderproxy.LabelSelectorMeta, getSelector)
+		}
+		resp, err := client.ListNodes(ctx, &emptypb.Empty{}, grpc.Header(&header))
 		if err != nil {
 			return err
 		}
+		printContinue(cmd, header)
 		return encodeListToStdout(cmd, resp.Nodes)
 	},
 }
@@ -113,16 +166,20 @@ var getRolesCmd = &cobra.Command{
 		}
 		defer closer.Close()
 		if len(args) == 1 {
-			resp, err := client.GetRole(cmd.Context(), &v1.Role{Name: args[0]})
+			var header metadata.MD
+			resp, err := client.GetRole(cmd.Context(), &v1.Role{Name: args[0]}, grpc.Header(&header))
 			if err != nil {
 				return err
 			}
+			printRevision(cmd, header)
 			return encodeToStdout(cmd, resp)
 		}
-		resp, err := client.ListRoles(cmd.Context(), &emptypb.Empty{})
+		var header metadata.MD
+		resp, err := client.ListRoles(listContext(cmd), &emptypb.Empty{}, grpc.Header(&header))
 		if err != nil {
 			return err
 		}
+		printContinue(cmd, header)
 		return encodeListToStdout(cmd, resp.Items)
 	},
 }
@@ -140,16 +197,20 @@ var getRoleBindingsCmd = &cobra.Command{
 		}
 		defer closer.Close()
 		if len(args) == 1 {
-			resp, err := client.GetRoleBinding(cmd.Context(), &v1.RoleBinding{Name: args[0]})
+			var header metadata.MD
+			resp, err := client.GetRoleBinding(cmd.Context(), &v1.RoleBinding{Name: args[0]}, grpc.Header(&header))
 			if err != nil {
 				return err
 			}
+			printRevision(cmd, header)
 			return encodeToStdout(cmd, resp)
 		}
-		resp, err := client.ListRoleBindings(cmd.Context(), &emptypb.Empty{})
+		var header metadata.MD
+		resp, err := client.ListRoleBindings(listContext(cmd), &emptypb.Empty{}, grpc.Header(&header))
 		if err != nil {
 			return err
 		}
+		printContinue(cmd, header)
 		return encodeListToStdout(cmd, resp.Items)
 	},
 }
@@ -167,16 +228,20 @@ var getGroupsCmd = &cobra.Command{
 		}
 		defer closer.Close()
 		if len(args) == 1 {
-			resp, err := client.GetGroup(cmd.Context(), &v1.Group{Name: args[0]})
+			var header metadata.MD
+			resp, err := client.GetGroup(cmd.Context(), &v1.Group{Name: args[0]}, grpc.Header(&header))
 			if err != nil {
 				return err
 			}
+			printRevision(cmd, header)
 			return encodeToStdout(cmd, resp)
 		}
-		resp, err := client.ListGroups(cmd.Context(), &emptypb.Empty{})
+		var header metadata.MD
+		resp, err := client.ListGroups(listContext(cmd), &emptypb.Empty{}, grpc.Header(&header))
 		if err != nil {
 			return err
 		}
+		printContinue(cmd, header)
 		return encodeListToStdout(cmd, resp.Items)
 	},
 }
@@ -194,16 +259,20 @@ var getNetworkACLsCmd = &cobra.Command{
 		}
 		defer closer.Close()
 		if len(args) == 1 {
-			resp, err := client.GetNetworkACL(cmd.Context(), &v1.NetworkACL{Name: args[0]})
+			var header metadata.MD
+			resp, err := client.GetNetworkACL(cmd.Context(), &v1.NetworkACL{Name: args[0]}, grpc.Header(&header))
 			if err != nil {
 				return err
 			}
+			printRevision(cmd, header)
 			return encodeToStdout(cmd, resp)
 		}
-		resp, err := client.ListNetworkACLs(cmd.Context(), &emptypb.Empty{})
+		var header metadata.MD
+		resp, err := client.ListNetworkACLs(listContext(cmd), &emptypb.Empty{}, grpc.Header(&header))
 		if err != nil {
 			return err
 		}
+		printContinue(cmd, header)
 		return encodeListToStdout(cmd, resp.Items)
 	},
 }
@@ -221,16 +290,20 @@ var getRoutesCmd = &cobra.Command{
 		}
 		defer closer.Close()
 		if len(args) == 1 {
-			resp, err := client.GetRoute(cmd.Context(), &v1.Route{Name: args[0]})
+			var header metadata.MD
+			resp, err := client.GetRoute(cmd.Context(), &v1.Route{Name: args[0]}, grpc.Header(&header))
 			if err != nil {
 				return err
 			}
+			printRevision(cmd, header)
 			return encodeToStdout(cmd, resp)
 		}
-		resp, err := client.ListRoutes(cmd.Context(), &emptypb.Empty{})
+		var header metadata.MD
+		resp, err := client.ListRoutes(listContext(cmd), &emptypb.Empty{}, grpc.Header(&header))
 		if err != nil {
 			return err
 		}
+		printContinue(cmd, header)
 		return encodeListToStdout(cmd, resp.Items)
 	},
 }
@@ -246,19 +319,23 @@ var getEdgesCmd = &cobra.Command{
 		}
 		defer closer.Close()
 		if getEdgeFrom != "" && getEdgeTo != "" {
+			var header metadata.MD
 			resp, err := client.GetEdge(cmd.Context(), &v1.MeshEdge{
 				Source: getEdgeFrom,
 				Target: getEdgeTo,
-			})
+			}, grpc.Header(&header))
 			if err != nil {
 				return err
 			}
+			printRevision(cmd, header)
 			return encodeToStdout(cmd, resp)
 		}
-		resp, err := client.ListEdges(cmd.Context(), &emptypb.Empty{})
+		var header metadata.MD
+		resp, err := client.ListEdges(listContext(cmd), &emptypb.Empty{}, grpc.Header(&header))
 		if err != nil {
 			return err
 		}
+		printContinue(cmd, header)
 		// Filter the list if the user has specified a source or target
 		if getEdgeFrom != "" || getEdgeTo != "" {
 			filtered := make([]*v1.MeshEdge, 0)
@@ -276,3 +353,31 @@ var getEdgesCmd = &cobra.Command{
 		return encodeListToStdout(cmd, resp.Items)
 	},
 }
+
+var getTopologyPoliciesCmd = &cobra.Command{
+	Use:     "topologypolicies [NAME]",
+	Short:   "Get topology policies from the mesh",
+	Aliases: []string{"topologypolicy", "tp"},
+	Args:    cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, closer, err := cliConfig.NewTopologyClient()
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+		if len(args) == 1 {
+			var header metadata.MD
+			resp, err := client.GetPolicy(cmd.Context(), &topology.Policy{Name: args[0]}, grpc.Header(&header))
+			if err != nil {
+				return err
+			}
+			printRevision(cmd, header)
+			return encodeToStdout(cmd, resp)
+		}
+		resp, err := client.ListPolicies(cmd.Context(), &emptypb.Empty{})
+		if err != nil {
+			return err
+		}
+		return encodeListToStdout(cmd, resp.Items)
+	},
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ctlcmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	"github.com/webmeshproj/webmesh/pkg/services/labels"
+)
+
+func init() {
+	labelCmd.AddCommand(labelNodeCmd)
+	rootCmd.AddCommand(labelCmd)
+}
+
+var labelCmd = &cobra.Command{
+	Use:   "label",
+	Short: "Update the labels on resources in the mesh",
+}
+
+var labelNodeCmd = &cobra.Command{
+	Use:   "node [NODE_ID] [KEY=VALUE...] [KEY-...]",
+	Short: "Set or remove labels on a node and print its labels",
+	Long: `Set or remove labels on a node and print its labels.
+
+Labels are given as KEY=VALUE to set them and as KEY- to remove them.
+Labels set this way are kept when the node rejoins, labels the node joined
+with are replaced by the ones it rejoins with.
+Without any labels, the current labels of the node are printed.`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeNodes(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		set := make(map[string]string)
+		var remove []string
+		for _, arg := range args[1:] {
+			if key, ok := strings.CutSuffix(arg, "-"); ok && !strings.Contains(arg, "=") {
+				remove = append(remove, key)
+				continue
+			}
+			parsed, err := peers.ParseLabels(arg)
+			if err != nil {
+				return err
+			}
+			if len(parsed) != 1 {
+				return fmt.Errorf("invalid label %q: expected KEY=VALUE or KEY-", arg)
+			}
+			for k, v := range parsed {
+				set[k] = v
+			}
+		}
+		client, closer, err := cliConfig.NewLabelsClient()
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+		resp, err := client.LabelNode(cmd.Context(), &labels.LabelNodeRequest{
+			Node:         args[0],
+			SetLabels:    set,
+			RemoveLabels: remove,
+		})
+		if err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(resp.GetLabels()))
+		for key := range resp.GetLabels() {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s=%s\n", key, resp.GetLabels()[key])
+		}
+		return nil
+	},
+}
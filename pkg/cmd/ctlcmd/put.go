@@ -17,11 +17,18 @@ limitations under the License.
 package ctlcmd
 
 import (
+	"context"
 	"errors"
 	"strconv"
 
 	"github.com/spf13/cobra"
 	v1 "github.com/webmeshproj/api/v1"
+	"google.golang.org/grpc/metadata"
+
+	rbacdb "github.com/webmeshproj/webmesh/pkg/meshdb/rbac"
+	topologydb "github.com/webmeshproj/webmesh/pkg/meshdb/topology"
+	"github.com/webmeshproj/webmesh/pkg/services/leaderproxy"
+	"github.com/webmeshproj/webmesh/pkg/services/topology"
 )
 
 var (
@@ -55,11 +62,19 @@ var (
 	putEdgeTo     string
 	putEdgeWeight int32
 	putEdgeICE    bool
+
+	putTopologyPolicyType     string
+	putTopologyPolicySelector string
+	putTopologyPolicyHubs     string
+	putTopologyPolicyGateways string
+	putTopologyPolicyWeight   int
+
+	putIfRevision uint64
 )
 
 func init() {
 	putRoleFlags := putRoleCmd.Flags()
-	putRoleFlags.StringArrayVar(&putRoleVerbs, "verb", nil, "verbs to add to the role")
+	putRoleFlags.StringArrayVar(&putRoleVerbs, "verb", nil, "verbs to add to the role (put, delete, raft-transfer-leadership, raft-list, raft-add, raft-remove, raft-suffrage, raft-force-remove or *)")
 	putRoleFlags.StringArrayVar(&putRoleResources, "resource", nil, "resources to add to the role")
 	putRoleFlags.StringArrayVar(&putRoleResourceNames, "resource-name", nil, "resource names to add to the role")
 	cobra.CheckErr(putRoleCmd.MarkFlagRequired("verb"))
@@ -115,12 +130,26 @@ func init() {
 	cobra.CheckErr(putEdgeCmd.MarkFlagRequired("from"))
 	cobra.CheckErr(putEdgeCmd.MarkFlagRequired("to"))
 
+	putTopologyPolicyFlags := putTopologyPolicyCmd.Flags()
+	putTopologyPolicyFlags.StringVar(&putTopologyPolicyType, "type", "", "type of the policy (full-mesh, hub-and-spoke or zone-mesh)")
+	putTopologyPolicyFlags.StringVar(&putTopologyPolicySelector, "selector", "", "label selector for the nodes the policy applies to (default: all nodes)")
+	putTopologyPolicyFlags.StringVar(&putTopologyPolicyHubs, "hubs", "", "label selector for the hubs of a hub-and-spoke policy")
+	putTopologyPolicyFlags.StringVar(&putTopologyPolicyGateways, "gateways", "", "label selector for the gateways between zones of a zone-mesh policy")
+	putTopologyPolicyFlags.IntVar(&putTopologyPolicyWeight, "weight", topologydb.DefaultWeight, "weight of the edges the policy creates")
+	cobra.CheckErr(putTopologyPolicyCmd.MarkFlagRequired("type"))
+	cobra.CheckErr(putTopologyPolicyCmd.RegisterFlagCompletionFunc("type", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{string(topologydb.TypeFullMesh), string(topologydb.TypeHubAndSpoke), string(topologydb.TypeZoneMesh)}, cobra.ShellCompDirectiveNoFileComp
+	}))
+
+	putCmd.PersistentFlags().Uint64Var(&putIfRevision, "if-revision", 0, "only apply the change if the resource is still at this revision (see get --show-revision)")
+
 	putCmd.AddCommand(putRoleCmd)
 	putCmd.AddCommand(putRoleBindingCmd)
 	putCmd.AddCommand(putGroupCmd)
 	putCmd.AddCommand(putNetworkACLCmd)
 	putCmd.AddCommand(putRouteCmd)
 	putCmd.AddCommand(putEdgeCmd)
+	putCmd.AddCommand(putTopologyPolicyCmd)
 
 	rootCmd.AddCommand(putCmd)
 }
@@ -130,6 +159,15 @@ var putCmd = &cobra.Command{
 	Short: "Create or update resources in the mesh",
 }
 
+// putContext returns the context for a put request, requesting a conditional
+// write if --if-revision was given.
+func putContext(cmd *cobra.Command) context.Context {
+	if !cmd.Flags().Changed("if-revision") {
+		return cmd.Context()
+	}
+	return metadata.AppendToOutgoingContext(cmd.Context(), leaderproxy.IfRevisionMeta, strconv.FormatUint(putIfRevision, 10))
+}
+
 var putRoleCmd = &cobra.Command{
 	Use:               "roles [NAME]",
 	Short:             "Create or update a role with a single rule in the mesh",
@@ -140,6 +178,14 @@ var putRoleCmd = &cobra.Command{
 		if len(args) == 0 {
 			return errors.New("no role name specified")
 		}
+		verbs := make([]v1.RuleVerb, len(putRoleVerbs))
+		for i, verb := range putRoleVerbs {
+			v, err := rbacdb.ParseVerb(verb)
+			if err != nil {
+				return err
+			}
+			verbs[i] = v
+		}
 		role := &v1.Role{
 			Name: args[0],
 			Rules: []*v1.Rule{
@@ -166,20 +212,7 @@ var putRoleCmd = &cobra.Command{
 						}
 						return resources
 					}(),
-					Verbs: func() []v1.RuleVerb {
-						verbs := make([]v1.RuleVerb, len(putRoleVerbs))
-						for i, verb := range putRoleVerbs {
-							switch verb {
-							case "put":
-								verbs[i] = v1.RuleVerb_VERB_PUT
-							case "delete":
-								verbs[i] = v1.RuleVerb_VERB_DELETE
-							case "*":
-								verbs[i] = v1.RuleVerb_VERB_ALL
-							}
-						}
-						return verbs
-					}(),
+					Verbs:         verbs,
 					ResourceNames: putRoleResourceNames,
 				},
 			},
@@ -189,7 +222,7 @@ var putRoleCmd = &cobra.Command{
 			return err
 		}
 		defer closer.Close()
-		_, err = client.PutRole(cmd.Context(), role)
+		_, err = client.PutRole(putContext(cmd), role)
 		if err != nil {
 			return err
 		}
@@ -242,7 +275,7 @@ var putRoleBindingCmd = &cobra.Command{
 			return err
 		}
 		defer closer.Close()
-		_, err = client.PutRoleBinding(cmd.Context(), roleBinding)
+		_, err = client.PutRoleBinding(putContext(cmd), roleBinding)
 		if err != nil {
 			return err
 		}
@@ -288,7 +321,7 @@ var putGroupCmd = &cobra.Command{
 			return err
 		}
 		defer closer.Close()
-		_, err = client.PutGroup(cmd.Context(), group)
+		_, err = client.PutGroup(putContext(cmd), group)
 		if err != nil {
 			return err
 		}
@@ -345,7 +378,7 @@ var putNetworkACLCmd = &cobra.Command{
 			return err
 		}
 		defer closer.Close()
-		_, err = client.PutNetworkACL(cmd.Context(), networkACL)
+		_, err = client.PutNetworkACL(putContext(cmd), networkACL)
 		if err != nil {
 			return err
 		}
@@ -375,7 +408,7 @@ var putRouteCmd = &cobra.Command{
 			return err
 		}
 		defer closer.Close()
-		_, err = client.PutRoute(cmd.Context(), route)
+		_, err = client.PutRoute(putContext(cmd), route)
 		if err != nil {
 			return err
 		}
@@ -403,7 +436,7 @@ var putEdgeCmd = &cobra.Command{
 		if putEdgeICE {
 			edge.Attributes[v1.EdgeAttributes_EDGE_ATTRIBUTE_ICE.String()] = "true"
 		}
-		_, err = client.PutEdge(cmd.Context(), edge)
+		_, err = client.PutEdge(putContext(cmd), edge)
 		if err != nil {
 			return err
 		}
@@ -411,3 +444,46 @@ var putEdgeCmd = &cobra.Command{
 		return nil
 	},
 }
+
+var putTopologyPolicyCmd = &cobra.Command{
+	Use:   "topologypolicies [NAME]",
+	Short: "Create or update a topology policy that the leader generates edges from",
+	Long: `Create or update a topology policy that the leader generates edges from.
+
+A full-mesh policy connects every selected node to every other selected node.
+A hub-and-spoke policy connects every selected node to the nodes selected by
+--hubs. A zone-mesh policy connects the selected nodes within each zone, and
+the nodes selected by --gateways across zones.
+
+Generated edges are marked with the policy name. The leader creates and
+removes them as nodes join and leave, and never changes other edges.`,
+	Aliases: []string{"topologypolicy", "tp"},
+	Args:    cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("no topology policy name specified")
+		}
+		policy := topologydb.Policy{
+			Name:     args[0],
+			Type:     topologydb.Type(putTopologyPolicyType),
+			Selector: putTopologyPolicySelector,
+			Hubs:     putTopologyPolicyHubs,
+			Gateways: putTopologyPolicyGateways,
+			Weight:   putTopologyPolicyWeight,
+		}
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+		client, closer, err := cliConfig.NewTopologyClient()
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+		_, err = client.PutPolicy(putContext(cmd), topology.NewPolicy(policy))
+		if err != nil {
+			return err
+		}
+		cmd.Println("put topology policy", policy.Name)
+		return nil
+	},
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ctlcmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/webmeshproj/webmesh/pkg/services/leadership"
+	"github.com/webmeshproj/webmesh/pkg/services/membership"
+)
+
+var (
+	raftAddServerVoter bool
+)
+
+func init() {
+	raftAddServerCmd.Flags().BoolVar(&raftAddServerVoter, "voter", false, "add the server as a voter")
+	raftCmd.AddCommand(raftTransferLeaderCmd)
+	raftCmd.AddCommand(raftServersCmd)
+	raftCmd.AddCommand(raftAddServerCmd)
+	raftCmd.AddCommand(raftRemoveServerCmd)
+	raftCmd.AddCommand(raftPromoteCmd)
+	raftCmd.AddCommand(raftDemoteCmd)
+	raftCmd.AddCommand(raftForceRemoveCmd)
+	rootCmd.AddCommand(raftCmd)
+}
+
+var raftCmd = &cobra.Command{
+	Use:   "raft",
+	Short: "Manage the raft cluster backing the mesh",
+}
+
+var raftTransferLeaderCmd = &cobra.Command{
+	Use:   "transfer-leader [NODE]",
+	Short: "Transfer raft leadership to another voter",
+	Long: `Transfer raft leadership to another voter.
+
+If NODE is given, leadership is transferred to that voter. Otherwise it is
+transferred to the most up-to-date voter. This is useful for moving the leader
+off of a node before maintenance.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeNodes(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var target string
+		if len(args) > 0 {
+			target = args[0]
+		}
+		client, closer, err := cliConfig.NewLeadershipClient()
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+		_, err = client.TransferLeadership(cmd.Context(), &leadership.TransferLeadershipRequest{Id: target})
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			cmd.Println("Leadership transferred")
+		} else {
+			cmd.Printf("Leadership transferred to %s\n", target)
+		}
+		return nil
+	},
+}
+
+var raftServersCmd = &cobra.Command{
+	Use:     "servers",
+	Short:   "List the servers in the raft configuration",
+	Aliases: []string{"list-servers"},
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		client, closer, err := cliConfig.NewMembershipClient()
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+		resp, err := client.ListServers(cmd.Context(), &emptypb.Empty{})
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tADDRESS\tSUFFRAGE\tLEADER\tLAST CONTACT\tAPPLIED")
+		for _, srv := range resp.GetServers() {
+			lastContact := "-"
+			if srv.GetLastContact() != nil {
+				lastContact = srv.GetLastContact().AsTime().Format(time.RFC3339)
+			}
+			applied := fmt.Sprintf("%d", srv.GetAppliedIndex())
+			if srv.GetError() != "" {
+				applied = "unreachable"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\t%s\n",
+				srv.GetId(),
+				srv.GetAddress(),
+				suffrageName(srv.GetSuffrage()),
+				srv.GetLeader(),
+				lastContact,
+				applied,
+			)
+		}
+		return w.Flush()
+	},
+}
+
+var raftAddServerCmd = &cobra.Command{
+	Use:   "add-server [ID] [ADDRESS]",
+	Short: "Add a server to the raft configuration",
+	Long: `Add a server to the raft configuration.
+
+ADDRESS is the raft address of the server. The server is added as a non-voter
+unless --voter is given.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, closer, err := cliConfig.NewMembershipClient()
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+		_, err = client.AddServer(cmd.Context(), &membership.AddServerRequest{
+			Id:      args[0],
+			Address: args[1],
+			Voter:   raftAddServerVoter,
+		})
+		if err != nil {
+			return err
+		}
+		cmd.Printf("Added server %s\n", args[0])
+		return nil
+	},
+}
+
+var raftRemoveServerCmd = &cobra.Command{
+	Use:               "remove-server [ID]",
+	Short:             "Remove a server from the raft configuration",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeNodes(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, closer, err := cliConfig.NewMembershipClient()
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+		_, err = client.RemoveServer(cmd.Context(), &membership.ServerRequest{Id: args[0]})
+		if err != nil {
+			return err
+		}
+		cmd.Printf("Removed server %s\n", args[0])
+		return nil
+	},
+}
+
+var raftPromoteCmd = &cobra.Command{
+	Use:               "promote [ID]",
+	Short:             "Promote a non-voter to a voter",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeNodes(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, closer, err := cliConfig.NewMembershipClient()
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+		_, err = client.PromoteVoter(cmd.Context(), &membership.ServerRequest{Id: args[0]})
+		if err != nil {
+			return err
+		}
+		cmd.Printf("Promoted %s to voter\n", args[0])
+		return nil
+	},
+}
+
+var raftDemoteCmd = &cobra.Command{
+	Use:               "demote [ID]",
+	Short:             "Demote a voter to a non-voter",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeNodes(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, closer, err := cliConfig.NewMembershipClient()
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+		_, err = client.DemoteVoter(cmd.Context(), &membership.ServerRequest{Id: args[0]})
+		if err != nil {
+			return err
+		}
+		cmd.Printf("Demoted %s to non-voter\n", args[0])
+		return nil
+	},
+}
+
+var raftForceRemoveCmd = &cobra.Command{
+	Use:   "force-remove [ID]",
+	Short: "Force remove a dead server from the mesh",
+	Long: `Force remove a dead server from the mesh.
+
+The server is removed from the raft configuration without waiting on it and its
+registration is deleted from the mesh. Only use this for servers that will not
+come back.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeNodes(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, closer, err := cliConfig.NewMembershipClient()
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+		_, err = client.ForceRemoveServer(cmd.Context(), &membership.ServerRequest{Id: args[0]})
+		if err != nil {
+			return err
+		}
+		cmd.Printf("Force removed server %s\n", args[0])
+		return nil
+	},
+}
+
+// suffrageName returns the name raft gives the suffrage.
+func suffrageName(s membership.Suffrage) string {
+	switch s {
+	case membership.Suffrage_SUFFRAGE_VOTER:
+		return "Voter"
+	case membership.Suffrage_SUFFRAGE_NONVOTER:
+		return "Nonvoter"
+	case membership.Suffrage_SUFFRAGE_STAGING:
+		return "Staging"
+	default:
+		return "Unknown"
+	}
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ctlcmd
+
+import (
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func init() {
+	rootCmd.AddCommand(rebuildIndexesCmd)
+}
+
+var rebuildIndexesCmd = &cobra.Command{
+	Use:   "rebuild-indexes",
+	Short: "Rebuild the secondary indexes over nodes in the mesh database",
+	Long: `Rebuild the secondary indexes over nodes in the mesh database.
+
+Indexes are maintained automatically as nodes are written. This is only needed
+once for meshes created before the indexes existed or before an upgrade added
+new ones, until then node queries fall back to scanning every node.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		client, closer, err := cliConfig.NewMaintenanceClient()
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+		resp, err := client.RebuildIndexes(cmd.Context(), &emptypb.Empty{})
+		if err != nil {
+			return err
+		}
+		cmd.Printf("Indexed %d nodes\n", resp.GetNodes())
+		return nil
+	},
+}
@@ -17,20 +17,32 @@ limitations under the License.
 package ctlcmd
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"os"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 	v1 "github.com/webmeshproj/api/v1"
+
+	"github.com/webmeshproj/webmesh/pkg/meshdb/snapshots"
 )
 
 var (
-	snapshotOutput string
+	snapshotOutput      string
+	snapshotInspectKeys bool
+	snapshotJSON        bool
 )
 
 func init() {
 	fl := snapshotCmd.Flags()
 	fl.StringVar(&snapshotOutput, "output", "", "Output file (default: stdout)")
+	snapshotInspectCmd.Flags().BoolVar(&snapshotInspectKeys, "keys", false, "List every key in the snapshot")
+	snapshotCmd.PersistentFlags().BoolVar(&snapshotJSON, "json", false, "Print inspect and diff results as JSON")
+	snapshotCmd.AddCommand(snapshotInspectCmd)
+	snapshotCmd.AddCommand(snapshotDiffCmd)
 	rootCmd.AddCommand(snapshotCmd)
 }
 
@@ -63,3 +75,91 @@ var snapshotCmd = &cobra.Command{
 		return err
 	},
 }
+
+var snapshotInspectCmd = &cobra.Command{
+	Use:   "inspect [FILE]",
+	Short: "Show the contents of a snapshot file without restoring it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inspection, err := inspectSnapshotFile(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		if snapshotJSON {
+			return printJSON(cmd.OutOrStdout(), inspection)
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PREFIX\tKEYS\tBYTES")
+		for _, p := range inspection.Prefixes {
+			fmt.Fprintf(w, "%s\t%d\t%d\n", p.Prefix, p.Keys, p.Bytes)
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "KIND\tNAME")
+		for _, r := range inspection.Resources {
+			fmt.Fprintf(w, "%s\t%s\n", r.Kind, r.Name)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if snapshotInspectKeys {
+			cmd.Println()
+			for _, key := range inspection.Keys {
+				cmd.Println(key)
+			}
+		}
+		return nil
+	},
+}
+
+var snapshotDiffCmd = &cobra.Command{
+	Use:   "diff [A] [B]",
+	Short: "Show the resources added, removed or changed between two snapshot files",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := inspectSnapshotFile(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		b, err := inspectSnapshotFile(cmd.Context(), args[1])
+		if err != nil {
+			return err
+		}
+		changes := snapshots.Diff(a, b)
+		if snapshotJSON {
+			return printJSON(cmd.OutOrStdout(), changes)
+		}
+		for _, c := range changes {
+			switch c.Type {
+			case snapshots.ChangeAdded:
+				cmd.Printf("+ %s %s\n", c.Kind, c.Name)
+			case snapshots.ChangeRemoved:
+				cmd.Printf("- %s %s\n", c.Kind, c.Name)
+			case snapshots.ChangeChanged:
+				cmd.Printf("~ %s %s\n", c.Kind, c.Name)
+				cmd.Printf("    old: %s\n", c.Old)
+				cmd.Printf("    new: %s\n", c.New)
+			}
+		}
+		return nil
+	},
+}
+
+func inspectSnapshotFile(ctx context.Context, path string) (*snapshots.Inspection, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := snapshots.Load(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", path, err)
+	}
+	defer st.Close()
+	return snapshots.Inspect(ctx, st)
+}
+
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
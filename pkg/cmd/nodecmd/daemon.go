@@ -174,7 +174,7 @@ func (app *AppDaemon) Connect(ctx context.Context, req *v1.ConnectRequest) (*v1.
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "error creating mesh: %v", err)
 	}
-	err = conn.Open(ctx, cfg.Services.ToFeatureSet())
+	err = services.OpenMesh(ctx, conn, cfg.Mesh, cfg.Services, cfg.Services.ToFeatureSet())
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "error opening mesh: %v", err)
 	}
@@ -142,7 +142,7 @@ func executeSingleMesh(ctx context.Context, config *Options) error {
 	if !config.Global.DisableFeatureAdvertisement {
 		features = config.Services.ToFeatureSet()
 	}
-	err = st.Open(ctx, features)
+	err = services.OpenMesh(ctx, st, config.Mesh, config.Services, features)
 	if err != nil {
 		return fmt.Errorf("failed to open mesh connection: %w", err)
 	}
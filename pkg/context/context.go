@@ -60,6 +60,12 @@ func WithCancel(ctx Context) (Context, CancelFunc) {
 	return context.WithCancel(ctx)
 }
 
+// AfterFunc arranges to call f in its own goroutine after ctx is done.
+// Calling the returned stop function stops the association of ctx with f.
+func AfterFunc(ctx Context, f func()) (stop func() bool) {
+	return context.AfterFunc(ctx, f)
+}
+
 type logContextKey struct{}
 
 // WithLogger returns a context with the given logger set.
@@ -42,6 +42,7 @@ import (
 	"github.com/webmeshproj/webmesh/pkg/plugins/builtins/basicauth"
 	"github.com/webmeshproj/webmesh/pkg/plugins/builtins/ldap"
 	"github.com/webmeshproj/webmesh/pkg/raft"
+	"github.com/webmeshproj/webmesh/pkg/raft/autopilot"
 	"github.com/webmeshproj/webmesh/pkg/storage"
 )
 
@@ -131,6 +132,7 @@ func NewWithLogger(opts *Options, log *slog.Logger) (Mesh, error) {
 		kvSubCancel:      func() {},
 		closec:           make(chan struct{}),
 		campfires:        make(map[string]campfire.CampfireChannel),
+		voterHealth:      autopilot.NewHealth(),
 	}
 	return st, nil
 }
@@ -191,6 +193,7 @@ type meshStore struct {
 	campfiremu       sync.Mutex
 	open             atomic.Bool
 	closec           chan struct{}
+	voterHealth      *autopilot.Health
 	// a flag set on test stores to indicate skipping certain operations
 	testStore bool
 }
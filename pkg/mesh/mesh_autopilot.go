@@ -0,0 +1,208 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mesh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/hashicorp/raft"
+	v1 "github.com/webmeshproj/api/v1"
+
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/rbac"
+	"github.com/webmeshproj/webmesh/pkg/raft/autopilot"
+)
+
+// autopilotInterval is how often the leader reconciles the voters in the cluster
+// and its own placement.
+const autopilotInterval = 5 * time.Second
+
+// runAutopilot keeps the configured number of voters in the cluster and hands
+// leadership off to preferred voters while this node is the leader.
+func (s *meshStore) runAutopilot() {
+	ticker := time.NewTicker(autopilotInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.closec:
+			return
+		case <-ticker.C:
+		}
+		if !s.raft.IsLeader() {
+			s.voterHealth.Reset()
+			continue
+		}
+		if err := s.runAutopilotOnce(time.Now()); err != nil {
+			s.log.Warn("Failed to run autopilot", slog.String("error", err.Error()))
+		}
+	}
+}
+
+func (s *meshStore) runAutopilotOnce(now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	servers, addrs, err := s.autopilotServers(ctx, now)
+	if err != nil {
+		return err
+	}
+	if s.opts.Mesh.AutopilotVoters > 0 {
+		changed, err := s.reconcileVoters(ctx, servers, addrs, now)
+		if err != nil || changed {
+			// Leadership is only considered against a settled configuration.
+			return err
+		}
+	}
+	return s.reconcileLeader(ctx, servers, now)
+}
+
+// autopilotServers returns the autopilot view of the servers in the raft
+// configuration and their addresses.
+func (s *meshStore) autopilotServers(ctx context.Context, now time.Time) ([]autopilot.Server, map[string]string, error) {
+	eligible, err := s.eligibleVoters(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	config := s.raft.Configuration()
+	ids := make([]string, len(config.Servers))
+	addrs := make(map[string]string, len(config.Servers))
+	for i, server := range config.Servers {
+		ids[i] = string(server.ID)
+		addrs[string(server.ID)] = string(server.Address)
+	}
+	since, failingSince := s.voterHealth.Observe(ids, now)
+	p := peers.New(s.Storage())
+	servers := make([]autopilot.Server, len(config.Servers))
+	for i, server := range config.Servers {
+		id := string(server.ID)
+		srv := autopilot.Server{
+			ID:           id,
+			Voter:        server.Suffrage == raft.Voter,
+			Leader:       id == s.nodeID,
+			Eligible:     eligible(id),
+			Since:        since[id],
+			FailingSince: failingSince[id],
+		}
+		if node, err := p.Get(ctx, id); err == nil {
+			srv.Zone = node.ZoneAwarenessID
+		}
+		servers[i] = srv
+	}
+	return servers, addrs, nil
+}
+
+// reconcileVoters applies the voter changes planned by autopilot. It returns
+// true if any change was made.
+func (s *meshStore) reconcileVoters(ctx context.Context, servers []autopilot.Server, addrs map[string]string, now time.Time) (bool, error) {
+	actions := autopilot.Plan(autopilot.Config{
+		Voters:          s.opts.Mesh.AutopilotVoters,
+		Stabilization:   s.opts.Mesh.AutopilotStabilization,
+		RemoveUnhealthy: s.opts.Mesh.AutopilotRemoveUnhealthy,
+	}, servers, now)
+	for i, action := range actions {
+		log := s.log.With(
+			slog.String("action", string(action.Type)),
+			slog.String("node", action.Server),
+			slog.String("zone", action.Zone),
+			slog.String("reason", action.Reason),
+		)
+		var err error
+		switch action.Type {
+		case autopilot.ActionPromote:
+			err = s.raft.AddVoter(ctx, action.Server, addrs[action.Server])
+		case autopilot.ActionDemote:
+			err = s.raft.DemoteVoter(ctx, action.Server)
+		case autopilot.ActionRemove:
+			err = s.raft.RemoveServer(ctx, action.Server, false)
+		}
+		if err != nil {
+			// Later actions were planned assuming this one succeeded.
+			return i > 0, fmt.Errorf("autopilot %s %q: %w", action.Type, action.Server, err)
+		}
+		log.Info("Autopilot changed voter membership")
+		s.emitAutopilotEvent(ctx, action)
+	}
+	return len(actions) > 0, nil
+}
+
+// reconcileLeader hands leadership off to a preferred voter if one is healthy.
+func (s *meshStore) reconcileLeader(ctx context.Context, servers []autopilot.Server, now time.Time) error {
+	pref := autopilot.Preference{
+		Zone:       s.opts.Mesh.LeaderPreferredZone,
+		Priorities: s.opts.Mesh.LeaderPriorities,
+	}
+	target, ok := autopilot.PreferredLeader(pref, s.opts.Mesh.AutopilotStabilization, servers, now)
+	if !ok {
+		return nil
+	}
+	s.log.Info("Handing leadership off to preferred voter",
+		slog.String("node", target.ID), slog.String("zone", target.Zone))
+	if err := s.raft.TransferLeadership(ctx, target.ID); err != nil {
+		return fmt.Errorf("transfer leadership to %q: %w", target.ID, err)
+	}
+	return nil
+}
+
+// eligibleVoters returns a function reporting whether a node is a member of the
+// voters group.
+func (s *meshStore) eligibleVoters(ctx context.Context) (func(string) bool, error) {
+	group, err := rbac.New(s.Storage()).GetGroup(ctx, rbac.VotersGroup)
+	if err != nil {
+		if errors.Is(err, rbac.ErrGroupNotFound) {
+			return func(string) bool { return false }, nil
+		}
+		return nil, fmt.Errorf("get voters group: %w", err)
+	}
+	members := make(map[string]struct{})
+	for _, subject := range group.GetSubjects() {
+		if subject.GetType() == v1.SubjectType_SUBJECT_NODE || subject.GetType() == v1.SubjectType_SUBJECT_ALL {
+			members[subject.GetName()] = struct{}{}
+		}
+	}
+	return func(id string) bool {
+		_, all := members["*"]
+		_, ok := members[id]
+		return all || ok
+	}, nil
+}
+
+// emitAutopilotEvent reports a suffrage change to plugin watchers. Removals are
+// already reported as node leave events by the raft observer.
+func (s *meshStore) emitAutopilotEvent(ctx context.Context, action autopilot.Action) {
+	if !s.plugins.HasWatchers() || action.Type == autopilot.ActionRemove {
+		return
+	}
+	node, err := peers.New(s.Storage()).Get(ctx, action.Server)
+	if err != nil {
+		s.log.Warn("failed to lookup peer, can't emit event", slog.String("error", err.Error()))
+		return
+	}
+	status := v1.ClusterStatus_CLUSTER_VOTER
+	if action.Type == autopilot.ActionDemote {
+		status = v1.ClusterStatus_CLUSTER_NON_VOTER
+	}
+	err = s.plugins.Emit(ctx, &v1.Event{
+		Type:  v1.WatchEvent_WATCH_EVENT_NODE_JOIN,
+		Event: &v1.Event_Node{Node: node.Proto(status)},
+	})
+	if err != nil {
+		s.log.Warn("error sending autopilot event", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mesh
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/webmeshproj/webmesh/pkg/meshdb/backups"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/snapshots"
+)
+
+// startBackups starts taking scheduled backups whenever we are the leader.
+func (s *meshStore) startBackups() error {
+	dest, err := s.opts.Backup.NewDestination()
+	if err != nil {
+		return fmt.Errorf("create backup destination: %w", err)
+	}
+	// Backups are encrypted with the storage key so they are protected
+	// as well as the data they were taken from.
+	encryption, err := s.opts.Raft.Encryption()
+	if err != nil {
+		return fmt.Errorf("load storage encryption key: %w", err)
+	}
+	opts := backups.Options{
+		Interval: s.opts.Backup.Interval,
+		Retain:   s.opts.Backup.Retain,
+	}
+	if encryption != nil {
+		opts.EncryptionKey = encryption.Key
+	}
+	m := backups.NewManager(snapshots.New(s.raft.Storage()), dest, opts, s.log.With("component", "backups"))
+	s.log.Info("Starting scheduled backups",
+		slog.String("interval", s.opts.Backup.Interval.String()),
+		slog.Int("retain", s.opts.Backup.Retain),
+		slog.Bool("encrypted", encryption != nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.closec
+		cancel()
+	}()
+	go m.Run(ctx, s.raft.IsLeader)
+	return nil
+}
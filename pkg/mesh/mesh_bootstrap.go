@@ -125,11 +125,15 @@ func (s *meshStore) initialBootstrapLeader(ctx context.Context, features []v1.Fe
 	s.log.Info("newly bootstrapped cluster, setting IPv4/IPv6 networks",
 		slog.String("ipv4-network", s.opts.Bootstrap.IPv4Network),
 		slog.String("ipv6-network", meshnetworkv6.String()))
-	err = s.Storage().Put(ctx, state.IPv6PrefixKey, meshnetworkv6.String(), 0)
+	// All of the initial cluster state is staged in a single transaction and
+	// committed as one raft entry once it is complete.
+	tx := storage.NewTxn(s.Storage())
+	defer tx.Rollback()
+	err = tx.Put(ctx, state.IPv6PrefixKey, meshnetworkv6.String(), 0)
 	if err != nil {
 		return fmt.Errorf("set IPv6 prefix to db: %w", err)
 	}
-	err = s.Storage().Put(ctx, state.IPv4PrefixKey, meshnetworkv4.String(), 0)
+	err = tx.Put(ctx, state.IPv4PrefixKey, meshnetworkv4.String(), 0)
 	if err != nil {
 		return fmt.Errorf("set IPv4 prefix to db: %w", err)
 	}
@@ -137,13 +141,13 @@ func (s *meshStore) initialBootstrapLeader(ctx context.Context, features []v1.Fe
 	if !strings.HasSuffix(s.meshDomain, ".") {
 		s.meshDomain += "."
 	}
-	err = s.Storage().Put(ctx, state.MeshDomainKey, s.meshDomain, 0)
+	err = tx.Put(ctx, state.MeshDomainKey, s.meshDomain, 0)
 	if err != nil {
 		return fmt.Errorf("set mesh domain to db: %w", err)
 	}
 
 	// Initialize the RBAC system.
-	rb := rbac.New(s.Storage())
+	rb := rbac.New(tx)
 
 	// Create an admin role and add the admin user/node to it.
 	err = rb.PutRole(ctx, &v1.Role{
@@ -234,7 +238,7 @@ func (s *meshStore) initialBootstrapLeader(ctx context.Context, features []v1.Fe
 	}
 
 	// Initialize the Networking system.
-	nw := networking.New(s.Storage())
+	nw := networking.New(tx)
 
 	// Create a network ACL that ensures bootstrap servers and admins can continue to
 	// communicate with each other.
@@ -283,7 +287,11 @@ func (s *meshStore) initialBootstrapLeader(ctx context.Context, features []v1.Fe
 	// address. This is done by creating a new node in the database and then
 	// readding it to the cluster as a voter with the acquired address.
 	s.log.Info("Registering ourselves as a node in the cluster", slog.String("server-id", s.ID()))
-	p := peers.New(s.Storage())
+	p := peers.New(tx)
+	// Start with empty node indexes so they are maintained from the first write.
+	if _, err := p.RebuildIndexes(ctx); err != nil {
+		return fmt.Errorf("initialize node indexes: %w", err)
+	}
 	self := peers.Node{
 		ID:                 s.ID(),
 		GRPCPort:           s.opts.Mesh.GRPCAdvertisePort,
@@ -292,6 +300,7 @@ func (s *meshStore) initialBootstrapLeader(ctx context.Context, features []v1.Fe
 		WireGuardEndpoints: s.opts.WireGuard.Endpoints,
 		ZoneAwarenessID:    s.opts.Mesh.ZoneAwarenessID,
 		Features:           features,
+		Labels:             s.opts.Mesh.Labels,
 	}
 	// Go ahead and generate our private key.
 	s.log.Info("Generating wireguard key for ourselves")
@@ -393,6 +402,11 @@ func (s *meshStore) initialBootstrapLeader(ctx context.Context, features []v1.Fe
 			}
 		}
 	}
+	s.log.Debug("Committing initial cluster state", slog.Int("ops", len(tx.Ops())))
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("commit initial cluster state: %w", err)
+	}
 	// Determine what our raft address will be
 	var raftAddr string
 	if !s.opts.Mesh.NoIPv4 && !s.opts.Raft.PreferIPv6 {
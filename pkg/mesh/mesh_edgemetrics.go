@@ -0,0 +1,169 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mesh
+
+import (
+	"context"
+	"log/slog"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/dominikbraun/graph"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	"github.com/webmeshproj/webmesh/pkg/services/edgemetrics"
+	"github.com/webmeshproj/webmesh/pkg/util"
+)
+
+const (
+	// edgeProbeCount is the number of echo requests sent to each direct peer per probe.
+	edgeProbeCount = 5
+	// edgeProbeTimeout is how long a probe of a single direct peer may take.
+	edgeProbeTimeout = 5 * time.Second
+	// edgeMeasurementsStaleAfter is how many probe intervals a measurement is
+	// used to derive edge weights for after it was taken.
+	edgeMeasurementsStaleAfter = 3
+)
+
+// probeEdges periodically measures the links to direct peers and reports
+// the measurements to the leader until the store is closed.
+func (s *meshStore) probeEdges() {
+	ticker := time.NewTicker(s.opts.Mesh.EdgeProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.closec:
+			return
+		case <-ticker.C:
+		}
+		if err := s.reportEdgeMeasurements(); err != nil {
+			s.log.Warn("Failed to report edge measurements", slog.String("error", err.Error()))
+		}
+	}
+}
+
+func (s *meshStore) reportEdgeMeasurements() error {
+	wg := s.nw.WireGuard()
+	if wg == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), edgeProbeTimeout+10*time.Second)
+	defer cancel()
+	db := peers.New(s.Storage())
+	report := &edgemetrics.Report{
+		Node:  s.ID(),
+		Peers: make(map[string]*edgemetrics.Measurement),
+	}
+	var mu sync.Mutex
+	var probes sync.WaitGroup
+	for _, id := range wg.Peers() {
+		node, err := db.Get(ctx, id)
+		if err != nil {
+			s.log.Debug("Skipping probe of unknown peer", slog.String("peer", id), slog.String("error", err.Error()))
+			continue
+		}
+		var addr netip.Addr
+		if !s.opts.Mesh.NoIPv4 && node.PrivateIPv4.IsValid() {
+			addr = node.PrivateIPv4.Addr()
+		} else if !s.opts.Mesh.NoIPv6 && node.PrivateIPv6.IsValid() {
+			addr = node.PrivateIPv6.Addr()
+		} else {
+			continue
+		}
+		probes.Add(1)
+		go func(id string, addr netip.Addr) {
+			defer probes.Done()
+			ctx, cancel := context.WithTimeout(ctx, edgeProbeTimeout)
+			defer cancel()
+			rtt, loss, err := util.PingStats(ctx, addr, edgeProbeCount)
+			if err != nil {
+				s.log.Debug("Failed to probe peer", slog.String("peer", id), slog.String("error", err.Error()))
+				return
+			}
+			m := &edgemetrics.Measurement{
+				Loss:       loss,
+				MeasuredAt: timestamppb.Now(),
+			}
+			// The round trip time is only meaningful if a probe was answered.
+			if loss < 1 {
+				m.Rtt = durationpb.New(rtt)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			report.Peers[id] = m
+		}(id, addr)
+	}
+	probes.Wait()
+	if len(report.Peers) == 0 {
+		return nil
+	}
+	conn, err := s.DialLeader(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = edgemetrics.NewEdgeMetricsClient(conn).Report(ctx, report)
+	return err
+}
+
+// deriveEdgeWeights sets the weights of measured edges from their measurements
+// while this node is the leader.
+func (s *meshStore) deriveEdgeWeights() {
+	ticker := time.NewTicker(s.opts.Mesh.EdgeProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.closec:
+			return
+		case <-ticker.C:
+		}
+		if !s.raft.IsLeader() {
+			continue
+		}
+		if err := s.updateEdgeWeights(time.Now().UTC()); err != nil {
+			s.log.Warn("Failed to update edge weights", slog.String("error", err.Error()))
+		}
+	}
+}
+
+func (s *meshStore) updateEdgeWeights(now time.Time) error {
+	g := peers.New(s.Storage()).Graph()
+	edges, err := g.Edges()
+	if err != nil {
+		return err
+	}
+	staleAfter := edgeMeasurementsStaleAfter * s.opts.Mesh.EdgeProbeInterval
+	for _, edge := range edges {
+		m, ok := peers.MeasurementFromAttrs(edge.Properties.Attributes)
+		if !ok || now.Sub(m.MeasuredAt) > staleAfter {
+			continue
+		}
+		weight, ok := m.DeriveWeight(edge.Properties.Weight)
+		if !ok {
+			continue
+		}
+		log := s.log.With(slog.String("source", edge.Source), slog.String("target", edge.Target))
+		log.Debug("Updating edge weight from measurements", slog.Int("old", edge.Properties.Weight), slog.Int("new", weight))
+		if err := g.UpdateEdge(edge.Source, edge.Target, graph.EdgeWeight(weight)); err != nil {
+			log.Warn("Failed to update edge weight", slog.String("error", err.Error()))
+		}
+	}
+	return nil
+}
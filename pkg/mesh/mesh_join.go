@@ -27,12 +27,18 @@ import (
 	v1 "github.com/webmeshproj/api/v1"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/webmeshproj/webmesh/pkg/campfire"
 	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
 	meshnet "github.com/webmeshproj/webmesh/pkg/net"
+	"github.com/webmeshproj/webmesh/pkg/raft"
+	"github.com/webmeshproj/webmesh/pkg/services/leaderproxy"
+	"github.com/webmeshproj/webmesh/pkg/services/leases"
 )
 
 var (
@@ -215,12 +221,32 @@ func (s *meshStore) joinWithConn(ctx context.Context, c *grpc.ClientConn, featur
 	if err != nil {
 		return fmt.Errorf("load wireguard key: %w", err)
 	}
+	var leaseID string
+	if s.opts.Mesh.LeaseTTL > 0 {
+		// Attach our registration to a lease that we keep alive for
+		// as long as we are running.
+		lease, err := leases.NewLeasesClient(c).Grant(ctx, &leases.GrantLeaseRequest{
+			Ttl: durationpb.New(s.opts.Mesh.LeaseTTL),
+		})
+		if err != nil {
+			return fmt.Errorf("grant lease: %w", err)
+		}
+		leaseID = lease.GetId()
+		log.Debug("Granted lease for join", slog.String("lease", leaseID))
+		ctx = metadata.AppendToOutgoingContext(ctx, leaderproxy.LeaseMeta, leaseID)
+	}
+	if len(s.opts.Mesh.Labels) > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, leaderproxy.LabelsMeta, peers.FormatLabels(s.opts.Mesh.Labels))
+	}
 	req := s.newJoinRequest(features, key)
 	log.Debug("Sending join request to node", slog.Any("req", req))
 	resp, err := s.doJoinGRPC(ctx, c, req)
 	if err != nil {
 		return fmt.Errorf("join request: %w", err)
 	}
+	if leaseID != "" {
+		go s.keepAliveLease(leaseID, s.opts.Mesh.LeaseTTL)
+	}
 	return s.handleJoinResponse(ctx, resp, key)
 }
 
@@ -300,6 +326,18 @@ func (s *meshStore) handleJoinResponse(ctx context.Context, resp *v1.JoinRespons
 func (s *meshStore) doJoinGRPC(ctx context.Context, c *grpc.ClientConn, req *v1.JoinRequest) (*v1.JoinResponse, error) {
 	client := v1.NewNodeClient(c)
 	context.LoggerFrom(ctx).Debug("Sending join request to node over gRPC", slog.Any("req", req))
+	if s.opts.Raft.TLSMode != "" && s.opts.Raft.TLSMode != raft.TLSModeDisabled {
+		// The leader connects to us before we have learned the raft
+		// configuration, so we need to know who it is ahead of time.
+		status, err := client.GetStatus(ctx, &v1.GetStatusRequest{})
+		if err != nil {
+			return nil, fmt.Errorf("get leader: %w", err)
+		}
+		if status.GetCurrentLeader() == "" {
+			return nil, errors.New("get leader: no leader elected")
+		}
+		s.raft.ExpectServer(status.GetCurrentLeader())
+	}
 	return client.Join(ctx, req)
 }
 
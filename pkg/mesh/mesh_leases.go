@@ -0,0 +1,154 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mesh
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	leasesdb "github.com/webmeshproj/webmesh/pkg/meshdb/leases"
+	"github.com/webmeshproj/webmesh/pkg/services/leases"
+)
+
+// leaseReapInterval is how often the leader checks for expired leases.
+const leaseReapInterval = time.Second
+
+// keepAliveLease renews the lease with the given ID until the store is closed.
+func (s *meshStore) keepAliveLease(id string, ttl time.Duration) {
+	log := s.log.With("lease", id)
+	for {
+		err := s.runLeaseKeepAlive(id, ttl)
+		if err == nil {
+			return
+		}
+		if status.Code(err) == codes.NotFound {
+			log.Error("Lease was revoked, registration is no longer kept alive", slog.String("error", err.Error()))
+			return
+		}
+		log.Warn("Lease keepalive failed, retrying", slog.String("error", err.Error()))
+		select {
+		case <-s.closec:
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// runLeaseKeepAlive sends keepalives for the lease over a single stream to the leader.
+// It returns nil when the store is closed.
+func (s *meshStore) runLeaseKeepAlive(id string, ttl time.Duration) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn, err := s.DialLeader(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stream, err := leases.NewLeasesClient(conn).KeepAlive(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stream.CloseSend() }()
+	// Renew three times per TTL so a single missed keepalive does not expire the lease.
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	for {
+		if err := stream.Send(&leases.KeepAliveRequest{Id: id}); err != nil {
+			return err
+		}
+		if _, err := stream.Recv(); err != nil {
+			return err
+		}
+		select {
+		case <-s.closec:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// reapLeases revokes expired leases while this node is the leader. A lease is
+// never revoked sooner than one TTL after this node became leader, so that
+// holders get a chance to find the new leader before their keys are removed.
+func (s *meshStore) reapLeases() {
+	ticker := time.NewTicker(leaseReapInterval)
+	defer ticker.Stop()
+	var leaderSince time.Time
+	for {
+		select {
+		case <-s.closec:
+			return
+		case <-ticker.C:
+		}
+		if !s.raft.IsLeader() {
+			leaderSince = time.Time{}
+			continue
+		}
+		now := time.Now().UTC()
+		if leaderSince.IsZero() {
+			leaderSince = now
+		}
+		if err := s.revokeExpiredLeases(now, leaderSince); err != nil {
+			s.log.Warn("Failed to revoke expired leases", slog.String("error", err.Error()))
+		}
+	}
+}
+
+func (s *meshStore) revokeExpiredLeases(now, leaderSince time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	db := leasesdb.New(s.Storage())
+	all, err := db.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, lease := range all {
+		if !lease.Expired(now) || now.Before(leaderSince.Add(lease.TTL)) {
+			continue
+		}
+		log := s.log.With("lease", lease.ID)
+		log.Info("Lease expired, revoking")
+		nodes, err := db.Revoke(ctx, lease.ID)
+		if err != nil {
+			log.Warn("Failed to revoke lease", slog.String("error", err.Error()))
+			continue
+		}
+		for _, node := range nodes {
+			if !s.isRaftMember(node) {
+				continue
+			}
+			log.Info("Removing expired node from raft", slog.String("node", node))
+			if err := s.raft.RemoveServer(ctx, node, false); err != nil {
+				log.Warn("Failed to remove node from raft", slog.String("node", node), slog.String("error", err.Error()))
+			}
+		}
+	}
+	return nil
+}
+
+func (s *meshStore) isRaftMember(id string) bool {
+	for _, server := range s.raft.Configuration().Servers {
+		if string(server.ID) == id {
+			return true
+		}
+	}
+	return false
+}
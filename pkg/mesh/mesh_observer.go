@@ -35,6 +35,7 @@ func (s *meshStore) newObserver() func(raft.Observation) {
 		ctx := context.Background()
 		switch data := ev.Data.(type) {
 		case raft.FailedHeartbeatObservation:
+			s.voterHealth.Failed(string(data.PeerID), data.LastContact)
 			if s.opts.Mesh.HeartbeatPurgeThreshold <= 0 {
 				return
 			}
@@ -53,6 +54,7 @@ func (s *meshStore) newObserver() func(raft.Observation) {
 				delete(failedHeartBeats, data.PeerID)
 			}
 		case raft.ResumedHeartbeatObservation:
+			s.voterHealth.Resumed(string(data.PeerID))
 			if s.opts.Mesh.HeartbeatPurgeThreshold > 0 {
 				delete(failedHeartBeats, data.PeerID)
 			}
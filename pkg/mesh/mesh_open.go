@@ -26,6 +26,7 @@ import (
 	v1 "github.com/webmeshproj/api/v1"
 
 	"github.com/webmeshproj/webmesh/pkg/campfire"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/raftlogs"
 	"github.com/webmeshproj/webmesh/pkg/net"
 	"github.com/webmeshproj/webmesh/pkg/plugins"
 	"github.com/webmeshproj/webmesh/pkg/raft"
@@ -60,16 +61,35 @@ func (s *meshStore) Open(ctx context.Context, features []v1.Feature) (err error)
 		}
 	}
 	s.opts.Raft.OnApplyLog = func(ctx context.Context, term, index uint64, log *v1.RaftLogEntry) {
-		// Dispatch the log entry to any storage plugins.
-		if _, err := s.plugins.ApplyRaftLog(ctx, &v1.StoreLogRequest{
-			Term:  term,
-			Index: index,
-			Log:   log,
-		}); err != nil {
-			// This is non-fatal for now.
-			s.log.Error("failed to apply log to plugins", slog.String("error", err.Error()))
+		// Storage plugins only understand individual puts and deletes,
+		// so batches and conditional puts are expanded into them.
+		entries, err := raftlogs.Expand(log)
+		if err != nil {
+			s.log.Error("failed to expand log entry for plugins", slog.String("error", err.Error()))
+			return
+		}
+		// Dispatch the log entries to any storage plugins.
+		for _, entry := range entries {
+			if _, err := s.plugins.ApplyRaftLog(ctx, &v1.StoreLogRequest{
+				Term:  term,
+				Index: index,
+				Log:   entry,
+			}); err != nil {
+				// This is non-fatal for now.
+				s.log.Error("failed to apply log to plugins", slog.String("error", err.Error()))
+			}
 		}
 	}
+	if s.opts.Raft.TLSConfig == nil {
+		// The raft stream layer uses the same certificate as the node.
+		s.opts.Raft.TLSConfig = s.tlsConfig
+	}
+	if s.opts.Raft.Transport == raft.TransportGRPC {
+		// Raft is tunneled over the gRPC server of each node.
+		s.opts.Raft.GRPCPort = s.opts.Mesh.GRPCAdvertisePort
+		s.opts.Raft.DialGRPC = s.newGRPCConn
+		s.opts.Raft.GRPCInsecure = !s.plugins.HasAuth()
+	}
 	s.raft = raft.New(s.opts.Raft, s)
 	err = s.raft.Start(ctx, &raft.StartOptions{
 		NodeID: s.ID(),
@@ -144,9 +164,31 @@ func (s *meshStore) Open(ctx context.Context, features []v1.Feature) (err error)
 		}
 	}
 	// Register an update hook to watch for network changes.
-	s.kvSubCancel, err = s.raft.Storage().Subscribe(context.Background(), "", s.onDBUpdate)
+	s.kvSubCancel, err = s.raft.Storage().Watch(context.Background(), "", 0, s.onDBUpdate)
 	if err != nil {
-		return handleErr(fmt.Errorf("subscribe: %w", err))
+		return handleErr(fmt.Errorf("watch: %w", err))
+	}
+	// Revoke expired leases whenever we are the leader.
+	go s.reapLeases()
+	// Generate edges from the topology policies whenever we are the leader.
+	go s.reconcileTopology()
+	if s.opts.Mesh.AutopilotVoters > 0 || s.opts.Mesh.LeaderPreferredZone != "" || len(s.opts.Mesh.LeaderPriorities) > 0 {
+		// Keep the configured number of voters and the preferred leader whenever we are the leader.
+		go s.runAutopilot()
+	}
+	if s.opts.Mesh.EdgeProbeInterval > 0 {
+		// Measure the links to our direct peers and report them to the leader.
+		go s.probeEdges()
+		if s.opts.Mesh.EdgeLatencyWeights {
+			// Derive edge weights from the measurements whenever we are the leader.
+			go s.deriveEdgeWeights()
+		}
+	}
+	if s.opts.Backup.Enabled() {
+		// Take scheduled snapshot backups whenever we are the leader.
+		if err := s.startBackups(); err != nil {
+			return handleErr(err)
+		}
 	}
 	if s.opts.Mesh.WaitCampfirePSK != "" {
 		err := s.StartCampfire(ctx, campfire.Options{
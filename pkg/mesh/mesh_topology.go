@@ -0,0 +1,94 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mesh
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/topology"
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+const (
+	// topologyCheckInterval is how often the reconciler checks whether this
+	// node became the leader.
+	topologyCheckInterval = time.Second
+	// topologyResyncInterval is how often the leader reconciles edges against
+	// the topology policies when no nodes or policies have changed.
+	topologyResyncInterval = time.Minute
+	// topologyReconcileTimeout is how long a single reconcile may take.
+	topologyReconcileTimeout = time.Minute
+)
+
+// reconcileTopology creates and removes generated edges whenever nodes or
+// topology policies change while this node is the leader, and once when it
+// becomes the leader.
+func (s *meshStore) reconcileTopology() {
+	changed := make(chan struct{}, 1)
+	// Compaction events are delivered as changes too, so that changes
+	// that were no longer retained are reconciled.
+	notify := func(_ storage.Event) {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	for _, prefix := range []string{peers.NodesPrefix, topology.PoliciesPrefix} {
+		cancel, err := s.raft.Storage().Watch(context.Background(), prefix, 0, notify)
+		if err != nil {
+			s.log.Error("Failed to watch topology changes", slog.String("prefix", prefix), slog.String("error", err.Error()))
+			return
+		}
+		defer cancel()
+	}
+	ticker := time.NewTicker(topologyCheckInterval)
+	defer ticker.Stop()
+	var wasLeader, pending bool
+	var lastSync time.Time
+	for {
+		select {
+		case <-s.closec:
+			return
+		case <-changed:
+			pending = true
+		case <-ticker.C:
+		}
+		if !s.raft.IsLeader() {
+			wasLeader = false
+			continue
+		}
+		if wasLeader && !pending && time.Since(lastSync) < topologyResyncInterval {
+			continue
+		}
+		wasLeader, pending, lastSync = true, false, time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), topologyReconcileTimeout)
+		res, err := topology.Reconcile(ctx, s.Storage())
+		cancel()
+		if err != nil {
+			s.log.Warn("Failed to reconcile topology policies", slog.String("error", err.Error()))
+			pending = true
+			continue
+		}
+		if res.Changed() {
+			s.log.Info("Reconciled edges against topology policies",
+				slog.Int("added", res.Added), slog.Int("removed", res.Removed), slog.Int("updated", res.Updated))
+		}
+	}
+}
@@ -49,6 +49,8 @@ type Options struct {
 	TLS *TLSOptions `json:"tls,omitempty" yaml:"tls,omitempty" toml:"tls,omitempty" mapstructure:"tls,omitempty"`
 	// WireGuard are options for WireGuard.
 	WireGuard *WireGuardOptions `json:"wireguard,omitempty" yaml:"wireguard,omitempty" toml:"wireguard,omitempty" mapstructure:"wireguard,omitempty"`
+	// Backup are options for scheduled snapshot backups.
+	Backup *BackupOptions `json:"backup,omitempty" yaml:"backup,omitempty" toml:"backup,omitempty" mapstructure:"backup,omitempty"`
 	// Plugins are options for plugins.
 	Plugins *plugins.Options `yaml:"plugins,omitempty" json:"plugins,omitempty" toml:"plugins,omitempty" mapstructure:"plugins,omitempty"`
 }
@@ -63,6 +65,7 @@ func NewOptions(interfaceName string, wireguardPort, grpcPort, raftPort int) *Op
 		Raft:      raft.NewOptions(raftPort),
 		TLS:       NewTLSOptions(),
 		WireGuard: NewWireGuardOptions(interfaceName, wireguardPort),
+		Backup:    NewBackupOptions(),
 		Plugins:   plugins.NewOptions(),
 	}
 }
@@ -76,6 +79,7 @@ func NewDefaultOptions() *Options {
 		Raft:      raft.NewOptions(0),
 		TLS:       NewTLSOptions(),
 		WireGuard: NewWireGuardOptions("", 0),
+		Backup:    NewBackupOptions(),
 		Plugins:   plugins.NewOptions(),
 	}
 }
@@ -88,6 +92,7 @@ func (o *Options) BindFlags(fl *flag.FlagSet, ifaceName string, prefix ...string
 	o.Raft.BindFlags(fl, prefix...)
 	o.TLS.BindFlags(fl, prefix...)
 	o.WireGuard.BindFlags(fl, ifaceName, prefix...)
+	o.Backup.BindFlags(fl, prefix...)
 	o.Plugins.BindFlags(fl, prefix...)
 }
 
@@ -103,6 +108,7 @@ func (o *Options) DeepCopy() *Options {
 		Raft:      o.Raft.DeepCopy(),
 		TLS:       o.TLS.DeepCopy(),
 		WireGuard: o.WireGuard.DeepCopy(),
+		Backup:    o.Backup.DeepCopy(),
 		Plugins:   o.Plugins.DeepCopy(),
 	}
 }
@@ -126,12 +132,28 @@ func (o *Options) Validate() error {
 	if err := o.Raft.Validate(); err != nil {
 		return err
 	}
+	if o.Raft.TLSMode != "" && o.Raft.TLSMode != raft.TLSModeDisabled {
+		if o.TLS == nil || o.TLS.Insecure || o.Auth == nil || o.Auth.MTLS == nil {
+			return fmt.Errorf("raft TLS requires TLS and an mTLS node certificate")
+		}
+		if o.TLS.InsecureSkipVerify {
+			return fmt.Errorf("raft TLS cannot be used with TLS insecure skip verify")
+		}
+		if o.Mesh.JoinCampfirePSK != "" {
+			// A node joining over a campfire cannot learn the leader's ID
+			// before the leader connects to it.
+			return fmt.Errorf("raft TLS cannot be used when joining through a campfire")
+		}
+	}
 	if err := o.Bootstrap.Validate(); err != nil {
 		return err
 	}
 	if err := o.WireGuard.Validate(); err != nil {
 		return err
 	}
+	if err := o.Backup.Validate(); err != nil {
+		return err
+	}
 	return nil
 }
 
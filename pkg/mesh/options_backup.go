@@ -0,0 +1,144 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mesh
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/webmeshproj/webmesh/pkg/meshdb/backups"
+	"github.com/webmeshproj/webmesh/pkg/util"
+)
+
+const (
+	BackupIntervalEnvVar    = "BACKUP_INTERVAL"
+	BackupRetainEnvVar      = "BACKUP_RETAIN"
+	BackupDirEnvVar         = "BACKUP_DIR"
+	BackupS3EndpointEnvVar  = "BACKUP_S3_ENDPOINT"
+	BackupS3BucketEnvVar    = "BACKUP_S3_BUCKET"
+	BackupS3PrefixEnvVar    = "BACKUP_S3_PREFIX"
+	BackupS3RegionEnvVar    = "BACKUP_S3_REGION"
+	BackupS3AccessKeyEnvVar = "BACKUP_S3_ACCESS_KEY_ID"
+	BackupS3SecretKeyEnvVar = "BACKUP_S3_SECRET_ACCESS_KEY"
+)
+
+// BackupOptions are options for scheduled snapshot backups taken by the leader.
+// Backups are encrypted with the raft storage encryption key when one is set.
+type BackupOptions struct {
+	// Interval is the interval between backups. Backups are disabled if zero.
+	Interval time.Duration `json:"interval,omitempty" yaml:"interval,omitempty" toml:"interval,omitempty" mapstructure:"interval,omitempty"`
+	// Retain is the number of backups to keep. Zero keeps all backups.
+	Retain int `json:"retain,omitempty" yaml:"retain,omitempty" toml:"retain,omitempty" mapstructure:"retain,omitempty"`
+	// Dir is a local directory to write backups to.
+	Dir string `json:"dir,omitempty" yaml:"dir,omitempty" toml:"dir,omitempty" mapstructure:"dir,omitempty"`
+	// S3Endpoint is the URL of an S3-compatible object store to write backups to.
+	S3Endpoint string `json:"s3-endpoint,omitempty" yaml:"s3-endpoint,omitempty" toml:"s3-endpoint,omitempty" mapstructure:"s3-endpoint,omitempty"`
+	// S3Bucket is the bucket to write backups to.
+	S3Bucket string `json:"s3-bucket,omitempty" yaml:"s3-bucket,omitempty" toml:"s3-bucket,omitempty" mapstructure:"s3-bucket,omitempty"`
+	// S3Prefix is the key prefix for backups in the bucket.
+	S3Prefix string `json:"s3-prefix,omitempty" yaml:"s3-prefix,omitempty" toml:"s3-prefix,omitempty" mapstructure:"s3-prefix,omitempty"`
+	// S3Region is the region of the bucket.
+	S3Region string `json:"s3-region,omitempty" yaml:"s3-region,omitempty" toml:"s3-region,omitempty" mapstructure:"s3-region,omitempty"`
+	// S3AccessKeyID is the access key ID for the object store.
+	S3AccessKeyID string `json:"s3-access-key-id,omitempty" yaml:"s3-access-key-id,omitempty" toml:"s3-access-key-id,omitempty" mapstructure:"s3-access-key-id,omitempty"`
+	// S3SecretAccessKey is the secret access key for the object store.
+	S3SecretAccessKey string `json:"s3-secret-access-key,omitempty" yaml:"s3-secret-access-key,omitempty" toml:"s3-secret-access-key,omitempty" mapstructure:"s3-secret-access-key,omitempty"`
+}
+
+// NewBackupOptions returns new backup options with the default values.
+func NewBackupOptions() *BackupOptions {
+	return &BackupOptions{
+		Retain: 7,
+	}
+}
+
+// Enabled returns true if scheduled backups are enabled.
+func (o *BackupOptions) Enabled() bool {
+	return o != nil && o.Interval > 0
+}
+
+// Validate validates the backup options.
+func (o *BackupOptions) Validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+	if o.Retain < 0 {
+		return errors.New("backup retain must not be negative")
+	}
+	if o.Dir == "" && o.S3Endpoint == "" {
+		return errors.New("backup dir or s3 endpoint must be set when backups are enabled")
+	}
+	if o.Dir != "" && o.S3Endpoint != "" {
+		return errors.New("only one of backup dir or s3 endpoint may be set")
+	}
+	if o.S3Endpoint != "" && o.S3Bucket == "" {
+		return errors.New("backup s3 bucket must be set when using an s3 endpoint")
+	}
+	return nil
+}
+
+// NewDestination returns the configured backup destination.
+func (o *BackupOptions) NewDestination() (backups.Destination, error) {
+	if o.Dir != "" {
+		return backups.NewDirDestination(o.Dir)
+	}
+	return backups.NewS3Destination(backups.S3Options{
+		Endpoint:        o.S3Endpoint,
+		Bucket:          o.S3Bucket,
+		Prefix:          o.S3Prefix,
+		Region:          o.S3Region,
+		AccessKeyID:     o.S3AccessKeyID,
+		SecretAccessKey: o.S3SecretAccessKey,
+	})
+}
+
+// BindFlags binds the backup options to the flag set.
+func (o *BackupOptions) BindFlags(fl *flag.FlagSet, prefix ...string) {
+	var p string
+	if len(prefix) > 0 {
+		p = strings.Join(prefix, ".") + "."
+	}
+	fl.DurationVar(&o.Interval, p+"backup.interval", util.GetEnvDurationDefault(BackupIntervalEnvVar, 0),
+		"Interval between snapshot backups taken by the leader. Backups are disabled if zero.")
+	fl.IntVar(&o.Retain, p+"backup.retain", util.GetEnvIntDefault(BackupRetainEnvVar, 7),
+		"Number of backups to keep. Zero keeps all backups.")
+	fl.StringVar(&o.Dir, p+"backup.dir", util.GetEnvDefault(BackupDirEnvVar, ""),
+		"Local directory to write backups to.")
+	fl.StringVar(&o.S3Endpoint, p+"backup.s3-endpoint", util.GetEnvDefault(BackupS3EndpointEnvVar, ""),
+		"URL of an S3-compatible object store to write backups to.")
+	fl.StringVar(&o.S3Bucket, p+"backup.s3-bucket", util.GetEnvDefault(BackupS3BucketEnvVar, ""),
+		"Bucket to write backups to.")
+	fl.StringVar(&o.S3Prefix, p+"backup.s3-prefix", util.GetEnvDefault(BackupS3PrefixEnvVar, ""),
+		"Key prefix for backups in the bucket.")
+	fl.StringVar(&o.S3Region, p+"backup.s3-region", util.GetEnvDefault(BackupS3RegionEnvVar, "us-east-1"),
+		"Region of the bucket.")
+	fl.StringVar(&o.S3AccessKeyID, p+"backup.s3-access-key-id", util.GetEnvDefault(BackupS3AccessKeyEnvVar, ""),
+		"Access key ID for the object store.")
+	fl.StringVar(&o.S3SecretAccessKey, p+"backup.s3-secret-access-key", util.GetEnvDefault(BackupS3SecretKeyEnvVar, ""),
+		"Secret access key for the object store.")
+}
+
+// DeepCopy returns a deep copy of the backup options.
+func (o *BackupOptions) DeepCopy() *BackupOptions {
+	if o == nil {
+		return nil
+	}
+	out := *o
+	return &out
+}
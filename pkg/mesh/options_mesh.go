@@ -20,9 +20,13 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/webmeshproj/webmesh/pkg/campfire"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/leases"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
 	"github.com/webmeshproj/webmesh/pkg/util"
 )
 
@@ -47,6 +51,15 @@ const (
 	HeartbeatPurgeThresholdEnvVar = "MESH_HEARTBEAT_PURGE_THRESHOLD"
 	NoIPv4EnvVar                  = "MESH_NO_IPV4"
 	NoIPv6EnvVar                  = "MESH_NO_IPV6"
+	LeaseTTLEnvVar                = "MESH_LEASE_TTL"
+	AutopilotVotersEnvVar         = "MESH_AUTOPILOT_VOTERS"
+	AutopilotStabilizationEnvVar  = "MESH_AUTOPILOT_STABILIZATION"
+	AutopilotRemoveEnvVar         = "MESH_AUTOPILOT_REMOVE_UNHEALTHY"
+	LeaderPreferredZoneEnvVar     = "MESH_LEADER_PREFERRED_ZONE"
+	LeaderPrioritiesEnvVar        = "MESH_LEADER_PRIORITIES"
+	EdgeProbeIntervalEnvVar       = "MESH_EDGE_PROBE_INTERVAL"
+	EdgeLatencyWeightsEnvVar      = "MESH_EDGE_LATENCY_WEIGHTS"
+	NodeLabelsEnvVar              = "MESH_LABELS"
 )
 
 // MeshOptions are the options for participating in a mesh.
@@ -76,6 +89,11 @@ type MeshOptions struct {
 	// Routes are additional routes to advertise to the mesh. These routes are advertised to all peers.
 	// If the node is not allowed to put routes in the mesh, the node will be unable to join.
 	Routes []string `json:"routes,omitempty" yaml:"routes,omitempty" toml:"routes,omitempty" mapstructure:"routes,omitempty"`
+	// Labels are key/value labels to register the node with. They can be used to select
+	// the node in network ACLs, role bindings and routes. The node must be allowed to put
+	// all resources to join with labels. Labels set by an admin take precedence when the
+	// node rejoins, the labels it joined with before are replaced.
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty" toml:"labels,omitempty" mapstructure:"labels,omitempty"`
 	// DirectPeers are peers to request direct edges to. If the node is not allowed to create edges
 	// and data channels, the node will be unable to join.
 	DirectPeers []string `json:"direct-peers,omitempty" yaml:"direct-peers,omitempty" toml:"direct-peers,omitempty" mapstructure:"direct-peers,omitempty"`
@@ -91,6 +109,32 @@ type MeshOptions struct {
 	NoIPv4 bool `json:"no-ipv4,omitempty" yaml:"no-ipv4,omitempty" toml:"no-ipv4,omitempty" mapstructure:"no-ipv4,omitempty"`
 	// NoIPv6 disables IPv6 usage.
 	NoIPv6 bool `json:"no-ipv6,omitempty" yaml:"no-ipv6,omitempty" toml:"no-ipv6,omitempty" mapstructure:"no-ipv6,omitempty"`
+	// LeaseTTL is the TTL of the lease to attach the node's registration to when joining.
+	// The lease is renewed for as long as the node is running. If the node stops renewing
+	// it, the leader removes the node from the mesh. Zero disables leases.
+	LeaseTTL time.Duration `json:"lease-ttl,omitempty" yaml:"lease-ttl,omitempty" toml:"lease-ttl,omitempty" mapstructure:"lease-ttl,omitempty"`
+	// AutopilotVoters is the number of voters the leader keeps in the cluster by promoting
+	// members of the voters group and demoting failing voters. Zero disables autopilot.
+	AutopilotVoters int `json:"autopilot-voters,omitempty" yaml:"autopilot-voters,omitempty" toml:"autopilot-voters,omitempty" mapstructure:"autopilot-voters,omitempty"`
+	// AutopilotStabilization is how long a node must be healthy before autopilot promotes it or
+	// hands leadership to it, and how long a voter must fail heartbeats before autopilot demotes it.
+	AutopilotStabilization time.Duration `json:"autopilot-stabilization,omitempty" yaml:"autopilot-stabilization,omitempty" toml:"autopilot-stabilization,omitempty" mapstructure:"autopilot-stabilization,omitempty"`
+	// AutopilotRemoveUnhealthy removes failing voters from the cluster instead of demoting them.
+	AutopilotRemoveUnhealthy bool `json:"autopilot-remove-unhealthy,omitempty" yaml:"autopilot-remove-unhealthy,omitempty" toml:"autopilot-remove-unhealthy,omitempty" mapstructure:"autopilot-remove-unhealthy,omitempty"`
+	// LeaderPreferredZone is the zone awareness ID the leader should preferably be in. The leader
+	// hands off to a healthy voter in this zone when it is outside of it.
+	LeaderPreferredZone string `json:"leader-preferred-zone,omitempty" yaml:"leader-preferred-zone,omitempty" toml:"leader-preferred-zone,omitempty" mapstructure:"leader-preferred-zone,omitempty"`
+	// LeaderPriorities are leader priorities by node ID. The leader hands off to a healthy voter
+	// with a higher priority. Nodes without a priority have a priority of zero.
+	LeaderPriorities map[string]int `json:"leader-priorities,omitempty" yaml:"leader-priorities,omitempty" toml:"leader-priorities,omitempty" mapstructure:"leader-priorities,omitempty"`
+	// EdgeProbeInterval is how often the node measures the round trip time and packet loss to its
+	// direct peers and reports them to the leader. The leader stores smoothed measurements in the
+	// attributes of the edges. Zero disables probing.
+	EdgeProbeInterval time.Duration `json:"edge-probe-interval,omitempty" yaml:"edge-probe-interval,omitempty" toml:"edge-probe-interval,omitempty" mapstructure:"edge-probe-interval,omitempty"`
+	// EdgeLatencyWeights makes the leader derive the weights of measured edges from their
+	// measurements, overriding any weight they were given. The leader checks the measurements
+	// every EdgeProbeInterval.
+	EdgeLatencyWeights bool `json:"edge-latency-weights,omitempty" yaml:"edge-latency-weights,omitempty" toml:"edge-latency-weights,omitempty" mapstructure:"edge-latency-weights,omitempty"`
 }
 
 // NewMeshOptions creates a new MeshOptions with default values. If the grpcPort
@@ -130,8 +174,9 @@ func NewMeshOptions(grpcPort int) *MeshOptions {
 			}
 			return nil
 		}(),
-		MaxJoinRetries:    10,
-		GRPCAdvertisePort: grpcPort,
+		MaxJoinRetries:         10,
+		GRPCAdvertisePort:      grpcPort,
+		AutopilotStabilization: 10 * time.Second,
 	}
 }
 
@@ -187,6 +232,20 @@ This is only necessary if the node intends on being publicly accessible.`)
 		o.Routes = append(o.Routes, strings.Split(s, ",")...)
 		return nil
 	})
+	fl.Func(p+"mesh.labels", `Comma separated list of key=value labels to register the node with.
+	Labels can be used to select the node in network ACLs, role bindings and routes.`, func(s string) error {
+		labels, err := peers.ParseLabels(s)
+		if err != nil {
+			return err
+		}
+		if o.Labels == nil {
+			o.Labels = make(map[string]string)
+		}
+		for k, v := range labels {
+			o.Labels[k] = v
+		}
+		return nil
+	})
 	fl.Func(p+"mesh.direct-peers", `Comma separated list of peers to request direct edges to.
 	If the node is not allowed to create edges and data channels, the node will be unable to join.`, func(s string) error {
 		o.DirectPeers = append(o.DirectPeers, strings.Split(s, ",")...)
@@ -198,6 +257,37 @@ This is only necessary if the node intends on being publicly accessible.`)
 		"Do not request IPv4 assignments when joining.")
 	fl.BoolVar(&o.NoIPv6, p+"mesh.no-ipv6", util.GetEnvDefault(NoIPv6EnvVar, "false") == "true",
 		"Do not request IPv6 assignments when joining.")
+	fl.DurationVar(&o.LeaseTTL, p+"mesh.lease-ttl", util.GetEnvDurationDefault(LeaseTTLEnvVar, 0),
+		`TTL of a lease to attach the node's registration to when joining.
+	The node is removed from the mesh if it stops renewing the lease. Default is 0 (disabled).`)
+	fl.IntVar(&o.AutopilotVoters, p+"mesh.autopilot-voters", util.GetEnvIntDefault(AutopilotVotersEnvVar, 0),
+		`Number of voters the leader keeps in the cluster, spread across zone awareness IDs.
+	Members of the voters group are promoted as needed. Default is 0 (disabled).`)
+	fl.DurationVar(&o.AutopilotStabilization, p+"mesh.autopilot-stabilization", util.GetEnvDurationDefault(AutopilotStabilizationEnvVar, 10*time.Second),
+		"How long a node must be healthy before it is promoted, or failing before it is demoted, by autopilot.")
+	fl.BoolVar(&o.AutopilotRemoveUnhealthy, p+"mesh.autopilot-remove-unhealthy", util.GetEnvDefault(AutopilotRemoveEnvVar, "false") == "true",
+		"Remove failing voters from the cluster instead of demoting them.")
+	fl.StringVar(&o.LeaderPreferredZone, p+"mesh.leader-preferred-zone", util.GetEnvDefault(LeaderPreferredZoneEnvVar, ""),
+		"Zone awareness ID the leader should be in. The leader hands off to a healthy voter in this zone.")
+	fl.Func(p+"mesh.leader-priorities", `Comma separated list of node-id=priority leader priorities.
+	The leader hands off to a healthy voter with a higher priority.`, func(s string) error {
+		priorities, err := parseLeaderPriorities(s)
+		if err != nil {
+			return err
+		}
+		if o.LeaderPriorities == nil {
+			o.LeaderPriorities = make(map[string]int)
+		}
+		for id, priority := range priorities {
+			o.LeaderPriorities[id] = priority
+		}
+		return nil
+	})
+	fl.DurationVar(&o.EdgeProbeInterval, p+"mesh.edge-probe-interval", util.GetEnvDurationDefault(EdgeProbeIntervalEnvVar, 0),
+		`Interval for measuring the round trip time and packet loss to direct peers.
+	Measurements are reported to the leader and stored on edges. Default is 0 (disabled).`)
+	fl.BoolVar(&o.EdgeLatencyWeights, p+"mesh.edge-latency-weights", util.GetEnvDefault(EdgeLatencyWeightsEnvVar, "false") == "true",
+		"Derive the weights of measured edges from their measurements while this node is the leader.")
 }
 
 // Validate validates the MeshOptions.
@@ -214,6 +304,40 @@ func (o *MeshOptions) Validate() error {
 	if o.WaitCampfirePSK != "" && len(o.WaitCampfirePSK) != campfire.PSKSize {
 		return fmt.Errorf("invalid campfire PSK size")
 	}
+	if o.LeaseTTL != 0 && o.LeaseTTL < leases.MinTTL {
+		return fmt.Errorf("lease TTL must be at least %s", leases.MinTTL)
+	}
+	if o.AutopilotVoters < 0 {
+		return fmt.Errorf("autopilot voters cannot be negative")
+	}
+	if o.AutopilotVoters > 0 && o.AutopilotStabilization < 0 {
+		return fmt.Errorf("autopilot stabilization cannot be negative")
+	}
+	if len(o.LeaderPriorities) == 0 && os.Getenv(LeaderPrioritiesEnvVar) != "" {
+		// Parse the priorities from the environment variable.
+		priorities, err := parseLeaderPriorities(os.Getenv(LeaderPrioritiesEnvVar))
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", LeaderPrioritiesEnvVar, err)
+		}
+		o.LeaderPriorities = priorities
+	}
+	if len(o.Labels) == 0 && os.Getenv(NodeLabelsEnvVar) != "" {
+		// Parse the labels from the environment variable.
+		labels, err := peers.ParseLabels(os.Getenv(NodeLabelsEnvVar))
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", NodeLabelsEnvVar, err)
+		}
+		o.Labels = labels
+	}
+	if err := peers.ValidateLabels(o.Labels); err != nil {
+		return err
+	}
+	if o.EdgeProbeInterval < 0 {
+		return fmt.Errorf("edge probe interval cannot be negative")
+	}
+	if o.EdgeLatencyWeights && o.EdgeProbeInterval == 0 {
+		return fmt.Errorf("edge latency weights require an edge probe interval")
+	}
 	return nil
 }
 
@@ -228,5 +352,37 @@ func (o *MeshOptions) DeepCopy() *MeshOptions {
 	other.WaitCampfireTURNServers = append([]string(nil), o.WaitCampfireTURNServers...)
 	other.Routes = append([]string(nil), o.Routes...)
 	other.DirectPeers = append([]string(nil), o.DirectPeers...)
+	if o.Labels != nil {
+		other.Labels = make(map[string]string, len(o.Labels))
+		for k, v := range o.Labels {
+			other.Labels[k] = v
+		}
+	}
+	if o.LeaderPriorities != nil {
+		other.LeaderPriorities = make(map[string]int, len(o.LeaderPriorities))
+		for id, priority := range o.LeaderPriorities {
+			other.LeaderPriorities[id] = priority
+		}
+	}
 	return &other
 }
+
+// parseLeaderPriorities parses a comma separated list of node-id=priority pairs.
+func parseLeaderPriorities(s string) (map[string]int, error) {
+	out := make(map[string]int)
+	for _, pair := range strings.Split(s, ",") {
+		if pair == "" {
+			continue
+		}
+		id, priority, ok := strings.Cut(pair, "=")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid leader priority %q: must be node-id=priority", pair)
+		}
+		n, err := strconv.Atoi(priority)
+		if err != nil {
+			return nil, fmt.Errorf("invalid leader priority %q: %w", pair, err)
+		}
+		out[id] = n
+	}
+	return out, nil
+}
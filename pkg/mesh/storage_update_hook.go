@@ -24,15 +24,23 @@ import (
 
 	"github.com/webmeshproj/webmesh/pkg/meshdb/networking"
 	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	"github.com/webmeshproj/webmesh/pkg/storage"
 )
 
-func (s *meshStore) onDBUpdate(key, value string) {
-	s.log.Debug("db update trigger", "key", key)
+func (s *meshStore) onDBUpdate(ev storage.Event) {
+	s.log.Debug("db update trigger", "key", ev.Key, "type", ev.Type.String(), "revision", ev.Revision)
 	if s.testStore {
 		return
 	}
 	switch {
-	case isNodeChangeKey(key):
+	case ev.Type == storage.EventCompacted:
+		// We missed changes and can't tell what was affected, refresh everything.
+		go s.queuePeersUpdate()
+		go s.queueRouteUpdate()
+		if s.opts.Mesh != nil && s.opts.Mesh.UseMeshDNS && s.opts.Mesh.MeshDNSAdvertisePort == 0 {
+			go s.queueMeshDNSUpdate()
+		}
+	case isNodeChangeKey(ev.Key):
 		// Potentially need to update wireguard peers
 		go s.queuePeersUpdate()
 		if s.opts.Mesh != nil && s.opts.Mesh.UseMeshDNS && s.opts.Mesh.MeshDNSAdvertisePort == 0 {
@@ -40,7 +48,7 @@ func (s *meshStore) onDBUpdate(key, value string) {
 			// so we need to refresh the meshdns servers
 			go s.queueMeshDNSUpdate()
 		}
-	case isRouteChangeKey(key):
+	case isRouteChangeKey(ev.Key):
 		// Potentially need to update wireguard routes and peers
 		go s.queuePeersUpdate()
 		go s.queueRouteUpdate()
@@ -145,7 +145,7 @@ func (m *meshBridge) Start(ctx context.Context) error {
 			// This should never happen
 			return handleErr(fmt.Errorf("mesh %q not found", meshID))
 		}
-		err := mesh.Open(ctx, features)
+		err := services.OpenMesh(ctx, mesh, meshOpts.Mesh, meshOpts.Services, features)
 		if err != nil {
 			return handleErr(fmt.Errorf("failed to open mesh %q: %w", meshID, err))
 		}
@@ -0,0 +1,342 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package backups takes scheduled snapshots of the mesh database and keeps
+// them off the node in a pluggable destination.
+package backups
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/webmeshproj/webmesh/pkg/meshdb/snapshots"
+)
+
+// ErrNotFound is returned when a backup does not exist in a destination.
+var ErrNotFound = errors.New("backup not found")
+
+// ErrChecksumMismatch is returned when a backup does not match its recorded checksum.
+var ErrChecksumMismatch = errors.New("backup checksum mismatch")
+
+// ErrEncrypted is returned when an encrypted backup is downloaded without a key.
+var ErrEncrypted = errors.New("backup is encrypted")
+
+const (
+	// namePrefix and nameSuffix surround the timestamp in backup names.
+	namePrefix = "snapshot-"
+	nameSuffix = ".gz"
+	// ManifestName is the name of the object listing the backups in a
+	// destination.
+	ManifestName = "manifest.json"
+	// timeFormat is the timestamp format used in backup names. It sorts
+	// lexically in time order.
+	timeFormat = "20060102T150405.000Z"
+)
+
+// Destination is where backups are written.
+type Destination interface {
+	// Put writes an object with the given name.
+	Put(ctx context.Context, name string, data []byte) error
+	// Get opens the object with the given name. ErrNotFound is returned if
+	// it does not exist.
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// Delete removes the object with the given name. It is not an error if
+	// the object does not exist.
+	Delete(ctx context.Context, name string) error
+}
+
+// Backup describes a stored backup.
+type Backup struct {
+	// Name is the name of the backup in its destination.
+	Name string `json:"name"`
+	// Size is the size of the stored backup in bytes.
+	Size int64 `json:"size"`
+	// Checksum is the hex encoded SHA-256 checksum of the stored backup.
+	Checksum string `json:"checksum"`
+	// Encrypted is true if the backup is encrypted with the storage key.
+	Encrypted bool `json:"encrypted,omitempty"`
+	// CreatedAt is when the backup was taken.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// manifest is the object listing the backups in a destination, oldest
+// first. Objects it does not list are not considered backups.
+type manifest struct {
+	Backups []Backup `json:"backups"`
+}
+
+// Options are options for scheduled backups.
+type Options struct {
+	// Interval is the interval between backups.
+	Interval time.Duration
+	// Retain is the number of backups to keep. Zero keeps all of them.
+	Retain int
+	// EncryptionKey is the AES key backups are encrypted with. It is the
+	// storage encryption key when one is configured. Backups are written
+	// in plaintext if it is nil.
+	EncryptionKey []byte
+}
+
+// Manager takes backups and prunes old ones.
+type Manager struct {
+	snapshotter snapshots.Snapshotter
+	dest        Destination
+	opts        Options
+	log         *slog.Logger
+}
+
+// NewManager returns a new backup manager writing snapshots to the given destination.
+func NewManager(snapshotter snapshots.Snapshotter, dest Destination, opts Options, log *slog.Logger) *Manager {
+	return &Manager{
+		snapshotter: snapshotter,
+		dest:        dest,
+		opts:        opts,
+		log:         log,
+	}
+}
+
+// Run takes a backup every interval until the context is canceled. Backups
+// are only taken while shouldRun returns true, which is used to limit them
+// to the leader.
+func (m *Manager) Run(ctx context.Context, shouldRun func() bool) {
+	t := time.NewTicker(m.opts.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if !shouldRun() {
+			continue
+		}
+		if _, err := m.Backup(ctx); err != nil {
+			m.log.Error("scheduled backup failed", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// Backup takes a snapshot, writes it to the destination, records it in the
+// manifest, and prunes backups beyond the retention count.
+func (m *Manager) Backup(ctx context.Context) (Backup, error) {
+	start := time.Now().UTC()
+	snapshot, err := m.snapshotter.Snapshot(ctx)
+	if err != nil {
+		return Backup{}, fmt.Errorf("take snapshot: %w", err)
+	}
+	defer snapshot.Release()
+	var buf bytes.Buffer
+	if err := snapshot.Persist(&bufferSink{Buffer: &buf}); err != nil {
+		return Backup{}, fmt.Errorf("persist snapshot: %w", err)
+	}
+	data := buf.Bytes()
+	if m.opts.EncryptionKey != nil {
+		data, err = seal(m.opts.EncryptionKey, data)
+		if err != nil {
+			return Backup{}, fmt.Errorf("encrypt backup: %w", err)
+		}
+	}
+	sum := sha256.Sum256(data)
+	backup := Backup{
+		Name:      namePrefix + start.Format(timeFormat) + nameSuffix,
+		Size:      int64(len(data)),
+		Checksum:  hex.EncodeToString(sum[:]),
+		Encrypted: m.opts.EncryptionKey != nil,
+		CreatedAt: start.Truncate(time.Millisecond),
+	}
+	// The backup is written before the manifest so that the manifest
+	// always refers to complete backups.
+	if err := m.dest.Put(ctx, backup.Name, data); err != nil {
+		return Backup{}, fmt.Errorf("write backup: %w", err)
+	}
+	man, err := readManifest(ctx, m.dest)
+	if err != nil {
+		return Backup{}, err
+	}
+	man.Backups = append(man.Backups, backup)
+	var pruned []Backup
+	if m.opts.Retain > 0 && len(man.Backups) > m.opts.Retain {
+		pruned = man.Backups[:len(man.Backups)-m.opts.Retain]
+		man.Backups = man.Backups[len(man.Backups)-m.opts.Retain:]
+	}
+	if err := writeManifest(ctx, m.dest, man); err != nil {
+		return Backup{}, err
+	}
+	m.log.Info("backup complete",
+		slog.String("name", backup.Name),
+		slog.Int64("size", backup.Size),
+		slog.Bool("encrypted", backup.Encrypted),
+		slog.String("duration", time.Since(start).String()),
+	)
+	// Pruned backups are deleted after the manifest no longer lists them.
+	for _, b := range pruned {
+		m.log.Info("removing old backup", slog.String("name", b.Name))
+		if err := m.dest.Delete(ctx, b.Name); err != nil {
+			return backup, fmt.Errorf("prune backup %s: %w", b.Name, err)
+		}
+	}
+	return backup, nil
+}
+
+// List returns the backups in the destination, oldest first. It reads only
+// the manifest.
+func List(ctx context.Context, dest Destination) ([]Backup, error) {
+	man, err := readManifest(ctx, dest)
+	if err != nil {
+		return nil, err
+	}
+	return man.Backups, nil
+}
+
+// Latest returns the most recent backup in the destination.
+func Latest(ctx context.Context, dest Destination) (Backup, error) {
+	backups, err := List(ctx, dest)
+	if err != nil {
+		return Backup{}, err
+	}
+	if len(backups) == 0 {
+		return Backup{}, ErrNotFound
+	}
+	return backups[len(backups)-1], nil
+}
+
+// Download writes the named backup to w after verifying its checksum. An
+// encrypted backup is decrypted with key, and ErrEncrypted is returned if key
+// is nil. The written data can be used with the bootstrap restore-snapshot
+// option.
+func Download(ctx context.Context, dest Destination, name string, key []byte, w io.Writer) error {
+	backups, err := List(ctx, dest)
+	if err != nil {
+		return err
+	}
+	var backup *Backup
+	for i := range backups {
+		if backups[i].Name == name {
+			backup = &backups[i]
+			break
+		}
+	}
+	if backup == nil {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if backup.Encrypted && key == nil {
+		return fmt.Errorf("%w: %s", ErrEncrypted, name)
+	}
+	rc, err := dest.Get(ctx, name)
+	if err != nil {
+		return fmt.Errorf("get backup: %w", err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return fmt.Errorf("read backup: %w", err)
+	}
+	got := sha256.Sum256(data)
+	if hex.EncodeToString(got[:]) != backup.Checksum {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, name)
+	}
+	if backup.Encrypted {
+		data, err = open(key, data)
+		if err != nil {
+			return fmt.Errorf("decrypt backup: %w", err)
+		}
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("write backup: %w", err)
+	}
+	return nil
+}
+
+func readManifest(ctx context.Context, dest Destination) (*manifest, error) {
+	rc, err := dest.Get(ctx, ManifestName)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return &manifest{}, nil
+		}
+		return nil, fmt.Errorf("get backup manifest: %w", err)
+	}
+	defer rc.Close()
+	var man manifest
+	if err := json.NewDecoder(rc).Decode(&man); err != nil {
+		return nil, fmt.Errorf("decode backup manifest: %w", err)
+	}
+	return &man, nil
+}
+
+func writeManifest(ctx context.Context, dest Destination, man *manifest) error {
+	data, err := json.Marshal(man)
+	if err != nil {
+		return fmt.Errorf("encode backup manifest: %w", err)
+	}
+	if err := dest.Put(ctx, ManifestName, data); err != nil {
+		return fmt.Errorf("write backup manifest: %w", err)
+	}
+	return nil
+}
+
+// seal encrypts data with AES-GCM, prefixing the result with the nonce.
+func seal(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+// open decrypts data sealed by seal.
+func open(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// bufferSink is a raft.SnapshotSink that writes to a buffer.
+type bufferSink struct {
+	*bytes.Buffer
+}
+
+func (s *bufferSink) ID() string    { return "backup" }
+func (s *bufferSink) Cancel() error { return nil }
+func (s *bufferSink) Close() error  { return nil }
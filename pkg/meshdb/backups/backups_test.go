@@ -0,0 +1,229 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backups
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/webmeshproj/webmesh/pkg/meshdb/snapshots"
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+func TestBackups(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Directory", func(t *testing.T) {
+		t.Parallel()
+		dest, err := NewDirDestination(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		testDestination(t, dest, nil)
+	})
+
+	t.Run("Encrypted", func(t *testing.T) {
+		t.Parallel()
+		dest, err := NewDirDestination(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		testDestination(t, dest, []byte("0123456789abcdef0123456789abcdef"))
+	})
+
+	t.Run("S3", func(t *testing.T) {
+		t.Parallel()
+		s3 := newFakeS3("backups")
+		srv := httptest.NewServer(s3)
+		t.Cleanup(srv.Close)
+		dest, err := NewS3Destination(S3Options{
+			Endpoint:        srv.URL,
+			Bucket:          "backups",
+			Prefix:          "mesh",
+			AccessKeyID:     "access",
+			SecretAccessKey: "secret",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		testDestination(t, dest, nil)
+
+		// Listing backups should only read the manifest.
+		gets := s3.getCount()
+		if _, err := List(context.Background(), dest); err != nil {
+			t.Fatal(err)
+		}
+		if got := s3.getCount() - gets; got != 1 {
+			t.Fatalf("expected listing to issue 1 request, got %d", got)
+		}
+	})
+}
+
+func testDestination(t *testing.T, dest Destination, key []byte) {
+	t.Helper()
+	ctx := context.Background()
+	db, err := storage.NewTestStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Put(ctx, "/registry/foo", "bar", 0); err != nil {
+		t.Fatal(err)
+	}
+	snaps := snapshots.New(db)
+	m := NewManager(snaps, dest, Options{Retain: 2, EncryptionKey: key}, slog.Default())
+
+	var taken []Backup
+	for i := 0; i < 3; i++ {
+		b, err := m.Backup(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		taken = append(taken, b)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	// Only the last two backups should be retained.
+	backups, err := List(ctx, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups, got %d", len(backups))
+	}
+	if backups[0].Name != taken[1].Name || backups[1].Name != taken[2].Name {
+		t.Fatalf("expected the latest backups to be retained, got %v", backups)
+	}
+	if backups[1].Checksum != taken[2].Checksum || backups[1].Size != taken[2].Size {
+		t.Fatalf("expected listed backup to match taken backup, got %+v, want %+v", backups[1], taken[2])
+	}
+	latest, err := Latest(ctx, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Name != taken[2].Name {
+		t.Fatalf("expected latest backup %s, got %s", taken[2].Name, latest.Name)
+	}
+
+	if latest.Encrypted != (key != nil) {
+		t.Fatalf("expected encrypted to be %v, got %v", key != nil, latest.Encrypted)
+	}
+	// Pruned backups should be removed from the destination.
+	if _, err := dest.Get(ctx, taken[0].Name); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected pruned backup to be deleted, got %v", err)
+	}
+
+	if key != nil {
+		// An encrypted backup should not be readable without the key.
+		err := Download(ctx, dest, latest.Name, nil, io.Discard)
+		if !errors.Is(err, ErrEncrypted) {
+			t.Fatalf("expected encrypted backup error, got %v", err)
+		}
+		err = Download(ctx, dest, latest.Name, []byte("fedcba9876543210fedcba9876543210"), io.Discard)
+		if err == nil {
+			t.Fatal("expected decrypting with the wrong key to fail")
+		}
+	}
+
+	// A downloaded backup should restore the database.
+	var buf bytes.Buffer
+	if err := Download(ctx, dest, latest.Name, key, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(ctx, "/registry/foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := snaps.Restore(ctx, io.NopCloser(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	val, err := db.Get(ctx, "/registry/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != "bar" {
+		t.Fatalf("expected restored value bar, got %s", val)
+	}
+
+	// A corrupted backup should fail verification.
+	if err := dest.Put(ctx, latest.Name, []byte("corrupt")); err != nil {
+		t.Fatal(err)
+	}
+	err = Download(ctx, dest, latest.Name, key, io.Discard)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+}
+
+// fakeS3 is an in-memory stand-in for an S3-compatible object store.
+type fakeS3 struct {
+	bucket  string
+	objects map[string][]byte
+	gets    int
+	mu      sync.Mutex
+}
+
+func newFakeS3(bucket string) *fakeS3 {
+	return &fakeS3{bucket: bucket, objects: make(map[string][]byte)}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	key, ok := strings.CutPrefix(path, f.bucket+"/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = data
+	case http.MethodGet:
+		f.gets++
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeS3) getCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.gets
+}
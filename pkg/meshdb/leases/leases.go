@@ -0,0 +1,292 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package leases contains an interface for managing leases in the mesh.
+// A lease has a TTL and a set of attached keys. When a lease expires
+// without being renewed, the leader revokes it and removes every key
+// attached to it.
+package leases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+const (
+	// LeasesPrefix is where leases are stored in the database.
+	LeasesPrefix = "/registry/leases"
+	// AttachmentsPrefix is where the keys attached to leases are stored in
+	// the database, in the format /registry/lease-attachments/<id>/<key>.
+	AttachmentsPrefix = "/registry/lease-attachments"
+	// KeyLeasesPrefix is where the lease each attached key belongs to is
+	// stored in the database, in the format /registry/lease-keys/<key>.
+	KeyLeasesPrefix = "/registry/lease-keys"
+	// MinTTL is the minimum TTL of a lease.
+	MinTTL = 5 * time.Second
+)
+
+// ErrLeaseNotFound is returned when a lease is not found.
+var ErrLeaseNotFound = errors.New("lease not found")
+
+// Lease is a lease in the mesh.
+type Lease struct {
+	// ID is the ID of the lease.
+	ID string `json:"id"`
+	// TTL is how long the lease lives without being renewed.
+	TTL time.Duration `json:"ttl"`
+	// ExpiresAt is when the lease expires unless it is renewed.
+	ExpiresAt time.Time `json:"expiresAt"`
+	// Owner is the ID of the node or user that granted the lease.
+	Owner string `json:"owner,omitempty"`
+}
+
+// Expired returns true if the lease has expired at the given time.
+func (l Lease) Expired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+// Leases is the interface for managing leases.
+type Leases interface {
+	// Grant creates a new lease with the given TTL and owner.
+	Grant(ctx context.Context, ttl time.Duration, owner string) (Lease, error)
+	// Get returns a lease by ID.
+	Get(ctx context.Context, id string) (Lease, error)
+	// List returns all leases.
+	List(ctx context.Context) ([]Lease, error)
+	// KeepAlive renews a lease for another TTL.
+	KeepAlive(ctx context.Context, id string) (Lease, error)
+	// Attach attaches keys to a lease. They are removed when the lease
+	// is revoked. A key is attached to at most one lease, keys attached
+	// to another lease are moved to this one.
+	Attach(ctx context.Context, id string, keys ...string) error
+	// Detach detaches keys from whatever lease they are attached to, so
+	// that they are no longer removed when it is revoked.
+	Detach(ctx context.Context, keys ...string) error
+	// Keys returns the keys attached to a lease.
+	Keys(ctx context.Context, id string) ([]string, error)
+	// Revoke removes a lease and every key attached to it. Attached nodes
+	// are removed along with their edges. The IDs of the removed nodes
+	// are returned.
+	Revoke(ctx context.Context, id string) ([]string, error)
+}
+
+// New returns a new Leases interface.
+func New(st storage.Storage) Leases {
+	return &leases{st: st}
+}
+
+type leases struct {
+	st storage.Storage
+}
+
+func leaseKey(id string) string {
+	return fmt.Sprintf("%s/%s", LeasesPrefix, id)
+}
+
+func attachmentsPrefix(id string) string {
+	return fmt.Sprintf("%s/%s/", AttachmentsPrefix, id)
+}
+
+func keyLeaseKey(key string) string {
+	return fmt.Sprintf("%s/%s", KeyLeasesPrefix, key)
+}
+
+// Grant creates a new lease with the given TTL and owner.
+func (l *leases) Grant(ctx context.Context, ttl time.Duration, owner string) (Lease, error) {
+	if ttl < MinTTL {
+		return Lease{}, fmt.Errorf("lease TTL must be at least %s", MinTTL)
+	}
+	lease := Lease{
+		ID:        uuid.NewString(),
+		TTL:       ttl,
+		ExpiresAt: time.Now().UTC().Add(ttl),
+		Owner:     owner,
+	}
+	if err := l.put(ctx, lease); err != nil {
+		return Lease{}, err
+	}
+	return lease, nil
+}
+
+// Get returns a lease by ID.
+func (l *leases) Get(ctx context.Context, id string) (Lease, error) {
+	data, err := l.st.Get(ctx, leaseKey(id))
+	if err != nil {
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			return Lease{}, ErrLeaseNotFound
+		}
+		return Lease{}, fmt.Errorf("get lease: %w", err)
+	}
+	var lease Lease
+	if err := json.Unmarshal([]byte(data), &lease); err != nil {
+		return Lease{}, fmt.Errorf("unmarshal lease: %w", err)
+	}
+	return lease, nil
+}
+
+// List returns all leases.
+func (l *leases) List(ctx context.Context) ([]Lease, error) {
+	out := make([]Lease, 0)
+	err := l.st.IterPrefix(ctx, LeasesPrefix+"/", func(_, value string) error {
+		var lease Lease
+		if err := json.Unmarshal([]byte(value), &lease); err != nil {
+			return fmt.Errorf("unmarshal lease: %w", err)
+		}
+		out = append(out, lease)
+		return nil
+	})
+	return out, err
+}
+
+// KeepAlive renews a lease for another TTL.
+func (l *leases) KeepAlive(ctx context.Context, id string) (Lease, error) {
+	lease, err := l.Get(ctx, id)
+	if err != nil {
+		return Lease{}, err
+	}
+	lease.ExpiresAt = time.Now().UTC().Add(lease.TTL)
+	if err := l.put(ctx, lease); err != nil {
+		return Lease{}, err
+	}
+	return lease, nil
+}
+
+// Attach attaches keys to a lease, moving them from any lease they were
+// attached to before.
+func (l *leases) Attach(ctx context.Context, id string, keys ...string) error {
+	if _, err := l.Get(ctx, id); err != nil {
+		return err
+	}
+	ops, err := l.detachOps(ctx, keys)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		ops = append(ops,
+			storage.Op{Type: storage.OpPut, Key: attachmentsPrefix(id) + key, Value: key},
+			storage.Op{Type: storage.OpPut, Key: keyLeaseKey(key), Value: id},
+		)
+	}
+	if err := l.st.Batch(ctx, ops); err != nil {
+		return fmt.Errorf("attach keys: %w", err)
+	}
+	return nil
+}
+
+// Detach detaches keys from whatever lease they are attached to.
+func (l *leases) Detach(ctx context.Context, keys ...string) error {
+	ops, err := l.detachOps(ctx, keys)
+	if err != nil {
+		return err
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+	if err := l.st.Batch(ctx, ops); err != nil {
+		return fmt.Errorf("detach keys: %w", err)
+	}
+	return nil
+}
+
+// detachOps returns the operations that remove the attachments of the given
+// keys from the leases they are attached to. The lease of each key is looked
+// up in the reverse index, so no attachments are scanned.
+func (l *leases) detachOps(ctx context.Context, keys []string) ([]storage.Op, error) {
+	var ops []storage.Op
+	for _, key := range keys {
+		id, err := l.st.Get(ctx, keyLeaseKey(key))
+		if err != nil {
+			if errors.Is(err, storage.ErrKeyNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("get lease of %q: %w", key, err)
+		}
+		ops = append(ops,
+			storage.Op{Type: storage.OpDelete, Key: attachmentsPrefix(id) + key},
+			storage.Op{Type: storage.OpDelete, Key: keyLeaseKey(key)},
+		)
+	}
+	return ops, nil
+}
+
+// Keys returns the keys attached to a lease.
+func (l *leases) Keys(ctx context.Context, id string) ([]string, error) {
+	prefix := attachmentsPrefix(id)
+	keys, err := l.st.List(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("list attachments: %w", err)
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, prefix)
+	}
+	return keys, nil
+}
+
+// Revoke removes a lease and every key attached to it.
+func (l *leases) Revoke(ctx context.Context, id string) ([]string, error) {
+	keys, err := l.Keys(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// The lease and everything attached to it are removed in one batch.
+	tx := storage.NewTxn(l.st)
+	defer tx.Rollback()
+	var nodes []string
+	for _, key := range keys {
+		if nodeID, ok := strings.CutPrefix(key, peers.NodesPrefix+"/"); ok {
+			// Nodes go through peers so their edges and indexes go too.
+			if err := peers.New(tx).Delete(ctx, nodeID); err != nil {
+				return nil, fmt.Errorf("delete node %q: %w", nodeID, err)
+			}
+			nodes = append(nodes, nodeID)
+		} else if err := tx.Delete(ctx, key); err != nil {
+			return nil, fmt.Errorf("delete %q: %w", key, err)
+		}
+		if err := tx.Delete(ctx, attachmentsPrefix(id)+key); err != nil {
+			return nil, fmt.Errorf("delete attachment: %w", err)
+		}
+		if err := tx.Delete(ctx, keyLeaseKey(key)); err != nil {
+			return nil, fmt.Errorf("delete key lease: %w", err)
+		}
+	}
+	if err := tx.Delete(ctx, leaseKey(id)); err != nil {
+		return nil, fmt.Errorf("delete lease: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("revoke lease: %w", err)
+	}
+	return nodes, nil
+}
+
+func (l *leases) put(ctx context.Context, lease Lease) error {
+	data, err := json.Marshal(lease)
+	if err != nil {
+		return fmt.Errorf("marshal lease: %w", err)
+	}
+	if err := l.st.Put(ctx, leaseKey(lease.ID), string(data), 0); err != nil {
+		return fmt.Errorf("put lease: %w", err)
+	}
+	return nil
+}
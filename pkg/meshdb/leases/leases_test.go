@@ -0,0 +1,188 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+func TestLeases(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	st, err := storage.NewTestStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	l := New(st)
+	p := peers.New(st)
+
+	if _, err := l.Grant(ctx, time.Second, "owner"); err == nil {
+		t.Fatal("expected error granting lease below the minimum TTL")
+	}
+	lease, err := l.Grant(ctx, MinTTL, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease.Expired(time.Now()) {
+		t.Fatal("expected new lease to not be expired")
+	}
+	if !lease.Expired(lease.ExpiresAt) {
+		t.Fatal("expected lease to be expired at its expiry")
+	}
+
+	// Renewing the lease should push out the expiry.
+	time.Sleep(10 * time.Millisecond)
+	renewed, err := l.KeepAlive(ctx, lease.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !renewed.ExpiresAt.After(lease.ExpiresAt) {
+		t.Fatalf("expected renewed expiry %s to be after %s", renewed.ExpiresAt, lease.ExpiresAt)
+	}
+	if _, err := l.KeepAlive(ctx, "missing"); !errors.Is(err, ErrLeaseNotFound) {
+		t.Fatalf("expected ErrLeaseNotFound, got %v", err)
+	}
+
+	// Attach a node and a plain key to the lease.
+	for _, id := range []string{"a", "b"} {
+		if err := p.Put(ctx, peers.Node{ID: id, ZoneAwarenessID: "zone"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.PutEdge(ctx, peers.Edge{From: "a", To: "b", Weight: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Put(ctx, "/registry/routes/a-auto", "{}", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Attach(ctx, lease.ID, peers.NodesPrefix+"/a", "/registry/routes/a-auto"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Attach(ctx, "missing", "/foo"); !errors.Is(err, ErrLeaseNotFound) {
+		t.Fatalf("expected ErrLeaseNotFound, got %v", err)
+	}
+	keys, err := l.Keys(ctx, lease.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 attached keys, got %v", keys)
+	}
+
+	// Revoking the lease removes the node, its edges and the plain key.
+	nodes, err := l.Revoke(ctx, lease.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0] != "a" {
+		t.Fatalf("expected node a to be removed, got %v", nodes)
+	}
+	if _, err := p.Get(ctx, "a"); !errors.Is(err, peers.ErrNodeNotFound) {
+		t.Fatalf("expected node a to be deleted, got %v", err)
+	}
+	if _, err := p.Get(ctx, "b"); err != nil {
+		t.Fatalf("expected node b to remain, got %v", err)
+	}
+	edges, _, err := p.ListEdgesPage(ctx, storage.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edges) != 0 {
+		t.Fatalf("expected edges to be deleted, got %v", edges)
+	}
+	zone, err := p.ListByZoneID(ctx, "zone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zone) != 1 || zone[0].ID != "b" {
+		t.Fatalf("expected only node b in zone, got %v", zone)
+	}
+	if _, err := st.Get(ctx, "/registry/routes/a-auto"); !errors.Is(err, storage.ErrKeyNotFound) {
+		t.Fatalf("expected route to be deleted, got %v", err)
+	}
+	if _, err := l.Get(ctx, lease.ID); !errors.Is(err, ErrLeaseNotFound) {
+		t.Fatalf("expected lease to be deleted, got %v", err)
+	}
+	if keys, err := st.List(ctx, AttachmentsPrefix+"/"); err != nil || len(keys) != 0 {
+		t.Fatalf("expected no attachments left, got %v %v", keys, err)
+	}
+	if keys, err := st.List(ctx, KeyLeasesPrefix+"/"); err != nil || len(keys) != 0 {
+		t.Fatalf("expected no key leases left, got %v %v", keys, err)
+	}
+}
+
+func TestLeasesMoveAndDetach(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	st, err := storage.NewTestStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	l := New(st)
+
+	first, err := l.Grant(ctx, MinTTL, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := l.Grant(ctx, MinTTL, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Attach(ctx, first.ID, "/foo", "/bar"); err != nil {
+		t.Fatal(err)
+	}
+	// Attaching a key to another lease moves it there.
+	if err := l.Attach(ctx, second.ID, "/foo"); err != nil {
+		t.Fatal(err)
+	}
+	if keys, err := l.Keys(ctx, first.ID); err != nil || len(keys) != 1 || keys[0] != "/bar" {
+		t.Fatalf("expected only /bar on the first lease, got %v: %v", keys, err)
+	}
+	if keys, err := l.Keys(ctx, second.ID); err != nil || len(keys) != 1 || keys[0] != "/foo" {
+		t.Fatalf("expected only /foo on the second lease, got %v: %v", keys, err)
+	}
+	// Detaching a key removes it from its lease and leaves it in place
+	// when the lease is revoked.
+	if err := st.Put(ctx, "/foo", "value", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Detach(ctx, "/foo", "/missing"); err != nil {
+		t.Fatal(err)
+	}
+	if keys, err := l.Keys(ctx, second.ID); err != nil || len(keys) != 0 {
+		t.Fatalf("expected no keys on the second lease, got %v: %v", keys, err)
+	}
+	if _, err := l.Revoke(ctx, second.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Get(ctx, "/foo"); err != nil {
+		t.Fatalf("expected /foo to survive revoking its previous lease, got %v", err)
+	}
+	if keys, err := st.List(ctx, KeyLeasesPrefix+"/"); err != nil || len(keys) != 1 {
+		t.Fatalf("expected only the key lease of /bar, got %v %v", keys, err)
+	}
+}
@@ -18,12 +18,14 @@ limitations under the License.
 package networking
 
 import (
+	"errors"
 	"sort"
 	"strings"
 
 	v1 "github.com/webmeshproj/api/v1"
 
 	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
 	"github.com/webmeshproj/webmesh/pkg/meshdb/rbac"
 	"github.com/webmeshproj/webmesh/pkg/storage"
 )
@@ -48,6 +50,9 @@ func (a ACLs) Proto() []*v1.NetworkACL {
 type ACL struct {
 	*v1.NetworkACL
 	storage storage.Storage
+	// selected holds the IDs of the nodes matched by each selector reference
+	// in the ACL, resolved the first time the reference is evaluated.
+	selected map[string]map[string]struct{}
 }
 
 // Proto returns the protobuf representation of the ACL.
@@ -118,7 +123,7 @@ func (acl *ACL) Matches(ctx context.Context, action *v1.NetworkAction) bool {
 					groupName := strings.TrimPrefix(node, "group:")
 					group, err := rbac.New(acl.storage).GetGroup(ctx, groupName)
 					if err != nil {
-						if err != rbac.ErrGroupNotFound {
+						if !errors.Is(err, rbac.ErrGroupNotFound) {
 							context.LoggerFrom(ctx).Error("failed to get group", "group", groupName, "error", err)
 							return false
 						}
@@ -142,7 +147,7 @@ func (acl *ACL) Matches(ctx context.Context, action *v1.NetworkAction) bool {
 					}
 				}
 			}
-			if !containsOrWildcardMatch(acl.GetSourceNodes(), action.GetSrcNode()) {
+			if !containsOrWildcardMatch(acl.GetSourceNodes(), action.GetSrcNode()) && !acl.selectsNode(ctx, acl.GetSourceNodes(), action.GetSrcNode()) {
 				return false
 			}
 		}
@@ -159,7 +164,7 @@ func (acl *ACL) Matches(ctx context.Context, action *v1.NetworkAction) bool {
 					groupName := strings.TrimPrefix(node, "group:")
 					group, err := rbac.New(acl.storage).GetGroup(ctx, groupName)
 					if err != nil {
-						if err != rbac.ErrGroupNotFound {
+						if !errors.Is(err, rbac.ErrGroupNotFound) {
 							context.LoggerFrom(ctx).Error("failed to get group", "group", groupName, "error", err)
 							return false
 						}
@@ -183,7 +188,7 @@ func (acl *ACL) Matches(ctx context.Context, action *v1.NetworkAction) bool {
 					}
 				}
 			}
-			if !containsOrWildcardMatch(acl.GetDestinationNodes(), action.GetDstNode()) {
+			if !containsOrWildcardMatch(acl.GetDestinationNodes(), action.GetDstNode()) && !acl.selectsNode(ctx, acl.GetDestinationNodes(), action.GetDstNode()) {
 				return false
 			}
 		}
@@ -219,6 +224,51 @@ func (acl *ACL) Matches(ctx context.Context, action *v1.NetworkAction) bool {
 	return true
 }
 
+// selectsNode returns true if any of the selector references in the given
+// list of nodes match the labels of the node with the given ID.
+func (acl *ACL) selectsNode(ctx context.Context, refs []string, nodeID string) bool {
+	for _, ref := range refs {
+		if _, ok := acl.selectedNodes(ctx, ref)[nodeID]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// selectedNodes returns the IDs of the nodes matched by the given selector
+// reference, or nil if ref is not a valid selector reference. Each reference
+// is resolved once per ACL, so that evaluating an ACL against every pair of
+// nodes does not read the nodes for every pair.
+func (acl *ACL) selectedNodes(ctx context.Context, ref string) map[string]struct{} {
+	if ids, ok := acl.selected[ref]; ok {
+		return ids
+	}
+	if acl.selected == nil {
+		acl.selected = make(map[string]map[string]struct{})
+	}
+	sel, ok, err := peers.SelectorFromRef(ref)
+	if !ok {
+		acl.selected[ref] = nil
+		return nil
+	}
+	if err != nil {
+		context.LoggerFrom(ctx).Error("invalid node selector", "selector", ref, "error", err)
+		acl.selected[ref] = nil
+		return nil
+	}
+	nodes, err := peers.New(acl.storage).ListBySelector(ctx, sel)
+	if err != nil {
+		context.LoggerFrom(ctx).Error("failed to list nodes by selector", "selector", ref, "error", err)
+		return nil
+	}
+	ids := make(map[string]struct{}, len(nodes))
+	for _, node := range nodes {
+		ids[node.ID] = struct{}{}
+	}
+	acl.selected[ref] = ids
+	return ids
+}
+
 func containsOrWildcardMatch(ss []string, s string) bool {
 	for _, v := range ss {
 		if v == "*" {
@@ -61,6 +61,9 @@ type Networking interface {
 	DeleteNetworkACL(ctx context.Context, name string) error
 	// ListNetworkACLs returns a list of NetworkACLs.
 	ListNetworkACLs(ctx context.Context) (ACLs, error)
+	// ListNetworkACLsPage returns a page of NetworkACLs ordered by name and
+	// the token for the next page.
+	ListNetworkACLsPage(ctx context.Context, page storage.Page) (ACLs, string, error)
 
 	// PutRoute creates or updates a Route.
 	PutRoute(ctx context.Context, route *v1.Route) error
@@ -74,6 +77,9 @@ type Networking interface {
 	DeleteRoute(ctx context.Context, name string) error
 	// ListRoutes returns a list of Routes.
 	ListRoutes(ctx context.Context) ([]*v1.Route, error)
+	// ListRoutesPage returns a page of Routes ordered by name and the token
+	// for the next page.
+	ListRoutesPage(ctx context.Context, page storage.Page) ([]*v1.Route, string, error)
 
 	// FilterGraph filters the adjacency map in the given graph for the given node name according
 	// to the current network ACLs. If the ACL list is nil, an empty adjacency map is returned. An
@@ -153,8 +159,15 @@ func (n *networking) DeleteNetworkACL(ctx context.Context, name string) error {
 
 // ListNetworkACLs returns a list of NetworkACLs.
 func (n *networking) ListNetworkACLs(ctx context.Context) (ACLs, error) {
+	out, _, err := n.ListNetworkACLsPage(ctx, storage.Page{})
+	return out, err
+}
+
+// ListNetworkACLsPage returns a page of NetworkACLs and the token for the
+// next page.
+func (n *networking) ListNetworkACLsPage(ctx context.Context, page storage.Page) (ACLs, string, error) {
 	out := make(ACLs, 0)
-	err := n.IterPrefix(ctx, NetworkACLsPrefix, func(_, value string) error {
+	next, err := storage.IterPage(ctx, n, NetworkACLsPrefix, page, func(_, value string) error {
 		acl := &v1.NetworkACL{}
 		err := protojson.Unmarshal([]byte(value), acl)
 		if err != nil {
@@ -166,7 +179,7 @@ func (n *networking) ListNetworkACLs(ctx context.Context) (ACLs, error) {
 		})
 		return nil
 	})
-	return out, err
+	return out, next, err
 }
 
 // PutRoute creates or updates a Route.
@@ -208,10 +221,27 @@ func (n *networking) GetRoutesByNode(ctx context.Context, nodeName string) ([]*v
 		return nil, fmt.Errorf("list network routes: %w", err)
 	}
 	out := make([]*v1.Route, 0)
+	var node *peers.Node
 	for _, route := range routes {
 		r := route
 		if r.GetNode() == nodeName {
 			out = append(out, r)
+			continue
+		}
+		// Routes owned by a selector belong to every node it matches.
+		sel, ok, err := peers.SelectorFromRef(r.GetNode())
+		if !ok || err != nil {
+			continue
+		}
+		if node == nil {
+			found, err := peers.New(n).Get(ctx, nodeName)
+			if err != nil && !errors.Is(err, peers.ErrNodeNotFound) {
+				return nil, fmt.Errorf("get node: %w", err)
+			}
+			node = &found
+		}
+		if node.ID != "" && sel.Matches(node.Labels) {
+			out = append(out, r)
 		}
 	}
 	return out, nil
@@ -248,8 +278,14 @@ func (n *networking) DeleteRoute(ctx context.Context, name string) error {
 
 // ListRoutes returns a list of Routes.
 func (n *networking) ListRoutes(ctx context.Context) ([]*v1.Route, error) {
+	out, _, err := n.ListRoutesPage(ctx, storage.Page{})
+	return out, err
+}
+
+// ListRoutesPage returns a page of Routes and the token for the next page.
+func (n *networking) ListRoutesPage(ctx context.Context, page storage.Page) ([]*v1.Route, string, error) {
 	out := make([]*v1.Route, 0)
-	err := n.IterPrefix(ctx, RoutesPrefix, func(_, value string) error {
+	next, err := storage.IterPage(ctx, n, RoutesPrefix, page, func(_, value string) error {
 		route := &v1.Route{}
 		err := protojson.Unmarshal([]byte(value), route)
 		if err != nil {
@@ -258,7 +294,7 @@ func (n *networking) ListRoutes(ctx context.Context) ([]*v1.Route, error) {
 		out = append(out, route)
 		return nil
 	})
-	return out, err
+	return out, next, err
 }
 
 // FilterGraph filters the adjacency map in the given graph for the given node name according
@@ -60,12 +60,35 @@ func (g *GraphStore) AddVertex(nodeID string, node Node, props graph.VertexPrope
 	if nodeID == "" {
 		return fmt.Errorf("node ID must not be empty")
 	}
+	return g.putVertex(context.Background(), node, nil)
+}
+
+// putVertex writes the node and moves its index entries in the same batch.
+// The node is written only if it is still at the revision it was read at,
+// so that a concurrent change cannot leave its indexes stale. If revision
+// is not nil, that is also required to be the given revision.
+func (g *GraphStore) putVertex(ctx context.Context, node Node, revision *uint64) error {
 	data, err := json.Marshal(node)
 	if err != nil {
 		return fmt.Errorf("marshal node: %w", err)
 	}
-	key := fmt.Sprintf("%s/%s", NodesPrefix, nodeID)
-	if err := g.Put(context.Background(), key, string(data), 0); err != nil {
+	key := fmt.Sprintf("%s/%s", NodesPrefix, node.ID)
+	var old *Node
+	value, rev, err := g.GetRevision(ctx, key)
+	if err == nil {
+		var current Node
+		if err := json.Unmarshal([]byte(value), &current); err != nil {
+			return fmt.Errorf("unmarshal node: %w", err)
+		}
+		old = &current
+	} else if !errors.Is(err, storage.ErrKeyNotFound) {
+		return fmt.Errorf("get node: %w", err)
+	}
+	if revision != nil && *revision != rev {
+		return fmt.Errorf("%w: key %q is at revision %d, expected %d", storage.ErrRevisionMismatch, key, rev, *revision)
+	}
+	ops := append(indexOps(old, &node), storage.Op{Type: storage.OpPutIfRevision, Key: key, Value: string(data), Revision: rev})
+	if err := g.Batch(ctx, ops); err != nil {
 		return fmt.Errorf("put node: %w", err)
 	}
 	return nil
@@ -101,11 +124,8 @@ func (g *GraphStore) RemoveVertex(nodeID string) error {
 		return fmt.Errorf("node ID must not be empty")
 	}
 	key := fmt.Sprintf("%s/%s", NodesPrefix, nodeID)
-	_, err := g.Get(context.Background(), key)
+	node, _, err := g.Vertex(nodeID)
 	if err != nil {
-		if errors.Is(err, storage.ErrKeyNotFound) {
-			err = graph.ErrVertexNotFound
-		}
 		return err
 	}
 	// Check if the node has edges.
@@ -124,7 +144,8 @@ func (g *GraphStore) RemoveVertex(nodeID string) error {
 			return graph.ErrVertexHasEdges
 		}
 	}
-	if err := g.Delete(context.Background(), key); err != nil {
+	ops := append(indexOps(&node, nil), storage.Op{Type: storage.OpDelete, Key: key})
+	if err := g.Batch(context.Background(), ops); err != nil {
 		return fmt.Errorf("delete node: %w", err)
 	}
 	return nil
@@ -312,3 +333,22 @@ func (g *GraphStore) ListEdges() ([]graph.Edge[string], error) {
 	})
 	return edges, err
 }
+
+// PutEdgeOps returns the operations that store the given edge in a batch.
+// The edge is stored in both directions, the same way the undirected graph
+// stores it, without checking the existing nodes and edges.
+func PutEdgeOps(edge Edge) ([]storage.Op, error) {
+	ops := make([]storage.Op, 0, 2)
+	for _, e := range []Edge{edge, {From: edge.To, To: edge.From, Weight: edge.Weight, Attrs: edge.Attrs}} {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, fmt.Errorf("marshal edge: %w", err)
+		}
+		ops = append(ops, storage.Op{
+			Type:  storage.OpPut,
+			Key:   fmt.Sprintf("%s/%s/%s", EdgesPrefix, e.From, e.To),
+			Value: string(data),
+		})
+	}
+	return ops, nil
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package peers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	v1 "github.com/webmeshproj/api/v1"
+
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+// NodeIndexesPrefix is where secondary indexes over nodes are stored in the
+// database. Index entries are written in the same batch as the node they
+// refer to and their values are the ID of the node.
+//
+//	/registry/indexes/nodes/zone/<zone>/<id>
+//	/registry/indexes/nodes/feature/<feature>/<id>
+//	/registry/indexes/nodes/public/<id>
+//	/registry/indexes/nodes/label/<key>/<value>/<id>
+const NodeIndexesPrefix = "/registry/indexes/nodes"
+
+// NodeIndexesVersionKey is set once the node indexes have been built. Until
+// it is present, queries fall back to scanning every node.
+const NodeIndexesVersionKey = NodeIndexesPrefix + "/version"
+
+// nodeIndexesVersion is the current version of the node indexes. Bumping it
+// causes queries to ignore indexes until they are rebuilt.
+const nodeIndexesVersion = "2"
+
+func zoneIndexPrefix(zoneID string) string {
+	return fmt.Sprintf("%s/zone/%s/", NodeIndexesPrefix, url.PathEscape(zoneID))
+}
+
+func featureIndexPrefix(feature v1.Feature) string {
+	return fmt.Sprintf("%s/feature/%s/", NodeIndexesPrefix, feature.String())
+}
+
+func labelIndexPrefix(key, value string) string {
+	return fmt.Sprintf("%s/label/%s/%s/", NodeIndexesPrefix, url.PathEscape(key), url.PathEscape(value))
+}
+
+func publicIndexPrefix() string {
+	return NodeIndexesPrefix + "/public/"
+}
+
+// indexKeys returns the index keys for the given node.
+func indexKeys(node Node) []string {
+	keys := []string{zoneIndexPrefix(node.ZoneAwarenessID) + node.ID}
+	for _, feature := range node.Features {
+		keys = append(keys, featureIndexPrefix(feature)+node.ID)
+	}
+	if node.PrimaryEndpoint != "" {
+		keys = append(keys, publicIndexPrefix()+node.ID)
+	}
+	for key, value := range node.Labels {
+		keys = append(keys, labelIndexPrefix(key, value)+node.ID)
+	}
+	return keys
+}
+
+// indexOps returns the operations to move the indexes of a node from old to
+// new. A nil old means the node is new and a nil new means it is being
+// removed.
+func indexOps(old, new *Node) []storage.Op {
+	var ops []storage.Op
+	want := make(map[string]struct{})
+	if new != nil {
+		for _, key := range indexKeys(*new) {
+			if _, ok := want[key]; ok {
+				continue
+			}
+			want[key] = struct{}{}
+			ops = append(ops, storage.Op{Type: storage.OpPut, Key: key, Value: new.ID})
+		}
+	}
+	if old != nil {
+		for _, key := range indexKeys(*old) {
+			if _, ok := want[key]; ok {
+				continue
+			}
+			want[key] = struct{}{}
+			ops = append(ops, storage.Op{Type: storage.OpDelete, Key: key})
+		}
+	}
+	return ops
+}
+
+// indexed returns true if the node indexes have been built.
+func (p *peers) indexed(ctx context.Context) (bool, error) {
+	version, err := p.db.Get(ctx, NodeIndexesVersionKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("get index version: %w", err)
+	}
+	return version == nodeIndexesVersion, nil
+}
+
+// listIndex returns the nodes referenced by the index entries under the
+// given prefix.
+func (p *peers) listIndex(ctx context.Context, prefix string) ([]Node, error) {
+	keys, err := p.db.List(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("list index: %w", err)
+	}
+	out := make([]Node, 0, len(keys))
+	for _, key := range keys {
+		id := strings.TrimPrefix(key, prefix)
+		node, err := p.Get(ctx, id)
+		if err != nil {
+			if errors.Is(err, ErrNodeNotFound) {
+				// The index is stale, skip the entry until it is rebuilt.
+				continue
+			}
+			return nil, err
+		}
+		out = append(out, node)
+	}
+	return out, nil
+}
+
+// listFiltered returns the nodes under the given index prefix. If the
+// indexes have not been built, every node is scanned with the given filter.
+func (p *peers) listFiltered(ctx context.Context, prefix string, filter func(Node) bool) ([]Node, error) {
+	ok, err := p.indexed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return p.listIndex(ctx, prefix)
+	}
+	nodes, err := p.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list nodes: %w", err)
+	}
+	out := make([]Node, 0)
+	for _, node := range nodes {
+		if filter(node) {
+			out = append(out, node)
+		}
+	}
+	return out, nil
+}
+
+func (p *peers) ListBySelector(ctx context.Context, sel Selector) ([]Node, error) {
+	var candidates []Node
+	var err error
+	if r, ok := indexedRequirement(sel); ok {
+		candidates, err = p.listByLabel(ctx, r.Key, r.Values)
+	} else {
+		candidates, err = p.List(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Node, 0)
+	for _, node := range candidates {
+		if sel.Matches(node.Labels) {
+			out = append(out, node)
+		}
+	}
+	return out, nil
+}
+
+// indexedRequirement returns the first requirement of the selector that can
+// be answered from the label index.
+func indexedRequirement(sel Selector) (Requirement, bool) {
+	for _, r := range sel {
+		if r.Op == SelectorOpEquals || r.Op == SelectorOpIn {
+			return r, true
+		}
+	}
+	return Requirement{}, false
+}
+
+// listByLabel returns the nodes with the given label set to any of the given values.
+func (p *peers) listByLabel(ctx context.Context, key string, values []string) ([]Node, error) {
+	var out []Node
+	seen := make(map[string]struct{})
+	for _, value := range values {
+		nodes, err := p.listFiltered(ctx, labelIndexPrefix(key, value), func(n Node) bool {
+			v, ok := n.Labels[key]
+			return ok && v == value
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			if _, ok := seen[node.ID]; ok {
+				continue
+			}
+			seen[node.ID] = struct{}{}
+			out = append(out, node)
+		}
+	}
+	return out, nil
+}
+
+// indexRebuildChunkSize is the maximum number of operations RebuildIndexes
+// writes in a single batch.
+const indexRebuildChunkSize = 512
+
+// RebuildIndexes rebuilds the secondary indexes over nodes from scratch and
+// returns the number of nodes indexed. The version key is removed first so
+// that queries scan every node while the indexes are written in chunks of at
+// most indexRebuildChunkSize operations, and it is put back once they are
+// complete.
+func (p *peers) RebuildIndexes(ctx context.Context) (int, error) {
+	err := p.db.Delete(ctx, NodeIndexesVersionKey)
+	if err != nil && !errors.Is(err, storage.ErrKeyNotFound) {
+		return 0, fmt.Errorf("delete index version: %w", err)
+	}
+	var ops []storage.Op
+	flush := func(force bool) error {
+		if len(ops) == 0 || (!force && len(ops) < indexRebuildChunkSize) {
+			return nil
+		}
+		if err := p.db.Batch(ctx, ops); err != nil {
+			return fmt.Errorf("write indexes: %w", err)
+		}
+		ops = nil
+		return nil
+	}
+	// Collect the nodes before writing, writes are not allowed while
+	// iterating over the underlying storage.
+	var nodes []Node
+	err = p.db.IterPrefix(ctx, NodesPrefix+"/", func(_, value string) error {
+		var node Node
+		if err := json.Unmarshal([]byte(value), &node); err != nil {
+			return fmt.Errorf("unmarshal node: %w", err)
+		}
+		nodes = append(nodes, node)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	want := map[string]struct{}{NodeIndexesVersionKey: {}}
+	for _, node := range nodes {
+		node := node
+		for _, op := range indexOps(nil, &node) {
+			want[op.Key] = struct{}{}
+			ops = append(ops, op)
+		}
+		if err := flush(false); err != nil {
+			return 0, err
+		}
+	}
+	// Remove any stale entries left over from a previous index. Entries for
+	// nodes that changed since they were read above are left alone.
+	existing, err := p.db.List(ctx, NodeIndexesPrefix+"/")
+	if err != nil {
+		return 0, fmt.Errorf("list indexes: %w", err)
+	}
+	for _, key := range existing {
+		if _, ok := want[key]; ok {
+			continue
+		}
+		if node, err := p.Get(ctx, key[strings.LastIndex(key, "/")+1:]); err == nil {
+			if slices.Contains(indexKeys(node), key) {
+				continue
+			}
+		}
+		ops = append(ops, storage.Op{Type: storage.OpDelete, Key: key})
+		if err := flush(false); err != nil {
+			return 0, err
+		}
+	}
+	if err := flush(true); err != nil {
+		return 0, err
+	}
+	if err := p.db.Put(ctx, NodeIndexesVersionKey, nodeIndexesVersion, 0); err != nil {
+		return 0, fmt.Errorf("write index version: %w", err)
+	}
+	return len(nodes), nil
+}
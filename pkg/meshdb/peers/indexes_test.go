@@ -0,0 +1,216 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package peers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	v1 "github.com/webmeshproj/api/v1"
+
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+func TestNodeIndexes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	st, err := storage.NewTestStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	p := New(st)
+
+	nodes := []Node{
+		{ID: "a", ZoneAwarenessID: "zone-1", PrimaryEndpoint: "1.1.1.1", Features: []v1.Feature{v1.Feature_MESH_DNS}},
+		{ID: "b", ZoneAwarenessID: "zone-1", Features: []v1.Feature{v1.Feature_MESH_DNS, v1.Feature_ICE_NEGOTIATION}},
+		{ID: "c", ZoneAwarenessID: "zone-2"},
+	}
+	// Written before the indexes exist, queries should fall back to a scan.
+	for _, n := range nodes {
+		if err := p.Put(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(t *testing.T) {
+		t.Helper()
+		expectIDs(t, "zone-1", func() ([]Node, error) { return p.ListByZoneID(ctx, "zone-1") }, "a", "b")
+		expectIDs(t, "zone-2", func() ([]Node, error) { return p.ListByZoneID(ctx, "zone-2") }, "c")
+		expectIDs(t, "mesh dns", func() ([]Node, error) { return p.ListByFeature(ctx, v1.Feature_MESH_DNS) }, "a", "b")
+		expectIDs(t, "ice", func() ([]Node, error) { return p.ListByFeature(ctx, v1.Feature_ICE_NEGOTIATION) }, "b")
+		expectIDs(t, "public", func() ([]Node, error) { return p.ListPublicNodes(ctx) }, "a")
+	}
+	check(t)
+	count, err := p.RebuildIndexes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(nodes) {
+		t.Fatalf("expected %d nodes indexed, got %d", len(nodes), count)
+	}
+	check(t)
+
+	// Updates should move the node between index entries.
+	nodes[0].ZoneAwarenessID = "zone-2"
+	nodes[0].PrimaryEndpoint = ""
+	nodes[0].Features = nil
+	if err := p.Put(ctx, nodes[0]); err != nil {
+		t.Fatal(err)
+	}
+	expectIDs(t, "zone-1", func() ([]Node, error) { return p.ListByZoneID(ctx, "zone-1") }, "b")
+	expectIDs(t, "zone-2", func() ([]Node, error) { return p.ListByZoneID(ctx, "zone-2") }, "a", "c")
+	expectIDs(t, "mesh dns", func() ([]Node, error) { return p.ListByFeature(ctx, v1.Feature_MESH_DNS) }, "b")
+	expectIDs(t, "public", func() ([]Node, error) { return p.ListPublicNodes(ctx) })
+
+	// Deletes should remove every index entry of the node.
+	if err := p.Delete(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := st.List(ctx, NodeIndexesPrefix+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		zoneIndexPrefix("zone-2") + "a",
+		zoneIndexPrefix("zone-2") + "c",
+		NodeIndexesVersionKey,
+	}
+	sort.Strings(want)
+	if len(keys) != len(want) {
+		t.Fatalf("expected index keys %v, got %v", want, keys)
+	}
+	for i := range keys {
+		if keys[i] != want[i] {
+			t.Fatalf("expected index keys %v, got %v", want, keys)
+		}
+	}
+}
+
+func TestRebuildIndexesInChunks(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	st, err := storage.NewTestStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	p := New(st)
+
+	// Enough nodes that the rebuild spans several batches.
+	total := indexRebuildChunkSize + 10
+	for i := 0; i < total; i++ {
+		if err := p.Put(ctx, Node{ID: fmt.Sprintf("node-%d", i), ZoneAwarenessID: "zone-1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stale := zoneIndexPrefix("zone-gone") + "node-0"
+	if err := st.Put(ctx, stale, "node-0", 0); err != nil {
+		t.Fatal(err)
+	}
+	count, err := p.RebuildIndexes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != total {
+		t.Fatalf("expected %d nodes indexed, got %d", total, count)
+	}
+	if _, err := st.Get(ctx, stale); err == nil {
+		t.Fatal("expected stale index entry to be removed")
+	}
+	if ok, err := p.(*peers).indexed(ctx); err != nil || !ok {
+		t.Fatalf("expected indexes to be marked built, got %v: %v", ok, err)
+	}
+	nodes, err := p.ListByZoneID(ctx, "zone-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != total {
+		t.Fatalf("expected %d nodes in zone-1, got %d", total, len(nodes))
+	}
+}
+
+func expectIDs(t *testing.T, name string, list func() ([]Node, error), ids ...string) {
+	t.Helper()
+	nodes, err := list()
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	var got []string
+	for _, n := range nodes {
+		got = append(got, n.ID)
+	}
+	sort.Strings(got)
+	if len(got) != len(ids) {
+		t.Fatalf("%s: expected nodes %v, got %v", name, ids, got)
+	}
+	for i := range got {
+		if got[i] != ids[i] {
+			t.Fatalf("%s: expected nodes %v, got %v", name, ids, got)
+		}
+	}
+}
+
+func TestPutIfRevision(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	st, err := storage.NewTestStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	p := New(st)
+
+	// A revision of zero should only create the node.
+	node := Node{ID: "a", Labels: map[string]string{"env": "dev"}}
+	if err := p.PutIfRevision(ctx, node, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.PutIfRevision(ctx, node, 0); !errors.Is(err, storage.ErrRevisionMismatch) {
+		t.Fatalf("expected ErrRevisionMismatch creating existing node, got %v", err)
+	}
+	_, rev, err := p.GetRevision(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A write in between should make the revision stale.
+	node.ZoneAwarenessID = "zone-1"
+	if err := p.Put(ctx, node); err != nil {
+		t.Fatal(err)
+	}
+	node.Labels = map[string]string{"env": "prod"}
+	if err := p.PutIfRevision(ctx, node, rev); !errors.Is(err, storage.ErrRevisionMismatch) {
+		t.Fatalf("expected ErrRevisionMismatch with stale revision, got %v", err)
+	}
+	_, rev, err = p.GetRevision(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.PutIfRevision(ctx, node, rev); err != nil {
+		t.Fatal(err)
+	}
+	sel, err := ParseSelector("env=prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectIDs(t, "env=prod", func() ([]Node, error) { return p.ListBySelector(ctx, sel) }, "a")
+}
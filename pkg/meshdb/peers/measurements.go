@@ -0,0 +1,162 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package peers
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+const (
+	// EdgeAttributeRTT is the edge attribute holding the smoothed round trip
+	// time between the two nodes of an edge in milliseconds.
+	EdgeAttributeRTT = "rtt_ms"
+	// EdgeAttributeLoss is the edge attribute holding the smoothed fraction
+	// of probes between the two nodes of an edge that went unanswered.
+	EdgeAttributeLoss = "loss"
+	// EdgeAttributeMeasuredAt is the edge attribute holding the time of the
+	// last measurement of an edge in RFC3339 format.
+	EdgeAttributeMeasuredAt = "measured_at"
+)
+
+// MeasurementSmoothing is the weight a new measurement is given when it is
+// folded into the smoothed measurement of an edge.
+const MeasurementSmoothing = 0.3
+
+// WeightHysteresis is the relative change a weight derived from a measurement
+// must make before it replaces the current weight of an edge.
+const WeightHysteresis = 0.2
+
+// LossPenalty is the round trip time added to the weight of an edge for
+// every probe that goes unanswered.
+const LossPenalty = time.Second
+
+// Measurement is a measurement of the link between two directly
+// connected nodes.
+type Measurement struct {
+	// RTT is the average round trip time.
+	RTT time.Duration `json:"rtt"`
+	// Loss is the fraction of probes that went unanswered.
+	Loss float64 `json:"loss"`
+	// MeasuredAt is when the measurement was taken.
+	MeasuredAt time.Time `json:"measuredAt"`
+}
+
+// MeasurementFromAttrs returns the measurement stored in the given edge attributes.
+// It returns false if the edge has not been measured.
+func MeasurementFromAttrs(attrs map[string]string) (Measurement, bool) {
+	var m Measurement
+	rtt, err := strconv.ParseFloat(attrs[EdgeAttributeRTT], 64)
+	if err != nil {
+		return m, false
+	}
+	loss, err := strconv.ParseFloat(attrs[EdgeAttributeLoss], 64)
+	if err != nil {
+		return m, false
+	}
+	measuredAt, err := time.Parse(time.RFC3339, attrs[EdgeAttributeMeasuredAt])
+	if err != nil {
+		return m, false
+	}
+	m.RTT = time.Duration(rtt * float64(time.Millisecond))
+	m.Loss = loss
+	m.MeasuredAt = measuredAt
+	return m, true
+}
+
+// Attrs returns the measurement as edge attributes.
+func (m Measurement) Attrs() map[string]string {
+	return map[string]string{
+		EdgeAttributeRTT:        strconv.FormatFloat(float64(m.RTT)/float64(time.Millisecond), 'f', 3, 64),
+		EdgeAttributeLoss:       strconv.FormatFloat(m.Loss, 'f', 3, 64),
+		EdgeAttributeMeasuredAt: m.MeasuredAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// Answered returns true if any probe of the measurement was answered. The
+// round trip time of a measurement without answers is meaningless.
+func (m Measurement) Answered() bool {
+	return m.Loss < 1
+}
+
+// Smooth folds the measurement into the previous smoothed measurement using
+// an exponentially weighted moving average. The previous round trip time is
+// kept if no probe of the measurement was answered.
+func (m Measurement) Smooth(prev Measurement) Measurement {
+	rtt := prev.RTT
+	if m.Answered() {
+		rtt += time.Duration(MeasurementSmoothing * float64(m.RTT-prev.RTT))
+	}
+	return Measurement{
+		RTT:        rtt,
+		Loss:       prev.Loss + MeasurementSmoothing*(m.Loss-prev.Loss),
+		MeasuredAt: m.MeasuredAt,
+	}
+}
+
+// Weight derives an edge weight from the measurement. The weight is the
+// round trip time in milliseconds with LossPenalty added for lost probes,
+// and is never less than 1.
+func (m Measurement) Weight() int {
+	cost := m.RTT + time.Duration(m.Loss*float64(LossPenalty))
+	weight := int(math.Round(float64(cost) / float64(time.Millisecond)))
+	return max(weight, 1)
+}
+
+// DeriveWeight returns the weight derived from the measurement and whether it
+// should replace the current weight. It only should when it differs from the
+// current weight by more than WeightHysteresis, so that jitter in measurements
+// does not keep changing routes.
+func (m Measurement) DeriveWeight(current int) (int, bool) {
+	weight := m.Weight()
+	if current <= 0 {
+		return weight, true
+	}
+	delta := math.Abs(float64(weight - current))
+	return weight, delta >= 1 && delta > WeightHysteresis*float64(current)
+}
+
+// RecordMeasurementOps returns the operations that fold the measurement into
+// the smoothed measurement stored in the attributes of the edge between the
+// given nodes, so that the measurements of several edges can be written in
+// one batch. It returns an error wrapping ErrEdgeNotFound if the nodes are
+// not directly connected.
+func RecordMeasurementOps(g Graph, from, to string, m Measurement) ([]storage.Op, error) {
+	edge, err := g.Edge(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("get edge: %w", err)
+	}
+	if prev, ok := MeasurementFromAttrs(edge.Properties.Attributes); ok {
+		m = m.Smooth(prev)
+	}
+	attrs := maps.Clone(edge.Properties.Attributes)
+	if attrs == nil {
+		attrs = make(map[string]string)
+	}
+	maps.Copy(attrs, m.Attrs())
+	return PutEdgeOps(Edge{
+		From:   from,
+		To:     to,
+		Weight: edge.Properties.Weight,
+		Attrs:  attrs,
+	})
+}
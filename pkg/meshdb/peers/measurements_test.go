@@ -0,0 +1,124 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package peers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+func TestRecordMeasurement(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	st, err := storage.NewTestStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	p := New(st)
+	for _, id := range []string{"a", "b", "c"} {
+		if err := p.Put(ctx, Node{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.PutEdge(ctx, Edge{From: "a", To: "b", Weight: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	record := func(from, to string, m Measurement) error {
+		ops, err := RecordMeasurementOps(p.Graph(), from, to, m)
+		if err != nil {
+			return err
+		}
+		return st.Batch(ctx, ops)
+	}
+	check := func(rtt time.Duration, loss float64, measuredAt time.Time) {
+		t.Helper()
+		for _, pair := range [][2]string{{"a", "b"}, {"b", "a"}} {
+			edge, err := p.Graph().Edge(pair[0], pair[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			m, ok := MeasurementFromAttrs(edge.Properties.Attributes)
+			if !ok {
+				t.Fatalf("edge %s-%s has no measurement: %v", pair[0], pair[1], edge.Properties.Attributes)
+			}
+			if m.RTT != rtt {
+				t.Errorf("expected smoothed rtt %s, got %s", rtt, m.RTT)
+			}
+			if m.Loss != loss {
+				t.Errorf("expected smoothed loss %v, got %v", loss, m.Loss)
+			}
+			if !m.MeasuredAt.Equal(measuredAt) {
+				t.Errorf("expected measured at %s, got %s", measuredAt, m.MeasuredAt)
+			}
+			if edge.Properties.Weight != 1 {
+				t.Errorf("expected weight to be left alone, got %d", edge.Properties.Weight)
+			}
+		}
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	if err := record("b", "a", Measurement{RTT: 10 * time.Millisecond, MeasuredAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := record("a", "b", Measurement{RTT: 20 * time.Millisecond, Loss: 0.5, MeasuredAt: now.Add(time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	check(13*time.Millisecond, 0.15, now.Add(time.Second))
+
+	// A measurement without answers keeps the round trip time.
+	if err := record("a", "b", Measurement{Loss: 1, MeasuredAt: now.Add(2 * time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	check(13*time.Millisecond, 0.405, now.Add(2*time.Second))
+
+	_, err = RecordMeasurementOps(p.Graph(), "a", "c", Measurement{RTT: time.Millisecond, MeasuredAt: now})
+	if !errors.Is(err, ErrEdgeNotFound) {
+		t.Fatalf("expected edge not found, got %v", err)
+	}
+}
+
+func TestDeriveWeight(t *testing.T) {
+	t.Parallel()
+
+	tc := []struct {
+		name    string
+		m       Measurement
+		current int
+		weight  int
+		change  bool
+	}{
+		{"unweighted", Measurement{RTT: 40 * time.Millisecond}, 0, 40, true},
+		{"within hysteresis", Measurement{RTT: 45 * time.Millisecond}, 40, 45, false},
+		{"outside hysteresis", Measurement{RTT: 50 * time.Millisecond}, 40, 50, true},
+		{"lower", Measurement{RTT: 30 * time.Millisecond}, 40, 30, true},
+		{"loss", Measurement{RTT: 40 * time.Millisecond, Loss: 0.1}, 40, 140, true},
+		{"minimum", Measurement{RTT: 100 * time.Microsecond}, 1, 1, false},
+	}
+	for _, c := range tc {
+		weight, change := c.m.DeriveWeight(c.current)
+		if weight != c.weight || change != c.change {
+			t.Errorf("%s: expected (%d, %v), got (%d, %v)", c.name, c.weight, c.change, weight, change)
+		}
+	}
+}
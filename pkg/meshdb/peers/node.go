@@ -53,6 +53,12 @@ type Node struct {
 	DNSPort int `json:"dnsPort"`
 	// Features are the node's features.
 	Features []v1.Feature `json:"features"`
+	// Labels are arbitrary key/value labels for selecting the node.
+	Labels map[string]string `json:"labels,omitempty"`
+	// AdminLabels are the labels set on the node through the Labels API.
+	// They are included in Labels and are the only ones kept when the node
+	// rejoins.
+	AdminLabels map[string]string `json:"adminLabels,omitempty"`
 	// UpdatedAt is the time the node was last updated.
 	UpdatedAt time.Time `json:"updatedAt"`
 }
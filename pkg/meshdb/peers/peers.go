@@ -77,12 +77,21 @@ type Peers interface {
 	Graph() Graph
 	// Put creates or updates a node.
 	Put(ctx context.Context, n Node) error
+	// PutIfRevision updates a node only if it is still at the given revision.
+	// A revision of zero only creates the node. An error wrapping
+	// storage.ErrRevisionMismatch is returned otherwise.
+	PutIfRevision(ctx context.Context, n Node, revision uint64) error
 	// Get gets a node by ID.
 	Get(ctx context.Context, id string) (Node, error)
+	// GetRevision gets a node by ID along with its current revision.
+	GetRevision(ctx context.Context, id string) (Node, uint64, error)
 	// Delete deletes a node.
 	Delete(ctx context.Context, id string) error
 	// List lists all nodes.
 	List(ctx context.Context) ([]Node, error)
+	// ListPage lists a page of nodes ordered by ID. It returns the token
+	// for the next page, or an empty string if there are no more nodes.
+	ListPage(ctx context.Context, page storage.Page) ([]Node, string, error)
 	// ListIDs lists all node IDs.
 	ListIDs(ctx context.Context) ([]string, error)
 	// ListPublicNodes lists all public nodes.
@@ -91,8 +100,18 @@ type Peers interface {
 	ListByZoneID(ctx context.Context, zoneID string) ([]Node, error)
 	// ListByFeature lists all nodes with a given feature.
 	ListByFeature(ctx context.Context, feature v1.Feature) ([]Node, error)
+	// ListBySelector lists all nodes whose labels match the selector.
+	ListBySelector(ctx context.Context, sel Selector) ([]Node, error)
+	// RebuildIndexes rebuilds the secondary indexes over nodes from scratch
+	// and returns the number of nodes indexed. It is needed once for
+	// databases created before the indexes existed.
+	RebuildIndexes(ctx context.Context) (int, error)
 	// AddEdge adds an edge between two nodes.
 	PutEdge(ctx context.Context, edge Edge) error
+	// ListEdgesPage lists a page of edges ordered by source and target.
+	// It returns the token for the next page, or an empty string if there
+	// are no more edges.
+	ListEdgesPage(ctx context.Context, page storage.Page) ([]Edge, string, error)
 	// RemoveEdge removes an edge between two nodes.
 	RemoveEdge(ctx context.Context, from, to string) error
 	// DrawGraph draws the graph of nodes to the given Writer.
@@ -159,7 +178,26 @@ type peers struct {
 func (p *peers) Graph() Graph { return p.graph }
 
 func (p *peers) Put(ctx context.Context, node Node) error {
-	// Dedup the wireguard endpoints.
+	err := p.graph.AddVertex(normalize(node))
+	if err != nil {
+		return fmt.Errorf("put node: %w", err)
+	}
+	return nil
+}
+
+func (p *peers) PutIfRevision(ctx context.Context, node Node, revision uint64) error {
+	if node.ID == "" {
+		return fmt.Errorf("node ID must not be empty")
+	}
+	err := (&GraphStore{p.db}).putVertex(ctx, normalize(node), &revision)
+	if err != nil {
+		return fmt.Errorf("put node: %w", err)
+	}
+	return nil
+}
+
+// normalize dedups the wireguard endpoints of a node and sets its update time.
+func normalize(node Node) Node {
 	seen := make(map[string]struct{})
 	var wgendpoints []string
 	for _, endpoint := range node.WireGuardEndpoints {
@@ -171,11 +209,7 @@ func (p *peers) Put(ctx context.Context, node Node) error {
 	}
 	node.WireGuardEndpoints = wgendpoints
 	node.UpdatedAt = time.Now().UTC()
-	err := p.graph.AddVertex(node)
-	if err != nil {
-		return fmt.Errorf("put node: %w", err)
-	}
-	return nil
+	return node
 }
 
 func (p *peers) Get(ctx context.Context, id string) (Node, error) {
@@ -189,6 +223,21 @@ func (p *peers) Get(ctx context.Context, id string) (Node, error) {
 	return node, nil
 }
 
+func (p *peers) GetRevision(ctx context.Context, id string) (Node, uint64, error) {
+	value, rev, err := p.db.GetRevision(ctx, fmt.Sprintf("%s/%s", NodesPrefix, id))
+	if err != nil {
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			return Node{}, 0, ErrNodeNotFound
+		}
+		return Node{}, 0, fmt.Errorf("get node: %w", err)
+	}
+	var node Node
+	if err := json.Unmarshal([]byte(value), &node); err != nil {
+		return Node{}, 0, fmt.Errorf("unmarshal node: %w", err)
+	}
+	return node, rev, nil
+}
+
 func (p *peers) Delete(ctx context.Context, id string) error {
 	edges, err := p.graph.Edges()
 	if err != nil {
@@ -215,8 +264,13 @@ func (p *peers) Delete(ctx context.Context, id string) error {
 }
 
 func (p *peers) List(ctx context.Context) ([]Node, error) {
+	out, _, err := p.ListPage(ctx, storage.Page{})
+	return out, err
+}
+
+func (p *peers) ListPage(ctx context.Context, page storage.Page) ([]Node, string, error) {
 	out := make([]Node, 0)
-	err := p.db.IterPrefix(ctx, NodesPrefix, func(_, value string) error {
+	next, err := storage.IterPage(ctx, p.db, NodesPrefix, page, func(_, value string) error {
 		var node Node
 		err := json.Unmarshal([]byte(value), &node)
 		if err != nil {
@@ -225,7 +279,7 @@ func (p *peers) List(ctx context.Context) ([]Node, error) {
 		out = append(out, node)
 		return nil
 	})
-	return out, err
+	return out, next, err
 }
 
 func (p *peers) ListIDs(ctx context.Context) ([]string, error) {
@@ -241,48 +295,35 @@ func (p *peers) ListIDs(ctx context.Context) ([]string, error) {
 }
 
 func (p *peers) ListPublicNodes(ctx context.Context) ([]Node, error) {
-	nodes, err := p.List(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("list nodes: %w", err)
-	}
-	out := make([]Node, 0)
-	for _, node := range nodes {
-		n := node
-		if n.PrimaryEndpoint != "" {
-			out = append(out, n)
-		}
-	}
-	return out, nil
+	return p.listFiltered(ctx, publicIndexPrefix(), func(n Node) bool {
+		return n.PrimaryEndpoint != ""
+	})
 }
 
 func (p *peers) ListByZoneID(ctx context.Context, zoneID string) ([]Node, error) {
-	nodes, err := p.List(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("list nodes: %w", err)
-	}
-	out := make([]Node, 0)
-	for _, node := range nodes {
-		n := node
-		if n.ZoneAwarenessID == zoneID {
-			out = append(out, n)
-		}
-	}
-	return out, nil
+	return p.listFiltered(ctx, zoneIndexPrefix(zoneID), func(n Node) bool {
+		return n.ZoneAwarenessID == zoneID
+	})
 }
 
 func (p *peers) ListByFeature(ctx context.Context, feature v1.Feature) ([]Node, error) {
-	nodes, err := p.List(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("list nodes: %w", err)
-	}
-	out := make([]Node, 0)
-	for _, node := range nodes {
-		n := node
-		if n.HasFeature(feature) {
-			out = append(out, n)
+	return p.listFiltered(ctx, featureIndexPrefix(feature), func(n Node) bool {
+		return n.HasFeature(feature)
+	})
+}
+
+func (p *peers) ListEdgesPage(ctx context.Context, page storage.Page) ([]Edge, string, error) {
+	out := make([]Edge, 0)
+	next, err := storage.IterPage(ctx, p.db, EdgesPrefix+"/", page, func(_, value string) error {
+		var edge Edge
+		err := json.Unmarshal([]byte(value), &edge)
+		if err != nil {
+			return fmt.Errorf("unmarshal edge: %w", err)
 		}
-	}
-	return out, nil
+		out = append(out, edge)
+		return nil
+	})
+	return out, next, err
 }
 
 func (p *peers) PutEdge(ctx context.Context, edge Edge) error {
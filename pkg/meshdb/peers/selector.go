@@ -0,0 +1,296 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package peers
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// SelectorPrefix is the prefix of node references that select nodes by their
+// labels instead of by ID. It can be used in network ACL source and destination
+// nodes, role binding subjects and route owners, e.g. "selector:app=db,env!=dev".
+const SelectorPrefix = "selector:"
+
+var (
+	labelNameRegex   = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)$`)
+	labelPrefixRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+	setRequirement   = regexp.MustCompile(`^([^\s!=(),]+)\s+(in|notin)\s*\((.*)\)$`)
+)
+
+// ValidateLabelKey returns an error if the given label key is invalid. Keys are
+// an optional DNS subdomain prefix and a slash followed by a name of at most 63
+// alphanumeric characters, dashes, underscores and dots.
+func ValidateLabelKey(key string) error {
+	name := key
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		prefix := key[:i]
+		name = key[i+1:]
+		if len(prefix) == 0 || len(prefix) > 253 || !labelPrefixRegex.MatchString(prefix) {
+			return fmt.Errorf("invalid label key %q: prefix must be a DNS subdomain", key)
+		}
+	}
+	if len(name) == 0 || len(name) > 63 || !labelNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid label key %q: name must be 1-63 alphanumeric characters, '-', '_' or '.'", key)
+	}
+	return nil
+}
+
+// ValidateLabelValue returns an error if the given label value is invalid.
+// Values are empty or follow the same rules as the name of a key.
+func ValidateLabelValue(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > 63 || !labelNameRegex.MatchString(value) {
+		return fmt.Errorf("invalid label value %q: must be at most 63 alphanumeric characters, '-', '_' or '.'", value)
+	}
+	return nil
+}
+
+// ValidateLabels returns an error if any of the given labels are invalid.
+func ValidateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if err := ValidateLabelKey(k); err != nil {
+			return err
+		}
+		if err := ValidateLabelValue(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseLabels parses labels in the format "key=value,key2=value2".
+func ParseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label %q: expected key=value", pair)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if err := ValidateLabelKey(key); err != nil {
+			return nil, err
+		}
+		if err := ValidateLabelValue(value); err != nil {
+			return nil, err
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// FormatLabels formats labels in the format accepted by ParseLabels, sorted by key.
+func FormatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// SelectorOp is the operator of a selector requirement.
+type SelectorOp string
+
+const (
+	// SelectorOpEquals matches labels with the given value.
+	SelectorOpEquals SelectorOp = "="
+	// SelectorOpNotEquals matches labels without the given value, including
+	// when the label is not set.
+	SelectorOpNotEquals SelectorOp = "!="
+	// SelectorOpIn matches labels with one of the given values.
+	SelectorOpIn SelectorOp = "in"
+	// SelectorOpNotIn matches labels with none of the given values, including
+	// when the label is not set.
+	SelectorOpNotIn SelectorOp = "notin"
+	// SelectorOpExists matches labels that are set.
+	SelectorOpExists SelectorOp = "exists"
+	// SelectorOpNotExists matches labels that are not set.
+	SelectorOpNotExists SelectorOp = "!"
+)
+
+// Requirement is a single requirement of a selector.
+type Requirement struct {
+	// Key is the label key.
+	Key string
+	// Op is the operator.
+	Op SelectorOp
+	// Values are the values for the operator. Equality operators have
+	// exactly one and existence operators have none.
+	Values []string
+}
+
+// Matches returns true if the labels satisfy the requirement.
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Op {
+	case SelectorOpEquals, SelectorOpIn:
+		return ok && slices.Contains(r.Values, value)
+	case SelectorOpNotEquals, SelectorOpNotIn:
+		return !ok || !slices.Contains(r.Values, value)
+	case SelectorOpExists:
+		return ok
+	case SelectorOpNotExists:
+		return !ok
+	}
+	return false
+}
+
+// String returns the requirement in the format accepted by ParseSelector.
+func (r Requirement) String() string {
+	switch r.Op {
+	case SelectorOpEquals, SelectorOpNotEquals:
+		return r.Key + string(r.Op) + r.Values[0]
+	case SelectorOpIn, SelectorOpNotIn:
+		return fmt.Sprintf("%s %s (%s)", r.Key, r.Op, strings.Join(r.Values, ","))
+	case SelectorOpNotExists:
+		return "!" + r.Key
+	}
+	return r.Key
+}
+
+// Selector selects nodes by their labels. A node is selected when its labels
+// satisfy every requirement. An empty selector selects every node.
+type Selector []Requirement
+
+// ParseSelector parses a Kubernetes style label selector. Requirements are
+// separated by commas and take one of the following forms:
+//
+//	key=value, key==value, key!=value
+//	key in (value1,value2), key notin (value1,value2)
+//	key, !key
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+	for _, part := range splitRequirements(s) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		r, err := parseRequirement(part)
+		if err != nil {
+			return nil, fmt.Errorf("parse selector %q: %w", s, err)
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// Matches returns true if the labels satisfy every requirement of the selector.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the selector in the format accepted by ParseSelector.
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for i, r := range s {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// SelectorFromRef returns the selector of a node reference starting with
+// SelectorPrefix. It returns false if the reference is not a selector.
+func SelectorFromRef(ref string) (Selector, bool, error) {
+	if !strings.HasPrefix(ref, SelectorPrefix) {
+		return nil, false, nil
+	}
+	sel, err := ParseSelector(strings.TrimPrefix(ref, SelectorPrefix))
+	return sel, true, err
+}
+
+// IsValidIDOrSelector returns true if the given node reference is a valid
+// node ID or a valid selector starting with SelectorPrefix.
+func IsValidIDOrSelector(ref string) bool {
+	_, ok, err := SelectorFromRef(ref)
+	if ok {
+		return err == nil
+	}
+	return IsValidID(ref)
+}
+
+func parseRequirement(s string) (Requirement, error) {
+	if m := setRequirement.FindStringSubmatch(s); m != nil {
+		r := Requirement{Key: m[1], Op: SelectorOp(m[2])}
+		for _, v := range strings.Split(m[3], ",") {
+			v = strings.TrimSpace(v)
+			if err := ValidateLabelValue(v); err != nil {
+				return r, err
+			}
+			r.Values = append(r.Values, v)
+		}
+		return r, ValidateLabelKey(r.Key)
+	}
+	var r Requirement
+	switch {
+	case strings.HasPrefix(s, "!"):
+		r = Requirement{Key: strings.TrimSpace(s[1:]), Op: SelectorOpNotExists}
+	case strings.Contains(s, "!="):
+		key, value, _ := strings.Cut(s, "!=")
+		r = Requirement{Key: strings.TrimSpace(key), Op: SelectorOpNotEquals, Values: []string{strings.TrimSpace(value)}}
+	case strings.Contains(s, "="):
+		key, value, _ := strings.Cut(s, "=")
+		value = strings.TrimPrefix(value, "=")
+		r = Requirement{Key: strings.TrimSpace(key), Op: SelectorOpEquals, Values: []string{strings.TrimSpace(value)}}
+	default:
+		r = Requirement{Key: s, Op: SelectorOpExists}
+	}
+	if err := ValidateLabelKey(r.Key); err != nil {
+		return r, err
+	}
+	for _, v := range r.Values {
+		if err := ValidateLabelValue(v); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// splitRequirements splits a selector on the commas that are not inside
+// the parentheses of a set requirement.
+func splitRequirements(s string) []string {
+	var parts []string
+	var depth, start int
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package peers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+func TestParseSelector(t *testing.T) {
+	t.Parallel()
+
+	labels := map[string]string{"app": "db", "env": "prod", "example.com/tier": "1"}
+	tc := []struct {
+		selector string
+		matches  bool
+	}{
+		{"", true},
+		{"app=db", true},
+		{"app==db", true},
+		{"app!=db", false},
+		{"app=web", false},
+		{"missing!=db", true},
+		{"env in (prod, staging)", true},
+		{"env notin (prod,staging)", false},
+		{"app=db,env in (dev),example.com/tier", false},
+		{"app=db, env notin (dev), example.com/tier=1", true},
+		{"example.com/tier", true},
+		{"!example.com/tier", false},
+		{"!missing", true},
+	}
+	for _, c := range tc {
+		sel, err := ParseSelector(c.selector)
+		if err != nil {
+			t.Fatalf("%q: %v", c.selector, err)
+		}
+		if got := sel.Matches(labels); got != c.matches {
+			t.Errorf("%q: expected match %v, got %v", c.selector, c.matches, got)
+		}
+		again, err := ParseSelector(sel.String())
+		if err != nil || again.String() != sel.String() {
+			t.Errorf("%q: %q does not round trip: %v", c.selector, sel.String(), err)
+		}
+	}
+	for _, invalid := range []string{"=db", "app=d b", "app in (a", "-app", "a/b/c=d", "app=" + strings.Repeat("a", 64)} {
+		if _, err := ParseSelector(invalid); err == nil {
+			t.Errorf("expected %q to be invalid", invalid)
+		}
+	}
+	if !IsValidIDOrSelector("selector:app=db") || IsValidIDOrSelector("selector:app in") || !IsValidIDOrSelector("node-1") {
+		t.Error("unexpected node reference validation")
+	}
+}
+
+func TestListBySelector(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	st, err := storage.NewTestStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	p := New(st)
+
+	nodes := []Node{
+		{ID: "a", Labels: map[string]string{"app": "db", "env": "prod"}},
+		{ID: "b", Labels: map[string]string{"app": "db", "env": "dev"}},
+		{ID: "c", Labels: map[string]string{"app": "web", "env": "prod"}},
+		{ID: "d"},
+	}
+	for _, n := range nodes {
+		if err := p.Put(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list := func(selector string) func() ([]Node, error) {
+		return func() ([]Node, error) {
+			sel, err := ParseSelector(selector)
+			if err != nil {
+				return nil, err
+			}
+			return p.ListBySelector(ctx, sel)
+		}
+	}
+	check := func(t *testing.T) {
+		t.Helper()
+		expectIDs(t, "all", list(""), "a", "b", "c", "d")
+		expectIDs(t, "db", list("app=db"), "a", "b")
+		expectIDs(t, "prod db", list("env=prod,app=db"), "a")
+		expectIDs(t, "in", list("app in (db,web),env!=dev"), "a", "c")
+		expectIDs(t, "unlabeled", list("!app"), "d")
+	}
+	check(t)
+	if _, err := p.RebuildIndexes(ctx); err != nil {
+		t.Fatal(err)
+	}
+	check(t)
+
+	// Relabeling should move the node between index entries.
+	nodes[1].Labels = map[string]string{"app": "web"}
+	if err := p.Put(ctx, nodes[1]); err != nil {
+		t.Fatal(err)
+	}
+	expectIDs(t, "db", list("app=db"), "a")
+	expectIDs(t, "web", list("app=web"), "b", "c")
+}
@@ -43,7 +43,7 @@ func Apply(ctx context.Context, db storage.Storage, logEntry *v1.RaftLogEntry) *
 		err := db.Put(ctx, logEntry.GetKey(), logEntry.GetValue(), logEntry.Ttl.AsDuration())
 		res := &v1.RaftApplyResponse{}
 		if err != nil {
-			res.Error = err.Error()
+			SetResponseError(res, err)
 		}
 		res.Time = time.Since(start).String()
 		return res
@@ -54,13 +54,71 @@ func Apply(ctx context.Context, db storage.Storage, logEntry *v1.RaftLogEntry) *
 		err := db.Delete(ctx, logEntry.GetKey())
 		res := &v1.RaftApplyResponse{}
 		if err != nil {
-			res.Error = err.Error()
+			SetResponseError(res, err)
 		}
 		res.Time = time.Since(start).String()
 		return res
+	case RaftCommandType_PUT_IF_REVISION:
+		op, err := PutIfRevisionOp(logEntry)
+		if err != nil {
+			res := &v1.RaftApplyResponse{Time: time.Since(start).String()}
+			SetResponseError(res, err)
+			return res
+		}
+		log.Debug("applying conditional put",
+			slog.String("key", op.Key),
+			slog.String("value", op.Value),
+			slog.Uint64("revision", op.Revision),
+		)
+		err = db.PutIfRevision(ctx, op.Key, op.Value, op.Revision, op.TTL)
+		res := &v1.RaftApplyResponse{}
+		if err != nil {
+			SetResponseError(res, err)
+		}
+		res.Time = time.Since(start).String()
+		return res
+	case RaftCommandType_DELETE_IF_REVISION:
+		op, err := DeleteIfRevisionOp(logEntry)
+		if err != nil {
+			res := &v1.RaftApplyResponse{Time: time.Since(start).String()}
+			SetResponseError(res, err)
+			return res
+		}
+		log.Debug("applying conditional delete",
+			slog.String("key", op.Key),
+			slog.Uint64("revision", op.Revision),
+		)
+		err = db.Batch(ctx, []storage.Op{op})
+		res := &v1.RaftApplyResponse{}
+		if err != nil {
+			SetResponseError(res, err)
+		}
+		res.Time = time.Since(start).String()
+		return res
+	case RaftCommandType_BATCH:
+		ops, err := BatchOps(logEntry)
+		if err != nil {
+			res := &v1.RaftApplyResponse{Time: time.Since(start).String()}
+			SetResponseError(res, err)
+			return res
+		}
+		log.Debug("applying batch", slog.Int("ops", len(ops)))
+		err = db.Batch(ctx, ops)
+		res := &v1.RaftApplyResponse{}
+		if err != nil {
+			SetResponseError(res, err)
+		}
+		res.Time = time.Since(start).String()
+		return res
+	case RaftCommandType_COALESCED:
+		// Each coalesced entry has its own result, so they are applied with
+		// ApplyCoalesced.
+		return &v1.RaftApplyResponse{
+			Error: "coalesced entries must be applied with ApplyCoalesced",
+		}
 	default:
 		return &v1.RaftApplyResponse{
-			Error: fmt.Sprintf("unknown command type: %v", logEntry.GetType()),
+			Error: fmt.Errorf("%w: %v", ErrUnknownCommand, logEntry.GetType()).Error(),
 		}
 	}
 }
@@ -0,0 +1,158 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raftlogs
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	v1 "github.com/webmeshproj/api/v1"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+// NewBatchEntry returns a log entry that applies the given operations atomically.
+func NewBatchEntry(ops []storage.Op) (*v1.RaftLogEntry, error) {
+	entries := make([]*v1.RaftLogEntry, len(ops))
+	for i, op := range ops {
+		entry, err := opToEntry(op)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = entry
+	}
+	value, err := encodeEntries(entries)
+	if err != nil {
+		return nil, fmt.Errorf("marshal batch: %w", err)
+	}
+	return &v1.RaftLogEntry{
+		Type:  RaftCommandType_BATCH,
+		Value: value,
+	}, nil
+}
+
+// BatchEntries returns the entries contained in a BATCH log entry.
+func BatchEntries(logEntry *v1.RaftLogEntry) ([]*v1.RaftLogEntry, error) {
+	if logEntry.GetType() != RaftCommandType_BATCH {
+		return nil, fmt.Errorf("log entry is not a batch: %v", logEntry.GetType())
+	}
+	entries, err := decodeEntries(logEntry.GetValue())
+	if err != nil {
+		return nil, fmt.Errorf("decode batch: %w", err)
+	}
+	return entries, nil
+}
+
+// BatchOps returns the storage operations contained in a BATCH log entry.
+func BatchOps(logEntry *v1.RaftLogEntry) ([]storage.Op, error) {
+	entries, err := BatchEntries(logEntry)
+	if err != nil {
+		return nil, err
+	}
+	ops := make([]storage.Op, len(entries))
+	for i, entry := range entries {
+		ops[i], err = entryToOp(entry)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ops, nil
+}
+
+func opToEntry(op storage.Op) (*v1.RaftLogEntry, error) {
+	switch op.Type {
+	case storage.OpPut:
+		return &v1.RaftLogEntry{
+			Type:  v1.RaftCommandType_PUT,
+			Key:   op.Key,
+			Value: op.Value,
+			Ttl:   durationpb.New(op.TTL),
+		}, nil
+	case storage.OpDelete:
+		return &v1.RaftLogEntry{
+			Type: v1.RaftCommandType_DELETE,
+			Key:  op.Key,
+		}, nil
+	case storage.OpPutIfRevision:
+		return NewPutIfRevisionEntry(op.Key, op.Value, op.Revision, op.TTL), nil
+	case storage.OpDeleteIfRevision:
+		return NewDeleteIfRevisionEntry(op.Key, op.Revision), nil
+	default:
+		return nil, fmt.Errorf("unsupported batch operation: %v", op.Type)
+	}
+}
+
+func entryToOp(entry *v1.RaftLogEntry) (storage.Op, error) {
+	switch entry.GetType() {
+	case v1.RaftCommandType_PUT:
+		return storage.Op{
+			Type:  storage.OpPut,
+			Key:   entry.GetKey(),
+			Value: entry.GetValue(),
+			TTL:   entry.GetTtl().AsDuration(),
+		}, nil
+	case v1.RaftCommandType_DELETE:
+		return storage.Op{
+			Type: storage.OpDelete,
+			Key:  entry.GetKey(),
+		}, nil
+	case RaftCommandType_PUT_IF_REVISION:
+		return PutIfRevisionOp(entry)
+	case RaftCommandType_DELETE_IF_REVISION:
+		return DeleteIfRevisionOp(entry)
+	default:
+		return storage.Op{}, fmt.Errorf("unsupported batch entry type: %v", entry.GetType())
+	}
+}
+
+// encodeEntries encodes the entries as a base64 encoded stream of
+// length-delimited messages.
+func encodeEntries(entries []*v1.RaftLogEntry) (string, error) {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		if _, err := protodelim.MarshalTo(&buf, entry); err != nil {
+			return "", fmt.Errorf("marshal entry: %w", err)
+		}
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// decodeEntries decodes entries encoded by encodeEntries.
+func decodeEntries(value string) ([]*v1.RaftLogEntry, error) {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	r := bytes.NewReader(data)
+	var entries []*v1.RaftLogEntry
+	for {
+		var entry v1.RaftLogEntry
+		err := protodelim.UnmarshalFrom(r, &entry)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return entries, nil
+			}
+			return nil, fmt.Errorf("unmarshal entry: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raftlogs
+
+import (
+	"fmt"
+	"log/slog"
+
+	v1 "github.com/webmeshproj/api/v1"
+
+	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+// NewCoalescedEntry returns a log entry that applies each of the given entries
+// independently.
+func NewCoalescedEntry(entries []*v1.RaftLogEntry) (*v1.RaftLogEntry, error) {
+	for _, entry := range entries {
+		if entry.GetType() == RaftCommandType_COALESCED {
+			return nil, fmt.Errorf("coalesced entries cannot be nested")
+		}
+	}
+	value, err := encodeEntries(entries)
+	if err != nil {
+		return nil, fmt.Errorf("marshal coalesced entries: %w", err)
+	}
+	return &v1.RaftLogEntry{
+		Type:  RaftCommandType_COALESCED,
+		Value: value,
+	}, nil
+}
+
+// CoalescedEntries returns the entries contained in a COALESCED log entry.
+func CoalescedEntries(logEntry *v1.RaftLogEntry) ([]*v1.RaftLogEntry, error) {
+	if logEntry.GetType() != RaftCommandType_COALESCED {
+		return nil, fmt.Errorf("log entry is not coalesced: %v", logEntry.GetType())
+	}
+	entries, err := decodeEntries(logEntry.GetValue())
+	if err != nil {
+		return nil, fmt.Errorf("decode coalesced entries: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.GetType() == RaftCommandType_COALESCED {
+			return nil, fmt.Errorf("coalesced entries cannot be nested")
+		}
+	}
+	return entries, nil
+}
+
+// ApplyCoalesced applies each entry of a COALESCED log entry to the given
+// storage in order and returns their results. It is the only way coalesced
+// entries are applied. If onApply is not nil it is called with each entry and
+// its result after the entry is applied. An error is returned only if the
+// entries cannot be decoded.
+func ApplyCoalesced(ctx context.Context, db storage.Storage, logEntry *v1.RaftLogEntry, onApply func(entry *v1.RaftLogEntry, res *v1.RaftApplyResponse)) ([]*v1.RaftApplyResponse, error) {
+	entries, err := CoalescedEntries(logEntry)
+	if err != nil {
+		return nil, err
+	}
+	context.LoggerFrom(ctx).Debug("applying coalesced entries", slog.Int("count", len(entries)))
+	out := make([]*v1.RaftApplyResponse, len(entries))
+	for i, entry := range entries {
+		out[i] = Apply(ctx, db, entry)
+		if onApply != nil {
+			onApply(entry, out[i])
+		}
+	}
+	return out, nil
+}
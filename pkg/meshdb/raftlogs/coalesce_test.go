@@ -0,0 +1,81 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raftlogs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	v1 "github.com/webmeshproj/api/v1"
+
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+func TestApplyCoalesced(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db, err := storage.NewTestStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	entry, err := NewCoalescedEntry([]*v1.RaftLogEntry{
+		NewPutIfRevisionEntry("/test/key", "first", 0, 0),
+		// The key exists now, so this write must fail on its own.
+		NewPutIfRevisionEntry("/test/key", "second", 0, 0),
+		{Type: v1.RaftCommandType_PUT, Key: "/test/other", Value: "third"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(entry); err != nil {
+		t.Fatal(err)
+	}
+	var applied []string
+	results, err := ApplyCoalesced(ctx, db, entry, func(entry *v1.RaftLogEntry, res *v1.RaftApplyResponse) {
+		applied = append(applied, entry.GetKey())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || len(applied) != 3 {
+		t.Fatalf("expected 3 results and callbacks, got %d and %d", len(results), len(applied))
+	}
+	if err := ResponseError(results[0]); err != nil {
+		t.Fatalf("expected first write to succeed, got %v", err)
+	}
+	if err := ResponseError(results[1]); !errors.Is(err, storage.ErrRevisionMismatch) {
+		t.Fatalf("expected second write to fail with a revision mismatch, got %v", err)
+	}
+	if err := ResponseError(results[2]); err != nil {
+		t.Fatalf("expected third write to succeed, got %v", err)
+	}
+	val, err := db.Get(ctx, "/test/key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != "first" {
+		t.Fatalf("expected first value to be kept, got %s", val)
+	}
+
+	// Coalesced entries are only applied through ApplyCoalesced.
+	if res := Apply(ctx, db, entry); res.GetError() == "" {
+		t.Fatal("expected applying a coalesced entry with Apply to fail")
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raftlogs
+
+import (
+	"errors"
+	"fmt"
+
+	v1 "github.com/webmeshproj/api/v1"
+)
+
+// The command types below extend v1.RaftCommandType with the values declared
+// in RaftCommandTypeExtension. Any new command type must be added to the proto
+// enum, to this block and to Validate.
+const (
+	// RaftCommandType_BATCH is the command for atomically applying a batch of
+	// PUT, DELETE, PUT_IF_REVISION, and DELETE_IF_REVISION entries.
+	RaftCommandType_BATCH = v1.RaftCommandType(RaftCommandTypeExtension_BATCH)
+	// RaftCommandType_PUT_IF_REVISION is the command for setting the value of a
+	// key only if its current revision matches an expected revision.
+	RaftCommandType_PUT_IF_REVISION = v1.RaftCommandType(RaftCommandTypeExtension_PUT_IF_REVISION)
+	// RaftCommandType_DELETE_IF_REVISION is the command for removing a key only
+	// if its current revision matches an expected revision.
+	RaftCommandType_DELETE_IF_REVISION = v1.RaftCommandType(RaftCommandTypeExtension_DELETE_IF_REVISION)
+	// RaftCommandType_COALESCED is the command for applying independent entries
+	// from concurrent writers in a single raft log.
+	RaftCommandType_COALESCED = v1.RaftCommandType(RaftCommandTypeExtension_COALESCED)
+)
+
+// ErrUnknownCommand is returned when a log entry has a command type this
+// version does not know how to apply. Skipping such an entry would leave the
+// node's storage diverged from the rest of the cluster, so it must be treated
+// as fatal.
+var ErrUnknownCommand = errors.New("unknown raft command type")
+
+// Validate returns an error wrapping ErrUnknownCommand if the log entry, or any
+// entry nested in it, has a command type this version does not know how to apply.
+func Validate(logEntry *v1.RaftLogEntry) error {
+	switch logEntry.GetType() {
+	case v1.RaftCommandType_PUT, v1.RaftCommandType_DELETE, RaftCommandType_PUT_IF_REVISION, RaftCommandType_DELETE_IF_REVISION:
+		return nil
+	case RaftCommandType_BATCH:
+		entries, err := BatchEntries(logEntry)
+		if err != nil {
+			// Malformed entries are reported when the entry is applied.
+			return nil
+		}
+		for _, entry := range entries {
+			if err := Validate(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	case RaftCommandType_COALESCED:
+		entries, err := CoalescedEntries(logEntry)
+		if err != nil {
+			// Malformed entries are reported when the entry is applied.
+			return nil
+		}
+		for _, entry := range entries {
+			if err := Validate(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %v", ErrUnknownCommand, logEntry.GetType())
+	}
+}
@@ -0,0 +1,180 @@
+//
+//Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.31.0
+// 	protoc        (unknown)
+// source: meshdb/raftlogs/commands.proto
+
+package raftlogs
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// RaftCommandTypeExtension declares the command types this repository adds to
+// the RaftCommandType enum of the webmesh API. Log entries carry them in their
+// type like any other RaftCommandType. Values 100 and up are reserved for
+// them, so they can never collide with values added upstream. They must never
+// be renumbered or reused.
+type RaftCommandTypeExtension int32
+
+const (
+	// COMMAND_EXTENSION_UNKNOWN is the zero value and is never written.
+	RaftCommandTypeExtension_COMMAND_EXTENSION_UNKNOWN RaftCommandTypeExtension = 0
+	// BATCH atomically applies a batch of PUT, DELETE, PUT_IF_REVISION and
+	// DELETE_IF_REVISION entries. The entries are carried in the value of the log entry as a
+	// base64 encoded stream of length-delimited RaftLogEntry messages.
+	RaftCommandTypeExtension_BATCH RaftCommandTypeExtension = 100
+	// PUT_IF_REVISION sets the value of a key only if its current revision
+	// matches an expected revision. The expected revision is carried in the
+	// value of the log entry as "<revision>:<value>".
+	RaftCommandTypeExtension_PUT_IF_REVISION RaftCommandTypeExtension = 101
+	// DELETE_IF_REVISION removes a key only if its current revision matches
+	// an expected revision. The expected revision is carried in the value of
+	// the log entry.
+	RaftCommandTypeExtension_DELETE_IF_REVISION RaftCommandTypeExtension = 102
+	// COALESCED applies independent entries from concurrent writers in a
+	// single raft log. Unlike BATCH, each entry is applied on its own, in
+	// order, and has its own result. The entries are encoded the same way as
+	// the entries of a BATCH.
+	RaftCommandTypeExtension_COALESCED RaftCommandTypeExtension = 103
+)
+
+// Enum value maps for RaftCommandTypeExtension.
+var (
+	RaftCommandTypeExtension_name = map[int32]string{
+		0:   "COMMAND_EXTENSION_UNKNOWN",
+		100: "BATCH",
+		101: "PUT_IF_REVISION",
+		102: "DELETE_IF_REVISION",
+		103: "COALESCED",
+	}
+	RaftCommandTypeExtension_value = map[string]int32{
+		"COMMAND_EXTENSION_UNKNOWN": 0,
+		"BATCH":                     100,
+		"PUT_IF_REVISION":           101,
+		"DELETE_IF_REVISION":        102,
+		"COALESCED":                 103,
+	}
+)
+
+func (x RaftCommandTypeExtension) Enum() *RaftCommandTypeExtension {
+	p := new(RaftCommandTypeExtension)
+	*p = x
+	return p
+}
+
+func (x RaftCommandTypeExtension) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RaftCommandTypeExtension) Descriptor() protoreflect.EnumDescriptor {
+	return file_meshdb_raftlogs_commands_proto_enumTypes[0].Descriptor()
+}
+
+func (RaftCommandTypeExtension) Type() protoreflect.EnumType {
+	return &file_meshdb_raftlogs_commands_proto_enumTypes[0]
+}
+
+func (x RaftCommandTypeExtension) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RaftCommandTypeExtension.Descriptor instead.
+func (RaftCommandTypeExtension) EnumDescriptor() ([]byte, []int) {
+	return file_meshdb_raftlogs_commands_proto_rawDescGZIP(), []int{0}
+}
+
+var File_meshdb_raftlogs_commands_proto protoreflect.FileDescriptor
+
+var file_meshdb_raftlogs_commands_proto_rawDesc = []byte{
+	0x0a, 0x1e, 0x6d, 0x65, 0x73, 0x68, 0x64, 0x62, 0x2f, 0x72, 0x61, 0x66, 0x74, 0x6c, 0x6f, 0x67,
+	0x73, 0x2f, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x12, 0x13, 0x77, 0x65, 0x62, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x72, 0x61, 0x66, 0x74, 0x6c, 0x6f,
+	0x67, 0x73, 0x2e, 0x76, 0x31, 0x2a, 0x80, 0x01, 0x0a, 0x18, 0x52, 0x61, 0x66, 0x74, 0x43, 0x6f,
+	0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x54, 0x79, 0x70, 0x65, 0x45, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69,
+	0x6f, 0x6e, 0x12, 0x1d, 0x0a, 0x19, 0x43, 0x4f, 0x4d, 0x4d, 0x41, 0x4e, 0x44, 0x5f, 0x45, 0x58,
+	0x54, 0x45, 0x4e, 0x53, 0x49, 0x4f, 0x4e, 0x5f, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e, 0x10,
+	0x00, 0x12, 0x09, 0x0a, 0x05, 0x42, 0x41, 0x54, 0x43, 0x48, 0x10, 0x64, 0x12, 0x13, 0x0a, 0x0f,
+	0x50, 0x55, 0x54, 0x5f, 0x49, 0x46, 0x5f, 0x52, 0x45, 0x56, 0x49, 0x53, 0x49, 0x4f, 0x4e, 0x10,
+	0x65, 0x12, 0x16, 0x0a, 0x12, 0x44, 0x45, 0x4c, 0x45, 0x54, 0x45, 0x5f, 0x49, 0x46, 0x5f, 0x52,
+	0x45, 0x56, 0x49, 0x53, 0x49, 0x4f, 0x4e, 0x10, 0x66, 0x12, 0x0d, 0x0a, 0x09, 0x43, 0x4f, 0x41,
+	0x4c, 0x45, 0x53, 0x43, 0x45, 0x44, 0x10, 0x67, 0x42, 0x34, 0x5a, 0x32, 0x67, 0x69, 0x74, 0x68,
+	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x77, 0x65, 0x62, 0x6d, 0x65, 0x73, 0x68, 0x70, 0x72,
+	0x6f, 0x6a, 0x2f, 0x77, 0x65, 0x62, 0x6d, 0x65, 0x73, 0x68, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x6d,
+	0x65, 0x73, 0x68, 0x64, 0x62, 0x2f, 0x72, 0x61, 0x66, 0x74, 0x6c, 0x6f, 0x67, 0x73, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_meshdb_raftlogs_commands_proto_rawDescOnce sync.Once
+	file_meshdb_raftlogs_commands_proto_rawDescData = file_meshdb_raftlogs_commands_proto_rawDesc
+)
+
+func file_meshdb_raftlogs_commands_proto_rawDescGZIP() []byte {
+	file_meshdb_raftlogs_commands_proto_rawDescOnce.Do(func() {
+		file_meshdb_raftlogs_commands_proto_rawDescData = protoimpl.X.CompressGZIP(file_meshdb_raftlogs_commands_proto_rawDescData)
+	})
+	return file_meshdb_raftlogs_commands_proto_rawDescData
+}
+
+var file_meshdb_raftlogs_commands_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_meshdb_raftlogs_commands_proto_goTypes = []interface{}{
+	(RaftCommandTypeExtension)(0), // 0: webmesh.raftlogs.v1.RaftCommandTypeExtension
+}
+var file_meshdb_raftlogs_commands_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_meshdb_raftlogs_commands_proto_init() }
+func file_meshdb_raftlogs_commands_proto_init() {
+	if File_meshdb_raftlogs_commands_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_meshdb_raftlogs_commands_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   0,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_meshdb_raftlogs_commands_proto_goTypes,
+		DependencyIndexes: file_meshdb_raftlogs_commands_proto_depIdxs,
+		EnumInfos:         file_meshdb_raftlogs_commands_proto_enumTypes,
+	}.Build()
+	File_meshdb_raftlogs_commands_proto = out.File
+	file_meshdb_raftlogs_commands_proto_rawDesc = nil
+	file_meshdb_raftlogs_commands_proto_goTypes = nil
+	file_meshdb_raftlogs_commands_proto_depIdxs = nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raftlogs
+
+import (
+	"errors"
+
+	v1 "github.com/webmeshproj/api/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+// ErrorCode identifies an error in an apply response that callers are expected
+// to handle. It is carried next to the error message so that callers do not
+// have to match on the message.
+type ErrorCode uint64
+
+const (
+	// ErrorCodeUnknown is the code of errors that callers are not expected to handle.
+	ErrorCodeUnknown ErrorCode = iota
+	// ErrorCodeRevisionMismatch is the code of storage.ErrRevisionMismatch.
+	ErrorCodeRevisionMismatch
+	// ErrorCodeKeyNotFound is the code of storage.ErrKeyNotFound.
+	ErrorCodeKeyNotFound
+)
+
+// responseCodeField is the field number the error code is carried in on a
+// RaftApplyResponse. RaftApplyResponse is declared in the webmesh API, so
+// field numbers 1000 and up are reserved for fields added by this repository.
+// They can never collide with fields added upstream, and nodes that do not
+// know about them skip them as unknown fields.
+const responseCodeField protowire.Number = 1000
+
+// errorCodes maps error codes to the errors they represent.
+var errorCodes = map[ErrorCode]error{
+	ErrorCodeRevisionMismatch: storage.ErrRevisionMismatch,
+	ErrorCodeKeyNotFound:      storage.ErrKeyNotFound,
+}
+
+// SetResponseError sets the error of an apply response along with its code.
+func SetResponseError(res *v1.RaftApplyResponse, err error) {
+	res.Error = err.Error()
+	code := ErrorCodeUnknown
+	for c, target := range errorCodes {
+		if errors.Is(err, target) {
+			code = c
+			break
+		}
+	}
+	setResponseCode(res, code)
+}
+
+// ResponseCode returns the error code of an apply response.
+func ResponseCode(res *v1.RaftApplyResponse) ErrorCode {
+	b := res.ProtoReflect().GetUnknown()
+	code := ErrorCodeUnknown
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return ErrorCodeUnknown
+		}
+		b = b[n:]
+		if num == responseCodeField && typ == protowire.VarintType {
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return ErrorCodeUnknown
+			}
+			code = ErrorCode(v)
+			b = b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return ErrorCodeUnknown
+		}
+		b = b[n:]
+	}
+	return code
+}
+
+// ResponseError returns the error contained in an apply response, or nil if the
+// log entry was applied successfully. Errors that callers are expected to handle,
+// such as storage.ErrRevisionMismatch, can be checked with errors.Is.
+func ResponseError(res *v1.RaftApplyResponse) error {
+	if res.GetError() == "" {
+		return nil
+	}
+	if err, ok := errorCodes[ResponseCode(res)]; ok {
+		return &applyError{msg: res.GetError(), err: err}
+	}
+	return errors.New(res.GetError())
+}
+
+// setResponseCode replaces the error code carried in an apply response.
+func setResponseCode(res *v1.RaftApplyResponse, code ErrorCode) {
+	refl := res.ProtoReflect()
+	b := refl.GetUnknown()
+	var unknown []byte
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			break
+		}
+		m := protowire.ConsumeFieldValue(num, typ, b[n:])
+		if m < 0 {
+			break
+		}
+		if num != responseCodeField {
+			unknown = append(unknown, b[:n+m]...)
+		}
+		b = b[n+m:]
+	}
+	if code != ErrorCodeUnknown {
+		unknown = protowire.AppendTag(unknown, responseCodeField, protowire.VarintType)
+		unknown = protowire.AppendVarint(unknown, uint64(code))
+	}
+	refl.SetUnknown(unknown)
+}
+
+type applyError struct {
+	msg string
+	err error
+}
+
+func (e *applyError) Error() string { return e.msg }
+
+func (e *applyError) Unwrap() error { return e.err }
@@ -0,0 +1,75 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raftlogs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	v1 "github.com/webmeshproj/api/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+func TestResponseError(t *testing.T) {
+	t.Parallel()
+
+	tc := []struct {
+		name string
+		err  error
+		is   error
+	}{
+		{"revision mismatch", fmt.Errorf("put: %w", storage.ErrRevisionMismatch), storage.ErrRevisionMismatch},
+		{"key not found", storage.ErrKeyNotFound, storage.ErrKeyNotFound},
+		// The message alone must not be enough to match a sentinel.
+		{"unknown", errors.New(storage.ErrRevisionMismatch.Error()), nil},
+	}
+	for _, tt := range tc {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			res := &v1.RaftApplyResponse{Time: "1s"}
+			SetResponseError(res, tt.err)
+			data, err := proto.Marshal(res)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded v1.RaftApplyResponse
+			if err := proto.Unmarshal(data, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			got := ResponseError(&decoded)
+			if got == nil {
+				t.Fatal("expected an error")
+			}
+			if got.Error() != tt.err.Error() {
+				t.Errorf("expected message %q, got %q", tt.err.Error(), got.Error())
+			}
+			if tt.is != nil && !errors.Is(got, tt.is) {
+				t.Errorf("expected error to be %v", tt.is)
+			}
+			if tt.is == nil && (errors.Is(got, storage.ErrRevisionMismatch) || errors.Is(got, storage.ErrKeyNotFound)) {
+				t.Errorf("expected error to not match a sentinel, got %v", got)
+			}
+		})
+	}
+	if err := ResponseError(&v1.RaftApplyResponse{}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
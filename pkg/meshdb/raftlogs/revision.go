@@ -0,0 +1,132 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raftlogs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/webmeshproj/api/v1"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+// NewPutIfRevisionEntry returns a log entry that sets the value of a key only
+// if its current revision matches the given revision.
+func NewPutIfRevisionEntry(key, value string, revision uint64, ttl time.Duration) *v1.RaftLogEntry {
+	return &v1.RaftLogEntry{
+		Type:  RaftCommandType_PUT_IF_REVISION,
+		Key:   key,
+		Value: strconv.FormatUint(revision, 10) + ":" + value,
+		Ttl:   durationpb.New(ttl),
+	}
+}
+
+// PutIfRevisionOp returns the storage operation contained in a PUT_IF_REVISION
+// log entry.
+func PutIfRevisionOp(logEntry *v1.RaftLogEntry) (storage.Op, error) {
+	if logEntry.GetType() != RaftCommandType_PUT_IF_REVISION {
+		return storage.Op{}, fmt.Errorf("log entry is not a conditional put: %v", logEntry.GetType())
+	}
+	rev, value, ok := strings.Cut(logEntry.GetValue(), ":")
+	if !ok {
+		return storage.Op{}, errors.New("conditional put is missing a revision")
+	}
+	revision, err := strconv.ParseUint(rev, 10, 64)
+	if err != nil {
+		return storage.Op{}, fmt.Errorf("parse revision: %w", err)
+	}
+	return storage.Op{
+		Type:     storage.OpPutIfRevision,
+		Key:      logEntry.GetKey(),
+		Value:    value,
+		TTL:      logEntry.GetTtl().AsDuration(),
+		Revision: revision,
+	}, nil
+}
+
+// NewDeleteIfRevisionEntry returns a log entry that removes a key only if its
+// current revision matches the given revision.
+func NewDeleteIfRevisionEntry(key string, revision uint64) *v1.RaftLogEntry {
+	return &v1.RaftLogEntry{
+		Type:  RaftCommandType_DELETE_IF_REVISION,
+		Key:   key,
+		Value: strconv.FormatUint(revision, 10),
+	}
+}
+
+// DeleteIfRevisionOp returns the storage operation contained in a
+// DELETE_IF_REVISION log entry.
+func DeleteIfRevisionOp(logEntry *v1.RaftLogEntry) (storage.Op, error) {
+	if logEntry.GetType() != RaftCommandType_DELETE_IF_REVISION {
+		return storage.Op{}, fmt.Errorf("log entry is not a conditional delete: %v", logEntry.GetType())
+	}
+	revision, err := strconv.ParseUint(logEntry.GetValue(), 10, 64)
+	if err != nil {
+		return storage.Op{}, fmt.Errorf("parse revision: %w", err)
+	}
+	return storage.Op{
+		Type:     storage.OpDeleteIfRevision,
+		Key:      logEntry.GetKey(),
+		Revision: revision,
+	}, nil
+}
+
+// Expand returns the plain PUT and DELETE entries that were applied by the given
+// log entry. Batches are flattened and conditional puts and deletes are returned
+// as PUTs and DELETEs.
+// It is intended for consumers, such as storage plugins, that only understand
+// the basic command types.
+func Expand(logEntry *v1.RaftLogEntry) ([]*v1.RaftLogEntry, error) {
+	entries := []*v1.RaftLogEntry{logEntry}
+	if logEntry.GetType() == RaftCommandType_BATCH {
+		var err error
+		entries, err = BatchEntries(logEntry)
+		if err != nil {
+			return nil, err
+		}
+	}
+	out := make([]*v1.RaftLogEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.GetType() == RaftCommandType_DELETE_IF_REVISION {
+			out = append(out, &v1.RaftLogEntry{
+				Type: v1.RaftCommandType_DELETE,
+				Key:  entry.GetKey(),
+			})
+			continue
+		}
+		if entry.GetType() != RaftCommandType_PUT_IF_REVISION {
+			out = append(out, entry)
+			continue
+		}
+		op, err := PutIfRevisionOp(entry)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, &v1.RaftLogEntry{
+			Type:  v1.RaftCommandType_PUT,
+			Key:   op.Key,
+			Value: op.Value,
+			Ttl:   entry.GetTtl(),
+		})
+	}
+	return out, nil
+}
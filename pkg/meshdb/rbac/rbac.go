@@ -19,11 +19,13 @@ package rbac
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	v1 "github.com/webmeshproj/api/v1"
 	"google.golang.org/protobuf/encoding/protojson"
 
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
 	"github.com/webmeshproj/webmesh/pkg/storage"
 )
 
@@ -39,9 +41,12 @@ const (
 	// BootstrapVotersRoleBinding is the name of the bootstrap voters rolebinding.
 	BootstrapVotersRoleBinding = "bootstrap-voters"
 
-	rolesPrefix        = "/registry/roles"
-	rolebindingsPrefix = "/registry/rolebindings"
-	groupsPrefix       = "/registry/groups"
+	// RolesPrefix is where roles are stored in the database.
+	RolesPrefix = "/registry/roles"
+	// RoleBindingsPrefix is where rolebindings are stored in the database.
+	RoleBindingsPrefix = "/registry/rolebindings"
+	// GroupsPrefix is where groups are stored in the database.
+	GroupsPrefix = "/registry/groups"
 )
 
 // IsSystemRole returns true if the role is a system role.
@@ -87,6 +92,9 @@ type RBAC interface {
 	DeleteRole(ctx context.Context, name string) error
 	// ListRoles returns a list of all roles.
 	ListRoles(ctx context.Context) (RolesList, error)
+	// ListRolesPage returns a page of roles ordered by name and the token
+	// for the next page.
+	ListRolesPage(ctx context.Context, page storage.Page) (RolesList, string, error)
 
 	// PutRoleBinding creates or updates a rolebinding.
 	PutRoleBinding(ctx context.Context, rolebinding *v1.RoleBinding) error
@@ -96,6 +104,9 @@ type RBAC interface {
 	DeleteRoleBinding(ctx context.Context, name string) error
 	// ListRoleBindings returns a list of all rolebindings.
 	ListRoleBindings(ctx context.Context) ([]*v1.RoleBinding, error)
+	// ListRoleBindingsPage returns a page of rolebindings ordered by name and
+	// the token for the next page.
+	ListRoleBindingsPage(ctx context.Context, page storage.Page) ([]*v1.RoleBinding, string, error)
 
 	// PutGroup creates or updates a group.
 	PutGroup(ctx context.Context, group *v1.Group) error
@@ -105,6 +116,9 @@ type RBAC interface {
 	DeleteGroup(ctx context.Context, name string) error
 	// ListGroups returns a list of all groups.
 	ListGroups(ctx context.Context) ([]*v1.Group, error)
+	// ListGroupsPage returns a page of groups ordered by name and the token
+	// for the next page.
+	ListGroupsPage(ctx context.Context, page storage.Page) ([]*v1.Group, string, error)
 
 	// ListNodeRoles returns a list of all roles for a node.
 	ListNodeRoles(ctx context.Context, nodeID string) (RolesList, error)
@@ -143,7 +157,7 @@ func (r *rbac) PutRole(ctx context.Context, role *v1.Role) error {
 	if err != nil {
 		return fmt.Errorf("marshal role: %w", err)
 	}
-	key := fmt.Sprintf("%s/%s", rolesPrefix, role.GetName())
+	key := fmt.Sprintf("%s/%s", RolesPrefix, role.GetName())
 	err = r.Put(ctx, key, string(data), 0)
 	if err != nil {
 		return fmt.Errorf("put role: %w", err)
@@ -153,7 +167,7 @@ func (r *rbac) PutRole(ctx context.Context, role *v1.Role) error {
 
 // GetRole returns a role by name.
 func (r *rbac) GetRole(ctx context.Context, name string) (*v1.Role, error) {
-	key := fmt.Sprintf("%s/%s", rolesPrefix, name)
+	key := fmt.Sprintf("%s/%s", RolesPrefix, name)
 	data, err := r.Get(ctx, key)
 	if err != nil {
 		if err == storage.ErrKeyNotFound {
@@ -174,7 +188,7 @@ func (r *rbac) DeleteRole(ctx context.Context, name string) error {
 	if IsSystemRole(name) {
 		return fmt.Errorf("%w %q", ErrIsSystemRole, name)
 	}
-	key := fmt.Sprintf("%s/%s", rolesPrefix, name)
+	key := fmt.Sprintf("%s/%s", RolesPrefix, name)
 	err := r.Delete(ctx, key)
 	if err != nil {
 		return fmt.Errorf("delete role: %w", err)
@@ -184,8 +198,14 @@ func (r *rbac) DeleteRole(ctx context.Context, name string) error {
 
 // ListRoles returns a list of all roles.
 func (r *rbac) ListRoles(ctx context.Context) (RolesList, error) {
+	out, _, err := r.ListRolesPage(ctx, storage.Page{})
+	return out, err
+}
+
+// ListRolesPage returns a page of roles and the token for the next page.
+func (r *rbac) ListRolesPage(ctx context.Context, page storage.Page) (RolesList, string, error) {
 	out := make(RolesList, 0)
-	err := r.IterPrefix(ctx, rolesPrefix, func(_, value string) error {
+	next, err := storage.IterPage(ctx, r, RolesPrefix, page, func(_, value string) error {
 		role := &v1.Role{}
 		err := protojson.Unmarshal([]byte(value), role)
 		if err != nil {
@@ -194,7 +214,7 @@ func (r *rbac) ListRoles(ctx context.Context) (RolesList, error) {
 		out = append(out, role)
 		return nil
 	})
-	return out, err
+	return out, next, err
 }
 
 // PutRoleBinding creates or updates a rolebinding.
@@ -218,7 +238,7 @@ func (r *rbac) PutRoleBinding(ctx context.Context, rolebinding *v1.RoleBinding)
 	if len(rolebinding.GetSubjects()) == 0 {
 		return fmt.Errorf("rolebinding subjects cannot be empty")
 	}
-	key := fmt.Sprintf("%s/%s", rolebindingsPrefix, rolebinding.GetName())
+	key := fmt.Sprintf("%s/%s", RoleBindingsPrefix, rolebinding.GetName())
 	data, err := protojson.Marshal(rolebinding)
 	if err != nil {
 		return fmt.Errorf("marshal rolebinding: %w", err)
@@ -232,7 +252,7 @@ func (r *rbac) PutRoleBinding(ctx context.Context, rolebinding *v1.RoleBinding)
 
 // GetRoleBinding returns a rolebinding by name.
 func (r *rbac) GetRoleBinding(ctx context.Context, name string) (*v1.RoleBinding, error) {
-	key := fmt.Sprintf("%s/%s", rolebindingsPrefix, name)
+	key := fmt.Sprintf("%s/%s", RoleBindingsPrefix, name)
 	data, err := r.Get(ctx, key)
 	if err != nil {
 		if err == storage.ErrKeyNotFound {
@@ -253,7 +273,7 @@ func (r *rbac) DeleteRoleBinding(ctx context.Context, name string) error {
 	if IsSystemRoleBinding(name) {
 		return fmt.Errorf("%w %q", ErrIsSystemRoleBinding, name)
 	}
-	key := fmt.Sprintf("%s/%s", rolebindingsPrefix, name)
+	key := fmt.Sprintf("%s/%s", RoleBindingsPrefix, name)
 	err := r.Delete(ctx, key)
 	if err != nil {
 		return fmt.Errorf("delete rolebinding: %w", err)
@@ -263,8 +283,14 @@ func (r *rbac) DeleteRoleBinding(ctx context.Context, name string) error {
 
 // ListRoleBindings returns a list of all rolebindings.
 func (r *rbac) ListRoleBindings(ctx context.Context) ([]*v1.RoleBinding, error) {
+	out, _, err := r.ListRoleBindingsPage(ctx, storage.Page{})
+	return out, err
+}
+
+// ListRoleBindingsPage returns a page of rolebindings and the token for the next page.
+func (r *rbac) ListRoleBindingsPage(ctx context.Context, page storage.Page) ([]*v1.RoleBinding, string, error) {
 	out := make([]*v1.RoleBinding, 0)
-	err := r.IterPrefix(ctx, rolebindingsPrefix, func(_, value string) error {
+	next, err := storage.IterPage(ctx, r, RoleBindingsPrefix, page, func(_, value string) error {
 		rolebinding := &v1.RoleBinding{}
 		err := protojson.Unmarshal([]byte(value), rolebinding)
 		if err != nil {
@@ -273,7 +299,7 @@ func (r *rbac) ListRoleBindings(ctx context.Context) ([]*v1.RoleBinding, error)
 		out = append(out, rolebinding)
 		return nil
 	})
-	return out, err
+	return out, next, err
 }
 
 // PutGroup creates or updates a group.
@@ -284,7 +310,7 @@ func (r *rbac) PutGroup(ctx context.Context, group *v1.Group) error {
 	if len(group.GetSubjects()) == 0 {
 		return fmt.Errorf("group subjects cannot be empty")
 	}
-	key := fmt.Sprintf("%s/%s", groupsPrefix, group.GetName())
+	key := fmt.Sprintf("%s/%s", GroupsPrefix, group.GetName())
 	data, err := protojson.Marshal(group)
 	if err != nil {
 		return fmt.Errorf("marshal group: %w", err)
@@ -298,7 +324,7 @@ func (r *rbac) PutGroup(ctx context.Context, group *v1.Group) error {
 
 // GetGroup returns a group by name.
 func (r *rbac) GetGroup(ctx context.Context, name string) (*v1.Group, error) {
-	key := fmt.Sprintf("%s/%s", groupsPrefix, name)
+	key := fmt.Sprintf("%s/%s", GroupsPrefix, name)
 	data, err := r.Get(ctx, key)
 	if err != nil {
 		if err == storage.ErrKeyNotFound {
@@ -319,7 +345,7 @@ func (r *rbac) DeleteGroup(ctx context.Context, name string) error {
 	if IsSystemGroup(name) {
 		return fmt.Errorf("%w %q", ErrIsSystemGroup, name)
 	}
-	key := fmt.Sprintf("%s/%s", groupsPrefix, name)
+	key := fmt.Sprintf("%s/%s", GroupsPrefix, name)
 	err := r.Delete(ctx, key)
 	if err != nil {
 		return fmt.Errorf("delete group: %w", err)
@@ -329,8 +355,14 @@ func (r *rbac) DeleteGroup(ctx context.Context, name string) error {
 
 // ListGroups returns a list of all groups.
 func (r *rbac) ListGroups(ctx context.Context) ([]*v1.Group, error) {
+	out, _, err := r.ListGroupsPage(ctx, storage.Page{})
+	return out, err
+}
+
+// ListGroupsPage returns a page of groups and the token for the next page.
+func (r *rbac) ListGroupsPage(ctx context.Context, page storage.Page) ([]*v1.Group, string, error) {
 	out := make([]*v1.Group, 0)
-	err := r.IterPrefix(ctx, groupsPrefix, func(_, value string) error {
+	next, err := storage.IterPage(ctx, r, GroupsPrefix, page, func(_, value string) error {
 		group := &v1.Group{}
 		err := protojson.Unmarshal([]byte(value), group)
 		if err != nil {
@@ -339,7 +371,7 @@ func (r *rbac) ListGroups(ctx context.Context) ([]*v1.Group, error) {
 		out = append(out, group)
 		return nil
 	})
-	return out, err
+	return out, next, err
 }
 
 // ListNodeRoles returns a list of all roles for a node.
@@ -349,11 +381,31 @@ func (r *rbac) ListNodeRoles(ctx context.Context, nodeID string) (RolesList, err
 		return nil, fmt.Errorf("list rolebindings: %w", err)
 	}
 	out := make(RolesList, 0)
+	var node *peers.Node
+	// selects returns true if the subject name is a selector matching the node.
+	selects := func(name string) (bool, error) {
+		sel, ok, err := peers.SelectorFromRef(name)
+		if !ok || err != nil {
+			return false, nil
+		}
+		if node == nil {
+			found, err := peers.New(r).Get(ctx, nodeID)
+			if err != nil && !errors.Is(err, peers.ErrNodeNotFound) {
+				return false, fmt.Errorf("get node: %w", err)
+			}
+			node = &found
+		}
+		return node.ID != "" && sel.Matches(node.Labels), nil
+	}
 RoleBindings:
 	for _, rb := range rbs {
 		for _, subject := range rb.GetSubjects() {
 			if subject.GetType() == v1.SubjectType_SUBJECT_ALL || subject.GetType() == v1.SubjectType_SUBJECT_NODE {
-				if subject.GetName() == "*" || subject.GetName() == nodeID {
+				selected, err := selects(subject.GetName())
+				if err != nil {
+					return nil, err
+				}
+				if subject.GetName() == "*" || subject.GetName() == nodeID || selected {
 					role, err := r.GetRole(ctx, rb.GetRole())
 					if err != nil {
 						return nil, fmt.Errorf("get role: %w", err)
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbac
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "github.com/webmeshproj/api/v1"
+)
+
+// Raft verbs. RuleVerb is an open enum, so these extend it with the values
+// declared in RuleVerbExtension. They are evaluated against the votes
+// resource and are matched by VERB_ALL like any other verb.
+const (
+	// VerbRaftTransferLeadership allows transferring raft leadership between
+	// voters.
+	VerbRaftTransferLeadership = v1.RuleVerb(RuleVerbExtension_VERB_RAFT_TRANSFER_LEADERSHIP)
+	// VerbRaftList allows listing the servers in the Raft configuration.
+	VerbRaftList = v1.RuleVerb(RuleVerbExtension_VERB_RAFT_LIST)
+	// VerbRaftAdd allows adding servers to the Raft configuration.
+	VerbRaftAdd = v1.RuleVerb(RuleVerbExtension_VERB_RAFT_ADD)
+	// VerbRaftRemove allows removing servers from the Raft configuration.
+	VerbRaftRemove = v1.RuleVerb(RuleVerbExtension_VERB_RAFT_REMOVE)
+	// VerbRaftSuffrage allows promoting and demoting voters.
+	VerbRaftSuffrage = v1.RuleVerb(RuleVerbExtension_VERB_RAFT_SUFFRAGE)
+	// VerbRaftForceRemove allows force-removing dead servers, which also
+	// deletes their registration from the mesh.
+	VerbRaftForceRemove = v1.RuleVerb(RuleVerbExtension_VERB_RAFT_FORCE_REMOVE)
+)
+
+// verbNames are the short names of all verbs as used by wmctl.
+var verbNames = map[v1.RuleVerb]string{
+	v1.RuleVerb_VERB_PUT:       "put",
+	v1.RuleVerb_VERB_DELETE:    "delete",
+	v1.RuleVerb_VERB_ALL:       "*",
+	VerbRaftTransferLeadership: "raft-transfer-leadership",
+	VerbRaftList:               "raft-list",
+	VerbRaftAdd:                "raft-add",
+	VerbRaftRemove:             "raft-remove",
+	VerbRaftSuffrage:           "raft-suffrage",
+	VerbRaftForceRemove:        "raft-force-remove",
+}
+
+// IsValidVerb returns true if the verb is a known verb.
+func IsValidVerb(verb v1.RuleVerb) bool {
+	_, ok := verbNames[verb]
+	return ok
+}
+
+// VerbName returns the short name of the verb.
+func VerbName(verb v1.RuleVerb) string {
+	if name, ok := verbNames[verb]; ok {
+		return name
+	}
+	return verb.String()
+}
+
+// ParseVerb parses a verb from its short name.
+func ParseVerb(name string) (v1.RuleVerb, error) {
+	for verb, n := range verbNames {
+		if strings.EqualFold(n, name) {
+			return verb, nil
+		}
+	}
+	return v1.RuleVerb_VERB_UNKNOWN, fmt.Errorf("unknown verb %q", name)
+}
@@ -0,0 +1,183 @@
+//
+//Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.31.0
+// 	protoc        (unknown)
+// source: meshdb/rbac/verbs.proto
+
+package rbac
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// RuleVerbExtension declares the verbs this repository adds to the RuleVerb
+// enum of the webmesh API. Rules store them in their verbs like any other
+// RuleVerb, so the numbers must never collide with upstream values. Values
+// from 100 up are reserved for this repository.
+type RuleVerbExtension int32
+
+const (
+	// VERB_EXTENSION_UNKNOWN is the zero value and is never stored.
+	RuleVerbExtension_VERB_EXTENSION_UNKNOWN RuleVerbExtension = 0
+	// VERB_RAFT_TRANSFER_LEADERSHIP allows transferring raft leadership
+	// between voters.
+	RuleVerbExtension_VERB_RAFT_TRANSFER_LEADERSHIP RuleVerbExtension = 100
+	// VERB_RAFT_LIST allows listing the servers in the raft configuration.
+	RuleVerbExtension_VERB_RAFT_LIST RuleVerbExtension = 101
+	// VERB_RAFT_ADD allows adding servers to the raft configuration.
+	RuleVerbExtension_VERB_RAFT_ADD RuleVerbExtension = 102
+	// VERB_RAFT_REMOVE allows removing servers from the raft configuration.
+	RuleVerbExtension_VERB_RAFT_REMOVE RuleVerbExtension = 103
+	// VERB_RAFT_SUFFRAGE allows promoting and demoting voters.
+	RuleVerbExtension_VERB_RAFT_SUFFRAGE RuleVerbExtension = 104
+	// VERB_RAFT_FORCE_REMOVE allows force-removing dead servers, which also
+	// deletes their registration from the mesh.
+	RuleVerbExtension_VERB_RAFT_FORCE_REMOVE RuleVerbExtension = 105
+)
+
+// Enum value maps for RuleVerbExtension.
+var (
+	RuleVerbExtension_name = map[int32]string{
+		0:   "VERB_EXTENSION_UNKNOWN",
+		100: "VERB_RAFT_TRANSFER_LEADERSHIP",
+		101: "VERB_RAFT_LIST",
+		102: "VERB_RAFT_ADD",
+		103: "VERB_RAFT_REMOVE",
+		104: "VERB_RAFT_SUFFRAGE",
+		105: "VERB_RAFT_FORCE_REMOVE",
+	}
+	RuleVerbExtension_value = map[string]int32{
+		"VERB_EXTENSION_UNKNOWN":        0,
+		"VERB_RAFT_TRANSFER_LEADERSHIP": 100,
+		"VERB_RAFT_LIST":                101,
+		"VERB_RAFT_ADD":                 102,
+		"VERB_RAFT_REMOVE":              103,
+		"VERB_RAFT_SUFFRAGE":            104,
+		"VERB_RAFT_FORCE_REMOVE":        105,
+	}
+)
+
+func (x RuleVerbExtension) Enum() *RuleVerbExtension {
+	p := new(RuleVerbExtension)
+	*p = x
+	return p
+}
+
+func (x RuleVerbExtension) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RuleVerbExtension) Descriptor() protoreflect.EnumDescriptor {
+	return file_meshdb_rbac_verbs_proto_enumTypes[0].Descriptor()
+}
+
+func (RuleVerbExtension) Type() protoreflect.EnumType {
+	return &file_meshdb_rbac_verbs_proto_enumTypes[0]
+}
+
+func (x RuleVerbExtension) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RuleVerbExtension.Descriptor instead.
+func (RuleVerbExtension) EnumDescriptor() ([]byte, []int) {
+	return file_meshdb_rbac_verbs_proto_rawDescGZIP(), []int{0}
+}
+
+var File_meshdb_rbac_verbs_proto protoreflect.FileDescriptor
+
+var file_meshdb_rbac_verbs_proto_rawDesc = []byte{
+	0x0a, 0x17, 0x6d, 0x65, 0x73, 0x68, 0x64, 0x62, 0x2f, 0x72, 0x62, 0x61, 0x63, 0x2f, 0x76, 0x65,
+	0x72, 0x62, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0f, 0x77, 0x65, 0x62, 0x6d, 0x65,
+	0x73, 0x68, 0x2e, 0x72, 0x62, 0x61, 0x63, 0x2e, 0x76, 0x31, 0x2a, 0xc3, 0x01, 0x0a, 0x11, 0x52,
+	0x75, 0x6c, 0x65, 0x56, 0x65, 0x72, 0x62, 0x45, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e,
+	0x12, 0x1a, 0x0a, 0x16, 0x56, 0x45, 0x52, 0x42, 0x5f, 0x45, 0x58, 0x54, 0x45, 0x4e, 0x53, 0x49,
+	0x4f, 0x4e, 0x5f, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e, 0x10, 0x00, 0x12, 0x21, 0x0a, 0x1d,
+	0x56, 0x45, 0x52, 0x42, 0x5f, 0x52, 0x41, 0x46, 0x54, 0x5f, 0x54, 0x52, 0x41, 0x4e, 0x53, 0x46,
+	0x45, 0x52, 0x5f, 0x4c, 0x45, 0x41, 0x44, 0x45, 0x52, 0x53, 0x48, 0x49, 0x50, 0x10, 0x64, 0x12,
+	0x12, 0x0a, 0x0e, 0x56, 0x45, 0x52, 0x42, 0x5f, 0x52, 0x41, 0x46, 0x54, 0x5f, 0x4c, 0x49, 0x53,
+	0x54, 0x10, 0x65, 0x12, 0x11, 0x0a, 0x0d, 0x56, 0x45, 0x52, 0x42, 0x5f, 0x52, 0x41, 0x46, 0x54,
+	0x5f, 0x41, 0x44, 0x44, 0x10, 0x66, 0x12, 0x14, 0x0a, 0x10, 0x56, 0x45, 0x52, 0x42, 0x5f, 0x52,
+	0x41, 0x46, 0x54, 0x5f, 0x52, 0x45, 0x4d, 0x4f, 0x56, 0x45, 0x10, 0x67, 0x12, 0x16, 0x0a, 0x12,
+	0x56, 0x45, 0x52, 0x42, 0x5f, 0x52, 0x41, 0x46, 0x54, 0x5f, 0x53, 0x55, 0x46, 0x46, 0x52, 0x41,
+	0x47, 0x45, 0x10, 0x68, 0x12, 0x1a, 0x0a, 0x16, 0x56, 0x45, 0x52, 0x42, 0x5f, 0x52, 0x41, 0x46,
+	0x54, 0x5f, 0x46, 0x4f, 0x52, 0x43, 0x45, 0x5f, 0x52, 0x45, 0x4d, 0x4f, 0x56, 0x45, 0x10, 0x69,
+	0x42, 0x30, 0x5a, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x77,
+	0x65, 0x62, 0x6d, 0x65, 0x73, 0x68, 0x70, 0x72, 0x6f, 0x6a, 0x2f, 0x77, 0x65, 0x62, 0x6d, 0x65,
+	0x73, 0x68, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x6d, 0x65, 0x73, 0x68, 0x64, 0x62, 0x2f, 0x72, 0x62,
+	0x61, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_meshdb_rbac_verbs_proto_rawDescOnce sync.Once
+	file_meshdb_rbac_verbs_proto_rawDescData = file_meshdb_rbac_verbs_proto_rawDesc
+)
+
+func file_meshdb_rbac_verbs_proto_rawDescGZIP() []byte {
+	file_meshdb_rbac_verbs_proto_rawDescOnce.Do(func() {
+		file_meshdb_rbac_verbs_proto_rawDescData = protoimpl.X.CompressGZIP(file_meshdb_rbac_verbs_proto_rawDescData)
+	})
+	return file_meshdb_rbac_verbs_proto_rawDescData
+}
+
+var file_meshdb_rbac_verbs_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_meshdb_rbac_verbs_proto_goTypes = []interface{}{
+	(RuleVerbExtension)(0), // 0: webmesh.rbac.v1.RuleVerbExtension
+}
+var file_meshdb_rbac_verbs_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_meshdb_rbac_verbs_proto_init() }
+func file_meshdb_rbac_verbs_proto_init() {
+	if File_meshdb_rbac_verbs_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_meshdb_rbac_verbs_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   0,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_meshdb_rbac_verbs_proto_goTypes,
+		DependencyIndexes: file_meshdb_rbac_verbs_proto_depIdxs,
+		EnumInfos:         file_meshdb_rbac_verbs_proto_enumTypes,
+	}.Build()
+	File_meshdb_rbac_verbs_proto = out.File
+	file_meshdb_rbac_verbs_proto_rawDesc = nil
+	file_meshdb_rbac_verbs_proto_goTypes = nil
+	file_meshdb_rbac_verbs_proto_depIdxs = nil
+}
@@ -0,0 +1,254 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshots
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/webmeshproj/webmesh/pkg/meshdb/networking"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/rbac"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/topology"
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+// Resource kinds decoded from a snapshot.
+const (
+	KindNode           = "node"
+	KindNetworkACL     = "network-acl"
+	KindRoute          = "route"
+	KindRole           = "role"
+	KindRoleBinding    = "role-binding"
+	KindGroup          = "group"
+	KindTopologyPolicy = "topology-policy"
+)
+
+// Resource is a mesh resource decoded from a snapshot.
+type Resource struct {
+	// Kind is the kind of the resource.
+	Kind string `json:"kind"`
+	// Name is the name or ID of the resource.
+	Name string `json:"name"`
+	// Value is the resource encoded as compact JSON.
+	Value json.RawMessage `json:"value"`
+}
+
+// Inspection is the contents of a snapshot.
+type Inspection struct {
+	// Keys are all keys in the snapshot, sorted.
+	Keys []string `json:"keys"`
+	// Prefixes are the key counts and sizes by prefix.
+	Prefixes []storage.PrefixStats `json:"prefixes"`
+	// Resources are the decoded resources sorted by kind and name.
+	Resources []Resource `json:"resources"`
+}
+
+// ChangeType is the type of change to a resource between two snapshots.
+type ChangeType string
+
+const (
+	// ChangeAdded is a resource that only exists in the second snapshot.
+	ChangeAdded ChangeType = "added"
+	// ChangeRemoved is a resource that only exists in the first snapshot.
+	ChangeRemoved ChangeType = "removed"
+	// ChangeChanged is a resource that differs between the snapshots.
+	ChangeChanged ChangeType = "changed"
+)
+
+// Change is a difference in a resource between two snapshots.
+type Change struct {
+	// Type is the type of change.
+	Type ChangeType `json:"type"`
+	// Kind is the kind of the resource.
+	Kind string `json:"kind"`
+	// Name is the name or ID of the resource.
+	Name string `json:"name"`
+	// Old is the resource in the first snapshot, if present.
+	Old json.RawMessage `json:"old,omitempty"`
+	// New is the resource in the second snapshot, if present.
+	New json.RawMessage `json:"new,omitempty"`
+}
+
+// Load restores a snapshot into a new in-memory storage using the same
+// decode path as a node. The caller should close the returned storage.
+func Load(ctx context.Context, r io.Reader) (storage.Storage, error) {
+	st, err := storage.New(&storage.Options{InMemory: true, Silent: true})
+	if err != nil {
+		return nil, fmt.Errorf("create storage: %w", err)
+	}
+	if err := New(st).Restore(ctx, io.NopCloser(r)); err != nil {
+		st.Close()
+		return nil, err
+	}
+	return st, nil
+}
+
+// Inspect lists the keys and decodes the resources in the given storage,
+// usually one returned by Load.
+func Inspect(ctx context.Context, st storage.Storage) (*Inspection, error) {
+	keys, err := st.List(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("list keys: %w", err)
+	}
+	sort.Strings(keys)
+	prefixes, err := storage.Stats(ctx, st)
+	if err != nil {
+		return nil, fmt.Errorf("get prefix stats: %w", err)
+	}
+	resources, err := decodeResources(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Kind != resources[j].Kind {
+			return resources[i].Kind < resources[j].Kind
+		}
+		return resources[i].Name < resources[j].Name
+	})
+	return &Inspection{
+		Keys:      keys,
+		Prefixes:  prefixes,
+		Resources: resources,
+	}, nil
+}
+
+// Diff returns the resources that were added, removed or changed between
+// two inspected snapshots, sorted by kind and name.
+func Diff(a, b *Inspection) []Change {
+	type id struct{ kind, name string }
+	old := make(map[id]Resource, len(a.Resources))
+	for _, r := range a.Resources {
+		old[id{r.Kind, r.Name}] = r
+	}
+	var changes []Change
+	for _, r := range b.Resources {
+		key := id{r.Kind, r.Name}
+		prev, ok := old[key]
+		delete(old, key)
+		switch {
+		case !ok:
+			changes = append(changes, Change{Type: ChangeAdded, Kind: r.Kind, Name: r.Name, New: r.Value})
+		case !bytes.Equal(prev.Value, r.Value):
+			changes = append(changes, Change{Type: ChangeChanged, Kind: r.Kind, Name: r.Name, Old: prev.Value, New: r.Value})
+		}
+	}
+	for _, r := range old {
+		changes = append(changes, Change{Type: ChangeRemoved, Kind: r.Kind, Name: r.Name, Old: r.Value})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return changes[i].Kind < changes[j].Kind
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+func decodeResources(ctx context.Context, st storage.Storage) ([]Resource, error) {
+	var out []Resource
+	nodes, err := peers.New(st).List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list nodes: %w", err)
+	}
+	for _, node := range nodes {
+		data, err := json.Marshal(node)
+		if err != nil {
+			return nil, fmt.Errorf("marshal node %s: %w", node.ID, err)
+		}
+		out = append(out, Resource{Kind: KindNode, Name: node.ID, Value: data})
+	}
+	nw := networking.New(st)
+	acls, err := nw.ListNetworkACLs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list network acls: %w", err)
+	}
+	for _, acl := range acls {
+		if out, err = appendProto(out, KindNetworkACL, acl.GetName(), acl.NetworkACL); err != nil {
+			return nil, err
+		}
+	}
+	routes, err := nw.ListRoutes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list routes: %w", err)
+	}
+	for _, route := range routes {
+		if out, err = appendProto(out, KindRoute, route.GetName(), route); err != nil {
+			return nil, err
+		}
+	}
+	rb := rbac.New(st)
+	roles, err := rb.ListRoles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list roles: %w", err)
+	}
+	for _, role := range roles {
+		if out, err = appendProto(out, KindRole, role.GetName(), role); err != nil {
+			return nil, err
+		}
+	}
+	bindings, err := rb.ListRoleBindings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list role bindings: %w", err)
+	}
+	for _, binding := range bindings {
+		if out, err = appendProto(out, KindRoleBinding, binding.GetName(), binding); err != nil {
+			return nil, err
+		}
+	}
+	groups, err := rb.ListGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list groups: %w", err)
+	}
+	for _, group := range groups {
+		if out, err = appendProto(out, KindGroup, group.GetName(), group); err != nil {
+			return nil, err
+		}
+	}
+	policies, err := topology.New(st).List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list topology policies: %w", err)
+	}
+	for _, policy := range policies {
+		data, err := json.Marshal(policy)
+		if err != nil {
+			return nil, fmt.Errorf("marshal topology policy %s: %w", policy.Name, err)
+		}
+		out = append(out, Resource{Kind: KindTopologyPolicy, Name: policy.Name, Value: data})
+	}
+	return out, nil
+}
+
+func appendProto(out []Resource, kind, name string, msg proto.Message) ([]Resource, error) {
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s %s: %w", kind, name, err)
+	}
+	// protojson output is not stable, so compact it before it is compared.
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return nil, fmt.Errorf("compact %s %s: %w", kind, name, err)
+	}
+	return append(out, Resource{Kind: kind, Name: name, Value: buf.Bytes()}), nil
+}
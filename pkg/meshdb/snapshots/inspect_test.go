@@ -0,0 +1,111 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshots
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	v1 "github.com/webmeshproj/api/v1"
+
+	"github.com/webmeshproj/webmesh/pkg/meshdb/rbac"
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+func TestInspectDiff(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	db, err := storage.NewTestStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	roles := rbac.New(db)
+	putRole := func(name string, verb v1.RuleVerb) {
+		t.Helper()
+		err := roles.PutRole(ctx, &v1.Role{
+			Name: name,
+			Rules: []*v1.Rule{{
+				Resources: []v1.RuleResource{v1.RuleResource_RESOURCE_ROUTES},
+				Verbs:     []v1.RuleVerb{verb},
+			}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	takeSnapshot := func() *Inspection {
+		t.Helper()
+		snap, err := New(db).Snapshot(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer snap.Release()
+		var buf bytes.Buffer
+		if err := snap.Persist(&testSnapshotSink{&buf}); err != nil {
+			t.Fatal(err)
+		}
+		st, err := Load(ctx, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer st.Close()
+		inspection, err := Inspect(ctx, st)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return inspection
+	}
+
+	putRole("removed", v1.RuleVerb_VERB_PUT)
+	putRole("changed", v1.RuleVerb_VERB_PUT)
+	putRole("unchanged", v1.RuleVerb_VERB_PUT)
+	a := takeSnapshot()
+	if len(a.Resources) != 3 || len(a.Keys) != 3 {
+		t.Fatalf("expected 3 resources and keys, got %d and %d", len(a.Resources), len(a.Keys))
+	}
+	if len(a.Prefixes) != 1 || a.Prefixes[0].Prefix != rbac.RolesPrefix || a.Prefixes[0].Keys != 3 {
+		t.Fatalf("expected 3 keys under %s, got %+v", rbac.RolesPrefix, a.Prefixes)
+	}
+
+	if err := roles.DeleteRole(ctx, "removed"); err != nil {
+		t.Fatal(err)
+	}
+	putRole("changed", v1.RuleVerb_VERB_DELETE)
+	putRole("added", v1.RuleVerb_VERB_PUT)
+	b := takeSnapshot()
+
+	changes := Diff(a, b)
+	want := []struct {
+		typ  ChangeType
+		name string
+	}{
+		{ChangeAdded, "added"},
+		{ChangeChanged, "changed"},
+		{ChangeRemoved, "removed"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), changes)
+	}
+	for i, w := range want {
+		if changes[i].Type != w.typ || changes[i].Name != w.name || changes[i].Kind != KindRole {
+			t.Errorf("change %d: expected %s role %s, got %s %s %s", i, w.typ, w.name, changes[i].Type, changes[i].Kind, changes[i].Name)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshots
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Snapshot Metrics
+var (
+	// SnapshotDuration tracks how long it takes to create snapshots.
+	SnapshotDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "webmesh",
+		Name:      "snapshot_duration_seconds",
+		Help:      "Time taken to create a database snapshot.",
+	})
+
+	// SnapshotSize tracks the compressed size of the last snapshot.
+	SnapshotSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "webmesh",
+		Name:      "snapshot_size_bytes",
+		Help:      "The compressed size of the last database snapshot.",
+	})
+
+	// RestoreDuration tracks how long it takes to restore snapshots.
+	RestoreDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "webmesh",
+		Name:      "snapshot_restore_duration_seconds",
+		Help:      "Time taken to restore a database snapshot.",
+	})
+)
@@ -68,6 +68,8 @@ func (s *snapshotter) Snapshot(ctx context.Context) (raft.FSMSnapshot, error) {
 		return nil, fmt.Errorf("close gzip writer: %w", err)
 	}
 	snapshot := &snapshot{&buf}
+	SnapshotDuration.Observe(time.Since(start).Seconds())
+	SnapshotSize.Set(float64(buf.Len()))
 	s.log.Info("db snapshot complete",
 		slog.String("duration", time.Since(start).String()),
 		slog.String("size", snapshot.size()),
@@ -91,6 +93,7 @@ func (s *snapshotter) Restore(ctx context.Context, r io.ReadCloser) error {
 	if err := s.st.Restore(ctx, bytes.NewReader(data)); err != nil {
 		return fmt.Errorf("restore snapshot: %w", err)
 	}
+	RestoreDuration.Observe(time.Since(start).Seconds())
 	s.log.Info("db snapshot restore complete", slog.String("duration", time.Since(start).String()))
 	return nil
 }
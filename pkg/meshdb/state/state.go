@@ -59,6 +59,10 @@ const (
 	IPv4PrefixKey = MeshStatePrefix + "/ipv4prefix"
 	// MeshDomainKey is the key for the mesh domain.
 	MeshDomainKey = MeshStatePrefix + "/meshdomain"
+	// AllocationsKey is written with a revision-checked put by every join that
+	// assigns mesh addresses, so that concurrent joins conflict instead of
+	// being handed the same addresses.
+	AllocationsKey = MeshStatePrefix + "/allocations"
 )
 
 type state struct {
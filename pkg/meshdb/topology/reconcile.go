@@ -0,0 +1,333 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package topology
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+// EdgeAttributePolicy is the edge attribute that marks an edge as generated
+// by a topology policy. Its value is the name of the policy. Edges without
+// it are never changed by the reconciler.
+const EdgeAttributePolicy = "topology_policy"
+
+// reconcileBatchSize is the maximum number of operations written in a
+// single batch while reconciling.
+const reconcileBatchSize = 1000
+
+// Result is the outcome of a reconcile.
+type Result struct {
+	// Added is the number of edges created.
+	Added int
+	// Removed is the number of generated edges removed.
+	Removed int
+	// Updated is the number of generated edges moved to another policy.
+	Updated int
+}
+
+// Changed returns true if the reconcile changed any edges.
+func (r Result) Changed() bool {
+	return r.Added > 0 || r.Removed > 0 || r.Updated > 0
+}
+
+// Reconcile creates the edges the policies call for and removes generated
+// edges no policy calls for anymore. Edges that are not marked with
+// EdgeAttributePolicy are left alone, and an existing edge is never marked.
+// Edges are created, moved to another policy and removed with
+// revision-checked writes, so an error wrapping storage.ErrRevisionMismatch
+// is returned if an edge changed while reconciling, and the reconcile should
+// be retried.
+//
+// The changes are written in batches of at most reconcileBatchSize
+// operations. Each batch is applied atomically and never splits the
+// operations of one edge, but the batches are not atomic with each other.
+// If a batch fails, the batches before it stay applied and the returned
+// result counts only the edges they changed.
+func Reconcile(ctx context.Context, st storage.Storage) (Result, error) {
+	var res Result
+	policies, err := New(st).List(ctx)
+	if err != nil {
+		return res, fmt.Errorf("list policies: %w", err)
+	}
+	nodes, err := peers.New(st).List(ctx)
+	if err != nil {
+		return res, fmt.Errorf("list nodes: %w", err)
+	}
+	desired, err := desiredEdges(policies, nodes)
+	if err != nil {
+		return res, err
+	}
+	existing := make(map[pair]peers.Edge)
+	err = st.IterPrefix(ctx, peers.EdgesPrefix+"/", func(_, value string) error {
+		var edge peers.Edge
+		if err := json.Unmarshal([]byte(value), &edge); err != nil {
+			return fmt.Errorf("unmarshal edge: %w", err)
+		}
+		if _, ok := existing[newPair(edge.From, edge.To)]; !ok {
+			existing[newPair(edge.From, edge.To)] = edge
+		}
+		return nil
+	})
+	if err != nil {
+		return res, fmt.Errorf("list edges: %w", err)
+	}
+	var changes []change
+	for p, edge := range existing {
+		name, ok := edge.Attrs[EdgeAttributePolicy]
+		if !ok {
+			continue
+		}
+		policy, wanted := desired[p]
+		if wanted && policy.Name == name {
+			continue
+		}
+		// Read the edge again along with the revisions of its keys, so that
+		// it is only changed if it is still generated when it is written.
+		edge, revs, ok, err := readEdge(ctx, st, p)
+		if err != nil {
+			return res, err
+		}
+		if _, generated := edge.Attrs[EdgeAttributePolicy]; !ok || !generated {
+			continue
+		}
+		if !wanted {
+			changes = append(changes, change{kind: changeRemove, ops: deleteEdgeIfRevisionOps(revs)})
+			continue
+		}
+		edge.Attrs = maps.Clone(edge.Attrs)
+		edge.Attrs[EdgeAttributePolicy] = policy.Name
+		putOps, err := putEdgeIfRevisionOps(edge, revs)
+		if err != nil {
+			return res, err
+		}
+		changes = append(changes, change{kind: changeUpdate, ops: putOps})
+	}
+	for p, policy := range desired {
+		if _, ok := existing[p]; ok {
+			continue
+		}
+		weight := policy.Weight
+		if weight == 0 {
+			weight = DefaultWeight
+		}
+		// The edge is only created if it still does not exist when it is
+		// written, so that an edge created in the meantime is not marked.
+		putOps, err := putEdgeIfRevisionOps(peers.Edge{
+			From:   p.a,
+			To:     p.b,
+			Weight: weight,
+			Attrs:  map[string]string{EdgeAttributePolicy: policy.Name},
+		}, nil)
+		if err != nil {
+			return res, err
+		}
+		changes = append(changes, change{kind: changeAdd, ops: putOps})
+	}
+	for len(changes) > 0 {
+		var ops []storage.Op
+		n := 0
+		for n < len(changes) && (n == 0 || len(ops)+len(changes[n].ops) <= reconcileBatchSize) {
+			ops = append(ops, changes[n].ops...)
+			n++
+		}
+		if err := st.Batch(ctx, ops); err != nil {
+			return res, fmt.Errorf("write edges: %w", err)
+		}
+		for _, c := range changes[:n] {
+			res.count(c.kind)
+		}
+		changes = changes[n:]
+	}
+	return res, nil
+}
+
+// changeKind is the kind of change made to an edge.
+type changeKind int
+
+const (
+	changeAdd changeKind = iota
+	changeRemove
+	changeUpdate
+)
+
+// change is a change to one edge and the operations that make it.
+type change struct {
+	kind changeKind
+	ops  []storage.Op
+}
+
+func (r *Result) count(kind changeKind) {
+	switch kind {
+	case changeAdd:
+		r.Added++
+	case changeRemove:
+		r.Removed++
+	case changeUpdate:
+		r.Updated++
+	}
+}
+
+// readEdge returns the edge between the nodes of the pair and the revisions
+// of its keys. It returns false if the edge does not exist.
+func readEdge(ctx context.Context, st storage.Storage, p pair) (peers.Edge, map[string]uint64, bool, error) {
+	var edge peers.Edge
+	revs := make(map[string]uint64, 2)
+	for _, key := range []string{edgeKey(p.a, p.b), edgeKey(p.b, p.a)} {
+		value, rev, err := st.GetRevision(ctx, key)
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return edge, nil, false, fmt.Errorf("get edge: %w", err)
+		}
+		if err := json.Unmarshal([]byte(value), &edge); err != nil {
+			return edge, nil, false, fmt.Errorf("unmarshal edge: %w", err)
+		}
+		revs[key] = rev
+	}
+	return edge, revs, len(revs) > 0, nil
+}
+
+// putEdgeIfRevisionOps returns the operations that store the edge in both
+// directions only if the keys are still at the given revisions. A key
+// without a revision is only stored if it does not exist.
+func putEdgeIfRevisionOps(edge peers.Edge, revs map[string]uint64) ([]storage.Op, error) {
+	ops, err := peers.PutEdgeOps(edge)
+	if err != nil {
+		return nil, err
+	}
+	for i := range ops {
+		ops[i].Type = storage.OpPutIfRevision
+		ops[i].Revision = revs[ops[i].Key]
+	}
+	return ops, nil
+}
+
+// deleteEdgeIfRevisionOps returns the operations that remove the keys of an
+// edge only if they are still at the given revisions.
+func deleteEdgeIfRevisionOps(revs map[string]uint64) []storage.Op {
+	keys := make([]string, 0, len(revs))
+	for key := range revs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	ops := make([]storage.Op, 0, len(keys))
+	for _, key := range keys {
+		ops = append(ops, storage.Op{Type: storage.OpDeleteIfRevision, Key: key, Revision: revs[key]})
+	}
+	return ops
+}
+
+func edgeKey(from, to string) string {
+	return fmt.Sprintf("%s/%s/%s", peers.EdgesPrefix, from, to)
+}
+
+// pair is an unordered pair of node IDs.
+type pair struct{ a, b string }
+
+func newPair(a, b string) pair {
+	if b < a {
+		a, b = b, a
+	}
+	return pair{a, b}
+}
+
+// desiredEdges returns the edges called for by the policies and the policy
+// that owns each of them. When several policies call for the same edge, the
+// first policy by name owns it.
+func desiredEdges(policies []Policy, nodes []peers.Node) (map[pair]Policy, error) {
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+	out := make(map[pair]Policy)
+	for _, policy := range policies {
+		add := func(a, b string) {
+			if a == b {
+				return
+			}
+			if _, ok := out[newPair(a, b)]; !ok {
+				out[newPair(a, b)] = policy
+			}
+		}
+		selected, err := selectNodes(nodes, policy.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("policy %q: %w", policy.Name, err)
+		}
+		switch policy.Type {
+		case TypeFullMesh:
+			connectAll(selected, add)
+		case TypeHubAndSpoke:
+			hubs, err := selectNodes(selected, policy.Hubs)
+			if err != nil {
+				return nil, fmt.Errorf("policy %q: %w", policy.Name, err)
+			}
+			for _, hub := range hubs {
+				for _, node := range selected {
+					add(hub.ID, node.ID)
+				}
+			}
+		case TypeZoneMesh:
+			zones := make(map[string][]peers.Node)
+			for _, node := range selected {
+				zones[node.ZoneAwarenessID] = append(zones[node.ZoneAwarenessID], node)
+			}
+			for _, zone := range zones {
+				connectAll(zone, add)
+			}
+			gateways, err := selectNodes(selected, policy.Gateways)
+			if err != nil {
+				return nil, fmt.Errorf("policy %q: %w", policy.Name, err)
+			}
+			for i, a := range gateways {
+				for _, b := range gateways[i+1:] {
+					if a.ZoneAwarenessID != b.ZoneAwarenessID {
+						add(a.ID, b.ID)
+					}
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+func selectNodes(nodes []peers.Node, selector string) ([]peers.Node, error) {
+	sel, err := peers.ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	var out []peers.Node
+	for _, node := range nodes {
+		if sel.Matches(node.Labels) {
+			out = append(out, node)
+		}
+	}
+	return out, nil
+}
+
+func connectAll(nodes []peers.Node, add func(a, b string)) {
+	for i, a := range nodes {
+		for _, b := range nodes[i+1:] {
+			add(a.ID, b.ID)
+		}
+	}
+}
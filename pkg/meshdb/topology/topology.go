@@ -0,0 +1,190 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package topology contains an interface for managing topology policies in
+// the mesh. A topology policy describes the edges between a set of nodes,
+// and the leader reconciles the edges in the graph against the policies as
+// nodes join and leave.
+package topology
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+// PoliciesPrefix is where topology policies are stored in the database.
+// policies are indexed by their name in the format /registry/topology-policies/<name>.
+const PoliciesPrefix = "/registry/topology-policies"
+
+// ErrPolicyNotFound is returned when a topology policy is not found.
+var ErrPolicyNotFound = errors.New("topology policy not found")
+
+// Type is the type of a topology policy.
+type Type string
+
+const (
+	// TypeFullMesh connects every selected node to every other selected node.
+	TypeFullMesh Type = "full-mesh"
+	// TypeHubAndSpoke connects every selected node to every hub, and the
+	// hubs to each other.
+	TypeHubAndSpoke Type = "hub-and-spoke"
+	// TypeZoneMesh connects the selected nodes in the same zone to each
+	// other, and the gateways of every zone to the gateways of the others.
+	TypeZoneMesh Type = "zone-mesh"
+)
+
+// DefaultWeight is the weight of generated edges when a policy does not
+// set one.
+const DefaultWeight = 1
+
+// Policy is a topology policy.
+type Policy struct {
+	// Name is the name of the policy.
+	Name string `json:"name"`
+	// Type is the type of the policy.
+	Type Type `json:"type"`
+	// Selector selects the nodes the policy applies to. An empty
+	// selector selects every node.
+	Selector string `json:"selector,omitempty"`
+	// Hubs selects the hubs among the selected nodes of a
+	// hub-and-spoke policy.
+	Hubs string `json:"hubs,omitempty"`
+	// Gateways selects the gateways among the selected nodes of a
+	// zone-mesh policy.
+	Gateways string `json:"gateways,omitempty"`
+	// Weight is the weight of the edges the policy creates. Weights
+	// of existing edges are left alone, so that weights derived from
+	// measurements are kept.
+	Weight int `json:"weight,omitempty"`
+}
+
+// Validate returns an error if the policy is invalid.
+func (p Policy) Validate() error {
+	if !peers.IsValidID(p.Name) {
+		return fmt.Errorf("invalid policy name %q", p.Name)
+	}
+	if _, err := peers.ParseSelector(p.Selector); err != nil {
+		return fmt.Errorf("invalid selector: %w", err)
+	}
+	switch p.Type {
+	case TypeFullMesh:
+	case TypeHubAndSpoke:
+		if p.Hubs == "" {
+			return errors.New("hub-and-spoke policies require a hubs selector")
+		}
+		if _, err := peers.ParseSelector(p.Hubs); err != nil {
+			return fmt.Errorf("invalid hubs selector: %w", err)
+		}
+	case TypeZoneMesh:
+		if p.Gateways == "" {
+			return errors.New("zone-mesh policies require a gateways selector")
+		}
+		if _, err := peers.ParseSelector(p.Gateways); err != nil {
+			return fmt.Errorf("invalid gateways selector: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown policy type %q, must be one of %q, %q or %q", p.Type, TypeFullMesh, TypeHubAndSpoke, TypeZoneMesh)
+	}
+	if p.Weight < 0 {
+		return errors.New("weight must not be negative")
+	}
+	return nil
+}
+
+// Policies is the interface for managing topology policies.
+type Policies interface {
+	// Put creates or updates a policy.
+	Put(ctx context.Context, policy Policy) error
+	// Get returns a policy by name.
+	Get(ctx context.Context, name string) (Policy, error)
+	// Delete deletes a policy by name. The edges it generated are removed
+	// by the next reconcile.
+	Delete(ctx context.Context, name string) error
+	// List returns all policies ordered by name.
+	List(ctx context.Context) ([]Policy, error)
+}
+
+// New returns a new Policies interface.
+func New(st storage.Storage) Policies {
+	return &policies{st: st}
+}
+
+type policies struct {
+	st storage.Storage
+}
+
+func policyKey(name string) string {
+	return fmt.Sprintf("%s/%s", PoliciesPrefix, name)
+}
+
+// Put creates or updates a policy.
+func (p *policies) Put(ctx context.Context, policy Policy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("marshal policy: %w", err)
+	}
+	if err := p.st.Put(ctx, policyKey(policy.Name), string(data), 0); err != nil {
+		return fmt.Errorf("put policy: %w", err)
+	}
+	return nil
+}
+
+// Get returns a policy by name.
+func (p *policies) Get(ctx context.Context, name string) (Policy, error) {
+	data, err := p.st.Get(ctx, policyKey(name))
+	if err != nil {
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			return Policy{}, ErrPolicyNotFound
+		}
+		return Policy{}, fmt.Errorf("get policy: %w", err)
+	}
+	var policy Policy
+	if err := json.Unmarshal([]byte(data), &policy); err != nil {
+		return Policy{}, fmt.Errorf("unmarshal policy: %w", err)
+	}
+	return policy, nil
+}
+
+// Delete deletes a policy by name.
+func (p *policies) Delete(ctx context.Context, name string) error {
+	err := p.st.Delete(ctx, policyKey(name))
+	if err != nil && !errors.Is(err, storage.ErrKeyNotFound) {
+		return fmt.Errorf("delete policy: %w", err)
+	}
+	return nil
+}
+
+// List returns all policies ordered by name.
+func (p *policies) List(ctx context.Context) ([]Policy, error) {
+	out := make([]Policy, 0)
+	err := p.st.IterPrefix(ctx, PoliciesPrefix+"/", func(_, value string) error {
+		var policy Policy
+		if err := json.Unmarshal([]byte(value), &policy); err != nil {
+			return fmt.Errorf("unmarshal policy: %w", err)
+		}
+		out = append(out, policy)
+		return nil
+	})
+	return out, err
+}
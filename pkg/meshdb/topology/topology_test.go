@@ -0,0 +1,286 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package topology
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+func TestDesiredEdges(t *testing.T) {
+	t.Parallel()
+
+	nodes := []peers.Node{
+		{ID: "a1", ZoneAwarenessID: "a", Labels: map[string]string{"role": "gateway"}},
+		{ID: "a2", ZoneAwarenessID: "a"},
+		{ID: "b1", ZoneAwarenessID: "b", Labels: map[string]string{"role": "gateway"}},
+		{ID: "b2", ZoneAwarenessID: "b"},
+		{ID: "c1", ZoneAwarenessID: "c", Labels: map[string]string{"tier": "edge"}},
+	}
+	tc := []struct {
+		name   string
+		policy Policy
+		want   []pair
+	}{
+		{
+			name:   "full mesh",
+			policy: Policy{Name: "p", Type: TypeFullMesh, Selector: "tier!=edge"},
+			want: []pair{
+				{"a1", "a2"}, {"a1", "b1"}, {"a1", "b2"},
+				{"a2", "b1"}, {"a2", "b2"}, {"b1", "b2"},
+			},
+		},
+		{
+			name:   "hub and spoke",
+			policy: Policy{Name: "p", Type: TypeHubAndSpoke, Hubs: "role=gateway"},
+			want: []pair{
+				{"a1", "a2"}, {"a1", "b1"}, {"a1", "b2"}, {"a1", "c1"},
+				{"a2", "b1"}, {"b1", "b2"}, {"b1", "c1"},
+			},
+		},
+		{
+			name:   "zone mesh",
+			policy: Policy{Name: "p", Type: TypeZoneMesh, Gateways: "role=gateway"},
+			want: []pair{
+				{"a1", "a2"}, {"b1", "b2"}, {"a1", "b1"},
+			},
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			got, err := desiredEdges([]Policy{tt.policy}, nodes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d edges, got %d: %v", len(tt.want), len(got), got)
+			}
+			for _, p := range tt.want {
+				if _, ok := got[p]; !ok {
+					t.Errorf("expected edge %s-%s", p.a, p.b)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	st, err := storage.NewTestStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	p := peers.New(st)
+	for _, id := range []string{"a", "b", "c"} {
+		if err := p.Put(ctx, peers.Node{ID: id, Labels: map[string]string{"mesh": "yes"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A manual edge is never marked or removed.
+	if err := p.PutEdge(ctx, peers.Edge{From: "a", To: "b", Weight: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := New(st).Put(ctx, Policy{Name: "all", Type: TypeFullMesh, Selector: "mesh=yes", Weight: 10}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := Reconcile(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Added != 2 || res.Removed != 0 {
+		t.Fatalf("expected 2 edges added, got %+v", res)
+	}
+	edge, err := p.Graph().Edge("c", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edge.Properties.Weight != 10 || edge.Properties.Attributes[EdgeAttributePolicy] != "all" {
+		t.Fatalf("expected generated edge, got %+v", edge.Properties)
+	}
+	manual, err := p.Graph().Edge("a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := manual.Properties.Attributes[EdgeAttributePolicy]; ok {
+		t.Fatal("expected manual edge to not be marked")
+	}
+	if res, err := Reconcile(ctx, st); err != nil || res.Changed() {
+		t.Fatalf("expected reconcile to be a no-op, got %+v, %v", res, err)
+	}
+
+	// A node leaving the selection loses its generated edges.
+	if err := p.Put(ctx, peers.Node{ID: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	res, err = Reconcile(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Removed != 2 {
+		t.Fatalf("expected 2 edges removed, got %+v", res)
+	}
+	if _, err := p.Graph().Edge("a", "c"); err != peers.ErrEdgeNotFound {
+		t.Fatalf("expected edge to be removed, got %v", err)
+	}
+
+	// Deleting the policy leaves the manual edge.
+	if err := New(st).Delete(ctx, "all"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Reconcile(ctx, st); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Graph().Edge("a", "b"); err != nil {
+		t.Fatalf("expected manual edge to remain, got %v", err)
+	}
+}
+
+func TestEdgeIfRevisionOps(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	st, err := storage.NewTestStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	for _, id := range []string{"a", "b"} {
+		if err := peers.New(st).Put(ctx, peers.Node{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	generated := peers.Edge{From: "a", To: "b", Weight: 1, Attrs: map[string]string{EdgeAttributePolicy: "mesh"}}
+	ops, err := putEdgeIfRevisionOps(generated, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An edge created after the reconciler read the edges must not be marked.
+	if err := peers.New(st).PutEdge(ctx, peers.Edge{From: "a", To: "b", Weight: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Batch(ctx, ops); !errors.Is(err, storage.ErrRevisionMismatch) {
+		t.Fatalf("expected revision mismatch, got %v", err)
+	}
+
+	edge, revs, ok, err := readEdge(ctx, st, newPair("b", "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || len(revs) != 2 {
+		t.Fatalf("expected both directions of the edge, got %v", revs)
+	}
+	if _, marked := edge.Attrs[EdgeAttributePolicy]; marked || edge.Weight != 5 {
+		t.Fatalf("expected the manual edge to be left alone, got %+v", edge)
+	}
+	ops, err = putEdgeIfRevisionOps(generated, revs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Batch(ctx, ops); err != nil {
+		t.Fatalf("expected write at the read revisions to succeed, got %v", err)
+	}
+
+	// Removing the edge at the revisions read before it was marked must fail.
+	if err := st.Batch(ctx, deleteEdgeIfRevisionOps(revs)); !errors.Is(err, storage.ErrRevisionMismatch) {
+		t.Fatalf("expected revision mismatch removing a changed edge, got %v", err)
+	}
+	_, revs, _, err = readEdge(ctx, st, newPair("a", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Batch(ctx, deleteEdgeIfRevisionOps(revs)); err != nil {
+		t.Fatalf("expected removal at the read revisions to succeed, got %v", err)
+	}
+	if _, _, ok, err := readEdge(ctx, st, newPair("a", "b")); err != nil || ok {
+		t.Fatalf("expected the edge to be removed, got %v, %v", ok, err)
+	}
+}
+
+// failingBatches fails every batch after the first n.
+type failingBatches struct {
+	storage.Storage
+	n int
+}
+
+func (f *failingBatches) Batch(ctx context.Context, ops []storage.Op) error {
+	if f.n == 0 {
+		return errors.New("batch failed")
+	}
+	f.n--
+	return f.Storage.Batch(ctx, ops)
+}
+
+func TestReconcileBatchesNotAtomic(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	st, err := storage.NewTestStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	p := peers.New(st)
+	// A full mesh of 40 nodes is 780 edges, or 1560 operations, which is
+	// written in two batches.
+	const numNodes = 40
+	const numEdges = numNodes * (numNodes - 1) / 2
+	for i := 0; i < numNodes; i++ {
+		if err := p.Put(ctx, peers.Node{ID: fmt.Sprintf("node-%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := New(st).Put(ctx, Policy{Name: "all", Type: TypeFullMesh}); err != nil {
+		t.Fatal(err)
+	}
+
+	// When the second batch fails the first one stays applied.
+	res, err := Reconcile(ctx, &failingBatches{Storage: st, n: 1})
+	if err == nil {
+		t.Fatal("expected the second batch to fail")
+	}
+	if res.Added != reconcileBatchSize/2 {
+		t.Fatalf("expected %d edges added by the first batch, got %+v", reconcileBatchSize/2, res)
+	}
+	edges, err := p.Graph().Edges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edges) != reconcileBatchSize/2 {
+		t.Fatalf("expected %d edges from the first batch, got %d", reconcileBatchSize/2, len(edges))
+	}
+
+	// A retry creates the rest.
+	res, err = Reconcile(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Added != numEdges-reconcileBatchSize/2 {
+		t.Fatalf("expected %d edges added on retry, got %+v", numEdges-reconcileBatchSize/2, res)
+	}
+}
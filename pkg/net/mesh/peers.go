@@ -18,6 +18,7 @@ limitations under the License.
 package mesh
 
 import (
+	"container/heap"
 	"context"
 	"fmt"
 	"net/netip"
@@ -55,6 +56,10 @@ func WireGuardPeersFor(ctx context.Context, st storage.Storage, peerID string) (
 			ourRoutes = append(ourRoutes, prefix)
 		}
 	}
+	allowedIPs, err := allowedIPsByHop(ctx, nw, graph, adjacencyMap, peerID, ourRoutes)
+	if err != nil {
+		return nil, fmt.Errorf("compute allowed IPs: %w", err)
+	}
 	directAdjacents := adjacencyMap[peerID]
 	out := make([]*v1.WireGuardPeer, 0, len(directAdjacents))
 	for adjacent, edge := range directAdjacents {
@@ -105,147 +110,208 @@ func WireGuardPeersFor(ctx context.Context, st storage.Storage, peerID string) (
 				peer.Ice = true
 			}
 		}
-		allowedIPs, allowedRoutes, err := recursePeers(ctx, nw, graph, adjacencyMap, peerID, ourRoutes, &node)
-		if err != nil {
-			return nil, fmt.Errorf("recurse allowed IPs: %w", err)
-		}
 		var ourAllowedIPs []string
-		for _, ip := range allowedIPs {
+		for _, ip := range allowedIPs[adjacent] {
 			ourAllowedIPs = append(ourAllowedIPs, ip.String())
 		}
-		var ourAllowedRoutes []string
-		for _, route := range allowedRoutes {
-			ourAllowedRoutes = append(ourAllowedRoutes, route.String())
-		}
 		peer.AllowedIps = ourAllowedIPs
-		peer.AllowedRoutes = ourAllowedRoutes
 		out = append(out, peer)
 	}
 	return out, nil
 }
 
-func recursePeers(
+// allowedIPsByHop returns the prefixes to route through each direct adjacent of
+// thisPeer. Every reachable node is routed through the first hop on its cheapest
+// path, and a prefix is only ever assigned to a single hop. Direct adjacents are
+// always reached directly.
+func allowedIPsByHop(
 	ctx context.Context,
 	nw networking.Networking,
 	graph peers.Graph,
 	adjacencyMap networking.AdjacencyMap,
 	thisPeer string,
 	thisRoutes []netip.Prefix,
-	node *peers.Node,
-) (allowedIPs, allowedRoutes []netip.Prefix, err error) {
+) (map[string][]netip.Prefix, error) {
+	nodes := make(map[string]peers.Node, len(adjacencyMap))
+	for id := range adjacencyMap {
+		if id == thisPeer {
+			continue
+		}
+		node, err := graph.Vertex(id)
+		if err != nil {
+			return nil, fmt.Errorf("get vertex: %w", err)
+		}
+		if node.PublicKey == (wgtypes.Key{}) {
+			continue
+		}
+		nodes[id] = node
+	}
+	hops := shortestPaths(adjacencyMap, nodes, thisPeer)
+	allowed := make(map[string][]netip.Prefix)
+	assigned := make(map[netip.Prefix]struct{})
+	assign := func(hop string, node peers.Node) error {
+		prefixes, err := nodePrefixes(ctx, nw, node, thisRoutes)
+		if err != nil {
+			return err
+		}
+		for _, prefix := range prefixes {
+			if _, ok := assigned[prefix]; ok {
+				continue
+			}
+			assigned[prefix] = struct{}{}
+			allowed[hop] = append(allowed[hop], prefix)
+		}
+		return nil
+	}
+	// Direct adjacents claim their own addresses and routes first.
+	for _, hop := range hops {
+		if hop.node == hop.via {
+			if err := assign(hop.via, nodes[hop.node]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, hop := range hops {
+		if hop.node != hop.via {
+			if err := assign(hop.via, nodes[hop.node]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return allowed, nil
+}
+
+// nodePrefixes returns the private addresses of the node and the routes it
+// exposes, excluding routes also exposed by this peer.
+func nodePrefixes(ctx context.Context, nw networking.Networking, node peers.Node, thisRoutes []netip.Prefix) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
 	if node.PrivateIPv4.IsValid() {
-		allowedIPs = append(allowedIPs, node.PrivateIPv4)
+		prefixes = append(prefixes, node.PrivateIPv4)
 	}
 	if node.PrivateIPv6.IsValid() {
-		allowedIPs = append(allowedIPs, node.PrivateIPv6)
+		prefixes = append(prefixes, node.PrivateIPv6)
 	}
 	// Does this peer expose routes?
 	routes, err := nw.GetRoutesByNode(ctx, node.ID)
 	if err != nil {
-		return nil, nil, fmt.Errorf("get routes by node: %w", err)
-	}
-	if len(routes) > 0 {
-		for _, route := range routes {
-			for _, cidr := range route.GetDestinationCidrs() {
-				prefix, err := netip.ParsePrefix(cidr)
-				if err != nil {
-					return nil, nil, fmt.Errorf("parse prefix: %w", err)
-				}
-				if !slices.Contains(allowedIPs, prefix) && !slices.Contains(thisRoutes, prefix) {
-					allowedIPs = append(allowedIPs, prefix)
-				}
-			}
-		}
-	}
-	edgeIPs, edgeRoutes, err := recurseEdges(ctx, nw, graph, adjacencyMap, thisPeer, thisRoutes, node, nil)
-	if err != nil {
-		return nil, nil, fmt.Errorf("recurse edge allowed IPs: %w", err)
-	}
-	for _, ip := range edgeIPs {
-		if !slices.Contains(allowedIPs, ip) {
-			allowedIPs = append(allowedIPs, ip)
-		}
+		return nil, fmt.Errorf("get routes by node: %w", err)
 	}
-	for _, route := range edgeRoutes {
-		if !slices.Contains(allowedRoutes, route) {
-			allowedRoutes = append(allowedRoutes, route)
+	for _, route := range routes {
+		for _, cidr := range route.GetDestinationCidrs() {
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("parse prefix: %w", err)
+			}
+			if !slices.Contains(prefixes, prefix) && !slices.Contains(thisRoutes, prefix) {
+				prefixes = append(prefixes, prefix)
+			}
 		}
 	}
-	return
+	return prefixes, nil
 }
 
-func recurseEdges(
-	ctx context.Context,
-	nw networking.Networking,
-	graph peers.Graph,
-	adjacencyMap networking.AdjacencyMap,
-	thisPeer string,
-	thisRoutes []netip.Prefix,
-	node *peers.Node,
-	visited map[string]struct{},
-) (allowedIPs, allowedRoutes []netip.Prefix, err error) {
-	if visited == nil {
-		visited = make(map[string]struct{})
-	}
-	directAdjacents := adjacencyMap[thisPeer]
-	visited[node.ID] = struct{}{}
-	targets := adjacencyMap[node.ID]
-	for target := range targets {
-		if target == thisPeer {
-			continue
-		}
-		if _, ok := directAdjacents[target]; ok {
-			continue
-		}
-		if _, ok := visited[target]; ok {
+// hop is the direct adjacent a node is reached through.
+type hop struct {
+	node string
+	via  string
+}
+
+// shortestPaths computes the cheapest paths from source to every node in nodes
+// over the adjacency map, using the edge weights as costs. Only nodes in nodes are
+// used as destinations or to forward traffic. Ties are broken by the number of
+// hops and then by the ID of the first hop, so every node computes the same paths
+// for the same graph. The result is ordered by cost.
+func shortestPaths(adjacencyMap networking.AdjacencyMap, nodes map[string]peers.Node, source string) []hop {
+	dist := make(map[string]pathCost)
+	settled := make(map[string]struct{})
+	queue := &pathQueue{}
+	for adjacent, edge := range adjacencyMap[source] {
+		if _, ok := nodes[adjacent]; !ok {
 			continue
 		}
-		visited[target] = struct{}{}
-		targetNode, err := graph.Vertex(target)
-		if err != nil {
-			return nil, nil, fmt.Errorf("get vertex: %w", err)
-		}
-		if targetNode.PublicKey == (wgtypes.Key{}) {
+		// Direct adjacents are always reached directly.
+		cost := pathCost{node: adjacent, weight: edgeWeight(edge.Properties.Weight), hops: 1, via: adjacent}
+		dist[adjacent] = cost
+		heap.Push(queue, cost)
+	}
+	var out []hop
+	for queue.Len() > 0 {
+		cur := heap.Pop(queue).(pathCost)
+		if _, ok := settled[cur.node]; ok {
 			continue
 		}
-		if targetNode.PrivateIPv4.IsValid() {
-			allowedIPs = append(allowedIPs, targetNode.PrivateIPv4)
-		}
-		if targetNode.PrivateIPv6.IsValid() {
-			allowedIPs = append(allowedIPs, targetNode.PrivateIPv6)
-		}
-		// Does this peer expose routes?
-		routes, err := nw.GetRoutesByNode(ctx, targetNode.ID)
-		if err != nil {
-			return nil, nil, fmt.Errorf("get routes by node: %w", err)
-		}
-		if len(routes) > 0 {
-			for _, route := range routes {
-				for _, cidr := range route.GetDestinationCidrs() {
-					prefix, err := netip.ParsePrefix(cidr)
-					if err != nil {
-						return nil, nil, fmt.Errorf("parse prefix: %w", err)
-					}
-					if !slices.Contains(allowedIPs, prefix) && !slices.Contains(thisRoutes, prefix) {
-						allowedIPs = append(allowedIPs, prefix)
-					}
-				}
+		settled[cur.node] = struct{}{}
+		out = append(out, hop{node: cur.node, via: cur.via})
+		for next, edge := range adjacencyMap[cur.node] {
+			if next == source {
+				continue
 			}
-		}
-		ips, ipRoutes, err := recurseEdges(ctx, nw, graph, adjacencyMap, thisPeer, thisRoutes, &targetNode, visited)
-		if err != nil {
-			return nil, nil, fmt.Errorf("recurse allowed IPs: %w", err)
-		}
-		for _, ip := range ips {
-			if !slices.Contains(allowedIPs, ip) {
-				allowedIPs = append(allowedIPs, ip)
+			if _, ok := nodes[next]; !ok {
+				continue
 			}
-		}
-		for _, ipRoute := range ipRoutes {
-			if !slices.Contains(allowedRoutes, ipRoute) {
-				allowedRoutes = append(allowedRoutes, ipRoute)
+			if _, ok := settled[next]; ok {
+				continue
+			}
+			if _, ok := adjacencyMap[source][next]; ok {
+				continue
 			}
+			cost := pathCost{
+				node:   next,
+				weight: cur.weight + edgeWeight(edge.Properties.Weight),
+				hops:   cur.hops + 1,
+				via:    cur.via,
+			}
+			if known, ok := dist[next]; ok && !cost.less(known) {
+				continue
+			}
+			dist[next] = cost
+			heap.Push(queue, cost)
 		}
 	}
-	return
+	return out
+}
+
+// edgeWeight returns the cost of traversing an edge. Negative weights are
+// treated as zero.
+func edgeWeight(weight int) int {
+	if weight < 0 {
+		return 0
+	}
+	return weight
+}
+
+// pathCost is the cost of the best known path to a node.
+type pathCost struct {
+	node   string
+	weight int
+	hops   int
+	via    string
+}
+
+func (c pathCost) less(o pathCost) bool {
+	if c.weight != o.weight {
+		return c.weight < o.weight
+	}
+	if c.hops != o.hops {
+		return c.hops < o.hops
+	}
+	if c.via != o.via {
+		return c.via < o.via
+	}
+	return c.node < o.node
+}
+
+// pathQueue is a priority queue of path costs.
+type pathQueue []pathCost
+
+func (q pathQueue) Len() int           { return len(q) }
+func (q pathQueue) Less(i, j int) bool { return q[i].less(q[j]) }
+func (q pathQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x any)        { *q = append(*q, x.(pathCost)) }
+func (q *pathQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
 }
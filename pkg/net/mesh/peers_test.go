@@ -18,6 +18,7 @@ package mesh
 
 import (
 	"context"
+	"fmt"
 	"net/netip"
 	"reflect"
 	"sort"
@@ -495,6 +496,79 @@ func TestWireGuardPeers(t *testing.T) {
 	}
 }
 
+func TestWireGuardPeersWeighted(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db, err := storage.NewTestStorage()
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	defer db.Close()
+	peerdb := peers.New(db)
+	nw := networking.New(db)
+	err = nw.PutNetworkACL(ctx, &v1.NetworkACL{
+		Name:             "allow-all",
+		Action:           v1.ACLAction_ACTION_ACCEPT,
+		SourceNodes:      []string{"*"},
+		DestinationNodes: []string{"*"},
+		SourceCidrs:      []string{"*"},
+		DestinationCidrs: []string{"*"},
+	})
+	if err != nil {
+		t.Fatalf("put network acl: %v", err)
+	}
+	for i, peerID := range []string{"a", "b", "c", "d", "e"} {
+		err = peerdb.Put(ctx, peers.Node{
+			ID:          peerID,
+			PublicKey:   mustGenerateKey(t).PublicKey(),
+			PrivateIPv4: netip.MustParsePrefix(fmt.Sprintf("172.16.0.%d/32", i+1)),
+		})
+		if err != nil {
+			t.Fatalf("put peer %q: %v", peerID, err)
+		}
+	}
+	putEdge := func(from, to string, weight int) {
+		t.Helper()
+		if err := peerdb.PutEdge(ctx, peers.Edge{From: from, To: to, Weight: weight}); err != nil {
+			t.Fatalf("put edge from %q to %q: %v", from, to, err)
+		}
+	}
+	// a reaches d and e through either b or c.
+	putEdge("a", "b", 1)
+	putEdge("a", "c", 1)
+	putEdge("b", "d", 1)
+	putEdge("c", "d", 5)
+	putEdge("d", "e", 1)
+	check := func(want map[string][]string) {
+		t.Helper()
+		peers, err := WireGuardPeersFor(ctx, db, "a")
+		if err != nil {
+			t.Fatalf("get peers: %v", err)
+		}
+		got := make(map[string][]string)
+		for _, p := range peers {
+			sort.Strings(p.AllowedIps)
+			got[p.Id] = p.AllowedIps
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+	check(map[string][]string{
+		"b": {"172.16.0.2/32", "172.16.0.4/32", "172.16.0.5/32"},
+		"c": {"172.16.0.3/32"},
+	})
+	// Making the path through b more expensive moves d and e to c.
+	if err := peerdb.RemoveEdge(ctx, "b", "d"); err != nil {
+		t.Fatalf("remove edge: %v", err)
+	}
+	putEdge("b", "d", 10)
+	check(map[string][]string{
+		"b": {"172.16.0.2/32"},
+		"c": {"172.16.0.3/32", "172.16.0.4/32", "172.16.0.5/32"},
+	})
+}
+
 func mustGenerateKey(t *testing.T) wgtypes.Key {
 	t.Helper()
 	key, err := wgtypes.GeneratePrivateKey()
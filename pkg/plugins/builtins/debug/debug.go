@@ -19,6 +19,7 @@ limitations under the License.
 package debug
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net"
@@ -143,6 +144,7 @@ func (p *Plugin) serve(opts Options) {
 		mux.HandleFunc(fmt.Sprintf("%s/db/list", pathPrefix), p.handleDBList)
 		mux.HandleFunc(fmt.Sprintf("%s/db/get", pathPrefix), p.handleDBGet)
 		mux.HandleFunc(fmt.Sprintf("%s/db/iter-prefix", pathPrefix), p.handleDBIterPrefix)
+		mux.HandleFunc(fmt.Sprintf("%s/db/stats", pathPrefix), p.handleDBStats)
 	}
 	server := &http.Server{
 		Addr:    opts.ListenAddress,
@@ -223,6 +225,27 @@ func (p *Plugin) handleDBIterPrefix(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "not implemented", http.StatusNotImplemented)
 }
 
+func (p *Plugin) handleDBStats(w http.ResponseWriter, r *http.Request) {
+	p.datamux.Lock()
+	defer p.datamux.Unlock()
+	defer r.Body.Close()
+	if p.data == nil {
+		http.Error(w, "plugin not configured", http.StatusInternalServerError)
+		return
+	}
+	log := context.LoggerFrom(r.Context())
+	log.Info("computing database usage by prefix")
+	stats, err := storage.Stats(r.Context(), p.data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		log.Error("error encoding database stats", "err", err.Error())
+	}
+}
+
 func logRequest(next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := context.LoggerFrom(r.Context())
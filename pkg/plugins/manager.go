@@ -17,6 +17,7 @@ import (
 
 	"github.com/webmeshproj/webmesh/pkg/context"
 	"github.com/webmeshproj/webmesh/pkg/plugins/clients"
+	"github.com/webmeshproj/webmesh/pkg/plugins/plugindb"
 	"github.com/webmeshproj/webmesh/pkg/storage"
 )
 
@@ -281,11 +282,12 @@ func (m *manager) handleQueryClient(plugin string, db storage.Storage, client cl
 			var result v1.PluginQueryResult
 			result.Id = query.GetId()
 			result.Key = query.GetQuery()
-			val, err := db.Get(queries.Context(), query.GetQuery())
+			val, rev, err := db.GetRevision(queries.Context(), query.GetQuery())
 			if err != nil {
 				result.Error = err.Error()
 			} else {
 				result.Value = []string{val}
+				plugindb.SetResultRevision(&result, rev)
 			}
 			err = queries.Send(&result)
 			if err != nil {
@@ -21,6 +21,7 @@ package plugindb
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"sync"
 	"time"
@@ -43,11 +44,18 @@ type pluginDB struct {
 }
 
 func (p *pluginDB) Get(ctx context.Context, key string) (string, error) {
+	value, _, err := p.GetRevision(ctx, key)
+	return value, err
+}
+
+// GetRevision returns the value of a key along with its revision. The revision
+// is carried in its own field of the query result, see ResultRevision.
+func (p *pluginDB) GetRevision(ctx context.Context, key string) (string, uint64, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	id, err := uuid.NewRandom()
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
 	req := &v1.PluginQuery{
 		Id:      id.String(),
@@ -55,17 +63,26 @@ func (p *pluginDB) Get(ctx context.Context, key string) (string, error) {
 		Query:   key,
 	}
 	if err := p.srv.Send(req); err != nil {
-		return "", err
+		return "", 0, err
 	}
 	resp, err := p.srv.Recv()
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
 	if len(resp.GetValue()) == 0 {
 		// This should never happen, but just in case.
-		return "", storage.ErrKeyNotFound
+		return "", 0, storage.ErrKeyNotFound
 	}
-	return resp.GetValue()[0], nil
+	revision, _, err := ResultRevision(resp)
+	if err != nil {
+		return "", 0, fmt.Errorf("parse revision: %w", err)
+	}
+	return resp.GetValue()[0], revision, nil
+}
+
+// GetTTL returns the time left until a key expires.
+func (p *pluginDB) GetTTL(ctx context.Context, key string) (time.Duration, error) {
+	return 0, errors.New("get ttl not implemented")
 }
 
 // Put sets the value of a key.
@@ -73,11 +90,21 @@ func (p *pluginDB) Put(ctx context.Context, key, value string, ttl time.Duration
 	return errors.New("put not implemented")
 }
 
+// PutIfRevision sets the value of a key if its revision matches.
+func (p *pluginDB) PutIfRevision(ctx context.Context, key, value string, revision uint64, ttl time.Duration) error {
+	return errors.New("put if revision not implemented")
+}
+
 // Delete removes a key.
 func (p *pluginDB) Delete(ctx context.Context, key string) error {
 	return errors.New("delete not implemented")
 }
 
+// Batch applies the given operations atomically.
+func (p *pluginDB) Batch(ctx context.Context, ops []storage.Op) error {
+	return errors.New("batch not implemented")
+}
+
 // List returns all keys with a given prefix.
 func (p *pluginDB) List(ctx context.Context, prefix string) ([]string, error) {
 	p.mu.Lock()
@@ -135,6 +162,17 @@ func (p *pluginDB) IterPrefix(ctx context.Context, prefix string, fn storage.Pre
 	}
 }
 
+// IterPrefixAfter iterates over all keys with a given prefix that sort after
+// the given key.
+func (p *pluginDB) IterPrefixAfter(ctx context.Context, prefix, after string, fn storage.PrefixIterator) error {
+	return p.IterPrefix(ctx, prefix, func(key, value string) error {
+		if key <= after {
+			return nil
+		}
+		return fn(key, value)
+	})
+}
+
 // Snapshot returns a snapshot of the storage.
 func (p *pluginDB) Snapshot(ctx context.Context) (io.Reader, error) {
 	return nil, errors.New("snapshot not implemented")
@@ -145,6 +183,16 @@ func (p *pluginDB) Restore(ctx context.Context, r io.Reader) error {
 	return errors.New("restore not implemented")
 }
 
+// Revision returns the current revision of the storage.
+func (p *pluginDB) Revision(ctx context.Context) (uint64, error) {
+	return 0, errors.New("revision not implemented")
+}
+
+// Watch calls fn for every change to a key with the given prefix after the given revision.
+func (p *pluginDB) Watch(ctx context.Context, prefix string, revision uint64, fn storage.WatchFunc) (func(), error) {
+	return nil, errors.New("watch not implemented")
+}
+
 // Subscribe will call the given function whenever a key with the given prefix is changed.
 // The returned function can be called to unsubscribe.
 func (p *pluginDB) Subscribe(ctx context.Context, prefix string, fn storage.SubscribeFunc) (func(), error) {
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugindb
+
+import (
+	"errors"
+
+	v1 "github.com/webmeshproj/api/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ResultRevisionField is the field number the revision of a key is carried in
+// on a PluginQueryResult. PluginQueryResult is declared in the webmesh API, so
+// field numbers 1000 and up are reserved for fields added by this repository.
+// They can never collide with fields added upstream, and older plugins skip
+// them as unknown fields.
+const ResultRevisionField protowire.Number = 1000
+
+// SetResultRevision sets the revision of the key in a query result.
+func SetResultRevision(result *v1.PluginQueryResult, revision uint64) {
+	refl := result.ProtoReflect()
+	unknown := stripField(refl.GetUnknown(), ResultRevisionField)
+	unknown = protowire.AppendTag(unknown, ResultRevisionField, protowire.VarintType)
+	unknown = protowire.AppendVarint(unknown, revision)
+	refl.SetUnknown(unknown)
+}
+
+// ResultRevision returns the revision of the key in a query result. False is
+// returned if the result does not carry a revision.
+func ResultRevision(result *v1.PluginQueryResult) (uint64, bool, error) {
+	b := result.ProtoReflect().GetUnknown()
+	var revision uint64
+	var found bool
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return 0, false, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num == ResultRevisionField {
+			if typ != protowire.VarintType {
+				return 0, false, errors.New("revision is not a varint")
+			}
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return 0, false, protowire.ParseError(n)
+			}
+			revision, found = v, true
+			b = b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return 0, false, protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return revision, found, nil
+}
+
+// stripField returns the unknown fields with any occurrence of the given field removed.
+func stripField(b []byte, field protowire.Number) []byte {
+	var out []byte
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return out
+		}
+		m := protowire.ConsumeFieldValue(num, typ, b[n:])
+		if m < 0 {
+			return out
+		}
+		if num != field {
+			out = append(out, b[:n+m]...)
+		}
+		b = b[n+m:]
+	}
+	return out
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugindb
+
+import (
+	"testing"
+
+	v1 "github.com/webmeshproj/api/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestResultRevision(t *testing.T) {
+	t.Parallel()
+
+	result := &v1.PluginQueryResult{Id: "id", Key: "key", Value: []string{"value"}}
+	if _, ok, err := ResultRevision(result); err != nil || ok {
+		t.Fatalf("expected no revision, got ok=%v err=%v", ok, err)
+	}
+	SetResultRevision(result, 41)
+	SetResultRevision(result, 42)
+	data, err := proto.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded v1.PluginQueryResult
+	if err := proto.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.GetValue()) != 1 || decoded.GetValue()[0] != "value" {
+		t.Fatalf("expected value to be untouched, got %v", decoded.GetValue())
+	}
+	rev, ok, err := ResultRevision(&decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || rev != 42 {
+		t.Fatalf("expected revision 42, got %d (ok=%v)", rev, ok)
+	}
+}
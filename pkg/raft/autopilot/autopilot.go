@@ -0,0 +1,222 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package autopilot plans raft voter changes that keep a target number of
+// healthy voters spread across zones.
+package autopilot
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ActionType is the type of change to make to a server.
+type ActionType string
+
+const (
+	// ActionPromote promotes a non-voter to a voter.
+	ActionPromote ActionType = "promote"
+	// ActionDemote demotes a voter to a non-voter.
+	ActionDemote ActionType = "demote"
+	// ActionRemove removes a server from the raft configuration.
+	ActionRemove ActionType = "remove"
+)
+
+// Config is the configuration for planning voter changes.
+type Config struct {
+	// Voters is the target number of voters.
+	Voters int
+	// Stabilization is how long a server must be healthy before it is promoted,
+	// and how long a voter must fail heartbeats before it is demoted or removed.
+	Stabilization time.Duration
+	// RemoveUnhealthy removes failing voters from the cluster instead of
+	// demoting them.
+	RemoveUnhealthy bool
+}
+
+// Server is the state of a raft server as seen by the leader.
+type Server struct {
+	// ID is the server ID.
+	ID string
+	// Zone is the zone awareness ID of the server.
+	Zone string
+	// Voter is true if the server is a voter.
+	Voter bool
+	// Leader is true if the server is the leader. The leader is never demoted.
+	Leader bool
+	// Eligible is true if the server is allowed to become a voter.
+	Eligible bool
+	// Since is when the server was first seen in the configuration.
+	Since time.Time
+	// FailingSince is when the server started failing heartbeats. It is the
+	// zero time for healthy servers.
+	FailingSince time.Time
+}
+
+// Action is a change to make to a server.
+type Action struct {
+	// Type is the type of change.
+	Type ActionType
+	// Server is the ID of the server.
+	Server string
+	// Zone is the zone awareness ID of the server.
+	Zone string
+	// Reason describes why the change is made.
+	Reason string
+}
+
+// Plan returns the changes to make to bring the given servers to the configured
+// number of voters. Voters that have been failing for the stabilization window
+// are demoted or removed first. Eligible non-voters that have been healthy for the
+// stabilization window are then promoted, preferring zones with the fewest voters.
+// Excess voters are demoted from the zones with the most voters. Finally, at most
+// one voter is moved per plan from a zone with several voters to a zone without any.
+func Plan(cfg Config, servers []Server, now time.Time) []Action {
+	if cfg.Voters <= 0 {
+		return nil
+	}
+	sorted := make([]Server, len(servers))
+	copy(sorted, servers)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	var actions []Action
+	var voters, candidates []Server
+	zoneVoters := make(map[string]int)
+	for _, srv := range sorted {
+		switch {
+		case srv.Voter && !srv.Leader && failing(cfg, srv, now):
+			action := Action{
+				Type:   ActionDemote,
+				Server: srv.ID,
+				Zone:   srv.Zone,
+				Reason: fmt.Sprintf("failing heartbeats since %s", srv.FailingSince.UTC().Format(time.RFC3339)),
+			}
+			if cfg.RemoveUnhealthy {
+				action.Type = ActionRemove
+			}
+			actions = append(actions, action)
+		case srv.Voter:
+			voters = append(voters, srv)
+			zoneVoters[srv.Zone]++
+		case srv.Eligible && stable(cfg, srv, now):
+			candidates = append(candidates, srv)
+		}
+	}
+	for len(voters) < cfg.Voters && len(candidates) > 0 {
+		i := pickCandidate(candidates, zoneVoters)
+		srv := candidates[i]
+		candidates = append(candidates[:i], candidates[i+1:]...)
+		actions = append(actions, Action{
+			Type:   ActionPromote,
+			Server: srv.ID,
+			Zone:   srv.Zone,
+			Reason: fmt.Sprintf("voters below target (%d/%d)", len(voters), cfg.Voters),
+		})
+		voters = append(voters, srv)
+		zoneVoters[srv.Zone]++
+	}
+	for len(voters) > cfg.Voters {
+		i := pickExcess(voters, zoneVoters)
+		if i < 0 {
+			break
+		}
+		srv := voters[i]
+		voters = append(voters[:i], voters[i+1:]...)
+		zoneVoters[srv.Zone]--
+		actions = append(actions, Action{
+			Type:   ActionDemote,
+			Server: srv.ID,
+			Zone:   srv.Zone,
+			Reason: fmt.Sprintf("voters above target (%d/%d)", len(voters)+1, cfg.Voters),
+		})
+	}
+	if len(voters) == cfg.Voters {
+		actions = append(actions, rebalance(voters, candidates, zoneVoters)...)
+	}
+	return actions
+}
+
+// rebalance moves one voter from a zone with several voters to a zone without any.
+func rebalance(voters, candidates []Server, zoneVoters map[string]int) []Action {
+	for _, srv := range candidates {
+		if zoneVoters[srv.Zone] > 0 {
+			continue
+		}
+		i := -1
+		for j, v := range voters {
+			if !v.Leader && zoneVoters[v.Zone] > 1 && (i < 0 || zoneVoters[v.Zone] > zoneVoters[voters[i].Zone]) {
+				i = j
+			}
+		}
+		if i < 0 {
+			return nil
+		}
+		demoted := voters[i]
+		reason := fmt.Sprintf("spreading voters from zone %q to zone %q", demoted.Zone, srv.Zone)
+		return []Action{
+			{Type: ActionPromote, Server: srv.ID, Zone: srv.Zone, Reason: reason},
+			{Type: ActionDemote, Server: demoted.ID, Zone: demoted.Zone, Reason: reason},
+		}
+	}
+	return nil
+}
+
+// pickCandidate returns the index of the candidate in the zone with the fewest voters.
+func pickCandidate(candidates []Server, zoneVoters map[string]int) int {
+	best := 0
+	for i, srv := range candidates {
+		if zoneVoters[srv.Zone] < zoneVoters[candidates[best].Zone] {
+			best = i
+		}
+	}
+	return best
+}
+
+// pickExcess returns the index of the non-leader voter to demote, preferring
+// failing voters and then zones with the most voters. It returns -1 if only
+// the leader is left.
+func pickExcess(voters []Server, zoneVoters map[string]int) int {
+	best := -1
+	for i, srv := range voters {
+		if srv.Leader {
+			continue
+		}
+		if best < 0 {
+			best = i
+			continue
+		}
+		cur := voters[best]
+		curFailing, srvFailing := !cur.FailingSince.IsZero(), !srv.FailingSince.IsZero()
+		if srvFailing != curFailing {
+			if srvFailing {
+				best = i
+			}
+			continue
+		}
+		if zoneVoters[srv.Zone] > zoneVoters[cur.Zone] {
+			best = i
+		}
+	}
+	return best
+}
+
+func stable(cfg Config, srv Server, now time.Time) bool {
+	return srv.FailingSince.IsZero() && now.Sub(srv.Since) >= cfg.Stabilization
+}
+
+func failing(cfg Config, srv Server, now time.Time) bool {
+	return !srv.FailingSince.IsZero() && now.Sub(srv.FailingSince) >= cfg.Stabilization
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autopilot
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPlan(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-time.Hour)
+	cfg := Config{Voters: 3, Stabilization: time.Minute}
+	tc := []struct {
+		name    string
+		cfg     Config
+		servers []Server
+		want    []Action
+	}{
+		{
+			name: "disabled",
+			cfg:  Config{},
+			servers: []Server{
+				{ID: "a", Voter: true, Leader: true, Since: old},
+				{ID: "b", Eligible: true, Since: old},
+			},
+		},
+		{
+			name: "promotes across zones",
+			cfg:  cfg,
+			servers: []Server{
+				{ID: "a", Zone: "z1", Voter: true, Leader: true, Since: old},
+				{ID: "b", Zone: "z1", Eligible: true, Since: old},
+				{ID: "c", Zone: "z2", Eligible: true, Since: old},
+				{ID: "d", Zone: "z3", Eligible: true, Since: old},
+			},
+			want: []Action{
+				{Type: ActionPromote, Server: "c", Zone: "z2", Reason: "voters below target (1/3)"},
+				{Type: ActionPromote, Server: "d", Zone: "z3", Reason: "voters below target (2/3)"},
+			},
+		},
+		{
+			name: "skips ineligible and unstable servers",
+			cfg:  cfg,
+			servers: []Server{
+				{ID: "a", Voter: true, Leader: true, Since: old},
+				{ID: "b", Since: old},
+				{ID: "c", Eligible: true, Since: now},
+				{ID: "d", Eligible: true, Since: old, FailingSince: now},
+			},
+		},
+		{
+			name: "replaces failing voter",
+			cfg:  cfg,
+			servers: []Server{
+				{ID: "a", Zone: "z1", Voter: true, Leader: true, Since: old},
+				{ID: "b", Zone: "z2", Voter: true, Since: old, FailingSince: old},
+				{ID: "c", Zone: "z3", Voter: true, Since: old},
+				{ID: "d", Zone: "z2", Eligible: true, Since: old},
+			},
+			want: []Action{
+				{Type: ActionDemote, Server: "b", Zone: "z2", Reason: "failing heartbeats since " + old.UTC().Format(time.RFC3339)},
+				{Type: ActionPromote, Server: "d", Zone: "z2", Reason: "voters below target (2/3)"},
+			},
+		},
+		{
+			name: "removes failing voter",
+			cfg:  Config{Voters: 3, Stabilization: time.Minute, RemoveUnhealthy: true},
+			servers: []Server{
+				{ID: "a", Voter: true, Leader: true, Since: old},
+				{ID: "b", Voter: true, Since: old, FailingSince: old},
+				{ID: "c", Voter: true, Since: old},
+			},
+			want: []Action{
+				{Type: ActionRemove, Server: "b", Reason: "failing heartbeats since " + old.UTC().Format(time.RFC3339)},
+			},
+		},
+		{
+			name: "keeps voter within stabilization window",
+			cfg:  cfg,
+			servers: []Server{
+				{ID: "a", Voter: true, Leader: true, Since: old},
+				{ID: "b", Voter: true, Since: old, FailingSince: now},
+				{ID: "c", Voter: true, Since: old},
+			},
+		},
+		{
+			name: "demotes excess voters",
+			cfg:  cfg,
+			servers: []Server{
+				{ID: "a", Zone: "z1", Voter: true, Leader: true, Since: old},
+				{ID: "b", Zone: "z1", Voter: true, Since: old},
+				{ID: "c", Zone: "z2", Voter: true, Since: old},
+				{ID: "d", Zone: "z3", Voter: true, Since: old},
+			},
+			want: []Action{
+				{Type: ActionDemote, Server: "b", Zone: "z1", Reason: "voters above target (4/3)"},
+			},
+		},
+		{
+			name: "spreads voters to empty zone",
+			cfg:  cfg,
+			servers: []Server{
+				{ID: "a", Zone: "z1", Voter: true, Leader: true, Since: old},
+				{ID: "b", Zone: "z1", Voter: true, Since: old},
+				{ID: "c", Zone: "z2", Voter: true, Since: old},
+				{ID: "d", Zone: "z3", Eligible: true, Since: old},
+			},
+			want: []Action{
+				{Type: ActionPromote, Server: "d", Zone: "z3", Reason: `spreading voters from zone "z1" to zone "z3"`},
+				{Type: ActionDemote, Server: "b", Zone: "z1", Reason: `spreading voters from zone "z1" to zone "z3"`},
+			},
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Plan(tt.cfg, tt.servers, now)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPreferredLeader(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-time.Hour)
+	servers := []Server{
+		{ID: "a", Zone: "z1", Voter: true, Leader: true, Since: old},
+		{ID: "b", Zone: "z2", Voter: true, Since: old},
+		{ID: "c", Zone: "z2", Voter: true, Since: old},
+		{ID: "d", Zone: "z3", Voter: true, Since: now},
+		{ID: "e", Zone: "z3", Since: old},
+	}
+	tc := []struct {
+		name string
+		pref Preference
+		want string
+	}{
+		{name: "no preference"},
+		{name: "leader in preferred zone", pref: Preference{Zone: "z1"}},
+		{name: "preferred zone", pref: Preference{Zone: "z2"}, want: "b"},
+		{name: "unstable voters are skipped", pref: Preference{Zone: "z3"}},
+		{name: "priority", pref: Preference{Priorities: map[string]int{"c": 1}}, want: "c"},
+		{name: "priority outranks zone", pref: Preference{Zone: "z2", Priorities: map[string]int{"a": 1}}},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := PreferredLeader(tt.pref, time.Minute, servers, now)
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("expected no transfer, got %q", got.ID)
+				}
+				return
+			}
+			if !ok || got.ID != tt.want {
+				t.Fatalf("expected transfer to %q, got %q (%v)", tt.want, got.ID, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autopilot
+
+import (
+	"sync"
+	"time"
+)
+
+// Health tracks how long raft servers have been members and how long they have
+// been failing heartbeats, as observed by the leader. It is safe for concurrent use.
+type Health struct {
+	seen    map[string]time.Time
+	failing map[string]time.Time
+	mu      sync.Mutex
+}
+
+// NewHealth returns a new health tracker.
+func NewHealth() *Health {
+	return &Health{
+		seen:    make(map[string]time.Time),
+		failing: make(map[string]time.Time),
+	}
+}
+
+// Failed records a failed heartbeat to the given server. lastContact is the last
+// time the server was reached, if known.
+func (h *Health) Failed(id string, lastContact time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.failing[id]; ok {
+		return
+	}
+	if lastContact.IsZero() {
+		lastContact = time.Now()
+	}
+	h.failing[id] = lastContact
+}
+
+// Resumed records that heartbeats to the given server are succeeding again.
+func (h *Health) Resumed(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.failing, id)
+}
+
+// Observe records the current members of the configuration and returns when each
+// was first seen and when it started failing. Servers no longer in the
+// configuration are forgotten.
+func (h *Health) Observe(ids []string, now time.Time) (since, failingSince map[string]time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	since = make(map[string]time.Time, len(ids))
+	failingSince = make(map[string]time.Time)
+	members := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		members[id] = struct{}{}
+		if _, ok := h.seen[id]; !ok {
+			h.seen[id] = now
+		}
+		since[id] = h.seen[id]
+		if t, ok := h.failing[id]; ok {
+			failingSince[id] = t
+		}
+	}
+	for id := range h.seen {
+		if _, ok := members[id]; !ok {
+			delete(h.seen, id)
+			delete(h.failing, id)
+		}
+	}
+	return since, failingSince
+}
+
+// Reset forgets all tracked servers. It is called when leadership is lost so that
+// a later term starts its stabilization windows afresh.
+func (h *Health) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seen = make(map[string]time.Time)
+	h.failing = make(map[string]time.Time)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autopilot
+
+import (
+	"sort"
+	"time"
+)
+
+// Preference describes which voters should preferably hold leadership.
+type Preference struct {
+	// Zone is the preferred zone awareness ID for the leader.
+	Zone string
+	// Priorities are the leader priorities of servers by ID. Servers without
+	// a priority have a priority of zero. Priority outranks the zone.
+	Priorities map[string]int
+}
+
+// IsZero returns true if no preference is set.
+func (p Preference) IsZero() bool {
+	return p.Zone == "" && len(p.Priorities) == 0
+}
+
+// Outranks returns true if a is preferred over b as the leader.
+func (p Preference) Outranks(a, b Server) bool {
+	if p.Priorities[a.ID] != p.Priorities[b.ID] {
+		return p.Priorities[a.ID] > p.Priorities[b.ID]
+	}
+	return p.Zone != "" && a.Zone == p.Zone && b.Zone != p.Zone
+}
+
+// PreferredLeader returns the voter that leadership should be handed off to,
+// if any. Only voters that have been healthy for the stabilization window and
+// outrank the current leader are considered, so leadership does not move back
+// and forth between equally preferred voters.
+func PreferredLeader(pref Preference, stabilization time.Duration, servers []Server, now time.Time) (Server, bool) {
+	if pref.IsZero() {
+		return Server{}, false
+	}
+	var leader *Server
+	for i := range servers {
+		if servers[i].Leader {
+			leader = &servers[i]
+		}
+	}
+	if leader == nil {
+		return Server{}, false
+	}
+	sorted := make([]Server, len(servers))
+	copy(sorted, servers)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	best := *leader
+	cfg := Config{Stabilization: stabilization}
+	for _, srv := range sorted {
+		if !srv.Voter || srv.Leader || !stable(cfg, srv, now) {
+			continue
+		}
+		if pref.Outranks(srv, best) {
+			best = srv
+		}
+	}
+	return best, !best.Leader
+}
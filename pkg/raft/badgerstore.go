@@ -0,0 +1,219 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/hashicorp/raft"
+
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+var (
+	badgerLogsPrefix = []byte("logs-")
+	badgerMetaPrefix = []byte("meta-")
+)
+
+// badgerLogStore is a raft log and stable store backed by badger. Unlike
+// raft-badger, it can be opened with encryption enabled.
+type badgerLogStore struct {
+	db *badger.DB
+}
+
+func newBadgerLogStore(log *slog.Logger, path string, enc *storage.EncryptionOptions) (*badgerLogStore, error) {
+	opts := badger.DefaultOptions(path)
+	opts.Logger = storage.NewBadgerLogger(log)
+	db, err := storage.OpenBadger(opts, enc)
+	if err != nil {
+		return nil, err
+	}
+	return &badgerLogStore{db: db}, nil
+}
+
+// openBadgerLogStoreReadOnly opens an existing badger log store, including
+// stores created by raft-badger, without modifying it.
+func openBadgerLogStoreReadOnly(log *slog.Logger, path string, enc *storage.EncryptionOptions) (*badgerLogStore, error) {
+	opts := badger.DefaultOptions(path).WithReadOnly(true)
+	opts.Logger = storage.NewBadgerLogger(log)
+	db, err := storage.OpenBadger(opts, enc)
+	if err != nil {
+		return nil, err
+	}
+	return &badgerLogStore{db: db}, nil
+}
+
+func badgerLogKey(idx uint64) []byte {
+	key := make([]byte, len(badgerLogsPrefix)+8)
+	copy(key, badgerLogsPrefix)
+	binary.BigEndian.PutUint64(key[len(badgerLogsPrefix):], idx)
+	return key
+}
+
+func badgerMetaKey(key []byte) []byte {
+	return append(append([]byte{}, badgerMetaPrefix...), key...)
+}
+
+// FirstIndex returns the first index written. 0 for no entries.
+func (s *badgerLogStore) FirstIndex() (uint64, error) {
+	var first uint64
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: badgerLogsPrefix})
+		defer it.Close()
+		it.Rewind()
+		if it.Valid() {
+			first = binary.BigEndian.Uint64(it.Item().Key()[len(badgerLogsPrefix):])
+		}
+		return nil
+	})
+	return first, err
+}
+
+// LastIndex returns the last index written. 0 for no entries.
+func (s *badgerLogStore) LastIndex() (uint64, error) {
+	var last uint64
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: badgerLogsPrefix, Reverse: true})
+		defer it.Close()
+		it.Seek(badgerLogKey(math.MaxUint64))
+		if it.Valid() {
+			last = binary.BigEndian.Uint64(it.Item().Key()[len(badgerLogsPrefix):])
+		}
+		return nil
+	})
+	return last, err
+}
+
+// GetLog gets a log entry at a given index.
+func (s *badgerLogStore) GetLog(index uint64, log *raft.Log) error {
+	return s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(badgerLogKey(index))
+		if err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return raft.ErrLogNotFound
+			}
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return decodeLog(val, log)
+		})
+	})
+}
+
+// StoreLog stores a log entry.
+func (s *badgerLogStore) StoreLog(log *raft.Log) error {
+	return s.StoreLogs([]*raft.Log{log})
+}
+
+// StoreLogs stores multiple log entries.
+func (s *badgerLogStore) StoreLogs(logs []*raft.Log) error {
+	wb := s.db.NewWriteBatch()
+	defer wb.Cancel()
+	for _, log := range logs {
+		data, err := encodeLog(log)
+		if err != nil {
+			return err
+		}
+		if err := wb.Set(badgerLogKey(log.Index), data); err != nil {
+			return fmt.Errorf("store log %d: %w", log.Index, err)
+		}
+	}
+	return wb.Flush()
+}
+
+// DeleteRange deletes a range of log entries. The range is inclusive.
+func (s *badgerLogStore) DeleteRange(min, max uint64) error {
+	var keys [][]byte
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: badgerLogsPrefix})
+		defer it.Close()
+		for it.Seek(badgerLogKey(min)); it.Valid(); it.Next() {
+			key := it.Item().KeyCopy(nil)
+			if binary.BigEndian.Uint64(key[len(badgerLogsPrefix):]) > max {
+				break
+			}
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	wb := s.db.NewWriteBatch()
+	defer wb.Cancel()
+	for _, key := range keys {
+		if err := wb.Delete(key); err != nil {
+			return err
+		}
+	}
+	return wb.Flush()
+}
+
+// Set sets a key in the stable store.
+func (s *badgerLogStore) Set(key []byte, val []byte) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(badgerMetaKey(key), val)
+	})
+}
+
+// Get returns the value for key in the stable store.
+func (s *badgerLogStore) Get(key []byte) ([]byte, error) {
+	var val []byte
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(badgerMetaKey(key))
+		if err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return errStableKeyNotFound
+			}
+			return err
+		}
+		val, err = item.ValueCopy(nil)
+		return err
+	})
+	return val, err
+}
+
+// SetUint64 sets a uint64 value for key in the stable store. Like raft-badger,
+// uint64 values are stored under a doubled meta prefix so that stores can be
+// moved between the two implementations.
+func (s *badgerLogStore) SetUint64(key []byte, val uint64) error {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], val)
+	return s.Set(badgerMetaKey(key), buf[:])
+}
+
+// GetUint64 returns the uint64 value for key in the stable store.
+func (s *badgerLogStore) GetUint64(key []byte) (uint64, error) {
+	val, err := s.Get(badgerMetaKey(key))
+	if err != nil {
+		return 0, err
+	}
+	if len(val) != 8 {
+		return 0, fmt.Errorf("invalid uint64 value for key %q", key)
+	}
+	return binary.BigEndian.Uint64(val), nil
+}
+
+// Close closes the store.
+func (s *badgerLogStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	raftbadger "github.com/webmeshproj/raft-badger"
+)
+
+func TestBadgerLogStoreRaftBadgerLayout(t *testing.T) {
+	t.Parallel()
+
+	storePath := filepath.Join(t.TempDir(), RaftStorePath)
+	src, err := raftbadger.New(nil, storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := src.SetUint64([]byte("CurrentTerm"), 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := newBadgerLogStore(slog.Default(), storePath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if term, err := store.GetUint64([]byte("CurrentTerm")); err != nil || term != 3 {
+		t.Fatalf("expected current term 3, got %d: %v", term, err)
+	}
+	if err := store.SetUint64([]byte("LastVoteTerm"), 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := raftbadger.New(nil, storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	if term, err := dst.GetUint64([]byte("LastVoteTerm")); err != nil || term != 4 {
+		t.Fatalf("expected last vote term 4, got %d: %v", term, err)
+	}
+}
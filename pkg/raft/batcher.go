@@ -0,0 +1,264 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/raft"
+	v1 "github.com/webmeshproj/api/v1"
+
+	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/raftlogs"
+)
+
+// ErrBatcherClosed is returned when a write is submitted to a closed Batcher.
+var ErrBatcherClosed = errors.New("apply batcher is closed")
+
+// ApplyFunc submits data to the raft log. It matches (*raft.Raft).Apply.
+type ApplyFunc func(data []byte, timeout time.Duration) raft.ApplyFuture
+
+// BatcherOptions are options for a Batcher.
+type BatcherOptions struct {
+	// NodeID is the ID of the node used to label metrics.
+	NodeID string
+	// Window is how long to wait for more writes after the first write
+	// of a batch arrives. If zero, only writes already waiting are batched.
+	Window time.Duration
+	// MaxEntries is the maximum number of writes in a batch. A value of 1
+	// or less disables batching.
+	MaxEntries int
+	// MaxBytes is the maximum encoded size of the writes in a batch.
+	MaxBytes int
+	// ApplyTimeout is the timeout for enqueuing a batch with raft.
+	ApplyTimeout time.Duration
+}
+
+// Batcher coalesces concurrent writes into single raft log entries. Each write
+// is applied independently by the FSM and its result is returned to its caller.
+type Batcher struct {
+	apply  ApplyFunc
+	opts   BatcherOptions
+	reqs   chan *applyRequest
+	closec chan struct{}
+	done   chan struct{}
+}
+
+type applyRequest struct {
+	entry *v1.RaftLogEntry
+	size  int
+	resc  chan applyResult
+}
+
+type applyResult struct {
+	res *v1.RaftApplyResponse
+	err error
+}
+
+// NewBatcher returns a new Batcher that submits entries with the given apply
+// function. Close must be called to release its resources.
+func NewBatcher(apply ApplyFunc, opts BatcherOptions) *Batcher {
+	b := &Batcher{
+		apply:  apply,
+		opts:   opts,
+		reqs:   make(chan *applyRequest),
+		closec: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Apply submits the entry to raft, possibly together with other concurrent
+// writes, and waits for it to be applied by the FSM. Errors returned by raft
+// are returned as is. The response carries the result of applying the entry.
+func (b *Batcher) Apply(ctx context.Context, entry *v1.RaftLogEntry) (*v1.RaftApplyResponse, error) {
+	if entry.GetType() == raftlogs.RaftCommandType_COALESCED {
+		return nil, fmt.Errorf("coalesced entries cannot be batched")
+	}
+	if b.opts.MaxEntries <= 1 {
+		return b.applySingle(entry)
+	}
+	data, err := MarshalLogEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+	req := &applyRequest{
+		entry: entry,
+		size:  len(data),
+		resc:  make(chan applyResult, 1),
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-b.closec:
+		return nil, ErrBatcherClosed
+	case b.reqs <- req:
+	}
+	// Once the entry is queued it is applied regardless of the context.
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-req.resc:
+		return res.res, res.err
+	}
+}
+
+// Close stops the batcher. Writes already submitted to raft are not waited for.
+func (b *Batcher) Close() {
+	select {
+	case <-b.closec:
+		return
+	default:
+	}
+	close(b.closec)
+	<-b.done
+}
+
+func (b *Batcher) applySingle(entry *v1.RaftLogEntry) (*v1.RaftApplyResponse, error) {
+	data, err := MarshalLogEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+	ApplyBatchSize.WithLabelValues(b.opts.NodeID).Observe(1)
+	f := b.apply(data, b.opts.ApplyTimeout)
+	if err := f.Error(); err != nil {
+		return nil, err
+	}
+	return applyResponse(f.Response())
+}
+
+func (b *Batcher) run() {
+	defer close(b.done)
+	for {
+		var batch []*applyRequest
+		select {
+		case <-b.closec:
+			return
+		case req := <-b.reqs:
+			batch = append(batch, req)
+		}
+		b.collect(batch)
+	}
+}
+
+// collect gathers writes into the batch until the window expires or the
+// batch is full, and then submits it.
+func (b *Batcher) collect(batch []*applyRequest) {
+	size := batch[0].size
+	var timeout <-chan time.Time
+	if b.opts.Window > 0 {
+		timer := time.NewTimer(b.opts.Window)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+Collect:
+	for len(batch) < b.opts.MaxEntries && size < b.opts.MaxBytes {
+		if timeout == nil {
+			select {
+			case req := <-b.reqs:
+				batch = append(batch, req)
+				size += req.size
+				continue
+			default:
+				break Collect
+			}
+		}
+		select {
+		case req := <-b.reqs:
+			batch = append(batch, req)
+			size += req.size
+		case <-timeout:
+			break Collect
+		case <-b.closec:
+			break Collect
+		}
+	}
+	b.submit(batch)
+}
+
+// submit applies the batch and fans the results out to the callers once
+// the entry has been applied.
+func (b *Batcher) submit(batch []*applyRequest) {
+	fail := func(err error) {
+		for _, req := range batch {
+			req.resc <- applyResult{err: err}
+		}
+	}
+	entry := batch[0].entry
+	if len(batch) > 1 {
+		entries := make([]*v1.RaftLogEntry, len(batch))
+		for i, req := range batch {
+			entries[i] = req.entry
+		}
+		var err error
+		entry, err = raftlogs.NewCoalescedEntry(entries)
+		if err != nil {
+			fail(err)
+			return
+		}
+	}
+	data, err := MarshalLogEntry(entry)
+	if err != nil {
+		fail(err)
+		return
+	}
+	ApplyBatchSize.WithLabelValues(b.opts.NodeID).Observe(float64(len(batch)))
+	// Futures are enqueued in order so writes are applied in the order they
+	// were received, but they are waited on concurrently.
+	f := b.apply(data, b.opts.ApplyTimeout)
+	go func() {
+		if err := f.Error(); err != nil {
+			fail(err)
+			return
+		}
+		if len(batch) == 1 {
+			res, err := applyResponse(f.Response())
+			batch[0].resc <- applyResult{res: res, err: err}
+			return
+		}
+		results, ok := f.Response().([]*v1.RaftApplyResponse)
+		if !ok || len(results) != len(batch) {
+			// The FSM could not decode the entry and returned a single response.
+			res, err := applyResponse(f.Response())
+			if err != nil {
+				fail(err)
+				return
+			}
+			for _, req := range batch {
+				req.resc <- applyResult{res: res}
+			}
+			return
+		}
+		for i, req := range batch {
+			req.resc <- applyResult{res: results[i]}
+		}
+	}()
+}
+
+func applyResponse(res any) (*v1.RaftApplyResponse, error) {
+	switch res := res.(type) {
+	case *v1.RaftApplyResponse:
+		return res, nil
+	case nil:
+		return &v1.RaftApplyResponse{}, nil
+	default:
+		return nil, fmt.Errorf("unexpected apply response: %T", res)
+	}
+}
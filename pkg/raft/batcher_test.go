@@ -0,0 +1,203 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	v1 "github.com/webmeshproj/api/v1"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/raftlogs"
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+func TestBatcher(t *testing.T) {
+	t.Parallel()
+
+	log := newTestLog(t, 0)
+	b := NewBatcher(log.apply, BatcherOptions{
+		Window:     10 * time.Millisecond,
+		MaxEntries: 64,
+		MaxBytes:   1024 * 1024,
+	})
+	defer b.Close()
+
+	// Every writer tries to create the same key. Exactly one of them should
+	// succeed and the rest should see their own revision mismatch.
+	const writers = 16
+	var wg sync.WaitGroup
+	var succeeded, mismatched atomic.Int32
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			entry := raftlogs.NewPutIfRevisionEntry("/test/key", fmt.Sprintf("writer-%d", i), 0, 0)
+			res, err := b.Apply(context.Background(), entry)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			err = raftlogs.ResponseError(res)
+			switch {
+			case err == nil:
+				succeeded.Add(1)
+			case errors.Is(err, storage.ErrRevisionMismatch):
+				mismatched.Add(1)
+			default:
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if succeeded.Load() != 1 || mismatched.Load() != writers-1 {
+		t.Fatalf("expected 1 success and %d mismatches, got %d and %d", writers-1, succeeded.Load(), mismatched.Load())
+	}
+	if n := log.entries.Load(); n >= writers {
+		t.Fatalf("expected writes to be coalesced into fewer than %d raft logs, got %d", writers, n)
+	}
+
+	// Writes submitted one at a time are applied as they are.
+	entry := &v1.RaftLogEntry{Type: v1.RaftCommandType_PUT, Key: "/test/other", Value: "value", Ttl: durationpb.New(0)}
+	res, err := b.Apply(context.Background(), entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetError() != "" {
+		t.Fatal(res.GetError())
+	}
+	value, err := log.fsm.dataDB.Get(context.Background(), "/test/other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "value" {
+		t.Fatalf("expected value, got %q", value)
+	}
+}
+
+// BenchmarkApply compares the throughput of concurrent writes with and without
+// batching against a log that takes a fixed time to commit each entry.
+func BenchmarkApply(b *testing.B) {
+	for _, tc := range []struct {
+		name       string
+		maxEntries int
+	}{
+		{"Unbatched", 1},
+		{"Batched", 64},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			log := newTestLog(b, 100*time.Microsecond)
+			batcher := NewBatcher(log.apply, BatcherOptions{
+				Window:     100 * time.Microsecond,
+				MaxEntries: tc.maxEntries,
+				MaxBytes:   1024 * 1024,
+			})
+			defer batcher.Close()
+			var n atomic.Int64
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					entry := &v1.RaftLogEntry{
+						Type:  v1.RaftCommandType_PUT,
+						Key:   fmt.Sprintf("/bench/%d", n.Add(1)),
+						Value: "value",
+						Ttl:   durationpb.New(0),
+					}
+					res, err := batcher.Apply(context.Background(), entry)
+					if err != nil {
+						b.Error(err)
+						return
+					}
+					if res.GetError() != "" {
+						b.Error(res.GetError())
+						return
+					}
+				}
+			})
+			b.StopTimer()
+			b.ReportMetric(float64(log.entries.Load())/float64(b.N), "logs/op")
+		})
+	}
+}
+
+// testLog is a raft log that commits entries one at a time and applies them
+// to the FSM of a raft node.
+type testLog struct {
+	fsm     *raftNode
+	latency time.Duration
+	entries atomic.Int64
+	mu      sync.Mutex
+	index   uint64
+}
+
+func newTestLog(t testing.TB, latency time.Duration) *testLog {
+	db, err := storage.New(&storage.Options{InMemory: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &testLog{
+		fsm: &raftNode{
+			opts:   &Options{},
+			dataDB: db,
+			log:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		latency: latency,
+	}
+}
+
+func (l *testLog) apply(data []byte, _ time.Duration) raft.ApplyFuture {
+	f := &testFuture{done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.latency > 0 {
+			time.Sleep(l.latency)
+		}
+		l.entries.Add(1)
+		l.index++
+		f.index = l.index
+		f.res = l.fsm.Apply(&raft.Log{
+			Index: l.index,
+			Term:  1,
+			Type:  raft.LogCommand,
+			Data:  data,
+		})
+	}()
+	return f
+}
+
+type testFuture struct {
+	done  chan struct{}
+	index uint64
+	res   any
+}
+
+func (f *testFuture) Error() error  { <-f.done; return nil }
+func (f *testFuture) Index() uint64 { <-f.done; return f.index }
+func (f *testFuture) Response() any { <-f.done; return f.res }
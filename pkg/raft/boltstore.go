@@ -0,0 +1,198 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/raft"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltLogFileName is the name of the bolt log store file in the raft store path.
+const boltLogFileName = "raft.db"
+
+var (
+	boltLogsBucket   = []byte("logs")
+	boltStableBucket = []byte("stable")
+)
+
+// boltLogStore is a raft log and stable store backed by a bbolt file.
+type boltLogStore struct {
+	db *bolt.DB
+}
+
+func newBoltLogStore(path string) (*boltLogStore, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, fmt.Errorf("create raft store directory: %w", err)
+	}
+	db, err := bolt.Open(filepath.Join(path, boltLogFileName), 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open bolt log store: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{boltLogsBucket, boltStableBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		defer db.Close()
+		return nil, fmt.Errorf("create bolt log store buckets: %w", err)
+	}
+	return &boltLogStore{db: db}, nil
+}
+
+// openBoltLogStoreReadOnly opens an existing bolt log store without modifying it.
+func openBoltLogStoreReadOnly(path string) (*boltLogStore, error) {
+	db, err := bolt.Open(filepath.Join(path, boltLogFileName), 0600, &bolt.Options{Timeout: time.Second, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("open bolt log store: %w", err)
+	}
+	return &boltLogStore{db: db}, nil
+}
+
+func boltLogKey(idx uint64) []byte {
+	var key [8]byte
+	binary.BigEndian.PutUint64(key[:], idx)
+	return key[:]
+}
+
+// FirstIndex returns the first index written. 0 for no entries.
+func (s *boltLogStore) FirstIndex() (uint64, error) {
+	var first uint64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if k, _ := tx.Bucket(boltLogsBucket).Cursor().First(); k != nil {
+			first = binary.BigEndian.Uint64(k)
+		}
+		return nil
+	})
+	return first, err
+}
+
+// LastIndex returns the last index written. 0 for no entries.
+func (s *boltLogStore) LastIndex() (uint64, error) {
+	var last uint64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if k, _ := tx.Bucket(boltLogsBucket).Cursor().Last(); k != nil {
+			last = binary.BigEndian.Uint64(k)
+		}
+		return nil
+	})
+	return last, err
+}
+
+// GetLog gets a log entry at a given index.
+func (s *boltLogStore) GetLog(index uint64, log *raft.Log) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		val := tx.Bucket(boltLogsBucket).Get(boltLogKey(index))
+		if val == nil {
+			return raft.ErrLogNotFound
+		}
+		return decodeLog(val, log)
+	})
+}
+
+// StoreLog stores a log entry.
+func (s *boltLogStore) StoreLog(log *raft.Log) error {
+	return s.StoreLogs([]*raft.Log{log})
+}
+
+// StoreLogs stores multiple log entries.
+func (s *boltLogStore) StoreLogs(logs []*raft.Log) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltLogsBucket)
+		for _, log := range logs {
+			data, err := encodeLog(log)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(boltLogKey(log.Index), data); err != nil {
+				return fmt.Errorf("store log %d: %w", log.Index, err)
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteRange deletes a range of log entries. The range is inclusive.
+func (s *boltLogStore) DeleteRange(min, max uint64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltLogsBucket).Cursor()
+		for k, _ := c.Seek(boltLogKey(min)); k != nil; k, _ = c.Next() {
+			if binary.BigEndian.Uint64(k) > max {
+				break
+			}
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Set sets a key in the stable store.
+func (s *boltLogStore) Set(key []byte, val []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltStableBucket).Put(key, val)
+	})
+}
+
+// Get returns the value for key in the stable store.
+func (s *boltLogStore) Get(key []byte) ([]byte, error) {
+	var val []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(boltStableBucket).Get(key)
+		if v == nil {
+			return errStableKeyNotFound
+		}
+		val = append([]byte(nil), v...)
+		return nil
+	})
+	return val, err
+}
+
+// SetUint64 sets a uint64 value for key in the stable store.
+func (s *boltLogStore) SetUint64(key []byte, val uint64) error {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], val)
+	return s.Set(key, buf[:])
+}
+
+// GetUint64 returns the uint64 value for key in the stable store.
+func (s *boltLogStore) GetUint64(key []byte) (uint64, error) {
+	val, err := s.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	if len(val) != 8 {
+		return 0, errors.New("invalid uint64 value length")
+	}
+	return binary.BigEndian.Uint64(val), nil
+}
+
+// Close closes the store.
+func (s *boltLogStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,358 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-msgpack/codec"
+	"github.com/hashicorp/raft"
+	v1 "github.com/webmeshproj/api/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+// snapshotsDir and snapshotMetaFile mirror the layout of the raft file snapshot store.
+const (
+	snapshotsDir     = "snapshots"
+	snapshotMetaFile = "meta.json"
+)
+
+// DataDir is a read-only view of the raft data directory of a node that is not
+// running. It is used to inspect the raft log, stable state, snapshots and FSM
+// data when a node fails to start.
+type DataDir struct {
+	opts     *Options
+	log      *slog.Logger
+	logStore string
+	store    LogStableStore
+}
+
+// DataDirState is the raft state recorded in a data directory.
+type DataDirState struct {
+	// LogStore is the store holding the raft log and stable state.
+	LogStore string `json:"logStore"`
+	// CurrentTerm is the last term the node knew of.
+	CurrentTerm uint64 `json:"currentTerm"`
+	// LastVoteTerm is the term of the last vote cast by the node.
+	LastVoteTerm uint64 `json:"lastVoteTerm"`
+	// LastVoteCandidate is the address of the candidate the node last voted for.
+	LastVoteCandidate string `json:"lastVoteCandidate,omitempty"`
+	// FirstIndex is the index of the first log in the store.
+	FirstIndex uint64 `json:"firstIndex"`
+	// LastIndex is the index of the last log in the store.
+	LastIndex uint64 `json:"lastIndex"`
+	// Configuration is the latest raft configuration found in the log
+	// or the snapshots.
+	Configuration []DataDirServer `json:"configuration"`
+	// ConfigurationIndex is the index the configuration was written at.
+	ConfigurationIndex uint64 `json:"configurationIndex"`
+	// Snapshots are the snapshots in the data directory, newest first.
+	Snapshots []DataDirSnapshot `json:"snapshots"`
+}
+
+// DataDirServer is a server in a raft configuration.
+type DataDirServer struct {
+	ID       string `json:"id"`
+	Address  string `json:"address"`
+	Suffrage string `json:"suffrage"`
+}
+
+// DataDirSnapshot describes a snapshot in a data directory.
+type DataDirSnapshot struct {
+	ID                 string `json:"id"`
+	Index              uint64 `json:"index"`
+	Term               uint64 `json:"term"`
+	Size               int64  `json:"size"`
+	ConfigurationIndex uint64 `json:"configurationIndex"`
+
+	configuration raft.Configuration
+}
+
+// DataDirLog is a decoded raft log.
+type DataDirLog struct {
+	// Index is the index of the log.
+	Index uint64 `json:"index"`
+	// Term is the term the log was written in.
+	Term uint64 `json:"term"`
+	// Type is the raft log type.
+	Type string `json:"type"`
+	// AppendedAt is when the leader appended the log, if known.
+	AppendedAt time.Time `json:"appendedAt,omitempty"`
+	// Entry is the decoded entry of a command log.
+	Entry *v1.RaftLogEntry `json:"-"`
+	// Configuration is the decoded configuration of a configuration log.
+	Configuration []DataDirServer `json:"configuration,omitempty"`
+	// Error is set if the log data could not be decoded.
+	Error string `json:"error,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. The entry is encoded with protojson.
+func (l *DataDirLog) MarshalJSON() ([]byte, error) {
+	type log DataDirLog
+	out := struct {
+		*log
+		Entry json.RawMessage `json:"entry,omitempty"`
+	}{log: (*log)(l)}
+	if l.Entry != nil {
+		data, err := protojson.Marshal(l.Entry)
+		if err != nil {
+			return nil, err
+		}
+		out.Entry = data
+	}
+	return json.Marshal(out)
+}
+
+// OpenDataDir opens the raft log store in the data directory of the given options
+// read-only. The store type is detected from the files in the directory. The
+// encryption keys in the options are used if the stores are encrypted.
+func OpenDataDir(log *slog.Logger, opts *Options) (*DataDir, error) {
+	storePath := opts.StorePath()
+	if !exists(storePath) {
+		return nil, fmt.Errorf("raft store %s does not exist", storePath)
+	}
+	encryption, err := opts.Encryption()
+	if err != nil {
+		return nil, err
+	}
+	d := &DataDir{opts: opts, log: log}
+	if exists(filepath.Join(storePath, boltLogFileName)) {
+		d.logStore = LogStoreBolt
+		d.store, err = openBoltLogStoreReadOnly(storePath)
+	} else {
+		d.logStore = LogStoreBadger
+		d.store, err = openBadgerLogStoreReadOnly(log.With("component", "raftstore"), storePath, encryption)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open raft %s store: %w", d.logStore, err)
+	}
+	return d, nil
+}
+
+// Close closes the raft log store.
+func (d *DataDir) Close() error {
+	return d.store.Close()
+}
+
+// State returns the raft state recorded in the data directory.
+func (d *DataDir) State() (*DataDirState, error) {
+	state := &DataDirState{LogStore: d.logStore}
+	var err error
+	if state.CurrentTerm, err = d.getUint64([]byte("CurrentTerm")); err != nil {
+		return nil, fmt.Errorf("get current term: %w", err)
+	}
+	if state.LastVoteTerm, err = d.getUint64([]byte("LastVoteTerm")); err != nil {
+		return nil, fmt.Errorf("get last vote term: %w", err)
+	}
+	cand, err := d.store.Get([]byte("LastVoteCand"))
+	if err != nil && !errors.Is(err, errStableKeyNotFound) {
+		return nil, fmt.Errorf("get last vote candidate: %w", err)
+	}
+	state.LastVoteCandidate = string(cand)
+	if state.FirstIndex, err = d.store.FirstIndex(); err != nil {
+		return nil, fmt.Errorf("get first index: %w", err)
+	}
+	if state.LastIndex, err = d.store.LastIndex(); err != nil {
+		return nil, fmt.Errorf("get last index: %w", err)
+	}
+	snapshots, err := d.snapshots()
+	if err != nil {
+		return nil, fmt.Errorf("list snapshots: %w", err)
+	}
+	state.Snapshots = snapshots
+	// The latest configuration is the last one in the log, or the one in
+	// the newest snapshot if the log does not hold one.
+	if state.LastIndex > 0 {
+		for idx := state.LastIndex; idx >= state.FirstIndex && idx > 0; idx-- {
+			var l raft.Log
+			if err := d.store.GetLog(idx, &l); err != nil {
+				return nil, fmt.Errorf("get log %d: %w", idx, err)
+			}
+			if l.Type != raft.LogConfiguration {
+				continue
+			}
+			cfg, err := decodeConfiguration(l.Data)
+			if err != nil {
+				return nil, fmt.Errorf("decode configuration at %d: %w", idx, err)
+			}
+			state.Configuration = dataDirServers(cfg)
+			state.ConfigurationIndex = idx
+			return state, nil
+		}
+	}
+	if len(snapshots) > 0 {
+		state.Configuration = dataDirServers(snapshots[0].configuration)
+		state.ConfigurationIndex = snapshots[0].ConfigurationIndex
+	}
+	return state, nil
+}
+
+// Logs returns the logs in the inclusive range from to. Logs that are not in the
+// store, such as those compacted into a snapshot, are skipped. Log data that cannot
+// be decoded is reported in the Error field of the log.
+func (d *DataDir) Logs(from, to uint64) ([]*DataDirLog, error) {
+	first, err := d.store.FirstIndex()
+	if err != nil {
+		return nil, fmt.Errorf("get first index: %w", err)
+	}
+	last, err := d.store.LastIndex()
+	if err != nil {
+		return nil, fmt.Errorf("get last index: %w", err)
+	}
+	if from < first {
+		from = first
+	}
+	if to == 0 || to > last {
+		to = last
+	}
+	var out []*DataDirLog
+	for idx := from; idx <= to && idx > 0; idx++ {
+		var l raft.Log
+		if err := d.store.GetLog(idx, &l); err != nil {
+			if errors.Is(err, raft.ErrLogNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("get log %d: %w", idx, err)
+		}
+		entry := &DataDirLog{
+			Index:      l.Index,
+			Term:       l.Term,
+			Type:       l.Type.String(),
+			AppendedAt: l.AppendedAt,
+		}
+		switch l.Type {
+		case raft.LogCommand:
+			entry.Entry, err = UnmarshalLogEntry(l.Data)
+		case raft.LogConfiguration:
+			var cfg raft.Configuration
+			cfg, err = decodeConfiguration(l.Data)
+			entry.Configuration = dataDirServers(cfg)
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		out = append(out, entry)
+	}
+	return out, nil
+}
+
+// OpenStorage opens the FSM data storage in the data directory read-only.
+// The caller must close it.
+func (d *DataDir) OpenStorage() (storage.Storage, error) {
+	path := d.opts.DataStoragePath()
+	if !exists(path) {
+		return nil, fmt.Errorf("data storage %s does not exist", path)
+	}
+	encryption, err := d.opts.Encryption()
+	if err != nil {
+		return nil, err
+	}
+	backend := storage.Backend(d.opts.StorageBackend)
+	if backend == "" && exists(filepath.Join(path, storage.BoltFileName)) {
+		backend = storage.BackendBolt
+	}
+	st, err := storage.New(&storage.Options{
+		DiskPath:   path,
+		Backend:    backend,
+		Encryption: encryption,
+		ReadOnly:   true,
+		Silent:     true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open data storage: %w", err)
+	}
+	return st, nil
+}
+
+func (d *DataDir) getUint64(key []byte) (uint64, error) {
+	val, err := d.store.GetUint64(key)
+	if errors.Is(err, errStableKeyNotFound) {
+		return 0, nil
+	}
+	return val, err
+}
+
+// snapshots reads the metadata of the snapshots in the data directory.
+func (d *DataDir) snapshots() ([]DataDirSnapshot, error) {
+	dirs, err := os.ReadDir(filepath.Join(d.opts.DataDir, snapshotsDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []DataDirSnapshot
+	for _, dir := range dirs {
+		if !dir.IsDir() || strings.HasSuffix(dir.Name(), ".tmp") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(d.opts.DataDir, snapshotsDir, dir.Name(), snapshotMetaFile))
+		if err != nil {
+			d.log.Warn("skipping unreadable snapshot", slog.String("snapshot", dir.Name()), slog.String("error", err.Error()))
+			continue
+		}
+		var meta raft.SnapshotMeta
+		if err := json.Unmarshal(data, &meta); err != nil {
+			d.log.Warn("skipping invalid snapshot", slog.String("snapshot", dir.Name()), slog.String("error", err.Error()))
+			continue
+		}
+		out = append(out, DataDirSnapshot{
+			ID:                 meta.ID,
+			Index:              meta.Index,
+			Term:               meta.Term,
+			Size:               meta.Size,
+			ConfigurationIndex: meta.ConfigurationIndex,
+			configuration:      meta.Configuration,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Term != out[j].Term {
+			return out[i].Term > out[j].Term
+		}
+		return out[i].Index > out[j].Index
+	})
+	return out, nil
+}
+
+func decodeConfiguration(data []byte) (raft.Configuration, error) {
+	var cfg raft.Configuration
+	err := codec.NewDecoder(bytes.NewReader(data), &codec.MsgpackHandle{}).Decode(&cfg)
+	return cfg, err
+}
+
+func dataDirServers(cfg raft.Configuration) []DataDirServer {
+	out := make([]DataDirServer, len(cfg.Servers))
+	for i, srv := range cfg.Servers {
+		out[i] = DataDirServer{
+			ID:       string(srv.ID),
+			Address:  string(srv.Address),
+			Suffrage: srv.Suffrage.String(),
+		}
+	}
+	return out
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	v1 "github.com/webmeshproj/api/v1"
+
+	"github.com/webmeshproj/webmesh/pkg/context"
+)
+
+func TestDataDir(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	opts := NewOptions(0)
+	opts.ListenAddress = "127.0.0.1:0"
+	opts.DataDir = t.TempDir()
+	opts.HeartbeatTimeout = 500 * time.Millisecond
+	opts.ElectionTimeout = 500 * time.Millisecond
+	opts.LeaderLeaseTimeout = 500 * time.Millisecond
+	node := New(opts, nil)
+	if err := node.Start(ctx, &StartOptions{NodeID: "node-1"}); err != nil {
+		t.Fatal(err)
+	}
+	err := node.Bootstrap(ctx, &BootstrapOptions{
+		AdvertiseAddress: fmt.Sprintf("127.0.0.1:%d", node.ListenPort()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Storage().Put(ctx, "/registry/test", "value", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := OpenDataDir(slog.Default(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dir.Close()
+	state, err := dir.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.CurrentTerm == 0 || state.LastIndex == 0 {
+		t.Fatalf("expected a term and logs, got %+v", state)
+	}
+	if len(state.Configuration) != 1 || state.Configuration[0].ID != "node-1" || state.Configuration[0].Suffrage != "Voter" {
+		t.Fatalf("unexpected configuration: %+v", state.Configuration)
+	}
+	if len(state.Snapshots) == 0 {
+		t.Fatal("expected the snapshot taken on shutdown")
+	}
+
+	logs, err := dir.Logs(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, l := range logs {
+		if l.Error != "" {
+			t.Fatalf("log %d: %s", l.Index, l.Error)
+		}
+		if l.Entry != nil && l.Entry.GetType() == v1.RaftCommandType_PUT && l.Entry.GetKey() == "/registry/test" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected to find the put in the logs")
+	}
+
+	st, err := dir.OpenStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	value, err := st.Get(ctx, "/registry/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "value" {
+		t.Fatalf("expected value, got %q", value)
+	}
+	if err := st.Put(ctx, "/registry/other", "value", 0); err == nil {
+		t.Fatal("expected writes to the read-only storage to fail")
+	}
+}
@@ -72,6 +72,7 @@ func (r *raftNode) applyLog(l *raft.Log) (res any) {
 		log.Debug("finished applying log", slog.String("took", time.Since(start).String()))
 	}()
 	defer r.lastAppliedIndex.Store(l.Index)
+	defer LastAppliedIndex.WithLabelValues(string(r.nodeID)).Set(float64(l.Index))
 	defer r.currentTerm.Store(l.Term)
 
 	// Validate the term/index of the log entry.
@@ -93,6 +94,12 @@ func (r *raftNode) applyLog(l *raft.Log) (res any) {
 		}
 	}
 
+	if l.Type == raft.LogConfiguration {
+		if sl, ok := r.streamLayer.(*GRPCStreamLayer); ok {
+			// Stop holding connections to servers that left.
+			sl.RetainServers(raft.DecodeConfiguration(l.Data))
+		}
+	}
 	if l.Type != raft.LogCommand {
 		// We only care about command logs.
 		return &v1.RaftApplyResponse{
@@ -106,12 +113,21 @@ func (r *raftNode) applyLog(l *raft.Log) (res any) {
 		// This is a fatal error. We can't apply the log entry if we can't
 		// decode it. This should never happen.
 		log.Error("error decoding raft log entry", slog.String("error", err.Error()))
+		ApplyErrors.WithLabelValues(string(r.nodeID)).Inc()
 		return &v1.RaftApplyResponse{
 			Time:  time.Since(start).String(),
 			Error: fmt.Sprintf("decode log entry: %s", err.Error()),
 		}
 	}
 
+	// A command we do not understand was written by a newer version. Applying
+	// the entries after it would diverge our storage from the rest of the
+	// cluster, so halt instead of skipping it.
+	if err := raftlogs.Validate(cmd); err != nil {
+		log.Error("cannot apply raft log entry, this node must be upgraded", slog.String("error", err.Error()))
+		panic(fmt.Sprintf("apply raft log %d: %v", l.Index, err))
+	}
+
 	var ctx context.Context
 	var cancel context.CancelFunc
 	if r.opts.ApplyTimeout > 0 {
@@ -122,10 +138,42 @@ func (r *raftNode) applyLog(l *raft.Log) (res any) {
 	defer cancel()
 	ctx = context.WithLogger(ctx, log)
 
+	if cmd.GetType() == raftlogs.RaftCommandType_COALESCED {
+		// Each entry has its own result, which is returned to its writer by
+		// the batcher.
+		results, err := raftlogs.ApplyCoalesced(ctx, r.dataDB, cmd, func(entry *v1.RaftLogEntry, res *v1.RaftApplyResponse) {
+			r.afterApply(ctx, l, entry, res)
+		})
+		ApplyDuration.WithLabelValues(string(r.nodeID)).Observe(time.Since(start).Seconds())
+		if err != nil {
+			log.Error("error decoding coalesced log entry", slog.String("error", err.Error()))
+			ApplyErrors.WithLabelValues(string(r.nodeID)).Inc()
+			return &v1.RaftApplyResponse{
+				Time:  time.Since(start).String(),
+				Error: err.Error(),
+			}
+		}
+		return results
+	}
+
+	// Apply the log entry to the database.
+	applied := raftlogs.Apply(ctx, r.dataDB, cmd)
+	ApplyDuration.WithLabelValues(string(r.nodeID)).Observe(time.Since(start).Seconds())
+	r.afterApply(ctx, l, cmd, applied)
+	return applied
+}
+
+// afterApply records the result of applying an entry and forwards entries that
+// applied successfully to the OnApplyLog callback.
+func (r *raftNode) afterApply(ctx context.Context, l *raft.Log, entry *v1.RaftLogEntry, res *v1.RaftApplyResponse) {
+	if res.GetError() != "" {
+		ApplyErrors.WithLabelValues(string(r.nodeID)).Inc()
+		return
+	}
 	if r.opts.OnApplyLog != nil {
 		// Call the OnApplyLog callback in a goroutine to not block the local storage.
-		go r.opts.OnApplyLog(ctx, l.Term, l.Index, cmd)
+		// Entries that failed to apply, such as conditional puts whose revision did
+		// not match, are not forwarded.
+		go r.opts.OnApplyLog(ctx, l.Term, l.Index, entry)
 	}
-	// Apply the log entry to the database.
-	return raftlogs.Apply(ctx, r.dataDB, cmd)
 }
@@ -0,0 +1,205 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/go-msgpack/codec"
+	"github.com/hashicorp/raft"
+	raftbadger "github.com/webmeshproj/raft-badger"
+
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+const (
+	// LogStoreBadger stores the raft log and stable state in badger.
+	LogStoreBadger = "badger"
+	// LogStoreBolt stores the raft log and stable state in a single bbolt
+	// file. It uses considerably less memory than badger.
+	LogStoreBolt = "bolt"
+)
+
+// raftStableKeys are the keys hashicorp/raft writes to the stable store.
+var (
+	raftStableUint64Keys = [][]byte{[]byte("CurrentTerm"), []byte("LastVoteTerm")}
+	raftStableKeys       = [][]byte{[]byte("LastVoteCand")}
+)
+
+// errStableKeyNotFound is returned by the stable stores in this package when
+// a key has not been set. Raft matches it by its message, so the message must
+// not change, code in this package matches it with errors.Is.
+var errStableKeyNotFound = errors.New("not found")
+
+// LogStableStore is a closable raft log and stable store.
+type LogStableStore interface {
+	LogStoreCloser
+	StableStoreCloser
+}
+
+// newLogStore opens the raft log and stable store configured in the options,
+// migrating an existing badger store if the configured store is different.
+func (r *raftNode) newLogStore(log *slog.Logger, encryption *storage.EncryptionOptions) (LogStableStore, error) {
+	storePath := r.opts.StorePath()
+	switch r.opts.LogStore {
+	case LogStoreBolt:
+		if err := migrateBadgerToBolt(log, storePath); err != nil {
+			return nil, fmt.Errorf("migrate raft badger store to bolt: %w", err)
+		}
+		return newBoltLogStore(storePath)
+	case LogStoreBadger, "":
+		if exists(filepath.Join(storePath, boltLogFileName)) {
+			return nil, fmt.Errorf("raft store at %s was created by the %s log store", storePath, LogStoreBolt)
+		}
+		if encryption != nil {
+			return newBadgerLogStore(log, storePath, encryption)
+		}
+		return raftbadger.New(log, storePath)
+	default:
+		return nil, fmt.Errorf("unknown raft log store %q", r.opts.LogStore)
+	}
+}
+
+// migrateBadgerToBolt converts a raft-badger store at storePath to a bolt
+// store in place. The badger store is kept next to it with a .badger suffix
+// until the operator removes it. The migration is resumed if it was
+// interrupted.
+func migrateBadgerToBolt(log *slog.Logger, storePath string) error {
+	tmpPath := storePath + ".migrating"
+	backupPath := storePath + ".badger"
+	if !exists(storePath) && exists(tmpPath) && exists(backupPath) {
+		// We were interrupted after moving the badger store aside.
+		log.Info("Completing interrupted migration of raft store to bolt", slog.String("path", storePath))
+		return os.Rename(tmpPath, storePath)
+	}
+	if !exists(filepath.Join(storePath, "MANIFEST")) {
+		// No badger store to migrate.
+		return nil
+	}
+	if exists(backupPath) {
+		return fmt.Errorf("backup path %s already exists, remove it to migrate again", backupPath)
+	}
+	log.Info("Migrating raft store from badger to bolt", slog.String("path", storePath))
+	if err := os.RemoveAll(tmpPath); err != nil {
+		return fmt.Errorf("remove stale migration directory: %w", err)
+	}
+	src, err := raftbadger.New(log, storePath)
+	if err != nil {
+		return fmt.Errorf("open badger store: %w", err)
+	}
+	defer src.Close()
+	dst, err := newBoltLogStore(tmpPath)
+	if err != nil {
+		return fmt.Errorf("open bolt store: %w", err)
+	}
+	copied, err := copyLogStore(src, dst)
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("close bolt store: %w", err)
+	}
+	if err := src.Close(); err != nil {
+		return fmt.Errorf("close badger store: %w", err)
+	}
+	if err := os.Rename(storePath, backupPath); err != nil {
+		return fmt.Errorf("move badger store aside: %w", err)
+	}
+	if err := os.Rename(tmpPath, storePath); err != nil {
+		return fmt.Errorf("move bolt store into place: %w", err)
+	}
+	log.Info("Migrated raft store from badger to bolt",
+		slog.Int("logs", copied),
+		slog.String("backup", backupPath))
+	return nil
+}
+
+// copyLogStore copies all logs and raft's stable state from src to dst.
+func copyLogStore(src, dst LogStableStore) (int, error) {
+	first, err := src.FirstIndex()
+	if err != nil {
+		return 0, fmt.Errorf("get first index: %w", err)
+	}
+	last, err := src.LastIndex()
+	if err != nil {
+		return 0, fmt.Errorf("get last index: %w", err)
+	}
+	var copied int
+	if last > 0 {
+		const batchSize = 256
+		batch := make([]*raft.Log, 0, batchSize)
+		for idx := first; idx <= last; idx++ {
+			var l raft.Log
+			if err := src.GetLog(idx, &l); err != nil {
+				return copied, fmt.Errorf("get log %d: %w", idx, err)
+			}
+			batch = append(batch, &l)
+			if len(batch) == batchSize || idx == last {
+				if err := dst.StoreLogs(batch); err != nil {
+					return copied, fmt.Errorf("store logs: %w", err)
+				}
+				copied += len(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+	for _, key := range raftStableUint64Keys {
+		val, err := src.GetUint64(key)
+		if err != nil && !errors.Is(err, errStableKeyNotFound) {
+			return copied, fmt.Errorf("get %s: %w", key, err)
+		}
+		if err := dst.SetUint64(key, val); err != nil {
+			return copied, fmt.Errorf("set %s: %w", key, err)
+		}
+	}
+	for _, key := range raftStableKeys {
+		val, err := src.Get(key)
+		if err != nil && !errors.Is(err, errStableKeyNotFound) {
+			return copied, fmt.Errorf("get %s: %w", key, err)
+		}
+		if len(val) == 0 {
+			continue
+		}
+		if err := dst.Set(key, val); err != nil {
+			return copied, fmt.Errorf("set %s: %w", key, err)
+		}
+	}
+	return copied, nil
+}
+
+func encodeLog(log *raft.Log) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := codec.NewEncoder(&buf, &codec.MsgpackHandle{}).Encode(log); err != nil {
+		return nil, fmt.Errorf("encode log %d: %w", log.Index, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeLog(data []byte, log *raft.Log) error {
+	return codec.NewDecoder(bytes.NewReader(data), &codec.MsgpackHandle{}).Decode(log)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"bytes"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/raft"
+	raftbadger "github.com/webmeshproj/raft-badger"
+)
+
+func TestMigrateBadgerToBolt(t *testing.T) {
+	t.Parallel()
+
+	storePath := filepath.Join(t.TempDir(), RaftStorePath)
+	src, err := raftbadger.New(nil, storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logs []*raft.Log
+	for i := uint64(1); i <= 300; i++ {
+		logs = append(logs, &raft.Log{Index: i, Term: 1, Type: raft.LogCommand, Data: []byte{byte(i)}})
+	}
+	if err := src.StoreLogs(logs); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.SetUint64([]byte("CurrentTerm"), 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.Set([]byte("LastVoteCand"), []byte("node-1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrateBadgerToBolt(slog.Default(), storePath); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(storePath + ".badger") {
+		t.Fatal("expected the badger store to be kept as a backup")
+	}
+	// Migrating again should be a no-op.
+	if err := migrateBadgerToBolt(slog.Default(), storePath); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := newBoltLogStore(storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	first, _ := dst.FirstIndex()
+	last, _ := dst.LastIndex()
+	if first != 1 || last != 300 {
+		t.Fatalf("expected logs 1-300, got %d-%d", first, last)
+	}
+	var l raft.Log
+	if err := dst.GetLog(150, &l); err != nil {
+		t.Fatal(err)
+	}
+	if l.Index != 150 || !bytes.Equal(l.Data, []byte{150}) {
+		t.Fatalf("unexpected log %+v", l)
+	}
+	if term, err := dst.GetUint64([]byte("CurrentTerm")); err != nil || term != 3 {
+		t.Fatalf("expected current term 3, got %d: %v", term, err)
+	}
+	if cand, err := dst.Get([]byte("LastVoteCand")); err != nil || string(cand) != "node-1" {
+		t.Fatalf("expected last vote candidate node-1, got %q: %v", cand, err)
+	}
+	if _, err := dst.Get([]byte("missing")); err == nil || err.Error() != "not found" {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	if err := dst.DeleteRange(1, 100); err != nil {
+		t.Fatal(err)
+	}
+	if first, _ := dst.FirstIndex(); first != 101 {
+		t.Fatalf("expected first index 101 after delete, got %d", first)
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// storageMetricsInterval is how often the storage usage metrics are recorded.
+const storageMetricsInterval = 30 * time.Second
+
+// raftMetricsInterval is how often the replication metrics are recorded.
+const raftMetricsInterval = 5 * time.Second
+
+// Raft Metrics. Snapshot and restore durations are recorded by the
+// snapshots package.
+var (
+	// LastAppliedIndex tracks the index of the last log applied to the database.
+	LastAppliedIndex = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "webmesh",
+		Name:      "raft_last_applied_index",
+		Help:      "The index of the last raft log applied to the database.",
+	}, []string{"node_id"})
+
+	// ApplyDuration tracks how long it takes to apply a log to the database.
+	ApplyDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "webmesh",
+		Name:      "raft_apply_duration_seconds",
+		Help:      "Time taken to apply a raft log to the database.",
+	}, []string{"node_id"})
+
+	// ApplyErrors tracks the number of logs that failed to apply to the database.
+	ApplyErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "webmesh",
+		Name:      "raft_apply_errors_total",
+		Help:      "The number of raft logs that failed to apply to the database.",
+	}, []string{"node_id"})
+
+	// CommitDuration tracks how long the leader takes to commit and apply a log.
+	CommitDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "webmesh",
+		Name:      "raft_commit_duration_seconds",
+		Help:      "Time taken by the leader to commit and apply a raft log.",
+	}, []string{"node_id"})
+
+	// ApplyBatchSize tracks how many writes were coalesced into each raft log.
+	ApplyBatchSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "webmesh",
+		Name:      "raft_apply_batch_size",
+		Help:      "The number of writes coalesced into a single raft log.",
+		Buckets:   []float64{1, 2, 4, 8, 16, 32, 64, 128},
+	}, []string{"node_id"})
+
+	// ReplicationLag tracks how many committed logs a node has yet to apply.
+	// Each follower reports its own lag.
+	ReplicationLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "webmesh",
+		Name:      "raft_replication_lag_entries",
+		Help:      "The number of committed raft logs not yet applied on this node.",
+	}, []string{"node_id"})
+
+	// FollowerReplicationLag tracks how many logs on the leader each follower
+	// has yet to receive. It is only reported by the leader, which computes
+	// it from the last log index in each follower's append entries responses.
+	FollowerReplicationLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "webmesh",
+		Name:      "raft_follower_replication_lag_entries",
+		Help:      "The number of raft logs on the leader not yet replicated to a follower.",
+	}, []string{"node_id", "peer_id"})
+
+	// LastContact tracks the time since the node last heard from a peer. The
+	// leader reports every follower and followers report the leader.
+	LastContact = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "webmesh",
+		Name:      "raft_last_contact_seconds",
+		Help:      "Seconds since the node last heard from a raft peer.",
+	}, []string{"node_id", "peer_id"})
+
+	// IsLeader is set to 1 while the node is the leader.
+	IsLeader = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "webmesh",
+		Name:      "raft_is_leader",
+		Help:      "Whether the node is the raft leader.",
+	}, []string{"node_id"})
+
+	// LeaderChanges tracks the number of leader changes observed by the node.
+	LeaderChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "webmesh",
+		Name:      "raft_leader_changes_total",
+		Help:      "The number of raft leader changes observed by the node.",
+	}, []string{"node_id"})
+
+	// Elections tracks the number of elections started by the node.
+	Elections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "webmesh",
+		Name:      "raft_elections_total",
+		Help:      "The number of raft elections started by the node.",
+	}, []string{"node_id"})
+)
+
+// recordMetrics records the replication metrics for the node.
+func (r *raftNode) recordMetrics() {
+	nodeID := string(r.nodeID)
+	lag, _ := r.ReplicationLag()
+	ReplicationLag.WithLabelValues(nodeID).Set(float64(lag))
+	// Followers that left or a lost leadership must not leave stale series.
+	FollowerReplicationLag.DeletePartialMatch(prometheus.Labels{"node_id": nodeID})
+	if r.IsLeader() {
+		IsLeader.WithLabelValues(nodeID).Set(1)
+		lastIndex := r.raft.LastIndex()
+		for _, server := range r.Configuration().Servers {
+			if server.ID == r.nodeID {
+				continue
+			}
+			lastLog, ok := r.raftTransport.LastLog(server.ID)
+			if !ok {
+				continue
+			}
+			var lag uint64
+			if lastIndex > lastLog {
+				lag = lastIndex - lastLog
+			}
+			FollowerReplicationLag.WithLabelValues(nodeID, string(server.ID)).Set(float64(lag))
+		}
+	} else {
+		IsLeader.WithLabelValues(nodeID).Set(0)
+	}
+	// Peers come and go, so drop the series for any that have left.
+	LastContact.DeletePartialMatch(prometheus.Labels{"node_id": nodeID})
+	now := time.Now()
+	for _, server := range r.Configuration().Servers {
+		if server.ID == r.nodeID {
+			continue
+		}
+		last := r.LastContact(string(server.ID))
+		if last.IsZero() {
+			continue
+		}
+		LastContact.WithLabelValues(nodeID, string(server.ID)).Set(now.Sub(last).Seconds())
+	}
+}
@@ -18,6 +18,7 @@ package raft
 
 import (
 	"log/slog"
+	"time"
 
 	"github.com/hashicorp/raft"
 )
@@ -27,25 +28,47 @@ func (r *raftNode) observe() (closeCh, doneCh chan struct{}) {
 	doneCh = make(chan struct{})
 	go func() {
 		defer close(doneCh)
+		ticker := time.NewTicker(raftMetricsInterval)
+		defer ticker.Stop()
 		for {
 			select {
 			case <-closeCh:
 				r.log.Debug("stopping raft observer")
 				return
+			case <-ticker.C:
+				r.recordMetrics()
 			case ev := <-r.observerChan:
 				switch data := ev.Data.(type) {
 				case raft.RequestVoteRequest:
 					r.log.Debug("RequestVoteRequest", slog.Any("data", data))
 				case raft.RaftState:
 					r.log.Debug("RaftState", slog.String("data", data.String()))
+					if data == raft.Candidate {
+						Elections.WithLabelValues(string(r.nodeID)).Inc()
+					}
 				case raft.PeerObservation:
 					r.log.Debug("PeerObservation", slog.Any("data", data))
 				case raft.LeaderObservation:
 					r.log.Debug("LeaderObservation", slog.Any("data", data))
+					// Losing the leader is observed too, only count a new leader.
+					if data.LeaderID != "" {
+						LeaderChanges.WithLabelValues(string(r.nodeID)).Inc()
+					}
+					// Heartbeat failures are only tracked for the current term's followers.
+					r.contactMu.Lock()
+					r.failedContacts = make(map[raft.ServerID]time.Time)
+					r.contactMu.Unlock()
+					r.raftTransport.Reset()
 				case raft.ResumedHeartbeatObservation:
 					r.log.Debug("ResumedHeartbeatObservation", slog.Any("data", data))
+					r.contactMu.Lock()
+					delete(r.failedContacts, data.PeerID)
+					r.contactMu.Unlock()
 				case raft.FailedHeartbeatObservation:
 					r.log.Debug("FailedHeartbeatObservation", slog.Any("data", data))
+					r.contactMu.Lock()
+					r.failedContacts[data.PeerID] = data.LastContact
+					r.contactMu.Unlock()
 				}
 				if r.opts.OnObservation != nil {
 					r.opts.OnObservation(ev)
@@ -55,3 +78,26 @@ func (r *raftNode) observe() (closeCh, doneCh chan struct{}) {
 	}()
 	return closeCh, doneCh
 }
+
+// LastContact returns the last time the leader heard from the server with the given ID.
+// The leader heartbeats its followers continuously, so the current time is returned for
+// followers that are not failing heartbeats. On followers only the last contact with the
+// leader is known, and the zero time is returned for other servers.
+func (r *raftNode) LastContact(id string) time.Time {
+	if raft.ServerID(id) == r.nodeID {
+		return time.Now()
+	}
+	if r.raft.State() != raft.Leader {
+		_, leaderID := r.raft.LeaderWithID()
+		if leaderID == raft.ServerID(id) {
+			return r.raft.LastContact()
+		}
+		return time.Time{}
+	}
+	r.contactMu.Lock()
+	defer r.contactMu.Unlock()
+	if last, ok := r.failedContacts[raft.ServerID(id)]; ok {
+		return last
+	}
+	return time.Now()
+}
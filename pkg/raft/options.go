@@ -17,6 +17,7 @@ limitations under the License.
 package raft
 
 import (
+	"crypto/tls"
 	"errors"
 	"flag"
 	"fmt"
@@ -33,29 +34,41 @@ import (
 	v1 "github.com/webmeshproj/api/v1"
 
 	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/storage"
 	"github.com/webmeshproj/webmesh/pkg/util"
 )
 
 const (
-	RaftListenAddressEnvVar   = "RAFT_LISTEN_ADDRESS"
-	DataDirEnvVar             = "RAFT_DATA_DIR"
-	InMemoryEnvVar            = "RAFT_IN_MEMORY"
-	ConnectionPoolCountEnvVar = "RAFT_CONNECTION_POOL_COUNT"
-	ConnectionTimeoutEnvVar   = "RAFT_CONNECTION_TIMEOUT"
-	HeartbeatTimeoutEnvVar    = "RAFT_HEARTBEAT_TIMEOUT"
-	ElectionTimeoutEnvVar     = "RAFT_ELECTION_TIMEOUT"
-	ApplyTimeoutEnvVar        = "RAFT_APPLY_TIMEOUT"
-	CommitTimeoutEnvVar       = "RAFT_COMMIT_TIMEOUT"
-	MaxAppendEntriesEnvVar    = "RAFT_MAX_APPEND_ENTRIES"
-	LeaderLeaseTimeoutEnvVar  = "RAFT_LEADER_LEASE_TIMEOUT"
-	SnapshotIntervalEnvVar    = "RAFT_SNAPSHOT_INTERVAL"
-	SnapshotThresholdEnvVar   = "RAFT_SNAPSHOT_THRESHOLD"
-	SnapshotRetentionEnvVar   = "RAFT_SNAPSHOT_RETENTION"
-	ObserverChanBufferEnvVar  = "RAFT_OBSERVER_CHAN_BUFFER"
-	RaftLogLevelEnvVar        = "RAFT_LOG_LEVEL"
-	RaftPreferIPv6EnvVar      = "RAFT_PREFER_IPV6"
-	LeaveOnShutdownEnvVar     = "RAFT_LEAVE_ON_SHUTDOWN"
-	StartupTimeoutEnvVar      = "RAFT_STARTUP_TIMEOUT"
+	RaftListenAddressEnvVar    = "RAFT_LISTEN_ADDRESS"
+	DataDirEnvVar              = "RAFT_DATA_DIR"
+	InMemoryEnvVar             = "RAFT_IN_MEMORY"
+	ConnectionPoolCountEnvVar  = "RAFT_CONNECTION_POOL_COUNT"
+	ConnectionTimeoutEnvVar    = "RAFT_CONNECTION_TIMEOUT"
+	HeartbeatTimeoutEnvVar     = "RAFT_HEARTBEAT_TIMEOUT"
+	ElectionTimeoutEnvVar      = "RAFT_ELECTION_TIMEOUT"
+	ApplyTimeoutEnvVar         = "RAFT_APPLY_TIMEOUT"
+	CommitTimeoutEnvVar        = "RAFT_COMMIT_TIMEOUT"
+	MaxAppendEntriesEnvVar     = "RAFT_MAX_APPEND_ENTRIES"
+	LeaderLeaseTimeoutEnvVar   = "RAFT_LEADER_LEASE_TIMEOUT"
+	SnapshotIntervalEnvVar     = "RAFT_SNAPSHOT_INTERVAL"
+	SnapshotThresholdEnvVar    = "RAFT_SNAPSHOT_THRESHOLD"
+	SnapshotRetentionEnvVar    = "RAFT_SNAPSHOT_RETENTION"
+	ObserverChanBufferEnvVar   = "RAFT_OBSERVER_CHAN_BUFFER"
+	RaftLogLevelEnvVar         = "RAFT_LOG_LEVEL"
+	RaftPreferIPv6EnvVar       = "RAFT_PREFER_IPV6"
+	LeaveOnShutdownEnvVar      = "RAFT_LEAVE_ON_SHUTDOWN"
+	StartupTimeoutEnvVar       = "RAFT_STARTUP_TIMEOUT"
+	EncryptionKeyEnvVar        = "RAFT_ENCRYPTION_KEY"
+	EncryptionKeyFileEnvVar    = "RAFT_ENCRYPTION_KEY_FILE"
+	PreviousKeyEnvVar          = "RAFT_PREVIOUS_ENCRYPTION_KEY"
+	PreviousKeyFileEnvVar      = "RAFT_PREVIOUS_ENCRYPTION_KEY_FILE"
+	StorageBackendEnvVar       = "RAFT_STORAGE_BACKEND"
+	LogStoreEnvVar             = "RAFT_LOG_STORE"
+	TLSModeEnvVar              = "RAFT_TLS_MODE"
+	TransportEnvVar            = "RAFT_TRANSPORT"
+	ApplyBatchWindowEnvVar     = "RAFT_APPLY_BATCH_WINDOW"
+	ApplyBatchMaxEntriesEnvVar = "RAFT_APPLY_BATCH_MAX_ENTRIES"
+	ApplyBatchMaxBytesEnvVar   = "RAFT_APPLY_BATCH_MAX_BYTES"
 
 	// RaftStorePath is the raft stable and log store directory.
 	RaftStorePath = "raft-store"
@@ -103,11 +116,60 @@ type Options struct {
 	PreferIPv6 bool `json:"prefer-ipv6,omitempty" yaml:"prefer-ipv6,omitempty" toml:"prefer-ipv6,omitempty" mapstructure:"prefer-ipv6,omitempty"`
 	// LeaveOnShutdown is the leave on shutdown flag.
 	LeaveOnShutdown bool `json:"leave-on-shutdown,omitempty" yaml:"leave-on-shutdown,omitempty" toml:"leave-on-shutdown,omitempty" mapstructure:"leave-on-shutdown,omitempty"`
+	// StorageBackend is the backend used for the raft data store. Defaults to
+	// memory for in-memory stores and badger otherwise.
+	StorageBackend string `json:"storage-backend,omitempty" yaml:"storage-backend,omitempty" toml:"storage-backend,omitempty" mapstructure:"storage-backend,omitempty"`
+	// LogStore is the store used for the raft log and stable state. One of
+	// badger or bolt. Switching from badger to bolt migrates the existing store.
+	LogStore string `json:"log-store,omitempty" yaml:"log-store,omitempty" toml:"log-store,omitempty" mapstructure:"log-store,omitempty"`
+	// EncryptionKey is the key used to encrypt the raft log and data stores at rest.
+	// It must be 16, 24, or 32 bytes, prefixed with its encoding: hex:, base64:, or raw:.
+	EncryptionKey string `json:"encryption-key,omitempty" yaml:"encryption-key,omitempty" toml:"encryption-key,omitempty" mapstructure:"encryption-key,omitempty"`
+	// EncryptionKeyFile is a file containing the encryption key.
+	EncryptionKeyFile string `json:"encryption-key-file,omitempty" yaml:"encryption-key-file,omitempty" toml:"encryption-key-file,omitempty" mapstructure:"encryption-key-file,omitempty"`
+	// PreviousEncryptionKey is the key the stores are currently encrypted with when
+	// rotating to a new encryption key.
+	PreviousEncryptionKey string `json:"previous-encryption-key,omitempty" yaml:"previous-encryption-key,omitempty" toml:"previous-encryption-key,omitempty" mapstructure:"previous-encryption-key,omitempty"`
+	// PreviousEncryptionKeyFile is a file containing the previous encryption key.
+	PreviousEncryptionKeyFile string `json:"previous-encryption-key-file,omitempty" yaml:"previous-encryption-key-file,omitempty" toml:"previous-encryption-key-file,omitempty" mapstructure:"previous-encryption-key-file,omitempty"`
+	// TLSMode is the TLS mode for the raft transport. One of disabled, permissive,
+	// or strict. A plaintext cluster is moved to TLS by rolling every node to
+	// permissive and then every node to strict.
+	TLSMode string `json:"tls-mode,omitempty" yaml:"tls-mode,omitempty" toml:"tls-mode,omitempty" mapstructure:"tls-mode,omitempty"`
+	// Transport is the transport for raft RPCs. One of tcp or grpc. The grpc transport
+	// tunnels raft over the gRPC services port instead of listening on ListenAddress.
+	// All servers in a cluster must use the same transport.
+	Transport string `json:"transport,omitempty" yaml:"transport,omitempty" toml:"transport,omitempty" mapstructure:"transport,omitempty"`
+	// ApplyBatchWindow is how long the leader waits for concurrent writes to coalesce
+	// into a single raft log entry after the first write arrives.
+	ApplyBatchWindow time.Duration `json:"apply-batch-window,omitempty" yaml:"apply-batch-window,omitempty" toml:"apply-batch-window,omitempty" mapstructure:"apply-batch-window,omitempty"`
+	// ApplyBatchMaxEntries is the maximum number of writes coalesced into a single raft
+	// log entry. A value of 1 or less disables coalescing, which is the default. Coalesced
+	// entries cannot be applied by older versions, so it must only be enabled once every
+	// server in the cluster supports it.
+	ApplyBatchMaxEntries int `json:"apply-batch-max-entries,omitempty" yaml:"apply-batch-max-entries,omitempty" toml:"apply-batch-max-entries,omitempty" mapstructure:"apply-batch-max-entries,omitempty"`
+	// ApplyBatchMaxBytes is the maximum encoded size of the writes coalesced into a
+	// single raft log entry.
+	ApplyBatchMaxBytes int `json:"apply-batch-max-bytes,omitempty" yaml:"apply-batch-max-bytes,omitempty" toml:"apply-batch-max-bytes,omitempty" mapstructure:"apply-batch-max-bytes,omitempty"`
+
+	// TLSConfig is the TLS configuration holding the node's certificate and the CA
+	// used to verify peers. It is required when TLSMode is not disabled.
+	TLSConfig *tls.Config `json:"-" yaml:"-" toml:"-" mapstructure:"-"`
+	// GRPCPort is the advertised gRPC port used as the raft port by the grpc transport.
+	GRPCPort int `json:"-" yaml:"-" toml:"-" mapstructure:"-"`
+	// DialGRPC dials the gRPC server of other nodes for the grpc transport.
+	DialGRPC GRPCDialer `json:"-" yaml:"-" toml:"-" mapstructure:"-"`
+	// GRPCInsecure accepts raft streams from unauthenticated callers for the grpc
+	// transport. It is set when the gRPC server runs without authentication.
+	GRPCInsecure bool `json:"-" yaml:"-" toml:"-" mapstructure:"-"`
 
 	// Below are callbacks used internally or by external packages.
 	OnApplyLog        func(ctx context.Context, term, index uint64, log *v1.RaftLogEntry) `json:"-" yaml:"-" toml:"-" mapstructure:"-"`
 	OnSnapshotRestore func(ctx context.Context, meta *SnapshotMeta, data io.ReadCloser)   `json:"-" yaml:"-" toml:"-" mapstructure:"-"`
 	OnObservation     func(ev Observation)                                                `json:"-" yaml:"-" toml:"-" mapstructure:"-"`
+	// OnStarted is called once raft is started, before the cluster is bootstrapped
+	// or joined. The grpc transport uses it to start serving raft streams.
+	OnStarted func(ctx context.Context) error `json:"-" yaml:"-" toml:"-" mapstructure:"-"`
 }
 
 // NewOptions returns new raft options with the default values and given listen port.
@@ -136,6 +198,11 @@ func NewOptions(port int) *Options {
 		SnapshotRetention:  3,
 		ObserverChanBuffer: 100,
 		LogLevel:           "info",
+		LogStore:           LogStoreBadger,
+		TLSMode:            TLSModeDisabled,
+		Transport:          TransportTCP,
+		ApplyBatchWindow:   time.Millisecond,
+		ApplyBatchMaxBytes: 1024 * 1024,
 	}
 }
 
@@ -181,6 +248,33 @@ func (o *Options) BindFlags(fl *flag.FlagSet, prefix ...string) {
 		"Raft observer channel buffer size.")
 	fl.BoolVar(&o.LeaveOnShutdown, p+"raft.leave-on-shutdown", util.GetEnvDefault(LeaveOnShutdownEnvVar, "false") == "true",
 		"Leave the cluster when the server shuts down.")
+	fl.StringVar(&o.StorageBackend, p+"raft.storage-backend", util.GetEnvDefault(StorageBackendEnvVar, ""),
+		"Backend for the raft data store. One of badger, bolt, or memory. Defaults to memory for in-memory stores and badger otherwise.")
+	fl.StringVar(&o.LogStore, p+"raft.log-store", util.GetEnvDefault(LogStoreEnvVar, LogStoreBadger),
+		"Store for the raft log and stable state. One of badger or bolt. An existing badger store is migrated when switching to bolt.")
+	fl.StringVar(&o.EncryptionKey, p+"raft.encryption-key", util.GetEnvDefault(EncryptionKeyEnvVar, ""),
+		`Key used to encrypt the raft stores at rest, prefixed with its encoding: hex:, base64:, or raw:.
+Prefer the environment variable or a key file over the flag.`)
+	fl.StringVar(&o.EncryptionKeyFile, p+"raft.encryption-key-file", util.GetEnvDefault(EncryptionKeyFileEnvVar, ""),
+		"File containing the key used to encrypt the raft stores at rest.")
+	fl.StringVar(&o.PreviousEncryptionKey, p+"raft.previous-encryption-key", util.GetEnvDefault(PreviousKeyEnvVar, ""),
+		"Key the raft stores are currently encrypted with when rotating to a new key.")
+	fl.StringVar(&o.PreviousEncryptionKeyFile, p+"raft.previous-encryption-key-file", util.GetEnvDefault(PreviousKeyFileEnvVar, ""),
+		"File containing the key the raft stores are currently encrypted with when rotating to a new key.")
+	fl.StringVar(&o.TLSMode, p+"raft.tls-mode", util.GetEnvDefault(TLSModeEnvVar, TLSModeDisabled),
+		`TLS mode for the raft transport. One of disabled, permissive, or strict.
+Permissive accepts both TLS and plaintext peers and is used while rolling a cluster to strict.
+TLS uses the node's mTLS certificate and verifies that peer certificates match their raft server ID.`)
+	fl.StringVar(&o.Transport, p+"raft.transport", util.GetEnvDefault(TransportEnvVar, TransportTCP),
+		`Transport for raft RPCs. One of tcp or grpc. The grpc transport tunnels raft over the
+gRPC services port so no separate raft port is needed. All servers in a cluster must use the same transport.`)
+	fl.DurationVar(&o.ApplyBatchWindow, p+"raft.apply-batch-window", util.GetEnvDurationDefault(ApplyBatchWindowEnvVar, time.Millisecond),
+		"How long the leader waits for concurrent writes to coalesce into a single raft log entry.")
+	fl.IntVar(&o.ApplyBatchMaxEntries, p+"raft.apply-batch-max-entries", util.GetEnvIntDefault(ApplyBatchMaxEntriesEnvVar, 0),
+		`Maximum number of writes coalesced into a single raft log entry. A value of 1 or less disables coalescing.
+Only enable it once every server in the cluster supports coalesced entries, older versions cannot apply them.`)
+	fl.IntVar(&o.ApplyBatchMaxBytes, p+"raft.apply-batch-max-bytes", util.GetEnvIntDefault(ApplyBatchMaxBytesEnvVar, 1024*1024),
+		"Maximum encoded size of the writes coalesced into a single raft log entry.")
 }
 
 // Validate validates the raft options.
@@ -219,9 +313,85 @@ func (o *Options) Validate() error {
 	if o.SnapshotInterval <= 0 {
 		return errors.New("snapshot interval must be > 0")
 	}
+	if o.ApplyBatchWindow < 0 {
+		return errors.New("apply batch window must be >= 0")
+	}
+	if o.ApplyBatchMaxEntries > 1 && o.ApplyBatchMaxBytes <= 0 {
+		return errors.New("apply batch max bytes must be > 0")
+	}
+	if o.StorageBackend != "" {
+		var found bool
+		for _, backend := range storage.Backends() {
+			if string(backend) == o.StorageBackend {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown storage backend %q", o.StorageBackend)
+		}
+	}
+	switch o.LogStore {
+	case "", LogStoreBadger:
+	case LogStoreBolt:
+		if o.EncryptionKey != "" || o.EncryptionKeyFile != "" {
+			return errors.New("the bolt log store does not support encryption")
+		}
+	default:
+		return fmt.Errorf("unknown log store %q", o.LogStore)
+	}
+	switch o.TLSMode {
+	case "", TLSModeDisabled, TLSModePermissive, TLSModeStrict:
+	default:
+		return fmt.Errorf("unknown raft TLS mode %q", o.TLSMode)
+	}
+	switch o.Transport {
+	case "", TransportTCP:
+	case TransportGRPC:
+		if o.TLSMode != "" && o.TLSMode != TLSModeDisabled {
+			return errors.New("raft TLS mode does not apply to the grpc transport, it uses the gRPC server's TLS")
+		}
+	default:
+		return fmt.Errorf("unknown raft transport %q", o.Transport)
+	}
+	if o.EncryptionKey != "" && o.EncryptionKeyFile != "" {
+		return errors.New("only one of encryption key and encryption key file can be set")
+	}
+	if o.PreviousEncryptionKey != "" && o.PreviousEncryptionKeyFile != "" {
+		return errors.New("only one of previous encryption key and previous encryption key file can be set")
+	}
+	if (o.PreviousEncryptionKey != "" || o.PreviousEncryptionKeyFile != "") && o.EncryptionKey == "" && o.EncryptionKeyFile == "" {
+		return errors.New("previous encryption key requires an encryption key")
+	}
 	return nil
 }
 
+// Encryption loads the encryption options for the raft stores. It returns
+// nil if encryption is not configured.
+func (o *Options) Encryption() (*storage.EncryptionOptions, error) {
+	load := func(value, file string) ([]byte, error) {
+		if file != "" {
+			return storage.ReadEncryptionKey(file)
+		}
+		if value != "" {
+			return storage.ParseEncryptionKey(value)
+		}
+		return nil, nil
+	}
+	key, err := load(o.EncryptionKey, o.EncryptionKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load encryption key: %w", err)
+	}
+	if key == nil {
+		return nil, nil
+	}
+	previous, err := load(o.PreviousEncryptionKey, o.PreviousEncryptionKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load previous encryption key: %w", err)
+	}
+	return &storage.EncryptionOptions{Key: key, PreviousKey: previous}, nil
+}
+
 // RaftConfig builds a raft config.
 func (o *Options) RaftConfig(nodeID string) *raft.Config {
 	config := raft.DefaultConfig()
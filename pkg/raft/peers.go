@@ -17,6 +17,7 @@ limitations under the License.
 package raft
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/hashicorp/raft"
@@ -94,3 +95,42 @@ func (r *raftNode) RemoveServer(ctx context.Context, id string, wait bool) error
 	}
 	return err
 }
+
+// TransferLeadership transfers leadership to the voter with the given ID, or to
+// the most up-to-date voter if id is empty.
+func (r *raftNode) TransferLeadership(ctx context.Context, id string) error {
+	if r.raft.State() != raft.Leader {
+		return ErrNotLeader
+	}
+	var f raft.Future
+	if id == "" {
+		f = r.raft.LeadershipTransfer()
+	} else {
+		if raft.ServerID(id) == r.nodeID {
+			return nil
+		}
+		var addr raft.ServerAddress
+		for _, server := range r.Configuration().Servers {
+			if string(server.ID) == id && server.Suffrage == raft.Voter {
+				addr = server.Address
+			}
+		}
+		if addr == "" {
+			return fmt.Errorf("%w: %s", ErrNotVoter, id)
+		}
+		f = r.raft.LeadershipTransferToServer(raft.ServerID(id), addr)
+	}
+	// The transfer is bounded by the election timeout, the context only
+	// stops us from waiting on it.
+	errs := make(chan error, 1)
+	go func() { errs <- f.Error() }()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errs:
+		if err != nil && err == raft.ErrNotLeader {
+			return ErrNotLeader
+		}
+		return err
+	}
+}
@@ -23,11 +23,13 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/raft"
+	v1 "github.com/webmeshproj/api/v1"
 	"google.golang.org/grpc"
 
 	"github.com/webmeshproj/webmesh/pkg/context"
@@ -104,10 +106,16 @@ type Raft interface {
 	Raft() *raft.Raft
 	// Configuration returns the current raft configuration.
 	Configuration() raft.Configuration
+	// ExpectServer allows the server with the given ID to connect over the
+	// TLS stream layer before this node has learned a raft configuration.
+	// A joining node calls it with the ID of the leader that admitted it.
+	ExpectServer(id string)
 	// LastAppliedIndex returns the last applied index.
 	LastAppliedIndex() uint64
 	// ListenPort returns the listen port.
 	ListenPort() int
+	// StreamLayer returns the stream layer used by the raft transport.
+	StreamLayer() StreamLayer
 	// IsLeader returns true if the Raft node is the leader.
 	IsLeader() bool
 	// IsVoter returns true if the Raft node is a voter.
@@ -120,10 +128,28 @@ type Raft interface {
 	DemoteVoter(ctx context.Context, id string) error
 	// RemoveServer removes a peer from the cluster with timeout enforced by the context.
 	RemoveServer(ctx context.Context, id string, wait bool) error
+	// LastContact returns the last time the node heard from the server with the given ID.
+	// Followers only know their last contact with the leader and return the zero time
+	// for other servers.
+	LastContact(id string) time.Time
+	// TransferLeadership transfers leadership to the voter with the given ID, or to the
+	// most up-to-date voter if id is empty. It waits for the transfer to complete.
+	TransferLeadership(ctx context.Context, id string) error
 	// Restore restores the Raft node from a snapshot.
 	Restore(rdr io.ReadCloser) error
+	// ApplyLogEntry applies the log entry through the leader's write batcher and
+	// returns the result of applying it to the FSM. Concurrent calls may be coalesced
+	// into a single raft log entry. ErrNotLeader is returned if the node is not the leader.
+	ApplyLogEntry(ctx context.Context, entry *v1.RaftLogEntry) (*v1.RaftApplyResponse, error)
 	// Barrier issues a barrier request to the cluster. This is a no-op if the node is not the leader.
 	Barrier(ctx context.Context, timeout time.Duration) (took time.Duration, err error)
+	// ReadIndex confirms leadership with a quorum and waits until every entry committed
+	// before the call has been applied locally. Reads served after it returns are
+	// linearizable. ErrNotLeader is returned if the node is not the leader.
+	ReadIndex(ctx context.Context) error
+	// ReplicationLag returns the number of entries committed by the leader that have
+	// not yet been applied locally, and the last time the node heard from the leader.
+	ReplicationLag() (entries uint64, lastContact time.Time)
 	// Stop stops the Raft node.
 	Stop(ctx context.Context) error
 }
@@ -160,17 +186,24 @@ type raftNode struct {
 	lastAppliedIndex            atomic.Uint64
 	currentTerm                 atomic.Uint64
 	listenPort                  int
-	raftTransport               *raft.NetworkTransport
+	raftTransport               *replicationTransport
+	streamLayer                 StreamLayer
 	raftSnapshots               raft.SnapshotStore
 	logDB                       LogStoreCloser
 	stableDB                    StableStoreCloser
 	dataDB                      storage.Storage
 	raftDB                      *raftStorage
+	batcher                     *Batcher
 	snapshotter                 snapshots.Snapshotter
+	stopMetrics                 func()
 	observer                    *raft.Observer
 	observerChan                chan raft.Observation
 	observerClose, observerDone chan struct{}
+	failedContacts              map[raft.ServerID]time.Time
+	contactMu                   sync.Mutex
 	leaderDialer                LeaderDialer
+	expectedServers             map[raft.ServerID]struct{}
+	expectedMu                  sync.RWMutex
 	log                         *slog.Logger
 	mu                          sync.Mutex
 }
@@ -183,7 +216,12 @@ func newRaftNode(opts *Options, dialer LeaderDialer) *raftNode {
 	} else {
 		log = log.With(slog.String("storage", opts.DataDir))
 	}
-	return &raftNode{opts: opts, log: log, leaderDialer: dialer}
+	return &raftNode{
+		opts:           opts,
+		log:            log,
+		leaderDialer:   dialer,
+		failedContacts: make(map[raft.ServerID]time.Time),
+	}
 }
 
 // Start starts the Raft node.
@@ -205,17 +243,18 @@ func (r *raftNode) Start(ctx context.Context, opts *StartOptions) error {
 	}
 	// Create the raft network transport
 	r.log.Debug("creating raft network transport")
-	sl, err := NewStreamLayer(r.opts.ListenAddress)
+	sl, err := r.newStreamLayer()
 	if err != nil {
 		r.mu.Unlock()
 		return fmt.Errorf("new raft stream layer: %w", err)
 	}
+	r.streamLayer = sl
 	r.listenPort = sl.ListenPort()
-	r.raftTransport = raft.NewNetworkTransport(sl,
+	r.raftTransport = newReplicationTransport(raft.NewNetworkTransport(sl,
 		r.opts.ConnectionPoolCount,
 		r.opts.ConnectionTimeout,
 		&logWriter{log: r.log},
-	)
+	))
 	// Create the stores
 	r.log.Debug("creating raft stores")
 	err = r.createDataStores(ctx)
@@ -226,7 +265,20 @@ func (r *raftNode) Start(ctx context.Context, opts *StartOptions) error {
 	}
 	r.raftDB = &raftStorage{r.dataDB, r}
 	r.snapshotter = snapshots.New(r.dataDB)
+	// Record storage usage metrics for as long as we are running.
+	metricsCtx, cancelMetrics := context.WithCancel(context.Background())
+	metricsDone := make(chan struct{})
+	go func() {
+		defer close(metricsDone)
+		storage.NewMetricsRecorder(opts.NodeID, r.dataDB).Run(metricsCtx, storageMetricsInterval)
+	}()
+	stopMetrics := func() {
+		cancelMetrics()
+		<-metricsDone
+	}
+	r.stopMetrics = stopMetrics
 	handleErr := func(cause error) error {
+		defer stopMetrics()
 		defer r.raftTransport.Close()
 		defer r.closeDataStores(ctx)
 		return cause
@@ -252,11 +304,105 @@ func (r *raftNode) Start(ctx context.Context, opts *StartOptions) error {
 	})
 	r.raft.RegisterObserver(r.observer)
 	r.observerClose, r.observerDone = r.observe()
+	r.batcher = NewBatcher(r.raft.Apply, BatcherOptions{
+		NodeID:       opts.NodeID,
+		Window:       r.opts.ApplyBatchWindow,
+		MaxEntries:   r.opts.ApplyBatchMaxEntries,
+		MaxBytes:     r.opts.ApplyBatchMaxBytes,
+		ApplyTimeout: r.opts.ApplyTimeout,
+	})
 	// We're done here.
 	r.started.Store(true)
+	if r.opts.OnStarted != nil {
+		if err := r.opts.OnStarted(ctx); err != nil {
+			if serr := r.Stop(ctx); serr != nil {
+				r.log.Error("failed to stop raft", slog.String("error", serr.Error()))
+			}
+			return fmt.Errorf("on started: %w", err)
+		}
+	}
 	return nil
 }
 
+// newStreamLayer creates the stream layer for the configured transport and TLS mode.
+func (r *raftNode) newStreamLayer() (StreamLayer, error) {
+	if r.opts.Transport == TransportGRPC {
+		r.log.Debug("using gRPC raft stream layer", slog.Int("port", r.opts.GRPCPort))
+		return NewGRPCStreamLayer(r.opts.GRPCPort, GRPCStreamLayerOptions{
+			Dial:      r.opts.DialGRPC,
+			Validator: r.isServerID,
+			Insecure:  r.opts.GRPCInsecure,
+		})
+	}
+	switch r.opts.TLSMode {
+	case "", TLSModeDisabled:
+		return NewStreamLayer(r.opts.ListenAddress)
+	default:
+		r.log.Debug("using TLS raft stream layer", slog.String("mode", r.opts.TLSMode))
+		return NewTLSStreamLayer(r.opts.ListenAddress, TLSStreamLayerOptions{
+			Mode:      r.opts.TLSMode,
+			Config:    r.opts.TLSConfig,
+			Resolver:  r.serverIDForAddress,
+			Validator: r.isServerID,
+		})
+	}
+}
+
+// isServerID returns true if the given ID belongs to a server in the current
+// configuration. A node that has not learned any configuration yet only accepts
+// the servers it bootstraps with, or the leader that admitted it, since that
+// leader has to reach it before it can learn one.
+func (r *raftNode) isServerID(id raft.ServerID) bool {
+	if r.raft != nil {
+		future := r.raft.GetConfiguration()
+		if err := future.Error(); err != nil {
+			return false
+		}
+		servers := future.Configuration().Servers
+		if len(servers) > 0 {
+			for _, server := range servers {
+				if server.ID == id {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	r.expectedMu.RLock()
+	defer r.expectedMu.RUnlock()
+	_, ok := r.expectedServers[id]
+	return ok
+}
+
+// ExpectServer allows the server with the given ID to connect before this
+// node has learned a raft configuration.
+func (r *raftNode) ExpectServer(id string) {
+	r.expectedMu.Lock()
+	defer r.expectedMu.Unlock()
+	if r.expectedServers == nil {
+		r.expectedServers = make(map[raft.ServerID]struct{})
+	}
+	r.expectedServers[raft.ServerID(id)] = struct{}{}
+}
+
+// serverIDForAddress returns the ID of the server in the current configuration
+// with the given address.
+func (r *raftNode) serverIDForAddress(addr raft.ServerAddress) (raft.ServerID, bool) {
+	if r.raft == nil {
+		return "", false
+	}
+	future := r.raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return "", false
+	}
+	for _, server := range future.Configuration().Servers {
+		if server.Address == addr {
+			return server.ID, true
+		}
+	}
+	return "", false
+}
+
 // Bootstrap attempts to bootstrap the Raft cluster.
 func (r *raftNode) Bootstrap(ctx context.Context, opts *BootstrapOptions) error {
 	r.mu.Lock()
@@ -298,6 +444,9 @@ func (r *raftNode) Bootstrap(ctx context.Context, opts *BootstrapOptions) error
 			})
 		}
 	}
+	for _, server := range cfg.Servers {
+		r.ExpectServer(string(server.ID))
+	}
 	f := r.raft.BootstrapCluster(cfg)
 	err = f.Error()
 	if err != nil {
@@ -349,6 +498,11 @@ func (r *raftNode) ListenPort() int {
 	return r.listenPort
 }
 
+// StreamLayer returns the stream layer used by the raft transport.
+func (r *raftNode) StreamLayer() StreamLayer {
+	return r.streamLayer
+}
+
 // LastAppliedIndex returns the last applied index.
 func (r *raftNode) LastAppliedIndex() uint64 {
 	return r.lastAppliedIndex.Load()
@@ -375,6 +529,24 @@ func (r *raftNode) Storage() storage.Storage {
 	return r.raftDB
 }
 
+// ApplyLogEntry applies the log entry through the write batcher. The commit
+// duration covers the time spent waiting for the batch.
+func (r *raftNode) ApplyLogEntry(ctx context.Context, entry *v1.RaftLogEntry) (*v1.RaftApplyResponse, error) {
+	if !r.IsLeader() {
+		return nil, ErrNotLeader
+	}
+	start := time.Now()
+	res, err := r.batcher.Apply(ctx, entry)
+	CommitDuration.WithLabelValues(string(r.nodeID)).Observe(time.Since(start).Seconds())
+	if err != nil {
+		if errors.Is(err, raft.ErrNotLeader) {
+			return nil, ErrNotLeader
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
 // Barrier issues a barrier request to the cluster. If the node is not leader
 // then ErrNotLeader is returned.
 func (r *raftNode) Barrier(ctx context.Context, timeout time.Duration) (took time.Duration, err error) {
@@ -394,6 +566,48 @@ func (r *raftNode) Barrier(ctx context.Context, timeout time.Duration) (took tim
 	return took, err
 }
 
+// ReadIndex confirms leadership with a quorum and waits until every entry committed
+// before the call has been applied locally. It is implemented with a barrier, which
+// can only commit with the support of a quorum and only completes once all preceding
+// entries have been applied to the FSM.
+func (r *raftNode) ReadIndex(ctx context.Context) error {
+	timeout := r.opts.ApplyTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+		if timeout <= 0 {
+			return ctx.Err()
+		}
+	}
+	_, err := r.Barrier(ctx, timeout)
+	if err != nil {
+		if errors.Is(err, raft.ErrNotLeader) || errors.Is(err, raft.ErrLeadershipLost) {
+			return ErrNotLeader
+		}
+		return fmt.Errorf("barrier: %w", err)
+	}
+	return nil
+}
+
+// ReplicationLag returns the number of entries committed by the leader that have
+// not yet been applied locally, and the last time the node heard from the leader.
+// Both come from local raft state, so no request is made to the leader. A follower
+// only learns the commit index of entries it has received, so the lag alone does
+// not bound staleness; callers must also bound the last contact, since a follower
+// that has not heard from the leader recently may not know of newer commits. The
+// commit index is only exposed through the raft stats, and the raft applied index
+// is used over the FSM index since it also accounts for configuration entries.
+func (r *raftNode) ReplicationLag() (entries uint64, lastContact time.Time) {
+	commitIndex, _ := strconv.ParseUint(r.raft.Stats()["commit_index"], 10, 64)
+	applied := r.raft.AppliedIndex()
+	if commitIndex > applied {
+		entries = commitIndex - applied
+	}
+	if r.IsLeader() {
+		return entries, time.Now()
+	}
+	return entries, r.raft.LastContact()
+}
+
 // Stop stops the Raft node.
 func (r *raftNode) Stop(ctx context.Context) error {
 	if !r.started.Load() {
@@ -404,6 +618,7 @@ func (r *raftNode) Stop(ctx context.Context) error {
 	defer r.started.Store(false)
 	defer r.raftTransport.Close()
 	defer r.closeDataStores(ctx)
+	defer r.stopMetrics()
 	// If we were not running in memory, force a snapshot.
 	if !r.opts.InMemory {
 		r.log.Debug("taking raft storage snapshot")
@@ -413,6 +628,7 @@ func (r *raftNode) Stop(ctx context.Context) error {
 			r.log.Error("failed to take snapshot", slog.String("error", err.Error()))
 		}
 	}
+	r.batcher.Close()
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	if r.raft.State() == raft.Leader {
@@ -0,0 +1,129 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"sync"
+
+	"github.com/hashicorp/raft"
+)
+
+// replicationTransport wraps the raft network transport to record the last
+// log index each follower reports in its append entries responses. The
+// leader uses it to measure follower replication lag from its own raft
+// state without contacting the followers.
+type replicationTransport struct {
+	*raft.NetworkTransport
+	lastLogs map[raft.ServerID]uint64
+	mu       sync.Mutex
+}
+
+func newReplicationTransport(t *raft.NetworkTransport) *replicationTransport {
+	return &replicationTransport{
+		NetworkTransport: t,
+		lastLogs:         make(map[raft.ServerID]uint64),
+	}
+}
+
+// AppendEntries sends an append entries request and records the follower's
+// last log index.
+func (t *replicationTransport) AppendEntries(id raft.ServerID, target raft.ServerAddress, args *raft.AppendEntriesRequest, resp *raft.AppendEntriesResponse) error {
+	err := t.NetworkTransport.AppendEntries(id, target, args, resp)
+	if err == nil {
+		t.record(id, resp)
+	}
+	return err
+}
+
+// AppendEntriesPipeline returns a pipeline that records the follower's last
+// log index from every response.
+func (t *replicationTransport) AppendEntriesPipeline(id raft.ServerID, target raft.ServerAddress) (raft.AppendPipeline, error) {
+	p, err := t.NetworkTransport.AppendEntriesPipeline(id, target)
+	if err != nil {
+		return nil, err
+	}
+	return newReplicationPipeline(t, id, p), nil
+}
+
+// LastLog returns the last log index the follower reported and whether it
+// has reported one since the last reset.
+func (t *replicationTransport) LastLog(id raft.ServerID) (uint64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	index, ok := t.lastLogs[id]
+	return index, ok
+}
+
+// Reset forgets the recorded indexes. It is called on leader changes so a
+// new term does not report responses from a previous one.
+func (t *replicationTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastLogs = make(map[raft.ServerID]uint64)
+}
+
+func (t *replicationTransport) record(id raft.ServerID, resp *raft.AppendEntriesResponse) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastLogs[id] = resp.LastLog
+}
+
+// replicationPipeline forwards the futures of a pipeline to raft after
+// recording the responses.
+type replicationPipeline struct {
+	raft.AppendPipeline
+	consumer  chan raft.AppendFuture
+	closec    chan struct{}
+	closeOnce sync.Once
+}
+
+func newReplicationPipeline(t *replicationTransport, id raft.ServerID, p raft.AppendPipeline) *replicationPipeline {
+	rp := &replicationPipeline{
+		AppendPipeline: p,
+		consumer:       make(chan raft.AppendFuture),
+		closec:         make(chan struct{}),
+	}
+	go func() {
+		for {
+			select {
+			case <-rp.closec:
+				return
+			case future := <-p.Consumer():
+				if future.Error() == nil {
+					t.record(id, future.Response())
+				}
+				select {
+				case rp.consumer <- future:
+				case <-rp.closec:
+					return
+				}
+			}
+		}
+	}()
+	return rp
+}
+
+// Consumer returns the channel of completed futures.
+func (p *replicationPipeline) Consumer() <-chan raft.AppendFuture {
+	return p.consumer
+}
+
+// Close closes the underlying pipeline.
+func (p *replicationPipeline) Close() error {
+	p.closeOnce.Do(func() { close(p.closec) })
+	return p.AppendPipeline.Close()
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+func TestReplicationTransport(t *testing.T) {
+	t.Parallel()
+
+	follower, err := raft.NewTCPTransport("127.0.0.1:0", nil, 2, time.Second, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { follower.Close() })
+	leader, err := raft.NewTCPTransport("127.0.0.1:0", nil, 2, time.Second, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trans := newReplicationTransport(leader)
+	t.Cleanup(func() { trans.Close() })
+
+	// The follower reports the last entry of every request as its last log.
+	go func() {
+		for rpc := range follower.Consumer() {
+			req := rpc.Command.(*raft.AppendEntriesRequest)
+			rpc.Respond(&raft.AppendEntriesResponse{
+				Term:    req.Term,
+				LastLog: req.PrevLogEntry + uint64(len(req.Entries)),
+				Success: true,
+			}, nil)
+		}
+	}()
+
+	if _, ok := trans.LastLog("follower"); ok {
+		t.Fatal("expected no last log before any responses")
+	}
+	var resp raft.AppendEntriesResponse
+	err = trans.AppendEntries("follower", follower.LocalAddr(), &raft.AppendEntriesRequest{
+		Term:         1,
+		PrevLogEntry: 10,
+		Entries:      []*raft.Log{{Index: 11}},
+	}, &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last, _ := trans.LastLog("follower"); last != 11 {
+		t.Fatalf("expected last log 11, got %d", last)
+	}
+
+	// Pipelined responses are recorded before raft consumes them.
+	pipeline, err := trans.AppendEntriesPipeline("follower", follower.LocalAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pipeline.Close() })
+	_, err = pipeline.AppendEntries(&raft.AppendEntriesRequest{
+		Term:         1,
+		PrevLogEntry: 11,
+		Entries:      []*raft.Log{{Index: 12}, {Index: 13}},
+	}, &raft.AppendEntriesResponse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case future := <-pipeline.Consumer():
+		if err := future.Error(); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for pipelined response")
+	}
+	if last, _ := trans.LastLog("follower"); last != 13 {
+		t.Fatalf("expected last log 13, got %d", last)
+	}
+
+	// A leader change forgets the recorded indexes.
+	trans.Reset()
+	if _, ok := trans.LastLog("follower"); ok {
+		t.Fatal("expected no last log after reset")
+	}
+}
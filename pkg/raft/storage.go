@@ -26,11 +26,11 @@ import (
 	"github.com/golang/snappy"
 	"github.com/hashicorp/raft"
 	v1 "github.com/webmeshproj/api/v1"
-	raftbadger "github.com/webmeshproj/raft-badger"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/durationpb"
 
 	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/raftlogs"
 	"github.com/webmeshproj/webmesh/pkg/storage"
 )
 
@@ -110,16 +110,22 @@ func (r *raftNode) createDataStores(ctx context.Context) error {
 		r.logDB = raftstore
 		r.stableDB = raftstore
 		r.raftSnapshots = raft.NewInmemSnapshotStore()
-		r.dataDB, err = storage.New(&storage.Options{InMemory: true})
+		r.dataDB, err = storage.New(&storage.Options{
+			InMemory: true,
+			Backend:  storage.Backend(r.opts.StorageBackend),
+		})
 		if err != nil {
 			err = fmt.Errorf("new inmem storage: %w", err)
 		}
 		return err
 	}
-	storePath := r.opts.StorePath()
-	raftstore, err := raftbadger.New(r.log.With("component", "raftbadger"), storePath)
+	encryption, err := r.opts.Encryption()
 	if err != nil {
-		return fmt.Errorf("new raft badger: %w", err)
+		return err
+	}
+	raftstore, err := r.newLogStore(r.log.With("component", "raftstore"), encryption)
+	if err != nil {
+		return fmt.Errorf("new raft log store: %w", err)
 	}
 	r.logDB = raftstore
 	r.stableDB = raftstore
@@ -134,7 +140,7 @@ func (r *raftNode) createDataStores(ctx context.Context) error {
 	handleErr := func(cause error) error {
 		defer func() {
 			if err := raftstore.Close(); err != nil {
-				r.log.Error("failed to close raftbadger store", slog.String("error", err.Error()))
+				r.log.Error("failed to close raft log store", slog.String("error", err.Error()))
 			}
 		}()
 		return cause
@@ -143,7 +149,9 @@ func (r *raftNode) createDataStores(ctx context.Context) error {
 		return handleErr(fmt.Errorf("new file snapshot store: %w", err))
 	}
 	r.dataDB, err = storage.New(&storage.Options{
-		DiskPath: r.opts.DataStoragePath(),
+		DiskPath:   r.opts.DataStoragePath(),
+		Backend:    storage.Backend(r.opts.StorageBackend),
+		Encryption: encryption,
 	})
 	if err != nil {
 		return handleErr(fmt.Errorf("new disk storage: %w", err))
@@ -189,6 +197,21 @@ func (rs *raftStorage) Put(ctx context.Context, key, value string, ttl time.Dura
 	return rs.sendLogToLeader(ctx, &logEntry)
 }
 
+// PutIfRevision sets the value of a key only if its current revision matches
+// the given revision. The condition is evaluated when the log is applied.
+func (rs *raftStorage) PutIfRevision(ctx context.Context, key, value string, revision uint64, ttl time.Duration) error {
+	if !rs.raft.IsVoter() {
+		return ErrNotVoter
+	}
+	logEntry := raftlogs.NewPutIfRevisionEntry(key, value, revision, ttl)
+	if rs.raft.IsLeader() {
+		// lock is taken in the FSM
+		return rs.applyLog(ctx, logEntry)
+	}
+	// We need to forward the request to the leader.
+	return rs.sendLogToLeader(ctx, logEntry)
+}
+
 // Delete removes a key.
 func (rs *raftStorage) Delete(ctx context.Context, key string) error {
 	if !rs.raft.IsVoter() {
@@ -206,6 +229,26 @@ func (rs *raftStorage) Delete(ctx context.Context, key string) error {
 	return rs.sendLogToLeader(ctx, &logEntry)
 }
 
+// Batch applies the given operations atomically as a single Raft log entry.
+func (rs *raftStorage) Batch(ctx context.Context, ops []storage.Op) error {
+	if !rs.raft.IsVoter() {
+		return ErrNotVoter
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+	logEntry, err := raftlogs.NewBatchEntry(ops)
+	if err != nil {
+		return fmt.Errorf("new batch entry: %w", err)
+	}
+	if rs.raft.IsLeader() {
+		// lock is taken in the FSM
+		return rs.applyLog(ctx, logEntry)
+	}
+	// We need to forward the request to the leader.
+	return rs.sendLogToLeader(ctx, logEntry)
+}
+
 // Snapshot returns a snapshot of the storage.
 func (rs *raftStorage) Snapshot(ctx context.Context) (io.Reader, error) {
 	return nil, errors.New("not implemented")
@@ -230,8 +273,8 @@ func (rs *raftStorage) sendLogToLeader(ctx context.Context, logEntry *v1.RaftLog
 		return fmt.Errorf("apply log entry: %w", err)
 	}
 	log.Debug("applied log entry", slog.String("time", resp.GetTime()))
-	if resp.GetError() != "" {
-		return fmt.Errorf("apply log entry: %s", resp.GetError())
+	if err := raftlogs.ResponseError(resp); err != nil {
+		return fmt.Errorf("apply log entry: %w", err)
 	}
 	return nil
 }
@@ -247,24 +290,20 @@ func (rs *raftStorage) applyLog(ctx context.Context, logEntry *v1.RaftLogEntry)
 			rs.raft.log.Error("barrier error", slog.String("error", err.Error()))
 		}
 	}()
-	timeout := rs.raft.opts.ApplyTimeout
-	if deadline, ok := ctx.Deadline(); ok {
-		timeout = time.Until(deadline)
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, rs.raft.opts.ApplyTimeout)
+		defer cancel()
 	}
-	data, err := MarshalLogEntry(logEntry)
+	resp, err := rs.raft.ApplyLogEntry(ctx, logEntry)
 	if err != nil {
-		return fmt.Errorf("marshal log entry: %w", err)
-	}
-	f := rs.raft.Raft().Apply(data, timeout)
-	if err := f.Error(); err != nil {
-		if errors.Is(err, raft.ErrNotLeader) {
+		if errors.Is(err, ErrNotLeader) {
 			return ErrNotLeader
 		}
 		return fmt.Errorf("apply log entry: %w", err)
 	}
-	resp := f.Response().(*v1.RaftApplyResponse)
-	if resp.GetError() != "" {
-		return fmt.Errorf("apply log entry data: %s", resp.GetError())
+	if err := raftlogs.ResponseError(resp); err != nil {
+		return fmt.Errorf("apply log entry data: %w", err)
 	}
 	return nil
 }
@@ -0,0 +1,292 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/services/rafttransport"
+)
+
+const (
+	// TransportTCP carries raft RPCs over a dedicated TCP listener.
+	TransportTCP = "tcp"
+	// TransportGRPC tunnels raft RPCs over streams on the gRPC services port.
+	TransportGRPC = "grpc"
+)
+
+// grpcStreamChunkSize is the maximum number of bytes sent in a single stream message.
+const grpcStreamChunkSize = 64 * 1024
+
+// GRPCDialer dials the gRPC server at the given address.
+type GRPCDialer func(ctx context.Context, address string) (*grpc.ClientConn, error)
+
+// GRPCStreamLayerOptions are options for the gRPC stream layer.
+type GRPCStreamLayerOptions struct {
+	// Dial dials the gRPC server of other nodes.
+	Dial GRPCDialer
+	// Validator reports whether the authenticated caller of an incoming
+	// stream is a raft server. Other callers are rejected.
+	Validator ServerIDValidator
+	// Insecure accepts streams from callers without an authenticated
+	// identity. It must only be set when the gRPC server runs without
+	// authentication.
+	Insecure bool
+}
+
+// GRPCStreamLayer is a StreamLayer that tunnels raft connections over
+// bidirectional gRPC streams. Incoming connections arrive through the
+// stream handler registered on the node's gRPC server, so they pass
+// through the server's TLS and authentication.
+type GRPCStreamLayer struct {
+	rafttransport.UnimplementedRaftTransportServer
+
+	port     int
+	dial     GRPCDialer
+	validate ServerIDValidator
+	insecure bool
+	conns    chan net.Conn
+	closec   chan struct{}
+	once     sync.Once
+	clients  map[raft.ServerAddress]*grpc.ClientConn
+	mu       sync.Mutex
+}
+
+// NewGRPCStreamLayer creates a new stream layer for the gRPC server listening on
+// the given port. Raft addresses must point at the gRPC port of each server.
+func NewGRPCStreamLayer(port int, opts GRPCStreamLayerOptions) (*GRPCStreamLayer, error) {
+	if opts.Dial == nil {
+		return nil, fmt.Errorf("gRPC raft transport requires a dialer")
+	}
+	if opts.Validator == nil {
+		return nil, fmt.Errorf("gRPC raft transport requires a server ID validator")
+	}
+	return &GRPCStreamLayer{
+		port:     port,
+		dial:     opts.Dial,
+		validate: opts.Validator,
+		insecure: opts.Insecure,
+		conns:    make(chan net.Conn),
+		closec:   make(chan struct{}),
+		clients:  make(map[raft.ServerAddress]*grpc.ClientConn),
+	}, nil
+}
+
+// ListenPort returns the gRPC port raft connections are accepted on.
+func (g *GRPCStreamLayer) ListenPort() int {
+	return g.port
+}
+
+// Addr returns the address raft connections are accepted on.
+func (g *GRPCStreamLayer) Addr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv6unspecified, Port: g.port}
+}
+
+// Accept waits for and returns the next raft connection.
+func (g *GRPCStreamLayer) Accept() (net.Conn, error) {
+	select {
+	case conn := <-g.conns:
+		return conn, nil
+	case <-g.closec:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops accepting raft connections and closes the client connections
+// to other servers.
+func (g *GRPCStreamLayer) Close() error {
+	g.once.Do(func() {
+		close(g.closec)
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		for addr, cc := range g.clients {
+			_ = cc.Close()
+			delete(g.clients, addr)
+		}
+	})
+	return nil
+}
+
+// Dial is used to create a new outgoing connection. Streams to the same
+// address share a single client connection.
+func (g *GRPCStreamLayer) Dial(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	cc, err := g.clientConn(address, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", address, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := rafttransport.NewRaftTransportClient(cc).Stream(ctx)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("open raft stream to %s: %w", address, err)
+	}
+	remote, _ := net.ResolveTCPAddr("tcp", string(address))
+	return pipeStream(stream, g.Addr(), remote, func() {
+		_ = stream.CloseSend()
+		cancel()
+	}), nil
+}
+
+// clientConn returns the client connection to the given address, dialing it
+// if there is none yet.
+func (g *GRPCStreamLayer) clientConn(address raft.ServerAddress, timeout time.Duration) (*grpc.ClientConn, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	select {
+	case <-g.closec:
+		return nil, ErrClosed
+	default:
+	}
+	if cc, ok := g.clients[address]; ok {
+		return cc, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cc, err := g.dial(ctx, string(address))
+	if err != nil {
+		return nil, err
+	}
+	g.clients[address] = cc
+	return cc, nil
+}
+
+// RetainServers closes and forgets the client connections to every address
+// that is not in the given configuration, so that connections to servers
+// that left the cluster are not kept open.
+func (g *GRPCStreamLayer) RetainServers(config raft.Configuration) {
+	keep := make(map[raft.ServerAddress]struct{}, len(config.Servers))
+	for _, server := range config.Servers {
+		keep[server.Address] = struct{}{}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for addr, cc := range g.clients {
+		if _, ok := keep[addr]; !ok {
+			_ = cc.Close()
+			delete(g.clients, addr)
+		}
+	}
+}
+
+// Stream hands an incoming raft transport stream to raft as a connection
+// and waits until either side closes it. Only raft servers may open
+// streams.
+func (g *GRPCStreamLayer) Stream(stream rafttransport.RaftTransport_StreamServer) error {
+	ctx := stream.Context()
+	if caller, ok := context.AuthenticatedCallerFrom(ctx); ok {
+		if !g.validate(raft.ServerID(caller)) {
+			return status.Errorf(codes.PermissionDenied, "%q is not a raft server", caller)
+		}
+	} else if !g.insecure {
+		return status.Error(codes.Unauthenticated, "raft streams require an authenticated caller")
+	}
+	var remote net.Addr
+	if p, ok := peer.FromContext(ctx); ok {
+		remote = p.Addr
+	}
+	done := make(chan struct{})
+	conn := pipeStream(stream, g.Addr(), remote, func() { close(done) })
+	select {
+	case g.conns <- conn:
+	case <-g.closec:
+		conn.Close()
+		return ErrClosed
+	case <-ctx.Done():
+		conn.Close()
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		conn.Close()
+	}
+	return nil
+}
+
+// messageStream is the part of a gRPC stream used to carry raft traffic.
+type messageStream interface {
+	SendMsg(m any) error
+	RecvMsg(m any) error
+}
+
+// pipeStream returns a connection whose data is carried over the stream. The
+// returned connection supports deadlines. onClose is called once the
+// connection is closed or the stream fails.
+func pipeStream(stream messageStream, local, remote net.Addr, onClose func()) net.Conn {
+	conn, inner := net.Pipe()
+	// Stream to connection.
+	go func() {
+		defer inner.Close()
+		for {
+			var msg rafttransport.StreamData
+			if err := stream.RecvMsg(&msg); err != nil {
+				return
+			}
+			if _, err := inner.Write(msg.GetData()); err != nil {
+				return
+			}
+		}
+	}()
+	// Connection to stream.
+	go func() {
+		defer onClose()
+		defer inner.Close()
+		buf := make([]byte, grpcStreamChunkSize)
+		for {
+			n, err := inner.Read(buf)
+			if n > 0 {
+				if err := stream.SendMsg(&rafttransport.StreamData{Data: buf[:n]}); err != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return &streamConn{Conn: conn, local: local, remote: remote}
+}
+
+// streamConn reports the addresses of the stream instead of the pipe.
+type streamConn struct {
+	net.Conn
+	local, remote net.Addr
+}
+
+func (c *streamConn) LocalAddr() net.Addr {
+	if c.local == nil {
+		return c.Conn.LocalAddr()
+	}
+	return c.local
+}
+
+func (c *streamConn) RemoteAddr() net.Addr {
+	if c.remote == nil {
+		return c.Conn.RemoteAddr()
+	}
+	return c.remote
+}
@@ -0,0 +1,540 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/services/rafttransport"
+)
+
+func TestTLSStreamLayer(t *testing.T) {
+	t.Parallel()
+
+	ca, caKey := newTestCA(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	configFor := func(id string) *tls.Config {
+		return &tls.Config{
+			Certificates: []tls.Certificate{newTestCert(t, ca, caKey, id)},
+			RootCAs:      roots,
+		}
+	}
+	resolveTo := func(id string) ServerIDResolver {
+		return func(raft.ServerAddress) (raft.ServerID, bool) { return raft.ServerID(id), id != "" }
+	}
+	members := func(ids ...string) ServerIDValidator {
+		return func(id raft.ServerID) bool {
+			for _, member := range ids {
+				if string(id) == member {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	newLayerWithMembers := func(t *testing.T, mode, id, peer string, validator ServerIDValidator) StreamLayer {
+		t.Helper()
+		sl, err := NewTLSStreamLayer("127.0.0.1:0", TLSStreamLayerOptions{
+			Mode:      mode,
+			Config:    configFor(id),
+			Resolver:  resolveTo(peer),
+			Validator: validator,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { sl.Close() })
+		return sl
+	}
+	newLayer := func(t *testing.T, mode, id, peer string) StreamLayer {
+		t.Helper()
+		return newLayerWithMembers(t, mode, id, peer, members("server", "client"))
+	}
+
+	t.Run("TLS", func(t *testing.T) {
+		server := newLayer(t, TLSModeStrict, "server", "")
+		client := newLayer(t, TLSModeStrict, "client", "server")
+		go serveEcho(server)
+		conn, err := client.Dial(raft.ServerAddress(server.Addr().String()), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if _, ok := conn.(*tls.Conn); !ok {
+			t.Fatalf("expected a TLS connection, got %T", conn)
+		}
+		expectEcho(t, conn)
+	})
+
+	t.Run("WrongServerID", func(t *testing.T) {
+		server := newLayer(t, TLSModeStrict, "server", "")
+		client := newLayer(t, TLSModePermissive, "client", "other")
+		go acceptAndRead(server)
+		_, err := client.Dial(raft.ServerAddress(server.Addr().String()), time.Second)
+		if !errors.Is(err, ErrPeerIdentity) {
+			t.Fatalf("expected ErrPeerIdentity, got %v", err)
+		}
+	})
+
+	t.Run("UnknownAddress", func(t *testing.T) {
+		server := newLayer(t, TLSModeStrict, "server", "")
+		client := newLayer(t, TLSModePermissive, "client", "")
+		go acceptAndRead(server)
+		_, err := client.Dial(raft.ServerAddress(server.Addr().String()), time.Second)
+		if !errors.Is(err, ErrPeerIdentity) {
+			t.Fatalf("expected ErrPeerIdentity, got %v", err)
+		}
+	})
+
+	t.Run("RejectsNonMember", func(t *testing.T) {
+		server := newLayerWithMembers(t, TLSModeStrict, "server", "", members("server"))
+		client := newLayer(t, TLSModeStrict, "client", "server")
+		go serveEcho(server)
+		conn, err := client.Dial(raft.ServerAddress(server.Addr().String()), time.Second)
+		if err != nil {
+			// The server may reject the certificate during the handshake.
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte{1})
+		var b [1]byte
+		if _, err := io.ReadFull(conn, b[:]); err == nil {
+			t.Fatal("expected connection from a non-member to be rejected")
+		}
+	})
+
+	t.Run("VerifiesDespiteInsecureSkipVerify", func(t *testing.T) {
+		config := configFor("server")
+		config.InsecureSkipVerify = true
+		server, err := NewTLSStreamLayer("127.0.0.1:0", TLSStreamLayerOptions{
+			Mode:      TLSModeStrict,
+			Config:    config,
+			Resolver:  resolveTo(""),
+			Validator: members("server", "client"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer server.Close()
+		otherCA, otherKey := newTestCA(t)
+		client, err := NewTLSStreamLayer("127.0.0.1:0", TLSStreamLayerOptions{
+			Mode: TLSModeStrict,
+			Config: &tls.Config{
+				Certificates:       []tls.Certificate{newTestCert(t, otherCA, otherKey, "client")},
+				RootCAs:            roots,
+				InsecureSkipVerify: true,
+			},
+			Resolver:  resolveTo("server"),
+			Validator: members("server"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		go serveEcho(server)
+		conn, err := client.Dial(raft.ServerAddress(server.Addr().String()), time.Second)
+		if err != nil {
+			// The server may reject the certificate during the handshake.
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte{1})
+		var b [1]byte
+		if _, err := io.ReadFull(conn, b[:]); err == nil {
+			t.Fatal("expected a certificate from an untrusted CA to be rejected")
+		}
+	})
+
+	t.Run("StrictRejectsPlaintext", func(t *testing.T) {
+		server := newLayer(t, TLSModeStrict, "server", "")
+		plain, err := NewStreamLayer("127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer plain.Close()
+		conn, err := plain.Dial(raft.ServerAddress(server.Addr().String()), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if _, err := conn.Write([]byte{0}); err != nil {
+			t.Fatal(err)
+		}
+		errs := make(chan error, 1)
+		go func() { errs <- acceptAndRead(server) }()
+		if err := <-errs; err == nil {
+			t.Fatal("expected plaintext connection to be rejected")
+		}
+	})
+
+	t.Run("PermissiveAcceptsPlaintext", func(t *testing.T) {
+		server := newLayer(t, TLSModePermissive, "server", "")
+		plain, err := NewStreamLayer("127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer plain.Close()
+		go serveEcho(server)
+		conn, err := plain.Dial(raft.ServerAddress(server.Addr().String()), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		expectEcho(t, conn)
+	})
+
+	t.Run("PermissiveFallsBackToPlaintext", func(t *testing.T) {
+		plain, err := NewStreamLayer("127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer plain.Close()
+		// A plaintext raft server drops connections starting with an unknown RPC type.
+		go func() {
+			for {
+				conn, err := plain.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					defer conn.Close()
+					var b [1]byte
+					if _, err := conn.Read(b[:]); err != nil || b[0] == tlsRecordTypeHandshake {
+						return
+					}
+					_, _ = conn.Write(b[:])
+				}()
+			}
+		}()
+		client := newLayer(t, TLSModePermissive, "client", "server")
+		conn, err := client.Dial(raft.ServerAddress(plain.Addr().String()), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if _, ok := conn.(*tls.Conn); ok {
+			t.Fatal("expected a plaintext connection")
+		}
+		expectEcho(t, conn)
+	})
+}
+
+// serveEcho accepts a single connection on the server and echoes back
+// the first byte read from it.
+func TestExpectedServers(t *testing.T) {
+	t.Parallel()
+
+	// Before a configuration is known, only expected servers are accepted.
+	r := newRaftNode(NewOptions(0), nil)
+	if r.isServerID("leader") {
+		t.Fatal("expected an unknown server to be rejected")
+	}
+	r.ExpectServer("leader")
+	if !r.isServerID("leader") {
+		t.Fatal("expected an expected server to be accepted")
+	}
+	if r.isServerID("other") {
+		t.Fatal("expected an unknown server to be rejected")
+	}
+}
+
+func serveEcho(server StreamLayer) {
+	sconn, err := server.Accept()
+	if err != nil {
+		return
+	}
+	defer sconn.Close()
+	var b [1]byte
+	if _, err := io.ReadFull(sconn, b[:]); err != nil {
+		return
+	}
+	_, _ = sconn.Write(b[:])
+}
+
+// expectEcho writes a byte on conn and checks that it is echoed back.
+func expectEcho(t *testing.T, conn net.Conn) {
+	t.Helper()
+	if _, err := conn.Write([]byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	var b [1]byte
+	if _, err := io.ReadFull(conn, b[:]); err != nil {
+		t.Fatal(err)
+	}
+	if b[0] != 1 {
+		t.Fatalf("expected echo of 1, got %d", b[0])
+	}
+}
+
+func acceptAndRead(server StreamLayer) error {
+	conn, err := server.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var b [1]byte
+	_, err = conn.Read(b[:])
+	return err
+}
+
+func newTestCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func newTestCert(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, cn string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestGRPCStreamLayer(t *testing.T) {
+	t.Parallel()
+
+	// newServer serves a stream layer that accepts the given members. Callers
+	// are authenticated by the node ID they send in their metadata.
+	newServer := func(t *testing.T, insecureCallers bool, ids ...string) (*GRPCStreamLayer, string) {
+		t.Helper()
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		layer, err := NewGRPCStreamLayer(ln.Addr().(*net.TCPAddr).Port, GRPCStreamLayerOptions{
+			Dial:      dialInsecure(new(int)),
+			Validator: func(id raft.ServerID) bool { return slices.Contains(ids, string(id)) },
+			Insecure:  insecureCallers,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { layer.Close() })
+		srv := grpc.NewServer(grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			md, _ := metadata.FromIncomingContext(ss.Context())
+			if callers := md.Get("x-test-caller"); len(callers) > 0 {
+				ss = &callerStream{ServerStream: ss, ctx: context.WithAuthenticatedCaller(ss.Context(), callers[0])}
+			}
+			return handler(srv, ss)
+		}))
+		rafttransport.RegisterRaftTransportServer(srv, layer)
+		go func() { _ = srv.Serve(ln) }()
+		t.Cleanup(srv.Stop)
+		return layer, ln.Addr().String()
+	}
+	newClient := func(t *testing.T, caller string, dials *int) *GRPCStreamLayer {
+		t.Helper()
+		dial := dialInsecure(dials)
+		if caller != "" {
+			dial = func(ctx context.Context, address string) (*grpc.ClientConn, error) {
+				*dials++
+				return grpc.DialContext(ctx, address,
+					grpc.WithTransportCredentials(insecure.NewCredentials()),
+					grpc.WithStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+						return streamer(metadata.AppendToOutgoingContext(ctx, "x-test-caller", caller), desc, cc, method, opts...)
+					}),
+				)
+			}
+		}
+		layer, err := NewGRPCStreamLayer(0, GRPCStreamLayerOptions{
+			Dial:      dial,
+			Validator: func(raft.ServerID) bool { return true },
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { layer.Close() })
+		return layer
+	}
+	expectRejected := func(t *testing.T, conn net.Conn) {
+		t.Helper()
+		defer conn.Close()
+		if err := conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		var b [1]byte
+		if _, err := conn.Read(b[:]); !errors.Is(err, io.EOF) {
+			t.Fatalf("expected the stream to be rejected, got %v", err)
+		}
+	}
+
+	t.Run("Member", func(t *testing.T) {
+		t.Parallel()
+		server, addr := newServer(t, false, "server", "client")
+		var dials int
+		client := newClient(t, "client", &dials)
+		go serveEcho(server)
+		conn, err := client.Dial(raft.ServerAddress(addr), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		expectEcho(t, conn)
+		// The server closed its side after echoing.
+		if err := conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		var b [1]byte
+		if _, err := conn.Read(b[:]); !errors.Is(err, io.EOF) {
+			t.Fatalf("expected EOF after the server closed the connection, got %v", err)
+		}
+		// A second connection reuses the client connection.
+		go serveEcho(server)
+		conn2, err := client.Dial(raft.ServerAddress(addr), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn2.Close()
+		expectEcho(t, conn2)
+		if dials != 1 {
+			t.Fatalf("expected a single dial, got %d", dials)
+		}
+		// The client connection is kept while the server is in the
+		// configuration and dropped once it leaves.
+		client.RetainServers(raft.Configuration{Servers: []raft.Server{{ID: "server", Address: raft.ServerAddress(addr)}}})
+		go serveEcho(server)
+		conn3, err := client.Dial(raft.ServerAddress(addr), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn3.Close()
+		expectEcho(t, conn3)
+		if dials != 1 {
+			t.Fatalf("expected a single dial, got %d", dials)
+		}
+		client.RetainServers(raft.Configuration{})
+		go serveEcho(server)
+		conn4, err := client.Dial(raft.ServerAddress(addr), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn4.Close()
+		expectEcho(t, conn4)
+		if dials != 2 {
+			t.Fatalf("expected the client connection to be dialed again, got %d dials", dials)
+		}
+
+		// Closing the layer stops accepting connections.
+		server.Close()
+		if _, err := server.Accept(); !errors.Is(err, net.ErrClosed) {
+			t.Fatalf("expected net.ErrClosed, got %v", err)
+		}
+	})
+
+	t.Run("RejectsNonMember", func(t *testing.T) {
+		t.Parallel()
+		_, addr := newServer(t, false, "server")
+		conn, err := newClient(t, "user", new(int)).Dial(raft.ServerAddress(addr), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectRejected(t, conn)
+	})
+
+	t.Run("RejectsUnauthenticated", func(t *testing.T) {
+		t.Parallel()
+		_, addr := newServer(t, false, "server")
+		conn, err := newClient(t, "", new(int)).Dial(raft.ServerAddress(addr), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectRejected(t, conn)
+	})
+
+	t.Run("Insecure", func(t *testing.T) {
+		t.Parallel()
+		server, addr := newServer(t, true, "server")
+		go serveEcho(server)
+		conn, err := newClient(t, "", new(int)).Dial(raft.ServerAddress(addr), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		expectEcho(t, conn)
+	})
+}
+
+// dialInsecure returns a dialer without transport security that counts its dials.
+func dialInsecure(dials *int) GRPCDialer {
+	return func(ctx context.Context, address string) (*grpc.ClientConn, error) {
+		*dials++
+		return grpc.DialContext(ctx, address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+}
+
+// callerStream overrides the context of a server stream.
+type callerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *callerStream) Context() context.Context {
+	return s.ctx
+}
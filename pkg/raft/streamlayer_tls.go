@@ -0,0 +1,253 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+const (
+	// TLSModeDisabled disables TLS on the raft transport.
+	TLSModeDisabled = "disabled"
+	// TLSModePermissive accepts both TLS and plaintext connections and dials
+	// peers with TLS, falling back to plaintext for peers that do not speak it.
+	TLSModePermissive = "permissive"
+	// TLSModeStrict only accepts and dials TLS connections.
+	TLSModeStrict = "strict"
+)
+
+// tlsRecordTypeHandshake is the first byte of a TLS ClientHello. Raft RPCs
+// start with a small RPC type, so the two can be told apart from the first byte.
+const tlsRecordTypeHandshake = 0x16
+
+// ErrPeerIdentity is returned when a peer's certificate does not match its raft server ID.
+var ErrPeerIdentity = errors.New("peer certificate does not match raft server ID")
+
+// ServerIDResolver returns the raft server ID for an address. It returns false
+// if the address does not belong to a known server.
+type ServerIDResolver func(addr raft.ServerAddress) (raft.ServerID, bool)
+
+// ServerIDValidator reports whether a raft server ID belongs to a member of the
+// current raft configuration.
+type ServerIDValidator func(id raft.ServerID) bool
+
+// TLSStreamLayerOptions are options for a TLS stream layer.
+type TLSStreamLayerOptions struct {
+	// Mode is the TLS mode, either permissive or strict.
+	Mode string
+	// Config holds the node's certificate and the CA pool used to verify peers.
+	// Peers are always verified against the CA pool, InsecureSkipVerify is
+	// not honored.
+	Config *tls.Config
+	// Resolver looks up the server ID expected behind a dialed address. Dialing
+	// an address it does not know fails with ErrPeerIdentity.
+	Resolver ServerIDResolver
+	// Validator checks that the CN of an accepted connection's certificate is
+	// a member of the raft configuration.
+	Validator ServerIDValidator
+}
+
+// NewTLSStreamLayer creates a new stream layer listening on the given address that
+// secures connections with TLS. Peers must present a certificate signed by a trusted
+// CA whose CN is the ID of a server in the raft configuration. When dialing, the CN
+// must match the ID of the server at the dialed address.
+func NewTLSStreamLayer(addr string, opts TLSStreamLayerOptions) (StreamLayer, error) {
+	if opts.Mode != TLSModePermissive && opts.Mode != TLSModeStrict {
+		return nil, fmt.Errorf("invalid TLS stream layer mode %q", opts.Mode)
+	}
+	if opts.Config == nil || len(opts.Config.Certificates) == 0 {
+		return nil, errors.New("raft TLS requires a node certificate")
+	}
+	if opts.Resolver == nil || opts.Validator == nil {
+		return nil, errors.New("raft TLS requires a server ID resolver and validator")
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen %s: %w", addr, err)
+	}
+	roots := opts.Config.RootCAs
+	serverConfig := opts.Config.Clone()
+	serverConfig.ClientCAs = roots
+	serverConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	serverConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("peer did not present a certificate")
+		}
+		id := raft.ServerID(cs.PeerCertificates[0].Subject.CommonName)
+		if !opts.Validator(id) {
+			return fmt.Errorf("%w: %q is not in the raft configuration", ErrPeerIdentity, id)
+		}
+		return nil
+	}
+	return &tlsStreamLayer{
+		tcpStreamLayer: tcpStreamLayer{Listener: ln, Dialer: &net.Dialer{}},
+		mode:           opts.Mode,
+		config:         opts.Config,
+		serverConfig:   serverConfig,
+		resolve:        opts.Resolver,
+	}, nil
+}
+
+type tlsStreamLayer struct {
+	tcpStreamLayer
+	mode         string
+	config       *tls.Config
+	serverConfig *tls.Config
+	resolve      ServerIDResolver
+}
+
+// Accept waits for and returns the next connection. The TLS handshake, or
+// the plaintext check in strict mode, happens on the first read.
+func (t *tlsStreamLayer) Accept() (net.Conn, error) {
+	conn, err := t.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &sniffConn{Conn: conn, layer: t}, nil
+}
+
+// Dial is used to create a new outgoing connection.
+func (t *tlsStreamLayer) Dial(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	conn, err := t.dialTLS(address, timeout)
+	if err == nil || t.mode == TLSModeStrict || errors.Is(err, ErrPeerIdentity) {
+		return conn, err
+	}
+	var verifyErr *tls.CertificateVerificationError
+	if errors.As(err, &verifyErr) {
+		return nil, err
+	}
+	// The peer may not have been rolled to TLS yet.
+	return t.tcpStreamLayer.Dial(address, timeout)
+}
+
+func (t *tlsStreamLayer) dialTLS(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	conn, err := t.DialContext(ctx, "tcp", string(address))
+	if err != nil {
+		return nil, err
+	}
+	expected, ok := t.resolve(address)
+	if !ok || expected == "" {
+		conn.Close()
+		return nil, fmt.Errorf("%w: no server is known at %s", ErrPeerIdentity, address)
+	}
+	config := t.config.Clone()
+	// Raft addresses are IPs, so peers are verified by CA and server ID
+	// instead of by hostname.
+	config.InsecureSkipVerify = true
+	config.VerifyPeerCertificate = nil
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		return t.verifyPeer(cs, expected)
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+func (t *tlsStreamLayer) verifyPeer(cs tls.ConnectionState, expected raft.ServerID) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("peer did not present a certificate")
+	}
+	leaf := cs.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         t.config.RootCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return &tls.CertificateVerificationError{UnverifiedCertificates: cs.PeerCertificates, Err: err}
+	}
+	if leaf.Subject.CommonName != string(expected) {
+		return fmt.Errorf("%w: expected %q, got %q", ErrPeerIdentity, expected, leaf.Subject.CommonName)
+	}
+	return nil
+}
+
+// sniffConn is an accepted connection that is upgraded to TLS if the peer
+// starts a TLS handshake.
+type sniffConn struct {
+	net.Conn
+	layer *tlsStreamLayer
+	once  sync.Once
+	conn  net.Conn
+	err   error
+}
+
+func (c *sniffConn) detect() {
+	br := bufio.NewReader(c.Conn)
+	first, err := br.Peek(1)
+	if err != nil {
+		c.err = err
+		return
+	}
+	buffered := &bufferedConn{Conn: c.Conn, r: br}
+	if first[0] == tlsRecordTypeHandshake {
+		c.conn = tls.Server(buffered, c.layer.serverConfig)
+		return
+	}
+	if c.layer.mode == TLSModeStrict {
+		c.err = fmt.Errorf("rejected plaintext raft connection from %s", c.Conn.RemoteAddr())
+		c.Conn.Close()
+		return
+	}
+	c.conn = buffered
+}
+
+func (c *sniffConn) Read(b []byte) (int, error) {
+	c.once.Do(c.detect)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.conn.Read(b)
+}
+
+func (c *sniffConn) Write(b []byte) (int, error) {
+	c.once.Do(c.detect)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.conn.Write(b)
+}
+
+// bufferedConn is a connection whose reads go through a buffered reader
+// that may already hold peeked bytes.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
@@ -33,6 +33,7 @@ func (s *Server) GetEdge(ctx context.Context, edge *v1.MeshEdge) (*v1.MeshEdge,
 	if edge.GetTarget() == "" {
 		return nil, status.Error(codes.InvalidArgument, "edge target is required")
 	}
+	rev := s.revisionOf(ctx, edgeKey(edge.GetSource(), edge.GetTarget()))
 	graphEdge, err := s.peers.Graph().Edge(edge.GetSource(), edge.GetTarget())
 	if err != nil {
 		if err == peers.ErrEdgeNotFound {
@@ -40,6 +41,7 @@ func (s *Server) GetEdge(ctx context.Context, edge *v1.MeshEdge) (*v1.MeshEdge,
 		}
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	sendRevision(ctx, rev)
 	return &v1.MeshEdge{
 		Source:     graphEdge.Source.ID,
 		Target:     graphEdge.Target.ID,
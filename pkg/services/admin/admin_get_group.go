@@ -30,6 +30,7 @@ func (s *Server) GetGroup(ctx context.Context, group *v1.Group) (*v1.Group, erro
 	if group.GetName() == "" {
 		return nil, status.Error(codes.InvalidArgument, "group name is required")
 	}
+	rev := s.revisionOf(ctx, groupKey(group.GetName()))
 	group, err := s.rbac.GetGroup(ctx, group.GetName())
 	if err != nil {
 		if err == rbacdb.ErrGroupNotFound {
@@ -37,5 +38,6 @@ func (s *Server) GetGroup(ctx context.Context, group *v1.Group) (*v1.Group, erro
 		}
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	sendRevision(ctx, rev)
 	return group, nil
 }
@@ -30,6 +30,7 @@ func (s *Server) GetNetworkACL(ctx context.Context, acl *v1.NetworkACL) (*v1.Net
 	if acl.GetName() == "" {
 		return nil, status.Error(codes.InvalidArgument, "acl name is required")
 	}
+	rev := s.revisionOf(ctx, networkACLKey(acl.GetName()))
 	out, err := s.networking.GetNetworkACL(ctx, acl.GetName())
 	if err != nil {
 		if err == networking.ErrACLNotFound {
@@ -37,5 +38,6 @@ func (s *Server) GetNetworkACL(ctx context.Context, acl *v1.NetworkACL) (*v1.Net
 		}
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	sendRevision(ctx, rev)
 	return out.Proto(), nil
 }
@@ -30,6 +30,7 @@ func (s *Server) GetRole(ctx context.Context, role *v1.Role) (*v1.Role, error) {
 	if role.GetName() == "" {
 		return nil, status.Error(codes.InvalidArgument, "name is required")
 	}
+	rev := s.revisionOf(ctx, roleKey(role.GetName()))
 	role, err := s.rbac.GetRole(ctx, role.GetName())
 	if err != nil {
 		if err == rbacdb.ErrRoleNotFound {
@@ -37,5 +38,6 @@ func (s *Server) GetRole(ctx context.Context, role *v1.Role) (*v1.Role, error) {
 		}
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	sendRevision(ctx, rev)
 	return role, nil
 }
@@ -30,6 +30,7 @@ func (s *Server) GetRoleBinding(ctx context.Context, rb *v1.RoleBinding) (*v1.Ro
 	if rb.GetName() == "" {
 		return nil, status.Error(codes.InvalidArgument, "name is required")
 	}
+	rev := s.revisionOf(ctx, roleBindingKey(rb.GetName()))
 	rb, err := s.rbac.GetRoleBinding(ctx, rb.GetName())
 	if err != nil {
 		if err == rbacdb.ErrRoleBindingNotFound {
@@ -37,5 +38,6 @@ func (s *Server) GetRoleBinding(ctx context.Context, rb *v1.RoleBinding) (*v1.Ro
 		}
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	sendRevision(ctx, rev)
 	return rb, nil
 }
@@ -30,6 +30,7 @@ func (s *Server) GetRoute(ctx context.Context, route *v1.Route) (*v1.Route, erro
 	if route.GetName() == "" {
 		return nil, status.Error(codes.InvalidArgument, "route name is required")
 	}
+	rev := s.revisionOf(ctx, routeKey(route.GetName()))
 	route, err := s.networking.GetRoute(ctx, route.GetName())
 	if err != nil {
 		if err == networking.ErrRouteNotFound {
@@ -37,5 +38,6 @@ func (s *Server) GetRoute(ctx context.Context, route *v1.Route) (*v1.Route, erro
 		}
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	sendRevision(ctx, rev)
 	return route, nil
 }
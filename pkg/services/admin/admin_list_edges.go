@@ -19,25 +19,28 @@ package admin
 
 import (
 	v1 "github.com/webmeshproj/api/v1"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/webmeshproj/webmesh/pkg/context"
 )
 
 func (s *Server) ListEdges(ctx context.Context, _ *emptypb.Empty) (*v1.MeshEdges, error) {
-	edges, err := s.peers.Graph().Edges()
+	page, err := listPage(ctx)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
 	}
+	edges, next, err := s.peers.ListEdgesPage(ctx, page)
+	if err != nil {
+		return nil, listError(err)
+	}
+	sendContinue(ctx, next)
 	out := make([]*v1.MeshEdge, len(edges))
 	for i, edge := range edges {
 		out[i] = &v1.MeshEdge{
-			Source:     edge.Source,
-			Target:     edge.Target,
-			Weight:     int32(edge.Properties.Weight),
-			Attributes: edge.Properties.Attributes,
+			Source:     edge.From,
+			Target:     edge.To,
+			Weight:     int32(edge.Weight),
+			Attributes: edge.Attrs,
 		}
 	}
 	return &v1.MeshEdges{Items: out}, nil
@@ -19,17 +19,20 @@ package admin
 
 import (
 	v1 "github.com/webmeshproj/api/v1"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/webmeshproj/webmesh/pkg/context"
 )
 
 func (s *Server) ListGroups(ctx context.Context, _ *emptypb.Empty) (*v1.Groups, error) {
-	groups, err := s.rbac.ListGroups(ctx)
+	page, err := listPage(ctx)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
 	}
+	groups, next, err := s.rbac.ListGroupsPage(ctx, page)
+	if err != nil {
+		return nil, listError(err)
+	}
+	sendContinue(ctx, next)
 	return &v1.Groups{Items: groups}, nil
 }
@@ -19,17 +19,20 @@ package admin
 
 import (
 	v1 "github.com/webmeshproj/api/v1"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/webmeshproj/webmesh/pkg/context"
 )
 
 func (s *Server) ListNetworkACLs(ctx context.Context, _ *emptypb.Empty) (*v1.NetworkACLs, error) {
-	acls, err := s.networking.ListNetworkACLs(ctx)
+	page, err := listPage(ctx)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
 	}
+	acls, next, err := s.networking.ListNetworkACLsPage(ctx, page)
+	if err != nil {
+		return nil, listError(err)
+	}
+	sendContinue(ctx, next)
 	return &v1.NetworkACLs{Items: acls.Proto()}, nil
 }
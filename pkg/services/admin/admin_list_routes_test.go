@@ -18,9 +18,18 @@ package admin
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"testing"
 
 	v1 "github.com/webmeshproj/api/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/webmeshproj/webmesh/pkg/services/leaderproxy"
+	"github.com/webmeshproj/webmesh/pkg/storage"
 )
 
 func TestListRoutes(t *testing.T) {
@@ -77,3 +86,77 @@ func TestListRoutes(t *testing.T) {
 		}
 	}
 }
+
+func TestListRoutesPaged(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	server := newTestServer(t)
+
+	for i := 0; i < 5; i++ {
+		_, err := server.PutRoute(ctx, &v1.Route{
+			Name:             fmt.Sprintf("route-%d", i),
+			Node:             "foo",
+			DestinationCidrs: []string{"0.0.0.0/0"},
+		})
+		if err != nil {
+			t.Fatalf("PutRoute() error = %v", err)
+		}
+	}
+
+	var names []string
+	var token string
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("ListRoutes() returned too many pages")
+		}
+		md := metadata.Pairs(leaderproxy.LimitMeta, strconv.Itoa(2))
+		if token != "" {
+			md.Set(leaderproxy.ContinueMeta, token)
+		}
+		stream := &headerStream{}
+		pageCtx := grpc.NewContextWithServerTransportStream(metadata.NewIncomingContext(ctx, md), stream)
+		routes, err := server.ListRoutes(pageCtx, nil)
+		if err != nil {
+			t.Fatalf("ListRoutes() error = %v", err)
+		}
+		if len(routes.GetItems()) > 2 {
+			t.Fatalf("ListRoutes() expected at most 2 routes, got %d", len(routes.GetItems()))
+		}
+		for _, route := range routes.GetItems() {
+			names = append(names, route.GetName())
+		}
+		next := stream.header.Get(leaderproxy.ContinueMeta)
+		if len(next) == 0 {
+			break
+		}
+		token = next[0]
+	}
+	want := []string{"route-0", "route-1", "route-2", "route-3", "route-4"}
+	if fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Fatalf("ListRoutes() expected %v, got %v", want, names)
+	}
+
+	// Tokens from other lists are rejected
+	md := metadata.Pairs(leaderproxy.ContinueMeta, storage.EncodeContinueToken("/not-a-route"))
+	_, err := server.ListRoutes(metadata.NewIncomingContext(ctx, md), nil)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("ListRoutes() expected InvalidArgument, got %v", err)
+	}
+}
+
+// headerStream captures the headers set by a handler.
+type headerStream struct {
+	header metadata.MD
+}
+
+func (h *headerStream) Method() string { return "" }
+
+func (h *headerStream) SetHeader(md metadata.MD) error {
+	h.header = metadata.Join(h.header, md)
+	return nil
+}
+
+func (h *headerStream) SendHeader(md metadata.MD) error { return h.SetHeader(md) }
+
+func (h *headerStream) SetTrailer(md metadata.MD) error { return nil }
@@ -56,14 +56,18 @@ func (s *Server) PutEdge(ctx context.Context, edge *v1.MeshEdge) (*emptypb.Empty
 			return nil, status.Errorf(codes.InvalidArgument, "invalid node ID: %s", id)
 		}
 	}
-	err := s.peers.PutEdge(ctx, peers.Edge{
+	st, err := s.storageFor(ctx, edgeKey(edge.GetSource(), edge.GetTarget()))
+	if err != nil {
+		return nil, err
+	}
+	err = peers.New(st).PutEdge(ctx, peers.Edge{
 		From:   edge.GetSource(),
 		To:     edge.GetTarget(),
 		Weight: int(edge.GetWeight()),
 		Attrs:  edge.GetAttributes(),
 	})
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, putError(err)
 	}
 	return &emptypb.Empty{}, nil
 }
@@ -25,6 +25,7 @@ import (
 
 	"github.com/webmeshproj/webmesh/pkg/context"
 	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	rbacdb "github.com/webmeshproj/webmesh/pkg/meshdb/rbac"
 	"github.com/webmeshproj/webmesh/pkg/services/rbac"
 )
 
@@ -65,9 +66,13 @@ func (s *Server) PutGroup(ctx context.Context, group *v1.Group) (*emptypb.Empty,
 			return nil, status.Error(codes.InvalidArgument, "subject name must be a valid node ID")
 		}
 	}
-	err := s.rbac.PutGroup(ctx, group)
+	st, err := s.storageFor(ctx, groupKey(group.GetName()))
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
+	}
+	err = rbacdb.New(st).PutGroup(ctx, group)
+	if err != nil {
+		return nil, putError(err)
 	}
 	return &emptypb.Empty{}, nil
 }
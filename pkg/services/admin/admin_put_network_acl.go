@@ -72,14 +72,18 @@ func (s *Server) PutNetworkACL(ctx context.Context, acl *v1.NetworkACL) (*emptyp
 	if len(acl.GetSourceNodes()) > 0 || len(acl.GetDestinationNodes()) > 0 {
 		nodes := append(acl.GetSourceNodes(), acl.GetDestinationNodes()...)
 		for _, node := range nodes {
-			if !peers.IsValidID(node) {
-				return nil, status.Errorf(codes.InvalidArgument, "invalid node id: %s", node)
+			if !peers.IsValidIDOrSelector(node) {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid node id or selector: %s", node)
 			}
 		}
 	}
-	err := s.networking.PutNetworkACL(ctx, acl)
+	st, err := s.storageFor(ctx, networkACLKey(acl.GetName()))
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
+	}
+	err = networking.New(st).PutNetworkACL(ctx, acl)
+	if err != nil {
+		return nil, putError(err)
 	}
 	return &emptypb.Empty{}, nil
 }
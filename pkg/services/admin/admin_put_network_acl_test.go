@@ -18,12 +18,16 @@ package admin
 
 import (
 	"context"
+	"strconv"
 	"testing"
 
 	v1 "github.com/webmeshproj/api/v1"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/webmeshproj/webmesh/pkg/meshdb/networking"
+	"github.com/webmeshproj/webmesh/pkg/services/leaderproxy"
 )
 
 func TestPutNetworkACL(t *testing.T) {
@@ -143,3 +147,53 @@ func TestPutNetworkACL(t *testing.T) {
 
 	runTestCases(t, tt, server.PutNetworkACL)
 }
+
+func TestPutNetworkACLIfRevision(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	ctx := context.Background()
+	acl := &v1.NetworkACL{
+		Name:             "foo",
+		Action:           v1.ACLAction_ACTION_ACCEPT,
+		DestinationNodes: []string{"foo"},
+	}
+	withRevision := func(rev uint64) context.Context {
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(leaderproxy.IfRevisionMeta, strconv.FormatUint(rev, 10)))
+	}
+
+	// A revision of zero should only allow creating the acl.
+	if _, err := server.PutNetworkACL(withRevision(0), acl); err != nil {
+		t.Fatalf("unexpected error creating acl: %v", err)
+	}
+	if _, err := server.PutNetworkACL(withRevision(0), acl); status.Code(err) != codes.Aborted {
+		t.Fatalf("expected %v creating existing acl, got: %v", codes.Aborted, err)
+	}
+
+	rev := server.revisionOf(ctx, networkACLKey("foo"))
+	if rev == 0 {
+		t.Fatal("expected acl to have a revision")
+	}
+	// A concurrent unconditional write moves the acl to a new revision.
+	acl.Priority = 1
+	if _, err := server.PutNetworkACL(ctx, acl); err != nil {
+		t.Fatalf("unexpected error updating acl: %v", err)
+	}
+	// Writing with the stale revision should fail.
+	acl.Priority = 2
+	if _, err := server.PutNetworkACL(withRevision(rev), acl); status.Code(err) != codes.Aborted {
+		t.Fatalf("expected %v with stale revision, got: %v", codes.Aborted, err)
+	}
+	// Writing with the current revision should succeed.
+	rev = server.revisionOf(ctx, networkACLKey("foo"))
+	if _, err := server.PutNetworkACL(withRevision(rev), acl); err != nil {
+		t.Fatalf("unexpected error with current revision: %v", err)
+	}
+	got, err := server.GetNetworkACL(ctx, &v1.NetworkACL{Name: "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GetPriority() != 2 {
+		t.Fatalf("expected acl priority to be 2, got %d", got.GetPriority())
+	}
+}
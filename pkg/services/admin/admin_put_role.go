@@ -64,7 +64,7 @@ func (s *Server) PutRole(ctx context.Context, role *v1.Role) (*emptypb.Empty, er
 		}
 	Verbs:
 		for _, verb := range rule.GetVerbs() {
-			if _, ok := v1.RuleVerb_name[int32(verb)]; !ok {
+			if !rbacdb.IsValidVerb(verb) {
 				return nil, status.Errorf(codes.InvalidArgument, "invalid verb: %v", verb)
 			}
 			if verb == v1.RuleVerb_VERB_ALL {
@@ -83,9 +83,13 @@ func (s *Server) PutRole(ctx context.Context, role *v1.Role) (*emptypb.Empty, er
 			}
 		}
 	}
-	err := s.rbac.PutRole(ctx, role)
+	st, err := s.storageFor(ctx, roleKey(role.GetName()))
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
+	}
+	err = rbacdb.New(st).PutRole(ctx, role)
+	if err != nil {
+		return nil, putError(err)
 	}
 	return &emptypb.Empty{}, nil
 }
@@ -67,13 +67,17 @@ func (s *Server) PutRoleBinding(ctx context.Context, rb *v1.RoleBinding) (*empty
 		if _, ok := v1.SubjectType_name[int32(subject.GetType())]; !ok {
 			return nil, status.Error(codes.InvalidArgument, "subject type must be valid")
 		}
-		if !peers.IsValidID(subject.GetName()) {
-			return nil, status.Error(codes.InvalidArgument, "subject name must be a valid node ID")
+		if !peers.IsValidIDOrSelector(subject.GetName()) {
+			return nil, status.Error(codes.InvalidArgument, "subject name must be a valid node ID or selector")
 		}
 	}
-	err := s.rbac.PutRoleBinding(ctx, rb)
+	st, err := s.storageFor(ctx, roleBindingKey(rb.GetName()))
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
+	}
+	err = rbacdb.New(st).PutRoleBinding(ctx, rb)
+	if err != nil {
+		return nil, putError(err)
 	}
 	return &emptypb.Empty{}, nil
 }
@@ -27,6 +27,7 @@ import (
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/networking"
 	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
 	"github.com/webmeshproj/webmesh/pkg/services/rbac"
 )
@@ -53,8 +54,8 @@ func (s *Server) PutRoute(ctx context.Context, route *v1.Route) (*emptypb.Empty,
 	}
 	if route.GetNode() == "" {
 		return nil, status.Error(codes.InvalidArgument, "node name is required")
-	} else if !peers.IsValidID(route.GetNode()) {
-		return nil, status.Error(codes.InvalidArgument, "invalid node ID")
+	} else if !peers.IsValidIDOrSelector(route.GetNode()) {
+		return nil, status.Error(codes.InvalidArgument, "invalid node ID or selector")
 	}
 	if len(route.GetDestinationCidrs()) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "at least one destination CIDR is required")
@@ -65,9 +66,13 @@ func (s *Server) PutRoute(ctx context.Context, route *v1.Route) (*emptypb.Empty,
 			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid CIDR %q: %v", cidr, err))
 		}
 	}
-	err := s.networking.PutRoute(ctx, route)
+	st, err := s.storageFor(ctx, routeKey(route.GetName()))
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
+	}
+	err = networking.New(st).PutRoute(ctx, route)
+	if err != nil {
+		return nil, putError(err)
 	}
 	return &emptypb.Empty{}, nil
 }
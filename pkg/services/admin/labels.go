@@ -0,0 +1,105 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admin
+
+import (
+	"errors"
+	"log/slog"
+
+	v1 "github.com/webmeshproj/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	"github.com/webmeshproj/webmesh/pkg/services/labels"
+	"github.com/webmeshproj/webmesh/pkg/services/leaderproxy"
+	"github.com/webmeshproj/webmesh/pkg/services/rbac"
+)
+
+var _ labels.LabelsServer = (*Server)(nil)
+
+var labelNodeAction = rbac.Actions{
+	{
+		Resource: v1.RuleResource_RESOURCE_ALL,
+		Verb:     v1.RuleVerb_VERB_PUT,
+	},
+}
+
+func (s *Server) LabelNode(ctx context.Context, req *labels.LabelNodeRequest) (*labels.LabelNodeResponse, error) {
+	if !s.store.Raft().IsLeader() {
+		return nil, status.Error(codes.FailedPrecondition, "not the leader")
+	}
+	id, set, remove := req.GetNode(), req.GetSetLabels(), req.GetRemoveLabels()
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "node id is required")
+	}
+	if ok, err := s.rbacEval.Evaluate(ctx, labelNodeAction.For(id)); !ok {
+		if err != nil {
+			context.LoggerFrom(ctx).Error("failed to evaluate label node action", "error", err)
+		}
+		return nil, status.Error(codes.PermissionDenied, "caller does not have permission to label nodes")
+	}
+	if err := peers.ValidateLabels(set); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ifRev, hasRev, err := leaderproxy.IfRevision(ctx)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	node, rev, err := s.peers.GetRevision(ctx, id)
+	if err != nil {
+		if errors.Is(err, peers.ErrNodeNotFound) {
+			return nil, status.Errorf(codes.NotFound, "node %q not found", id)
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if hasRev {
+		rev = ifRev
+	}
+	if len(set) > 0 || len(remove) > 0 {
+		node.Labels = applyLabels(node.Labels, set, remove)
+		node.AdminLabels = applyLabels(node.AdminLabels, set, remove)
+		// Write the node back only if it has not changed since it was read,
+		// so that a concurrent join or update is not lost. A caller that set
+		// the If-Revision header gets the revision it asked for instead.
+		if err := s.peers.PutIfRevision(ctx, node, rev); err != nil {
+			return nil, putError(err)
+		}
+		context.LoggerFrom(ctx).Info("updated node labels", slog.String("node", id), slog.String("labels", peers.FormatLabels(node.Labels)))
+	}
+	return &labels.LabelNodeResponse{
+		Node:   id,
+		Labels: node.Labels,
+	}, nil
+}
+
+// applyLabels returns a copy of the labels with the given keys removed and
+// the given labels set.
+func applyLabels(current, set map[string]string, remove []string) map[string]string {
+	out := make(map[string]string, len(current)+len(set))
+	for k, v := range current {
+		out[k] = v
+	}
+	for _, k := range remove {
+		delete(out, k)
+	}
+	for k, v := range set {
+		out[k] = v
+	}
+	return out
+}
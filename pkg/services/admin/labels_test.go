@@ -0,0 +1,115 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admin
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	"github.com/webmeshproj/webmesh/pkg/services/labels"
+	"github.com/webmeshproj/webmesh/pkg/services/leaderproxy"
+)
+
+func TestLabelNode(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	ctx := context.Background()
+	p := peers.New(server.store.Storage())
+	err := p.Put(ctx, peers.Node{
+		ID:     "foo",
+		Labels: map[string]string{"joined": "true"},
+	})
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	tt := []testCase[labels.LabelNodeRequest]{
+		{
+			name: "no node id",
+			code: codes.InvalidArgument,
+			req:  &labels.LabelNodeRequest{SetLabels: map[string]string{"env": "prod"}},
+		},
+		{
+			name: "invalid label",
+			code: codes.InvalidArgument,
+			req:  &labels.LabelNodeRequest{Node: "foo", SetLabels: map[string]string{"env": "not valid"}},
+		},
+		{
+			name: "non-existent node",
+			code: codes.NotFound,
+			req:  &labels.LabelNodeRequest{Node: "bar", SetLabels: map[string]string{"env": "prod"}},
+		},
+		{
+			name: "valid labels",
+			code: codes.OK,
+			req:  &labels.LabelNodeRequest{Node: "foo", SetLabels: map[string]string{"env": "prod"}},
+		},
+	}
+	runTestCases[labels.LabelNodeRequest, *labels.LabelNodeResponse](t, tt, server.LabelNode)
+
+	node, err := p.Get(ctx, "foo")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if node.Labels["env"] != "prod" || node.Labels["joined"] != "true" {
+		t.Fatalf("expected labels to be merged, got %v", node.Labels)
+	}
+	if len(node.AdminLabels) != 1 || node.AdminLabels["env"] != "prod" {
+		t.Fatalf("expected only the set label to be an admin label, got %v", node.AdminLabels)
+	}
+
+	// A label update with a stale revision should fail.
+	_, rev, err := p.GetRevision(ctx, "foo")
+	if err != nil {
+		t.Fatalf("GetRevision() error = %v", err)
+	}
+	node.ZoneAwarenessID = "zone-1"
+	if err := p.Put(ctx, node); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	withRevision := func(rev uint64) context.Context {
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(leaderproxy.IfRevisionMeta, strconv.FormatUint(rev, 10)))
+	}
+	req := &labels.LabelNodeRequest{Node: "foo", RemoveLabels: []string{"env"}}
+	if _, err := server.LabelNode(withRevision(rev), req); status.Code(err) != codes.Aborted {
+		t.Fatalf("expected %v with stale revision, got: %v", codes.Aborted, err)
+	}
+	resp, err := server.LabelNode(ctx, req)
+	if err != nil {
+		t.Fatalf("LabelNode() error = %v", err)
+	}
+	if _, ok := resp.GetLabels()["env"]; ok {
+		t.Fatalf("expected label to be removed, got %v", resp.GetLabels())
+	}
+	node, err = p.Get(ctx, "foo")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(node.AdminLabels) != 0 {
+		t.Fatalf("expected no admin labels, got %v", node.AdminLabels)
+	}
+	if node.ZoneAwarenessID != "zone-1" {
+		t.Fatalf("expected concurrent update to be kept, got zone %q", node.ZoneAwarenessID)
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admin
+
+import (
+	"log/slog"
+
+	v1 "github.com/webmeshproj/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/services/maintenance"
+	"github.com/webmeshproj/webmesh/pkg/services/rbac"
+)
+
+var rebuildIndexesAction = rbac.Actions{
+	{
+		Resource: v1.RuleResource_RESOURCE_ALL,
+		Verb:     v1.RuleVerb_VERB_PUT,
+	},
+}
+
+func (s *Server) RebuildIndexes(ctx context.Context, _ *emptypb.Empty) (*maintenance.RebuildIndexesResponse, error) {
+	if !s.store.Raft().IsLeader() {
+		return nil, status.Error(codes.FailedPrecondition, "not the leader")
+	}
+	if ok, err := s.rbacEval.Evaluate(ctx, rebuildIndexesAction); !ok {
+		if err != nil {
+			context.LoggerFrom(ctx).Error("failed to evaluate rebuild indexes action", "error", err)
+		}
+		return nil, status.Error(codes.PermissionDenied, "caller does not have permission to rebuild indexes")
+	}
+	count, err := s.peers.RebuildIndexes(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	context.LoggerFrom(ctx).Info("rebuilt node indexes", slog.Int("nodes", count))
+	return &maintenance.RebuildIndexesResponse{Nodes: uint64(count)}, nil
+}
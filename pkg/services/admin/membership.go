@@ -0,0 +1,285 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admin
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+	v1 "github.com/webmeshproj/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/webmeshproj/webmesh/pkg/context"
+	rbacdb "github.com/webmeshproj/webmesh/pkg/meshdb/rbac"
+	"github.com/webmeshproj/webmesh/pkg/services/membership"
+	"github.com/webmeshproj/webmesh/pkg/services/rbac"
+)
+
+var _ membership.MembershipServer = (*Server)(nil)
+
+// appliedIndexTimeout is how long ListServers waits on each remote server
+// for its applied index.
+const appliedIndexTimeout = 3 * time.Second
+
+var (
+	listServersAction = rbac.Actions{
+		{
+			Resource: v1.RuleResource_RESOURCE_VOTES,
+			Verb:     rbacdb.VerbRaftList,
+		},
+	}
+	addServerAction = rbac.Actions{
+		{
+			Resource: v1.RuleResource_RESOURCE_VOTES,
+			Verb:     rbacdb.VerbRaftAdd,
+		},
+	}
+	removeServerAction = rbac.Actions{
+		{
+			Resource: v1.RuleResource_RESOURCE_VOTES,
+			Verb:     rbacdb.VerbRaftRemove,
+		},
+	}
+	suffrageAction = rbac.Actions{
+		{
+			Resource: v1.RuleResource_RESOURCE_VOTES,
+			Verb:     rbacdb.VerbRaftSuffrage,
+		},
+	}
+	forceRemoveServerAction = rbac.Actions{
+		{
+			Resource: v1.RuleResource_RESOURCE_VOTES,
+			Verb:     rbacdb.VerbRaftForceRemove,
+		},
+	}
+)
+
+func (s *Server) ListServers(ctx context.Context, _ *emptypb.Empty) (*membership.ListServersResponse, error) {
+	if err := s.checkMembershipAction(ctx, listServersAction, "list raft servers"); err != nil {
+		return nil, err
+	}
+	servers := s.store.Raft().Configuration().Servers
+	applied := s.appliedIndexes(ctx, servers)
+	out := &membership.ListServersResponse{Servers: make([]*membership.Server, 0, len(servers))}
+	for _, srv := range servers {
+		id := string(srv.ID)
+		server := &membership.Server{
+			Id:       id,
+			Address:  string(srv.Address),
+			Suffrage: suffrage(srv.Suffrage),
+			Leader:   id == s.store.ID(),
+		}
+		if contact := s.store.Raft().LastContact(id); !contact.IsZero() {
+			server.LastContact = timestamppb.New(contact)
+		}
+		if res := applied[id]; res.err != nil {
+			server.Error = res.err.Error()
+		} else {
+			server.AppliedIndex = res.index
+		}
+		out.Servers = append(out.Servers, server)
+	}
+	return out, nil
+}
+
+func (s *Server) AddServer(ctx context.Context, req *membership.AddServerRequest) (*emptypb.Empty, error) {
+	if err := s.checkMembershipAction(ctx, addServerAction, "add raft servers"); err != nil {
+		return nil, err
+	}
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "server id must be specified")
+	}
+	if req.GetAddress() == "" {
+		return nil, status.Error(codes.InvalidArgument, "server address must be specified")
+	}
+	var err error
+	if req.GetVoter() {
+		err = s.store.Raft().AddVoter(ctx, req.GetId(), req.GetAddress())
+	} else {
+		err = s.store.Raft().AddNonVoter(ctx, req.GetId(), req.GetAddress())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to add server: %v", err)
+	}
+	context.LoggerFrom(ctx).Info("added raft server",
+		slog.String("id", req.GetId()),
+		slog.String("address", req.GetAddress()),
+		slog.Bool("voter", req.GetVoter()))
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) RemoveServer(ctx context.Context, req *membership.ServerRequest) (*emptypb.Empty, error) {
+	if err := s.checkMembershipAction(ctx, removeServerAction, "remove raft servers"); err != nil {
+		return nil, err
+	}
+	if _, err := s.lookupServer(req.GetId()); err != nil {
+		return nil, err
+	}
+	if err := s.store.Raft().RemoveServer(ctx, req.GetId(), true); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to remove server: %v", err)
+	}
+	context.LoggerFrom(ctx).Info("removed raft server", slog.String("id", req.GetId()))
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) PromoteVoter(ctx context.Context, req *membership.ServerRequest) (*emptypb.Empty, error) {
+	if err := s.checkMembershipAction(ctx, suffrageAction, "change raft suffrage"); err != nil {
+		return nil, err
+	}
+	srv, err := s.lookupServer(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if srv.Suffrage == raft.Voter {
+		return &emptypb.Empty{}, nil
+	}
+	if err := s.store.Raft().AddVoter(ctx, string(srv.ID), string(srv.Address)); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to promote server: %v", err)
+	}
+	context.LoggerFrom(ctx).Info("promoted raft server to voter", slog.String("id", req.GetId()))
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) DemoteVoter(ctx context.Context, req *membership.ServerRequest) (*emptypb.Empty, error) {
+	if err := s.checkMembershipAction(ctx, suffrageAction, "change raft suffrage"); err != nil {
+		return nil, err
+	}
+	srv, err := s.lookupServer(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if string(srv.ID) == s.store.ID() {
+		return nil, status.Error(codes.InvalidArgument, "cannot demote the leader, transfer leadership first")
+	}
+	if srv.Suffrage != raft.Voter {
+		return &emptypb.Empty{}, nil
+	}
+	if err := s.store.Raft().DemoteVoter(ctx, req.GetId()); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to demote server: %v", err)
+	}
+	context.LoggerFrom(ctx).Info("demoted raft server to non-voter", slog.String("id", req.GetId()))
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) ForceRemoveServer(ctx context.Context, req *membership.ServerRequest) (*emptypb.Empty, error) {
+	if err := s.checkMembershipAction(ctx, forceRemoveServerAction, "force remove raft servers"); err != nil {
+		return nil, err
+	}
+	if req.GetId() == s.store.ID() {
+		return nil, status.Error(codes.InvalidArgument, "cannot force remove the leader")
+	}
+	// The server may already be gone from the configuration, in which case
+	// we still clean up its registration.
+	if _, err := s.lookupServer(req.GetId()); err == nil {
+		if err := s.store.Raft().RemoveServer(ctx, req.GetId(), false); err != nil {
+			return nil, status.Errorf(codes.Unavailable, "failed to remove server: %v", err)
+		}
+	}
+	if err := s.peers.Delete(ctx, req.GetId()); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to delete peer: %v", err)
+	}
+	context.LoggerFrom(ctx).Warn("force removed raft server", slog.String("id", req.GetId()))
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) checkMembershipAction(ctx context.Context, action rbac.Actions, what string) error {
+	if !s.store.Raft().IsLeader() {
+		return status.Error(codes.FailedPrecondition, "not the leader")
+	}
+	if ok, err := s.rbacEval.Evaluate(ctx, action); !ok {
+		if err != nil {
+			context.LoggerFrom(ctx).Error("failed to evaluate membership action", "error", err)
+		}
+		return status.Errorf(codes.PermissionDenied, "caller does not have permission to %s", what)
+	}
+	return nil
+}
+
+func suffrage(s raft.ServerSuffrage) membership.Suffrage {
+	switch s {
+	case raft.Voter:
+		return membership.Suffrage_SUFFRAGE_VOTER
+	case raft.Nonvoter:
+		return membership.Suffrage_SUFFRAGE_NONVOTER
+	case raft.Staging:
+		return membership.Suffrage_SUFFRAGE_STAGING
+	default:
+		return membership.Suffrage_SUFFRAGE_UNKNOWN
+	}
+}
+
+func (s *Server) lookupServer(id string) (raft.Server, error) {
+	if id == "" {
+		return raft.Server{}, status.Error(codes.InvalidArgument, "server id must be specified")
+	}
+	for _, srv := range s.store.Raft().Configuration().Servers {
+		if string(srv.ID) == id {
+			return srv, nil
+		}
+	}
+	return raft.Server{}, status.Errorf(codes.NotFound, "server %q not found", id)
+}
+
+type appliedIndex struct {
+	index uint64
+	err   error
+}
+
+// appliedIndexes returns the applied index of each server. Remote servers are
+// queried in parallel with a short timeout.
+func (s *Server) appliedIndexes(ctx context.Context, servers []raft.Server) map[string]appliedIndex {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	out := make(map[string]appliedIndex, len(servers))
+	for _, srv := range servers {
+		id := string(srv.ID)
+		if id == s.store.ID() {
+			out[id] = appliedIndex{index: s.store.Raft().LastAppliedIndex()}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := s.remoteAppliedIndex(ctx, id)
+			mu.Lock()
+			out[id] = res
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+func (s *Server) remoteAppliedIndex(ctx context.Context, id string) appliedIndex {
+	ctx, cancel := context.WithTimeout(ctx, appliedIndexTimeout)
+	defer cancel()
+	conn, err := s.store.Dial(ctx, id)
+	if err != nil {
+		return appliedIndex{err: err}
+	}
+	defer conn.Close()
+	st, err := v1.NewNodeClient(conn).GetStatus(ctx, &v1.GetStatusRequest{Id: id})
+	if err != nil {
+		return appliedIndex{err: err}
+	}
+	return appliedIndex{index: st.GetLastApplied()}
+}
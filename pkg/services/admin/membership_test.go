@@ -0,0 +1,86 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admin
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/webmeshproj/webmesh/pkg/services/membership"
+)
+
+func TestListServers(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+
+	servers, err := server.ListServers(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(servers.GetServers()) != 1 {
+		t.Fatalf("expected 1 server, got %d", len(servers.GetServers()))
+	}
+	srv := servers.GetServers()[0]
+	if srv.GetId() != server.store.ID() {
+		t.Errorf("expected server id %q, got %q", server.store.ID(), srv.GetId())
+	}
+	if srv.GetSuffrage() != membership.Suffrage_SUFFRAGE_VOTER {
+		t.Errorf("expected voter suffrage, got %s", srv.GetSuffrage())
+	}
+	if !srv.GetLeader() {
+		t.Error("expected server to be the leader")
+	}
+	if srv.GetError() != "" {
+		t.Errorf("expected an applied index, got error %q", srv.GetError())
+	}
+}
+
+func TestChangeSuffrage(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+
+	tc := []testCase[membership.ServerRequest]{
+		{
+			name: "no server id",
+			req:  &membership.ServerRequest{},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "non-existent server",
+			req:  &membership.ServerRequest{Id: "non-existent-server"},
+			code: codes.NotFound,
+		},
+	}
+	runTestCases(t, tc, server.PromoteVoter)
+	runTestCases(t, tc, server.DemoteVoter)
+
+	runTestCase(t, testCase[membership.ServerRequest]{
+		name: "demote leader",
+		req:  &membership.ServerRequest{Id: server.store.ID()},
+		code: codes.InvalidArgument,
+	}, server.DemoteVoter)
+	runTestCase(t, testCase[membership.ServerRequest]{
+		name: "promote voter",
+		req:  &membership.ServerRequest{Id: server.store.ID()},
+		code: codes.OK,
+	}, server.PromoteVoter)
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admin
+
+import (
+	"errors"
+	"log/slog"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/services/leaderproxy"
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+// listPage returns the page of a list requested by the caller. The whole
+// list is returned if the caller did not ask for a page.
+func listPage(ctx context.Context) (storage.Page, error) {
+	page, _, err := leaderproxy.ListPage(ctx)
+	if err != nil {
+		return storage.Page{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return page, nil
+}
+
+// sendContinue returns the token for the next page to the caller.
+func sendContinue(ctx context.Context, next string) {
+	if err := leaderproxy.SendContinue(ctx, next); err != nil {
+		context.LoggerFrom(ctx).Warn("failed to set continue header", slog.String("error", err.Error()))
+	}
+}
+
+// listError converts an error from a list into a gRPC status.
+func listError(err error) error {
+	if errors.Is(err, storage.ErrInvalidContinueToken) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
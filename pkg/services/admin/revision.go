@@ -0,0 +1,128 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/networking"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	rbacdb "github.com/webmeshproj/webmesh/pkg/meshdb/rbac"
+	topologydb "github.com/webmeshproj/webmesh/pkg/meshdb/topology"
+	"github.com/webmeshproj/webmesh/pkg/services/leaderproxy"
+	"github.com/webmeshproj/webmesh/pkg/storage"
+)
+
+func roleKey(name string) string {
+	return fmt.Sprintf("%s/%s", rbacdb.RolesPrefix, name)
+}
+
+func roleBindingKey(name string) string {
+	return fmt.Sprintf("%s/%s", rbacdb.RoleBindingsPrefix, name)
+}
+
+func groupKey(name string) string {
+	return fmt.Sprintf("%s/%s", rbacdb.GroupsPrefix, name)
+}
+
+func networkACLKey(name string) string {
+	return fmt.Sprintf("%s/%s", networking.NetworkACLsPrefix, name)
+}
+
+func routeKey(name string) string {
+	return fmt.Sprintf("%s/%s", networking.RoutesPrefix, name)
+}
+
+func edgeKey(source, target string) string {
+	return fmt.Sprintf("%s/%s/%s", peers.EdgesPrefix, source, target)
+}
+
+func topologyPolicyKey(name string) string {
+	return fmt.Sprintf("%s/%s", topologydb.PoliciesPrefix, name)
+}
+
+// revisionOf returns the current revision of the given key, or 0 if it does
+// not exist. It is looked up before the resource itself is read, so that a
+// write racing with the read results in a conflict instead of a lost update.
+func (s *Server) revisionOf(ctx context.Context, key string) uint64 {
+	_, rev, err := s.store.Storage().GetRevision(ctx, key)
+	if err != nil && !errors.Is(err, storage.ErrKeyNotFound) {
+		context.LoggerFrom(ctx).Warn("failed to lookup revision", slog.String("key", key), slog.String("error", err.Error()))
+	}
+	return rev
+}
+
+// sendRevision returns the revision of a resource to the caller in the
+// response headers.
+func sendRevision(ctx context.Context, rev uint64) {
+	err := grpc.SetHeader(ctx, metadata.Pairs(leaderproxy.RevisionMeta, strconv.FormatUint(rev, 10)))
+	if err != nil {
+		context.LoggerFrom(ctx).Warn("failed to set revision header", slog.String("error", err.Error()))
+	}
+}
+
+// storageFor returns the storage to use for writing the given key. If the caller
+// set the If-Revision header, the write to the key is only applied if it is
+// still at the requested revision.
+func (s *Server) storageFor(ctx context.Context, key string) (storage.Storage, error) {
+	rev, ok, err := leaderproxy.IfRevision(ctx)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !ok {
+		return s.store.Storage(), nil
+	}
+	return &conditionalStorage{
+		Storage:  s.store.Storage(),
+		key:      key,
+		revision: rev,
+	}, nil
+}
+
+// putError converts an error from a write into a gRPC status.
+func putError(err error) error {
+	if errors.Is(err, storage.ErrRevisionMismatch) {
+		return status.Error(codes.Aborted, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// conditionalStorage turns puts to a single key into conditional puts.
+type conditionalStorage struct {
+	storage.Storage
+	key      string
+	revision uint64
+}
+
+// Put sets the value of a key. Puts to the conditional key only succeed if
+// it is still at the expected revision.
+func (c *conditionalStorage) Put(ctx context.Context, key, value string, ttl time.Duration) error {
+	if key == c.key {
+		return c.Storage.PutIfRevision(ctx, key, value, c.revision, ttl)
+	}
+	return c.Storage.Put(ctx, key, value, ttl)
+}
@@ -24,18 +24,28 @@ import (
 	"github.com/webmeshproj/webmesh/pkg/meshdb/networking"
 	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
 	rbacdb "github.com/webmeshproj/webmesh/pkg/meshdb/rbac"
+	topologydb "github.com/webmeshproj/webmesh/pkg/meshdb/topology"
+	"github.com/webmeshproj/webmesh/pkg/services/labels"
+	"github.com/webmeshproj/webmesh/pkg/services/maintenance"
+	"github.com/webmeshproj/webmesh/pkg/services/membership"
 	"github.com/webmeshproj/webmesh/pkg/services/rbac"
+	"github.com/webmeshproj/webmesh/pkg/services/topology"
 )
 
 // Server is the webmesh Admin service.
 type Server struct {
 	v1.UnimplementedAdminServer
+	labels.UnimplementedLabelsServer
+	maintenance.UnimplementedMaintenanceServer
+	membership.UnimplementedMembershipServer
+	topology.UnimplementedTopologyServer
 
 	store      meshdb.Store
 	peers      peers.Peers
 	rbac       rbacdb.RBAC
 	rbacEval   rbac.Evaluator
 	networking networking.Networking
+	topology   topologydb.Policies
 }
 
 // New creates a new admin server.
@@ -50,5 +60,6 @@ func New(store meshdb.Store, insecure bool) *Server {
 		rbac:       rbacdb.New(store.Storage()),
 		rbacEval:   rbacEval,
 		networking: networking.New(store.Storage()),
+		topology:   topologydb.New(store.Storage()),
 	}
 }
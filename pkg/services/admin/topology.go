@@ -0,0 +1,126 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admin
+
+import (
+	"errors"
+	"log/slog"
+
+	v1 "github.com/webmeshproj/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/webmeshproj/webmesh/pkg/context"
+	topologydb "github.com/webmeshproj/webmesh/pkg/meshdb/topology"
+	"github.com/webmeshproj/webmesh/pkg/services/rbac"
+	"github.com/webmeshproj/webmesh/pkg/services/topology"
+)
+
+var _ topology.TopologyServer = (*Server)(nil)
+
+// Topology policies can generate edges between any nodes, so they require
+// permissions on all edges.
+var (
+	putTopologyPolicyAction = rbac.Actions{
+		{
+			Resource: v1.RuleResource_RESOURCE_EDGES,
+			Verb:     v1.RuleVerb_VERB_PUT,
+		},
+	}
+	deleteTopologyPolicyAction = rbac.Actions{
+		{
+			Resource: v1.RuleResource_RESOURCE_EDGES,
+			Verb:     v1.RuleVerb_VERB_DELETE,
+		},
+	}
+)
+
+func (s *Server) PutPolicy(ctx context.Context, req *topology.Policy) (*emptypb.Empty, error) {
+	if !s.store.Raft().IsLeader() {
+		return nil, status.Error(codes.FailedPrecondition, "not the leader")
+	}
+	policy := topology.ParsePolicy(req)
+	if policy.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "policy name is required")
+	}
+	if ok, err := s.rbacEval.Evaluate(ctx, putTopologyPolicyAction); !ok {
+		if err != nil {
+			context.LoggerFrom(ctx).Error("failed to evaluate put topology policy action", "error", err)
+		}
+		return nil, status.Error(codes.PermissionDenied, "caller does not have permission to put topology policies")
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	st, err := s.storageFor(ctx, topologyPolicyKey(policy.Name))
+	if err != nil {
+		return nil, err
+	}
+	if err := topologydb.New(st).Put(ctx, policy); err != nil {
+		return nil, putError(err)
+	}
+	context.LoggerFrom(ctx).Info("put topology policy", slog.String("policy", policy.Name), slog.String("type", string(policy.Type)))
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) GetPolicy(ctx context.Context, req *topology.Policy) (*topology.Policy, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "policy name is required")
+	}
+	rev := s.revisionOf(ctx, topologyPolicyKey(req.GetName()))
+	policy, err := s.topology.Get(ctx, req.GetName())
+	if err != nil {
+		if errors.Is(err, topologydb.ErrPolicyNotFound) {
+			return nil, status.Errorf(codes.NotFound, "topology policy %q not found", req.GetName())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	sendRevision(ctx, rev)
+	return topology.NewPolicy(policy), nil
+}
+
+func (s *Server) DeletePolicy(ctx context.Context, req *topology.Policy) (*emptypb.Empty, error) {
+	if !s.store.Raft().IsLeader() {
+		return nil, status.Error(codes.FailedPrecondition, "not the leader")
+	}
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "policy name is required")
+	}
+	if ok, err := s.rbacEval.Evaluate(ctx, deleteTopologyPolicyAction); !ok {
+		if err != nil {
+			context.LoggerFrom(ctx).Error("failed to evaluate delete topology policy action", "error", err)
+		}
+		return nil, status.Error(codes.PermissionDenied, "caller does not have permission to delete topology policies")
+	}
+	if err := s.topology.Delete(ctx, req.GetName()); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) ListPolicies(ctx context.Context, _ *emptypb.Empty) (*topology.Policies, error) {
+	policies, err := s.topology.List(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	out := &topology.Policies{Items: make([]*topology.Policy, len(policies))}
+	for i, policy := range policies {
+		out.Items[i] = topology.NewPolicy(policy)
+	}
+	return out, nil
+}
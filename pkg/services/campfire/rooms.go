@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/webmeshproj/webmesh/pkg/meshdb"
+	"github.com/webmeshproj/webmesh/pkg/storage"
 )
 
 const (
@@ -42,7 +43,9 @@ type RoomManager struct {
 	mesh       meshdb.Store
 	log        *slog.Logger
 	cancelSubs func()
-	mu         sync.Mutex
+	// lastRev is the revision of the last event handled by the watch.
+	lastRev uint64
+	mu      sync.Mutex
 }
 
 // NewRoomManager returns a new room manager.
@@ -52,15 +55,58 @@ func NewRoomManager(mesh meshdb.Store, c net.PacketConn) (*RoomManager, error) {
 		mesh: mesh,
 		log:  slog.Default().With("service", "campfire"),
 	}
-	cancel, err := mesh.Storage().Subscribe(context.Background(), RoomsPrefix, rm.handleSubscription)
+	cancel, err := mesh.Storage().Watch(context.Background(), RoomsPrefix, 0, rm.handleWatch)
 	if err != nil {
-		return nil, fmt.Errorf("subscribe to rooms: %w", err)
+		return nil, fmt.Errorf("watch rooms: %w", err)
 	}
 	rm.cancelSubs = cancel
 	return rm, nil
 }
 
-func (r *RoomManager) handleSubscription(key, value string) {
+func (r *RoomManager) handleWatch(ev storage.Event) {
+	switch ev.Type {
+	case storage.EventPut:
+		r.lastRev = ev.Revision
+		r.dispatch(ev.Key, ev.Value)
+	case storage.EventDelete:
+		// Messages expire or members leave, nothing to dispatch.
+		r.lastRev = ev.Revision
+	case storage.EventCompacted:
+		// We fell behind the watch history, dispatch any messages stored
+		// since the last one we saw.
+		r.log.Warn("room watch compacted, replaying missed messages", "lastRevision", r.lastRev)
+		r.replay(ev.Revision)
+	}
+}
+
+func (r *RoomManager) replay(rev uint64) {
+	ctx := context.Background()
+	keys, err := r.mesh.Storage().List(ctx, RoomsPrefix)
+	if err != nil {
+		r.log.Error("failed to list rooms", "error", err)
+		return
+	}
+	for _, key := range keys {
+		if !isMessageKey(key) {
+			continue
+		}
+		value, keyRev, err := r.mesh.Storage().GetRevision(ctx, key)
+		if err != nil {
+			continue
+		}
+		if keyRev > r.lastRev {
+			r.dispatch(key, value)
+		}
+	}
+	r.lastRev = rev
+}
+
+func isMessageKey(key string) bool {
+	parts := strings.Split(strings.TrimPrefix(key, RoomsPrefix+"/"), "/")
+	return len(parts) >= 5 && parts[1] == "messages"
+}
+
+func (r *RoomManager) dispatch(key, value string) {
 	key = strings.TrimPrefix(key, RoomsPrefix+"/")
 	parts := strings.Split(key, "/")
 	if len(parts) < 2 {
@@ -0,0 +1,132 @@
+//
+//Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: services/labels/labels.proto
+
+package labels
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Labels_LabelNode_FullMethodName = "/webmesh.labels.v1.Labels/LabelNode"
+)
+
+// LabelsClient is the client API for Labels service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LabelsClient interface {
+	// LabelNode sets and removes labels on a node and returns the resulting
+	// labels of the node. The update is applied only if the node is still
+	// at the revision it was read at, or at the revision given in the
+	// If-Revision header.
+	LabelNode(ctx context.Context, in *LabelNodeRequest, opts ...grpc.CallOption) (*LabelNodeResponse, error)
+}
+
+type labelsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLabelsClient(cc grpc.ClientConnInterface) LabelsClient {
+	return &labelsClient{cc}
+}
+
+func (c *labelsClient) LabelNode(ctx context.Context, in *LabelNodeRequest, opts ...grpc.CallOption) (*LabelNodeResponse, error) {
+	out := new(LabelNodeResponse)
+	err := c.cc.Invoke(ctx, Labels_LabelNode_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LabelsServer is the server API for Labels service.
+// All implementations must embed UnimplementedLabelsServer
+// for forward compatibility
+type LabelsServer interface {
+	// LabelNode sets and removes labels on a node and returns the resulting
+	// labels of the node. The update is applied only if the node is still
+	// at the revision it was read at, or at the revision given in the
+	// If-Revision header.
+	LabelNode(context.Context, *LabelNodeRequest) (*LabelNodeResponse, error)
+	mustEmbedUnimplementedLabelsServer()
+}
+
+// UnimplementedLabelsServer must be embedded to have forward compatible implementations.
+type UnimplementedLabelsServer struct {
+}
+
+func (UnimplementedLabelsServer) LabelNode(context.Context, *LabelNodeRequest) (*LabelNodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LabelNode not implemented")
+}
+func (UnimplementedLabelsServer) mustEmbedUnimplementedLabelsServer() {}
+
+// UnsafeLabelsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LabelsServer will
+// result in compilation errors.
+type UnsafeLabelsServer interface {
+	mustEmbedUnimplementedLabelsServer()
+}
+
+func RegisterLabelsServer(s grpc.ServiceRegistrar, srv LabelsServer) {
+	s.RegisterService(&Labels_ServiceDesc, srv)
+}
+
+func _Labels_LabelNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LabelNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LabelsServer).LabelNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Labels_LabelNode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LabelsServer).LabelNode(ctx, req.(*LabelNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Labels_ServiceDesc is the grpc.ServiceDesc for Labels service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Labels_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "webmesh.labels.v1.Labels",
+	HandlerType: (*LabelsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "LabelNode",
+			Handler:    _Labels_LabelNode_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "services/labels/labels.proto",
+}
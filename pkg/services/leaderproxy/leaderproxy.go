@@ -20,6 +20,7 @@ package leaderproxy
 import (
 	"io"
 	"log/slog"
+	"time"
 
 	v1 "github.com/webmeshproj/api/v1"
 	"google.golang.org/grpc"
@@ -30,8 +31,20 @@ import (
 
 	"github.com/webmeshproj/webmesh/pkg/context"
 	"github.com/webmeshproj/webmesh/pkg/meshdb"
+	"github.com/webmeshproj/webmesh/pkg/services/edgemetrics"
+	"github.com/webmeshproj/webmesh/pkg/services/labels"
+	"github.com/webmeshproj/webmesh/pkg/services/leadership"
+	"github.com/webmeshproj/webmesh/pkg/services/leases"
+	"github.com/webmeshproj/webmesh/pkg/services/maintenance"
+	"github.com/webmeshproj/webmesh/pkg/services/membership"
+	"github.com/webmeshproj/webmesh/pkg/services/topology"
 )
 
+// maxLeaderContactAge is the longest a follower may go without hearing from the
+// leader and still serve bounded-staleness reads. A partitioned follower does
+// not learn of new commits, so its lag alone cannot be trusted.
+const maxLeaderContactAge = 5 * time.Second
+
 // Interceptor is the leaderproxy interceptor.
 type Interceptor struct {
 	store meshdb.Store
@@ -49,11 +62,20 @@ func (i *Interceptor) UnaryInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 		// Fast path - if we are the leader, it doesn't make sense to proxy the request.
 		log := context.LoggerFrom(ctx)
+		consistency, maxLag, err := ReadConsistency(ctx)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		policy, ok := MethodPolicyMap[info.FullMethod]
 		if i.store.Raft().IsLeader() {
 			log.Debug("currently the leader, handling request locally", slog.String("method", info.FullMethod))
+			if ok && policy == AllowNonLeader && consistency == ConsistencyLinearizable {
+				if err := i.store.Raft().ReadIndex(ctx); err != nil {
+					return nil, status.Errorf(codes.Unavailable, "confirm read index: %v", err)
+				}
+			}
 			return handler(ctx, req)
 		}
-		policy, ok := MethodPolicyMap[info.FullMethod]
 		if ok {
 			switch policy {
 			case RequireLocal:
@@ -65,6 +87,11 @@ func (i *Interceptor) UnaryInterceptor() grpc.UnaryServerInterceptor {
 					log.Debug("requestor prefers leader handling", slog.String("method", info.FullMethod))
 					return i.proxyUnaryToLeader(ctx, req, info, handler)
 				}
+				if !i.canServeRead(consistency, maxLag) {
+					log.Debug("local state does not satisfy requested consistency",
+						slog.String("method", info.FullMethod), slog.String("consistency", string(consistency)))
+					return i.proxyUnaryToLeader(ctx, req, info, handler)
+				}
 				return handler(ctx, req)
 			}
 		}
@@ -72,15 +99,38 @@ func (i *Interceptor) UnaryInterceptor() grpc.UnaryServerInterceptor {
 	}
 }
 
+// canServeRead returns true if a non-leader can serve a read at the given consistency.
+func (i *Interceptor) canServeRead(consistency Consistency, maxLag uint64) bool {
+	switch consistency {
+	case ConsistencyLinearizable:
+		return false
+	case ConsistencyBoundedStaleness:
+		lag, lastContact := i.store.Raft().ReplicationLag()
+		return lag <= maxLag && time.Since(lastContact) <= maxLeaderContactAge
+	default:
+		return true
+	}
+}
+
 // StreamInterceptor returns a gRPC stream interceptor that proxies requests to the leader node.
 func (i *Interceptor) StreamInterceptor() grpc.StreamServerInterceptor {
 	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		log := context.LoggerFrom(ss.Context())
+		ctx := ss.Context()
+		log := context.LoggerFrom(ctx)
+		consistency, maxLag, err := ReadConsistency(ctx)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		policy, ok := MethodPolicyMap[info.FullMethod]
 		if i.store.Raft().IsLeader() {
 			log.Debug("currently the leader, handling stream locally", slog.String("method", info.FullMethod))
+			if ok && policy == AllowNonLeader && consistency == ConsistencyLinearizable {
+				if err := i.store.Raft().ReadIndex(ctx); err != nil {
+					return status.Errorf(codes.Unavailable, "confirm read index: %v", err)
+				}
+			}
 			return handler(srv, ss)
 		}
-		policy, ok := MethodPolicyMap[info.FullMethod]
 		if ok {
 			switch policy {
 			case RequireLocal:
@@ -88,10 +138,15 @@ func (i *Interceptor) StreamInterceptor() grpc.StreamServerInterceptor {
 				return handler(srv, ss)
 			case AllowNonLeader:
 				log.Debug("stream allows non-leader handling", slog.String("method", info.FullMethod))
-				if HasPreferLeaderMeta(ss.Context()) {
+				if HasPreferLeaderMeta(ctx) {
 					log.Debug("requestor prefers leader handling of stream", slog.String("method", info.FullMethod))
 					return i.proxyStreamToLeader(srv, ss, info, handler)
 				}
+				if !i.canServeRead(consistency, maxLag) {
+					log.Debug("local state does not satisfy requested consistency",
+						slog.String("method", info.FullMethod), slog.String("consistency", string(consistency)))
+					return i.proxyStreamToLeader(srv, ss, info, handler)
+				}
 				return handler(srv, ss)
 			}
 		}
@@ -109,87 +164,152 @@ func (i *Interceptor) proxyUnaryToLeader(ctx context.Context, req any, info *grp
 	if peer, ok := context.AuthenticatedCallerFrom(ctx); ok {
 		ctx = metadata.AppendToOutgoingContext(ctx, ProxiedForMeta, peer)
 	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, key := range forwardedMeta {
+			if value := md.Get(key); len(value) > 0 {
+				ctx = metadata.AppendToOutgoingContext(ctx, key, value[0])
+			}
+		}
+	}
+	// Relay the revision and continue tokens back to the caller.
+	var header metadata.MD
+	resp, err := i.invokeLeader(ctx, conn, req, info, grpc.Header(&header))
+	for _, key := range relayedMeta {
+		if value := header.Get(key); len(value) > 0 {
+			if err := grpc.SetHeader(ctx, metadata.Pairs(key, value[0])); err != nil {
+				context.LoggerFrom(ctx).Warn("failed to relay header", slog.String("header", key), slog.String("error", err.Error()))
+			}
+		}
+	}
+	return resp, err
+}
+
+func (i *Interceptor) invokeLeader(ctx context.Context, conn *grpc.ClientConn, req any, info *grpc.UnaryServerInfo, opts ...grpc.CallOption) (any, error) {
 	switch info.FullMethod {
 	// Node API
 	case v1.Node_Join_FullMethodName:
-		return v1.NewNodeClient(conn).Join(ctx, req.(*v1.JoinRequest))
+		return v1.NewNodeClient(conn).Join(ctx, req.(*v1.JoinRequest), opts...)
 	case v1.Node_Update_FullMethodName:
-		return v1.NewNodeClient(conn).Update(ctx, req.(*v1.UpdateRequest))
+		return v1.NewNodeClient(conn).Update(ctx, req.(*v1.UpdateRequest), opts...)
 	case v1.Node_Leave_FullMethodName:
-		return v1.NewNodeClient(conn).Leave(ctx, req.(*v1.LeaveRequest))
+		return v1.NewNodeClient(conn).Leave(ctx, req.(*v1.LeaveRequest), opts...)
 	case v1.Node_Apply_FullMethodName:
-		return v1.NewNodeClient(conn).Apply(ctx, req.(*v1.RaftLogEntry))
+		return v1.NewNodeClient(conn).Apply(ctx, req.(*v1.RaftLogEntry), opts...)
 	case v1.Node_Snapshot_FullMethodName:
-		return v1.NewNodeClient(conn).Snapshot(ctx, req.(*v1.SnapshotRequest))
+		return v1.NewNodeClient(conn).Snapshot(ctx, req.(*v1.SnapshotRequest), opts...)
 	case v1.Node_GetStatus_FullMethodName:
-		return v1.NewNodeClient(conn).GetStatus(ctx, req.(*v1.GetStatusRequest))
+		return v1.NewNodeClient(conn).GetStatus(ctx, req.(*v1.GetStatusRequest), opts...)
 
 	// Mesh API
 	case v1.Mesh_GetNode_FullMethodName:
-		return v1.NewMeshClient(conn).GetNode(ctx, req.(*v1.GetNodeRequest))
+		return v1.NewMeshClient(conn).GetNode(ctx, req.(*v1.GetNodeRequest), opts...)
 	case v1.Mesh_ListNodes_FullMethodName:
-		return v1.NewMeshClient(conn).ListNodes(ctx, req.(*emptypb.Empty))
+		return v1.NewMeshClient(conn).ListNodes(ctx, req.(*emptypb.Empty), opts...)
 	case v1.Mesh_GetMeshGraph_FullMethodName:
-		return v1.NewMeshClient(conn).GetMeshGraph(ctx, req.(*emptypb.Empty))
+		return v1.NewMeshClient(conn).GetMeshGraph(ctx, req.(*emptypb.Empty), opts...)
 
 	// Peer Discovery API
 	case v1.PeerDiscovery_ListPeers_FullMethodName:
-		return v1.NewPeerDiscoveryClient(conn).ListPeers(ctx, req.(*emptypb.Empty))
+		return v1.NewPeerDiscoveryClient(conn).ListPeers(ctx, req.(*emptypb.Empty), opts...)
 
 	// Admin API
 	case v1.Admin_PutRole_FullMethodName:
-		return v1.NewAdminClient(conn).PutRole(ctx, req.(*v1.Role))
+		return v1.NewAdminClient(conn).PutRole(ctx, req.(*v1.Role), opts...)
 	case v1.Admin_DeleteRole_FullMethodName:
-		return v1.NewAdminClient(conn).DeleteRole(ctx, req.(*v1.Role))
+		return v1.NewAdminClient(conn).DeleteRole(ctx, req.(*v1.Role), opts...)
 	case v1.Admin_GetRole_FullMethodName:
-		return v1.NewAdminClient(conn).GetRole(ctx, req.(*v1.Role))
+		return v1.NewAdminClient(conn).GetRole(ctx, req.(*v1.Role), opts...)
 	case v1.Admin_ListRoles_FullMethodName:
-		return v1.NewAdminClient(conn).ListRoles(ctx, req.(*emptypb.Empty))
+		return v1.NewAdminClient(conn).ListRoles(ctx, req.(*emptypb.Empty), opts...)
 
 	case v1.Admin_PutRoleBinding_FullMethodName:
-		return v1.NewAdminClient(conn).PutRoleBinding(ctx, req.(*v1.RoleBinding))
+		return v1.NewAdminClient(conn).PutRoleBinding(ctx, req.(*v1.RoleBinding), opts...)
 	case v1.Admin_DeleteRoleBinding_FullMethodName:
-		return v1.NewAdminClient(conn).DeleteRoleBinding(ctx, req.(*v1.RoleBinding))
+		return v1.NewAdminClient(conn).DeleteRoleBinding(ctx, req.(*v1.RoleBinding), opts...)
 	case v1.Admin_GetRoleBinding_FullMethodName:
-		return v1.NewAdminClient(conn).GetRoleBinding(ctx, req.(*v1.RoleBinding))
+		return v1.NewAdminClient(conn).GetRoleBinding(ctx, req.(*v1.RoleBinding), opts...)
 	case v1.Admin_ListRoleBindings_FullMethodName:
-		return v1.NewAdminClient(conn).ListRoleBindings(ctx, req.(*emptypb.Empty))
+		return v1.NewAdminClient(conn).ListRoleBindings(ctx, req.(*emptypb.Empty), opts...)
 
 	case v1.Admin_PutGroup_FullMethodName:
-		return v1.NewAdminClient(conn).PutGroup(ctx, req.(*v1.Group))
+		return v1.NewAdminClient(conn).PutGroup(ctx, req.(*v1.Group), opts...)
 	case v1.Admin_DeleteGroup_FullMethodName:
-		return v1.NewAdminClient(conn).DeleteGroup(ctx, req.(*v1.Group))
+		return v1.NewAdminClient(conn).DeleteGroup(ctx, req.(*v1.Group), opts...)
 	case v1.Admin_GetGroup_FullMethodName:
-		return v1.NewAdminClient(conn).GetGroup(ctx, req.(*v1.Group))
+		return v1.NewAdminClient(conn).GetGroup(ctx, req.(*v1.Group), opts...)
 	case v1.Admin_ListGroups_FullMethodName:
-		return v1.NewAdminClient(conn).ListGroups(ctx, req.(*emptypb.Empty))
+		return v1.NewAdminClient(conn).ListGroups(ctx, req.(*emptypb.Empty), opts...)
 
 	case v1.Admin_PutNetworkACL_FullMethodName:
-		return v1.NewAdminClient(conn).PutNetworkACL(ctx, req.(*v1.NetworkACL))
+		return v1.NewAdminClient(conn).PutNetworkACL(ctx, req.(*v1.NetworkACL), opts...)
 	case v1.Admin_DeleteNetworkACL_FullMethodName:
-		return v1.NewAdminClient(conn).DeleteNetworkACL(ctx, req.(*v1.NetworkACL))
+		return v1.NewAdminClient(conn).DeleteNetworkACL(ctx, req.(*v1.NetworkACL), opts...)
 	case v1.Admin_GetNetworkACL_FullMethodName:
-		return v1.NewAdminClient(conn).GetNetworkACL(ctx, req.(*v1.NetworkACL))
+		return v1.NewAdminClient(conn).GetNetworkACL(ctx, req.(*v1.NetworkACL), opts...)
 	case v1.Admin_ListNetworkACLs_FullMethodName:
-		return v1.NewAdminClient(conn).ListNetworkACLs(ctx, req.(*emptypb.Empty))
+		return v1.NewAdminClient(conn).ListNetworkACLs(ctx, req.(*emptypb.Empty), opts...)
 
 	case v1.Admin_PutRoute_FullMethodName:
-		return v1.NewAdminClient(conn).PutRoute(ctx, req.(*v1.Route))
+		return v1.NewAdminClient(conn).PutRoute(ctx, req.(*v1.Route), opts...)
 	case v1.Admin_DeleteRoute_FullMethodName:
-		return v1.NewAdminClient(conn).DeleteRoute(ctx, req.(*v1.Route))
+		return v1.NewAdminClient(conn).DeleteRoute(ctx, req.(*v1.Route), opts...)
 	case v1.Admin_GetRoute_FullMethodName:
-		return v1.NewAdminClient(conn).GetRoute(ctx, req.(*v1.Route))
+		return v1.NewAdminClient(conn).GetRoute(ctx, req.(*v1.Route), opts...)
 	case v1.Admin_ListRoutes_FullMethodName:
-		return v1.NewAdminClient(conn).ListRoutes(ctx, req.(*emptypb.Empty))
+		return v1.NewAdminClient(conn).ListRoutes(ctx, req.(*emptypb.Empty), opts...)
 
 	case v1.Admin_PutEdge_FullMethodName:
-		return v1.NewAdminClient(conn).PutEdge(ctx, req.(*v1.MeshEdge))
+		return v1.NewAdminClient(conn).PutEdge(ctx, req.(*v1.MeshEdge), opts...)
 	case v1.Admin_DeleteEdge_FullMethodName:
-		return v1.NewAdminClient(conn).DeleteEdge(ctx, req.(*v1.MeshEdge))
+		return v1.NewAdminClient(conn).DeleteEdge(ctx, req.(*v1.MeshEdge), opts...)
 	case v1.Admin_GetEdge_FullMethodName:
-		return v1.NewAdminClient(conn).GetEdge(ctx, req.(*v1.MeshEdge))
+		return v1.NewAdminClient(conn).GetEdge(ctx, req.(*v1.MeshEdge), opts...)
 	case v1.Admin_ListEdges_FullMethodName:
-		return v1.NewAdminClient(conn).ListEdges(ctx, req.(*emptypb.Empty))
+		return v1.NewAdminClient(conn).ListEdges(ctx, req.(*emptypb.Empty), opts...)
+
+	// Maintenance API
+	case maintenance.Maintenance_RebuildIndexes_FullMethodName:
+		return maintenance.NewMaintenanceClient(conn).RebuildIndexes(ctx, req.(*emptypb.Empty), opts...)
+
+	// Labels API
+	case labels.Labels_LabelNode_FullMethodName:
+		return labels.NewLabelsClient(conn).LabelNode(ctx, req.(*labels.LabelNodeRequest), opts...)
+
+	// Topology API
+	case topology.Topology_PutPolicy_FullMethodName:
+		return topology.NewTopologyClient(conn).PutPolicy(ctx, req.(*topology.Policy), opts...)
+	case topology.Topology_DeletePolicy_FullMethodName:
+		return topology.NewTopologyClient(conn).DeletePolicy(ctx, req.(*topology.Policy), opts...)
+	case topology.Topology_GetPolicy_FullMethodName:
+		return topology.NewTopologyClient(conn).GetPolicy(ctx, req.(*topology.Policy), opts...)
+	case topology.Topology_ListPolicies_FullMethodName:
+		return topology.NewTopologyClient(conn).ListPolicies(ctx, req.(*emptypb.Empty), opts...)
+
+	// Leases API
+	case leases.Leases_Grant_FullMethodName:
+		return leases.NewLeasesClient(conn).Grant(ctx, req.(*leases.GrantLeaseRequest), opts...)
+
+	// Edge Metrics API
+	case edgemetrics.EdgeMetrics_Report_FullMethodName:
+		return edgemetrics.NewEdgeMetricsClient(conn).Report(ctx, req.(*edgemetrics.Report), opts...)
+
+	// Leadership API
+	case leadership.Leadership_TransferLeadership_FullMethodName:
+		return leadership.NewLeadershipClient(conn).TransferLeadership(ctx, req.(*leadership.TransferLeadershipRequest), opts...)
+
+	// Membership API
+	case membership.Membership_ListServers_FullMethodName:
+		return membership.NewMembershipClient(conn).ListServers(ctx, req.(*emptypb.Empty), opts...)
+	case membership.Membership_AddServer_FullMethodName:
+		return membership.NewMembershipClient(conn).AddServer(ctx, req.(*membership.AddServerRequest), opts...)
+	case membership.Membership_RemoveServer_FullMethodName:
+		return membership.NewMembershipClient(conn).RemoveServer(ctx, req.(*membership.ServerRequest), opts...)
+	case membership.Membership_PromoteVoter_FullMethodName:
+		return membership.NewMembershipClient(conn).PromoteVoter(ctx, req.(*membership.ServerRequest), opts...)
+	case membership.Membership_DemoteVoter_FullMethodName:
+		return membership.NewMembershipClient(conn).DemoteVoter(ctx, req.(*membership.ServerRequest), opts...)
+	case membership.Membership_ForceRemoveServer_FullMethodName:
+		return membership.NewMembershipClient(conn).ForceRemoveServer(ctx, req.(*membership.ServerRequest), opts...)
 
 	default:
 		return nil, status.Errorf(codes.Unimplemented, "unimplemented leader-proxy method: %s", info.FullMethod)
@@ -206,6 +326,13 @@ func (i *Interceptor) proxyStreamToLeader(srv any, ss grpc.ServerStream, info *g
 	if peer, ok := context.AuthenticatedCallerFrom(ctx); ok {
 		ctx = metadata.AppendToOutgoingContext(ctx, ProxiedForMeta, peer)
 	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, key := range forwardedMeta {
+			if value := md.Get(key); len(value) > 0 {
+				ctx = metadata.AppendToOutgoingContext(ctx, key, value[0])
+			}
+		}
+	}
 	switch info.FullMethod {
 	case v1.WebRTC_StartDataChannel_FullMethodName:
 		client := v1.NewWebRTCClient(conn)
@@ -214,6 +341,12 @@ func (i *Interceptor) proxyStreamToLeader(srv any, ss grpc.ServerStream, info *g
 			return err
 		}
 		return proxyStream[v1.StartDataChannelRequest, v1.DataChannelOffer](ctx, ss, stream)
+	case leases.Leases_KeepAlive_FullMethodName:
+		stream, err := leases.NewLeasesClient(conn).KeepAlive(ctx)
+		if err != nil {
+			return err
+		}
+		return proxyStream[leases.KeepAliveRequest, leases.KeepAliveResponse](ctx, ss, stream)
 	default:
 		return status.Errorf(codes.Unimplemented, "unimplemented leader-proxy method: %s", info.FullMethod)
 	}
@@ -236,7 +369,7 @@ func proxyStream[S, R any](ctx context.Context, ss grpc.ServerStream, cs grpc.Cl
 				context.LoggerFrom(ctx).Error("error receiving message from leader", slog.String("error", err.Error()))
 				return
 			}
-			if err := ss.SendMsg(msg); err != nil {
+			if err := ss.SendMsg(&msg); err != nil {
 				context.LoggerFrom(ctx).Error("error sending message to client", slog.String("error", err.Error()))
 				return
 			}
@@ -251,7 +384,7 @@ func proxyStream[S, R any](ctx context.Context, ss grpc.ServerStream, cs grpc.Cl
 			context.LoggerFrom(ctx).Error("error receiving message from client", slog.String("error", err.Error()))
 			return err
 		}
-		if err := cs.SendMsg(msg); err != nil {
+		if err := cs.SendMsg(&msg); err != nil {
 			context.LoggerFrom(ctx).Error("error sending message to leader", slog.String("error", err.Error()))
 			return err
 		}
@@ -18,8 +18,13 @@ package leaderproxy
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+
+	"github.com/webmeshproj/webmesh/pkg/storage"
 )
 
 const (
@@ -29,8 +34,191 @@ const (
 	ProxiedFromMeta = "x-webmesh-proxied-from"
 	// ProxiedForMeta is the metadata key for the Proxied-For header.
 	ProxiedForMeta = "x-webmesh-proxied-for"
+	// RevisionMeta is the metadata key for the Revision header. It is returned
+	// in the response headers of reads of individual resources.
+	RevisionMeta = "x-webmesh-revision"
+	// IfRevisionMeta is the metadata key for the If-Revision header. When set on
+	// a write, the write only succeeds if the resource is still at the given revision.
+	IfRevisionMeta = "x-webmesh-if-revision"
+	// LimitMeta is the metadata key for the Limit header. When set on a list,
+	// at most the given number of items are returned.
+	LimitMeta = "x-webmesh-limit"
+	// ContinueMeta is the metadata key for the Continue header. When set on a
+	// list, the listing resumes from the given token. It is returned in the
+	// response headers of a list when more items are available.
+	ContinueMeta = "x-webmesh-continue"
+	// LeaseMeta is the metadata key for the Lease header. When set on a join,
+	// the node's registration is attached to the given lease and removed when
+	// the lease expires.
+	LeaseMeta = "x-webmesh-lease"
+	// ConsistencyMeta is the metadata key for the Consistency header. It sets the
+	// read consistency required of requests that may be served by non-leaders.
+	ConsistencyMeta = "x-webmesh-consistency"
+	// MaxLagMeta is the metadata key for the Max-Lag header. It sets the number of
+	// committed entries a follower may lag behind for bounded-staleness reads.
+	MaxLagMeta = "x-webmesh-max-lag"
+	// LabelsMeta is the metadata key for the Labels header. When set on a join,
+	// the node is registered with the given comma separated key=value labels.
+	LabelsMeta = "x-webmesh-labels"
+	// LabelSelectorMeta is the metadata key for the Label-Selector header. When
+	// set on a list of nodes, only the nodes matching the selector are returned.
+	LabelSelectorMeta = "x-webmesh-label-selector"
+)
+
+// Consistency is the read consistency requested by a caller.
+type Consistency string
+
+const (
+	// ConsistencyAny serves reads from any node regardless of how far behind it is.
+	ConsistencyAny Consistency = "any"
+	// ConsistencyBoundedStaleness serves reads from a follower only if it is within
+	// the maximum lag of the leader's commit index and has heard from the leader recently.
+	ConsistencyBoundedStaleness Consistency = "bounded-staleness"
+	// ConsistencyLinearizable serves reads from the leader after confirming its
+	// leadership and applying everything committed before the read.
+	ConsistencyLinearizable Consistency = "linearizable"
 )
 
+// DefaultMaxLag is the default number of entries a follower may lag behind
+// the leader for bounded-staleness reads.
+const DefaultMaxLag = 16
+
+// ParseConsistency parses a read consistency. An empty string is treated as
+// ConsistencyAny.
+func ParseConsistency(s string) (Consistency, error) {
+	switch c := Consistency(s); c {
+	case "":
+		return ConsistencyAny, nil
+	case ConsistencyAny, ConsistencyBoundedStaleness, ConsistencyLinearizable:
+		return c, nil
+	default:
+		return "", fmt.Errorf("invalid consistency %q: must be one of %s, %s, or %s",
+			s, ConsistencyAny, ConsistencyBoundedStaleness, ConsistencyLinearizable)
+	}
+}
+
+// forwardedMeta are the request headers forwarded to the leader.
+var forwardedMeta = []string{IfRevisionMeta, LimitMeta, ContinueMeta, LeaseMeta, ConsistencyMeta, MaxLagMeta, LabelsMeta, LabelSelectorMeta}
+
+// relayedMeta are the response headers relayed back from the leader.
+var relayedMeta = []string{RevisionMeta, ContinueMeta}
+
+// IfRevision returns the revision from the If-Revision header. If the header
+// is not set then false is returned.
+func IfRevision(ctx context.Context) (uint64, bool, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, false, nil
+	}
+	ifRevision := md.Get(IfRevisionMeta)
+	if len(ifRevision) == 0 || ifRevision[0] == "" {
+		return 0, false, nil
+	}
+	rev, err := strconv.ParseUint(ifRevision[0], 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid %s header: %w", IfRevisionMeta, err)
+	}
+	return rev, true, nil
+}
+
+// ListPage returns the page requested by the Limit and Continue headers.
+// If neither header is set then false is returned.
+func ListPage(ctx context.Context) (storage.Page, bool, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return storage.Page{}, false, nil
+	}
+	var page storage.Page
+	var set bool
+	if limit := md.Get(LimitMeta); len(limit) > 0 && limit[0] != "" {
+		n, err := strconv.Atoi(limit[0])
+		if err != nil || n < 0 {
+			return storage.Page{}, false, fmt.Errorf("invalid %s header: %q", LimitMeta, limit[0])
+		}
+		page.Limit = n
+		set = true
+	}
+	if token := md.Get(ContinueMeta); len(token) > 0 && token[0] != "" {
+		page.Continue = token[0]
+		set = true
+	}
+	return page, set, nil
+}
+
+// SendContinue returns the token for the next page of a list to the caller
+// in the response headers. Nothing is sent for the last page.
+func SendContinue(ctx context.Context, next string) error {
+	if next == "" {
+		return nil
+	}
+	return grpc.SetHeader(ctx, metadata.Pairs(ContinueMeta, next))
+}
+
+// Lease returns the lease ID from the Lease header. If the header is not
+// set then false is returned.
+func Lease(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok {
+		lease := md.Get(LeaseMeta)
+		if len(lease) > 0 && lease[0] != "" {
+			return lease[0], true
+		}
+	}
+	return "", false
+}
+
+// Labels returns the labels from the Labels header. If the header is not set
+// then false is returned.
+func Labels(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok {
+		labels := md.Get(LabelsMeta)
+		if len(labels) > 0 && labels[0] != "" {
+			return labels[0], true
+		}
+	}
+	return "", false
+}
+
+// LabelSelector returns the selector from the Label-Selector header. If the
+// header is not set then false is returned.
+func LabelSelector(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok {
+		selector := md.Get(LabelSelectorMeta)
+		if len(selector) > 0 && selector[0] != "" {
+			return selector[0], true
+		}
+	}
+	return "", false
+}
+
+// ReadConsistency returns the read consistency and maximum lag requested by the
+// Consistency and Max-Lag headers. ConsistencyAny and DefaultMaxLag are returned
+// when the headers are not set.
+func ReadConsistency(ctx context.Context) (Consistency, uint64, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ConsistencyAny, DefaultMaxLag, nil
+	}
+	var consistency string
+	if c := md.Get(ConsistencyMeta); len(c) > 0 {
+		consistency = c[0]
+	}
+	c, err := ParseConsistency(consistency)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid %s header: %w", ConsistencyMeta, err)
+	}
+	maxLag := uint64(DefaultMaxLag)
+	if lag := md.Get(MaxLagMeta); len(lag) > 0 && lag[0] != "" {
+		maxLag, err = strconv.ParseUint(lag[0], 10, 64)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid %s header: %w", MaxLagMeta, err)
+		}
+	}
+	return c, maxLag, nil
+}
+
 // HasPreferLeaderMeta returns true if the context has the Prefer-Leader header set to true.
 func HasPreferLeaderMeta(ctx context.Context) bool {
 	md, ok := metadata.FromIncomingContext(ctx)
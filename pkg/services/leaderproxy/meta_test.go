@@ -0,0 +1,86 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderproxy
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestReadConsistency(t *testing.T) {
+	tc := []struct {
+		name        string
+		md          metadata.MD
+		consistency Consistency
+		maxLag      uint64
+		wantErr     bool
+	}{
+		{
+			name:        "no metadata",
+			consistency: ConsistencyAny,
+			maxLag:      DefaultMaxLag,
+		},
+		{
+			name:        "linearizable",
+			md:          metadata.Pairs(ConsistencyMeta, "linearizable"),
+			consistency: ConsistencyLinearizable,
+			maxLag:      DefaultMaxLag,
+		},
+		{
+			name:        "bounded staleness with max lag",
+			md:          metadata.Pairs(ConsistencyMeta, "bounded-staleness", MaxLagMeta, "3"),
+			consistency: ConsistencyBoundedStaleness,
+			maxLag:      3,
+		},
+		{
+			name:    "invalid consistency",
+			md:      metadata.Pairs(ConsistencyMeta, "strong"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid max lag",
+			md:      metadata.Pairs(ConsistencyMeta, "bounded-staleness", MaxLagMeta, "-1"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+			consistency, maxLag, err := ReadConsistency(ctx)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if consistency != tt.consistency {
+				t.Errorf("expected consistency %q, got %q", tt.consistency, consistency)
+			}
+			if maxLag != tt.maxLag {
+				t.Errorf("expected max lag %d, got %d", tt.maxLag, maxLag)
+			}
+		})
+	}
+}
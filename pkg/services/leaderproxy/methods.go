@@ -18,6 +18,15 @@ package leaderproxy
 
 import (
 	v1 "github.com/webmeshproj/api/v1"
+
+	"github.com/webmeshproj/webmesh/pkg/services/edgemetrics"
+	"github.com/webmeshproj/webmesh/pkg/services/labels"
+	"github.com/webmeshproj/webmesh/pkg/services/leadership"
+	"github.com/webmeshproj/webmesh/pkg/services/leases"
+	"github.com/webmeshproj/webmesh/pkg/services/maintenance"
+	"github.com/webmeshproj/webmesh/pkg/services/membership"
+	"github.com/webmeshproj/webmesh/pkg/services/rafttransport"
+	"github.com/webmeshproj/webmesh/pkg/services/topology"
 )
 
 // MethodPolicy defines the policy for routing requests to the leader.
@@ -84,4 +93,37 @@ var MethodPolicyMap = map[string]MethodPolicy{
 	v1.Admin_DeleteEdge_FullMethodName: RequireLeader,
 	v1.Admin_GetEdge_FullMethodName:    AllowNonLeader,
 	v1.Admin_ListEdges_FullMethodName:  AllowNonLeader,
+
+	// Maintenance API
+	maintenance.Maintenance_RebuildIndexes_FullMethodName: RequireLeader,
+
+	// Labels API
+	labels.Labels_LabelNode_FullMethodName: RequireLeader,
+
+	// Topology API
+	topology.Topology_PutPolicy_FullMethodName:    RequireLeader,
+	topology.Topology_DeletePolicy_FullMethodName: RequireLeader,
+	topology.Topology_GetPolicy_FullMethodName:    AllowNonLeader,
+	topology.Topology_ListPolicies_FullMethodName: AllowNonLeader,
+
+	// Raft Transport
+	rafttransport.RaftTransport_Stream_FullMethodName: RequireLocal,
+
+	// Leases API
+	leases.Leases_Grant_FullMethodName:     RequireLeader,
+	leases.Leases_KeepAlive_FullMethodName: RequireLeader,
+
+	// Edge Metrics API
+	edgemetrics.EdgeMetrics_Report_FullMethodName: RequireLeader,
+
+	// Leadership API
+	leadership.Leadership_TransferLeadership_FullMethodName: RequireLeader,
+
+	// Membership API
+	membership.Membership_ListServers_FullMethodName:       RequireLeader,
+	membership.Membership_AddServer_FullMethodName:         RequireLeader,
+	membership.Membership_RemoveServer_FullMethodName:      RequireLeader,
+	membership.Membership_PromoteVoter_FullMethodName:      RequireLeader,
+	membership.Membership_DemoteVoter_FullMethodName:       RequireLeader,
+	membership.Membership_ForceRemoveServer_FullMethodName: RequireLeader,
 }
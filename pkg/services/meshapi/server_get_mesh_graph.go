@@ -41,9 +41,10 @@ func (s *Server) GetMeshGraph(ctx context.Context, _ *emptypb.Empty) (*v1.MeshGr
 	}
 	for i, edge := range edges {
 		out.Edges[i] = &v1.MeshEdge{
-			Source: edge.Source,
-			Target: edge.Target,
-			Weight: int32(edge.Properties.Weight),
+			Source:     edge.Source,
+			Target:     edge.Target,
+			Weight:     int32(edge.Properties.Weight),
+			Attributes: edge.Properties.Attributes,
 		}
 	}
 	var buf bytes.Buffer
@@ -17,6 +17,7 @@ limitations under the License.
 package meshapi
 
 import (
+	"errors"
 	"log/slog"
 
 	v1 "github.com/webmeshproj/api/v1"
@@ -25,12 +26,42 @@ import (
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	"github.com/webmeshproj/webmesh/pkg/services/leaderproxy"
+	"github.com/webmeshproj/webmesh/pkg/storage"
 )
 
 func (s *Server) ListNodes(ctx context.Context, req *emptypb.Empty) (*v1.NodeList, error) {
-	node, err := s.peers.List(ctx)
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to get node: %v", err)
+	var node []peers.Node
+	if selector, ok := leaderproxy.LabelSelector(ctx); ok {
+		// Selected nodes are returned in a single page.
+		if _, paged, err := leaderproxy.ListPage(ctx); err != nil || paged {
+			return nil, status.Error(codes.InvalidArgument, "a label selector cannot be combined with a limit or continue token")
+		}
+		sel, err := peers.ParseSelector(selector)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		node, err = s.peers.ListBySelector(ctx, sel)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to list nodes: %v", err)
+		}
+	} else {
+		page, _, err := leaderproxy.ListPage(ctx)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		var next string
+		node, next, err = s.peers.ListPage(ctx, page)
+		if err != nil {
+			if errors.Is(err, storage.ErrInvalidContinueToken) {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+			return nil, status.Errorf(codes.Internal, "failed to get node: %v", err)
+		}
+		if err := leaderproxy.SendContinue(ctx, next); err != nil {
+			context.LoggerFrom(ctx).Warn("failed to set continue header", slog.String("error", err.Error()))
+		}
 	}
 	servers := s.store.Raft().Configuration().Servers
 	leader, err := s.store.Leader()
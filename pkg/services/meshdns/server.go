@@ -32,6 +32,7 @@ import (
 	"github.com/webmeshproj/webmesh/pkg/meshdb"
 	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
 	dnsutil "github.com/webmeshproj/webmesh/pkg/net/system/dns"
+	"github.com/webmeshproj/webmesh/pkg/storage"
 )
 
 // Options are the Mesh DNS server options.
@@ -141,7 +142,7 @@ func (s *Server) RegisterDomain(opts DomainOptions) error {
 		s.meshmuxes = append(s.meshmuxes, mux)
 	}
 	if opts.SubscribeForwarders {
-		cancel, err := opts.Mesh.Storage().Subscribe(context.Background(), peers.NodesPrefix, func(_, _ string) {
+		cancel, err := opts.Mesh.Storage().Watch(context.Background(), peers.NodesPrefix, 0, func(_ storage.Event) {
 			peers, err := peers.New(opts.Mesh.Storage()).ListByFeature(context.Background(), v1.Feature_FORWARD_MESH_DNS)
 			if err != nil {
 				s.log.Warn("failed to lookup peers with forward meshdns", slog.String("error", err.Error()))
@@ -28,15 +28,14 @@ import (
 	"google.golang.org/grpc/status"
 
 	"github.com/webmeshproj/webmesh/pkg/context"
-	meshraft "github.com/webmeshproj/webmesh/pkg/raft"
 )
 
 func (s *Server) Apply(ctx context.Context, log *v1.RaftLogEntry) (*v1.RaftApplyResponse, error) {
 	if !s.store.Raft().IsLeader() {
 		return nil, status.Errorf(codes.FailedPrecondition, "not leader")
 	}
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	// No lock is taken here. Log entries are serialized by raft and any
+	// conditions they carry are evaluated when they are applied.
 	start := time.Now()
 	peer, ok := peer.FromContext(ctx)
 	if !ok {
@@ -72,19 +71,19 @@ func (s *Server) Apply(ctx context.Context, log *v1.RaftLogEntry) (*v1.RaftApply
 	defer func() {
 		_, _ = s.store.Raft().Barrier(ctx, time.Second*15)
 	}()
-	data, err := meshraft.MarshalLogEntry(log)
+	applyCtx, cancel := context.WithTimeout(ctx, time.Second*15)
+	defer cancel()
+	// Return any error from applying the entry to the FSM, such as a
+	// failed conditional put, so that it reaches the caller.
+	res, err := s.store.Raft().ApplyLogEntry(applyCtx, log)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "marshal log entry: %v", err)
+		return &v1.RaftApplyResponse{
+			Time:  time.Since(start).String(),
+			Error: err.Error(),
+		}, nil
 	}
-	timeout := time.Second * 15
-	err = s.store.Raft().Raft().Apply(data, timeout).Error()
-	return &v1.RaftApplyResponse{
-		Time: time.Since(start).String(),
-		Error: func() string {
-			if err == nil {
-				return ""
-			}
-			return err.Error()
-		}(),
-	}, nil
+	// The response is returned as is so that the code of any error it
+	// carries reaches the caller.
+	res.Time = time.Since(start).String()
+	return res, nil
 }
@@ -22,11 +22,12 @@ import (
 	v1 "github.com/webmeshproj/api/v1"
 
 	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/networking"
 	"github.com/webmeshproj/webmesh/pkg/services/leaderproxy"
 )
 
-func (s *Server) ensurePeerRoutes(ctx context.Context, nodeID string, routes []string) (created bool, err error) {
-	current, err := s.networking.GetRoutesByNode(ctx, nodeID)
+func (s *Server) ensurePeerRoutes(ctx context.Context, nw networking.Networking, nodeID string, routes []string) (created bool, err error) {
+	current, err := nw.GetRoutesByNode(ctx, nodeID)
 	if err != nil {
 		return false, fmt.Errorf("get routes for node %q: %w", nodeID, err)
 	}
@@ -46,7 +47,7 @@ Routes:
 			DestinationCidrs: routes,
 		}
 		context.LoggerFrom(ctx).Debug("Adding new route for node", "node", nodeID, "route", &rt)
-		err = s.networking.PutRoute(ctx, &rt)
+		err = nw.PutRoute(ctx, &rt)
 		if err != nil {
 			return true, fmt.Errorf("put route for node %q: %w", nodeID, err)
 		}
@@ -60,6 +61,8 @@ func nodeAutoRoute(nodeID string) string {
 }
 
 func (s *Server) loadMeshState(ctx context.Context) error {
+	s.statemu.Lock()
+	defer s.statemu.Unlock()
 	var err error
 	if !s.ipv6Prefix.IsValid() {
 		context.LoggerFrom(ctx).Debug("Looking up mesh IPv6 prefix")
@@ -0,0 +1,97 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/webmeshproj/webmesh/pkg/storage"
+
+	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	"github.com/webmeshproj/webmesh/pkg/services/edgemetrics"
+)
+
+var _ edgemetrics.EdgeMetricsServer = (*Server)(nil)
+
+// Report folds the measurements a node took of the links to its direct
+// peers into the attributes of the edges between them. The edges of a
+// report are written in a single batch.
+func (s *Server) Report(ctx context.Context, report *edgemetrics.Report) (*emptypb.Empty, error) {
+	if !s.store.Raft().IsLeader() {
+		return nil, status.Errorf(codes.FailedPrecondition, "not leader")
+	}
+	if report.GetNode() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "report is missing the node ID")
+	}
+	if !s.insecure && !nodeIDMatchesContext(ctx, report.GetNode()) {
+		return nil, status.Errorf(codes.PermissionDenied, "node id %s does not match authenticated caller", report.GetNode())
+	}
+	graph := peers.New(s.store.Storage()).Graph()
+	var ops []storage.Op
+	for peer, in := range report.GetPeers() {
+		m, err := measurementFromProto(in)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid measurement for peer %q: %v", peer, err)
+		}
+		edgeOps, err := peers.RecordMeasurementOps(graph, report.GetNode(), peer, m)
+		if err != nil {
+			if errors.Is(err, peers.ErrEdgeNotFound) {
+				// The edge was removed after the node took the measurement.
+				s.log.Debug("Ignoring measurement of missing edge", slog.String("node", report.GetNode()), slog.String("peer", peer))
+				continue
+			}
+			return nil, status.Errorf(codes.Internal, "failed to record measurement: %v", err)
+		}
+		ops = append(ops, edgeOps...)
+	}
+	if len(ops) == 0 {
+		return &emptypb.Empty{}, nil
+	}
+	if err := s.store.Storage().Batch(ctx, ops); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to record measurements: %v", err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func measurementFromProto(in *edgemetrics.Measurement) (peers.Measurement, error) {
+	var m peers.Measurement
+	if err := in.GetMeasuredAt().CheckValid(); err != nil {
+		return m, fmt.Errorf("measured at: %w", err)
+	}
+	m.MeasuredAt = in.GetMeasuredAt().AsTime()
+	m.Loss = in.GetLoss()
+	if m.Loss < 0 || m.Loss > 1 {
+		return m, fmt.Errorf("loss must be between 0 and 1")
+	}
+	if in.GetRtt() != nil {
+		if err := in.GetRtt().CheckValid(); err != nil {
+			return m, fmt.Errorf("rtt: %w", err)
+		}
+		m.RTT = in.GetRtt().AsDuration()
+	}
+	if m.RTT < 0 {
+		return m, fmt.Errorf("rtt cannot be negative")
+	}
+	return m, nil
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package node
 
 import (
+	"errors"
 	"log/slog"
 	"net"
 	"net/netip"
@@ -29,12 +30,20 @@ import (
 	"google.golang.org/grpc/status"
 
 	"github.com/webmeshproj/webmesh/pkg/context"
+	leasesdb "github.com/webmeshproj/webmesh/pkg/meshdb/leases"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/networking"
 	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/state"
 	"github.com/webmeshproj/webmesh/pkg/net/mesh"
 	"github.com/webmeshproj/webmesh/pkg/services/leaderproxy"
 	"github.com/webmeshproj/webmesh/pkg/services/rbac"
+	"github.com/webmeshproj/webmesh/pkg/storage"
 )
 
+// maxRegisterAttempts is the number of times a join retries registering a node
+// that conflicted with a concurrent registration.
+const maxRegisterAttempts = 5
+
 var canVoteAction = &rbac.Action{
 	Verb:     v1.RuleVerb_VERB_PUT,
 	Resource: v1.RuleResource_RESOURCE_VOTES,
@@ -50,12 +59,18 @@ var canPutEdgeAction = &rbac.Action{
 	Resource: v1.RuleResource_RESOURCE_EDGES,
 }
 
+// canLabelNodeAction is required to join with labels. Labels select nodes
+// in role bindings and network ACLs, so setting them takes the same
+// permission as labeling a node through the Labels API.
+var canLabelNodeAction = &rbac.Action{
+	Verb:     v1.RuleVerb_VERB_PUT,
+	Resource: v1.RuleResource_RESOURCE_ALL,
+}
+
 func (s *Server) Join(ctx context.Context, req *v1.JoinRequest) (*v1.JoinResponse, error) {
 	if !s.store.Raft().IsLeader() {
 		return nil, status.Errorf(codes.FailedPrecondition, "not leader")
 	}
-	s.mu.Lock()
-	defer s.mu.Unlock()
 	log := s.log.With("op", "join", "id", req.GetId())
 	ctx = context.WithLogger(ctx, log)
 
@@ -88,6 +103,13 @@ func (s *Server) Join(ctx context.Context, req *v1.JoinRequest) (*v1.JoinRespons
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "invalid public key: %v", err)
 	}
+	var labels map[string]string
+	if rawLabels, ok := leaderproxy.Labels(ctx); ok {
+		labels, err = peers.ParseLabels(rawLabels)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid labels: %v", err)
+		}
+	}
 
 	// Check that the node is indeed who they say they are
 	if !s.insecure {
@@ -109,6 +131,9 @@ func (s *Server) Join(ctx context.Context, req *v1.JoinRequest) (*v1.JoinRespons
 				actions = append(actions, canNegDataChannelAction.For(peer)...)
 			}
 		}
+		if len(labels) > 0 {
+			actions = append(actions, canLabelNodeAction.For(req.GetId()))
+		}
 		if len(actions) > 0 {
 			allowed, err := s.rbacEval.Evaluate(ctx, actions)
 			if err != nil {
@@ -146,21 +171,168 @@ func (s *Server) Join(ctx context.Context, req *v1.JoinRequest) (*v1.JoinRespons
 		return cause
 	}
 
+	// Registration is optimistic. Concurrent joins that are handed the same
+	// addresses conflict when they commit, and the loser starts over.
+	var leasev4, leasev6 netip.Prefix
+	var undo []storage.Op
+	for attempt := 1; ; attempt++ {
+		leasev4, leasev6, undo, err = s.registerPeer(ctx, req, publicKey, labels)
+		if errors.Is(err, storage.ErrRevisionMismatch) && attempt < maxRegisterAttempts {
+			log.Debug("Peer registration conflicted with a concurrent change, retrying", slog.Int("attempt", attempt))
+			continue
+		}
+		if errors.Is(err, storage.ErrRevisionMismatch) {
+			return nil, status.Errorf(codes.Aborted, "failed to persist peer details to raft log: %v", err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+	cleanFuncs = append(cleanFuncs, func() {
+		if err := s.store.Storage().Batch(ctx, undo); err != nil {
+			log.Warn("Failed to revert peer registration", slog.String("error", err.Error()))
+		}
+	})
+
+	// Add peer to the raft cluster
+	var raftAddress string
+	if req.GetAssignIpv4() && !req.GetPreferRaftIpv6() {
+		// Prefer IPv4 for raft
+		raftAddress = net.JoinHostPort(leasev4.Addr().String(), strconv.Itoa(int(req.GetRaftPort())))
+	} else {
+		// Use IPv6
+		// TODO: Doesn't work when we are IPv4 only. Need to fix this.
+		// Basically if a single node is IPv4 only, we need to use IPv4 for raft.
+		// We may as well use IPv4 for everything in that case. Leave it for now,
+		// but need to document these requirements fully for dual-stack setups.
+		// Another option is to create another role of being neither an observer or voter,
+		// in which case another subscription method needs to be exposed for receiving updates.
+		raftAddress = net.JoinHostPort(leasev6.Addr().String(), strconv.Itoa(int(req.GetRaftPort())))
+	}
+	var wasMember bool
+	for _, server := range s.store.Raft().Configuration().Servers {
+		if string(server.ID) == req.GetId() {
+			wasMember = true
+			break
+		}
+	}
+	if req.GetAsVoter() {
+		log.Info("Adding candidate to cluster", slog.String("raft_address", raftAddress))
+		if err := s.store.Raft().AddVoter(ctx, req.GetId(), raftAddress); err != nil {
+			return nil, handleErr(status.Errorf(codes.Internal, "failed to add voter: %v", err))
+		}
+	} else {
+		log.Info("Adding non-voter to cluster", slog.String("raft_address", raftAddress))
+		if err := s.store.Raft().AddNonVoter(ctx, req.GetId(), raftAddress); err != nil {
+			return nil, handleErr(status.Errorf(codes.Internal, "failed to add non-voter: %v", err))
+		}
+	}
+	if !wasMember {
+		cleanFuncs = append(cleanFuncs, func() {
+			err := s.store.Raft().RemoveServer(ctx, req.GetId(), false)
+			if err != nil {
+				log.Warn("Failed to remove voter", slog.String("error", err.Error()))
+			}
+		})
+	}
+	// Start building the response
+	resp := &v1.JoinResponse{
+		MeshDomain:  s.meshDomain,
+		NetworkIpv4: s.ipv4Prefix.String(),
+		NetworkIpv6: s.ipv6Prefix.String(),
+		AddressIpv6: leasev6.String(),
+		AddressIpv4: func() string {
+			if leasev4.IsValid() {
+				return leasev4.String()
+			}
+			return ""
+		}(),
+	}
+	dnsServers, err := s.peers.ListByFeature(ctx, v1.Feature_MESH_DNS)
+	if err != nil {
+		log.Warn("could not lookup DNS servers", slog.String("error", err.Error()))
+	} else {
+		for _, peer := range dnsServers {
+			if peer.ID == req.GetId() {
+				continue
+			}
+			switch {
+			// Prefer the IPv4 address
+			case peer.PrivateDNSAddrV4().IsValid():
+				resp.DnsServers = append(resp.DnsServers, peer.PrivateDNSAddrV4().String())
+			case peer.PrivateDNSAddrV6().IsValid():
+				resp.DnsServers = append(resp.DnsServers, peer.PrivateDNSAddrV6().String())
+			}
+		}
+	}
+	peers, err := mesh.WireGuardPeersFor(ctx, s.store.Storage(), req.GetId())
+	if err != nil {
+		return nil, handleErr(status.Errorf(codes.Internal, "failed to get wireguard peers: %v", err))
+	}
+	var requiresICE bool
+	for _, peer := range peers {
+		if peer.PrimaryEndpoint == "" || peer.Ice {
+			requiresICE = true
+			break
+		}
+	}
+	resp.Peers = peers
+
+	// If the caller needs ICE servers, find all the eligible peers and return them
+	if requiresICE {
+		peers, err := s.peers.ListByFeature(ctx, v1.Feature_ICE_NEGOTIATION)
+		if err != nil {
+			return nil, handleErr(status.Errorf(codes.Internal, "failed to list peers by ICE feature: %v", err))
+		}
+		for _, peer := range peers {
+			if peer.ID == req.GetId() {
+				continue
+			}
+			// We only return peers that are publicly accessible for now.
+			// This should be configurable in the future.
+			publicAddr := peer.PublicRPCAddr()
+			if publicAddr.IsValid() {
+				resp.IceServers = append(resp.IceServers, publicAddr.String())
+			}
+		}
+		if len(resp.IceServers) == 0 {
+			log.Warn("no peers with ICE negotiation feature found, node is on its own")
+		}
+	}
+
+	log.Debug("Sending join response", slog.Any("response", resp))
+	return resp, nil
+}
+
+// registerPeer stages and commits the registration of a joining node. It
+// returns the addresses assigned to the node and the operations that revert
+// the registration. An error wrapping storage.ErrRevisionMismatch is returned
+// if the registration conflicted with a concurrent one and should be retried.
+func (s *Server) registerPeer(ctx context.Context, req *v1.JoinRequest, publicKey wgtypes.Key, labels map[string]string) (netip.Prefix, netip.Prefix, []storage.Op, error) {
+	log := context.LoggerFrom(ctx)
+	// All registry changes for the node are staged in a single transaction
+	// so that a failure part way through does not leave a partially
+	// registered node behind.
+	tx := storage.NewTxn(s.store.Storage())
+	defer tx.Rollback()
+	txpeers := peers.New(tx)
+
 	// Handle any new routes
 	if len(req.GetRoutes()) > 0 {
-		created, err := s.ensurePeerRoutes(ctx, req.GetId(), req.GetRoutes())
+		_, err := s.ensurePeerRoutes(ctx, networking.New(tx), req.GetId(), req.GetRoutes())
 		if err != nil {
-			return nil, handleErr(status.Errorf(codes.Internal, "failed to ensure peer routes: %v", err))
-		} else if created {
-			cleanFuncs = append(cleanFuncs, func() {
-				err := s.networking.DeleteRoute(ctx, nodeAutoRoute(req.GetId()))
-				if err != nil {
-					log.Warn("Failed to delete route", slog.String("error", err.Error()))
-				}
-			})
+			return netip.Prefix{}, netip.Prefix{}, nil, status.Errorf(codes.Internal, "failed to ensure peer routes: %v", err)
 		}
 	}
 
+	// Look up the revision of the allocations key before the addresses are
+	// assigned. It is written back conditionally with the registration, so a
+	// concurrent join that was handed the same addresses fails to commit.
+	_, allocRev, err := s.store.Storage().GetRevision(ctx, state.AllocationsKey)
+	if err != nil && !errors.Is(err, storage.ErrKeyNotFound) {
+		return netip.Prefix{}, netip.Prefix{}, nil, status.Errorf(codes.Internal, "failed to lookup address allocations: %v", err)
+	}
 	var leasev4, leasev6 netip.Prefix
 	// We always try to generate an IPv6 address for the peer, even if they choose not to
 	// use it. This helps enforce an upper bound on the umber of peers we can have in the network
@@ -173,7 +345,7 @@ func (s *Server) Join(ctx context.Context, req *v1.JoinRequest) (*v1.JoinRespons
 		Version: v1.AllocateIPRequest_IP_VERSION_6,
 	})
 	if err != nil {
-		return nil, handleErr(status.Errorf(codes.Internal, "failed to allocate IPv6 address: %v", err))
+		return netip.Prefix{}, netip.Prefix{}, nil, status.Errorf(codes.Internal, "failed to allocate IPv6 address: %v", err)
 	}
 	log.Debug("Assigned IPv6 address to peer", slog.String("ipv6", leasev6.String()))
 	// Acquire an IPv4 address for the peer only if requested
@@ -185,12 +357,31 @@ func (s *Server) Join(ctx context.Context, req *v1.JoinRequest) (*v1.JoinRespons
 			Version: v1.AllocateIPRequest_IP_VERSION_4,
 		})
 		if err != nil {
-			return nil, handleErr(status.Errorf(codes.Internal, "failed to allocate IPv4 address: %v", err))
+			return netip.Prefix{}, netip.Prefix{}, nil, status.Errorf(codes.Internal, "failed to allocate IPv4 address: %v", err)
 		}
 		log.Debug("Assigned IPv4 address to peer", slog.String("ipv4", leasev4.String()))
 	}
+	err = tx.PutIfRevision(ctx, state.AllocationsKey, req.GetId(), allocRev, 0)
+	if err != nil {
+		return netip.Prefix{}, netip.Prefix{}, nil, status.Errorf(codes.Internal, "failed to stage address allocation: %v", err)
+	}
+	// Keep the labels an admin set on a node that is rejoining. They take
+	// precedence over the labels it joins with, while the labels it joined
+	// with before are dropped.
+	var adminLabels map[string]string
+	if existing, err := txpeers.Get(ctx, req.GetId()); err == nil && len(existing.AdminLabels) > 0 {
+		adminLabels = existing.AdminLabels
+		merged := make(map[string]string, len(adminLabels)+len(labels))
+		for k, v := range labels {
+			merged[k] = v
+		}
+		for k, v := range adminLabels {
+			merged[k] = v
+		}
+		labels = merged
+	}
 	// Write the peer to the database
-	err = s.peers.Put(ctx, peers.Node{
+	err = txpeers.Put(ctx, peers.Node{
 		ID:                 req.GetId(),
 		PublicKey:          publicKey,
 		PrimaryEndpoint:    req.GetPrimaryEndpoint(),
@@ -202,16 +393,12 @@ func (s *Server) Join(ctx context.Context, req *v1.JoinRequest) (*v1.JoinRespons
 		Features:           req.GetFeatures(),
 		PrivateIPv4:        leasev4,
 		PrivateIPv6:        leasev6,
+		Labels:             labels,
+		AdminLabels:        adminLabels,
 	})
 	if err != nil {
-		return nil, handleErr(status.Errorf(codes.Internal, "failed to persist peer details to raft log: %v", err))
+		return netip.Prefix{}, netip.Prefix{}, nil, status.Errorf(codes.Internal, "failed to stage peer details: %v", err)
 	}
-	cleanFuncs = append(cleanFuncs, func() {
-		err := s.peers.Delete(ctx, req.GetId())
-		if err != nil {
-			log.Warn("failed to delete peer", slog.String("error", err.Error()))
-		}
-	})
 	// At this point we want to
 	// Add an edge from the joining server to the caller
 	joiningServer := string(s.store.ID())
@@ -219,31 +406,31 @@ func (s *Server) Join(ctx context.Context, req *v1.JoinRequest) (*v1.JoinRespons
 		joiningServer = proxiedFrom
 	}
 	log.Debug("adding edge between caller and joining server", slog.String("joining_server", joiningServer))
-	err = s.peers.PutEdge(ctx, peers.Edge{
+	err = txpeers.PutEdge(ctx, peers.Edge{
 		From:   joiningServer,
 		To:     req.GetId(),
 		Weight: 1,
 	})
 	if err != nil {
-		return nil, handleErr(status.Errorf(codes.Internal, "failed to add edge: %v", err))
+		return netip.Prefix{}, netip.Prefix{}, nil, status.Errorf(codes.Internal, "failed to add edge: %v", err)
 	}
 	if req.GetPrimaryEndpoint() != "" {
 		// Add an edge between the caller and all other nodes with public endpoints
 		// TODO: This should be done according to network policy and batched
-		allPeers, err := s.peers.ListPublicNodes(ctx)
+		allPeers, err := txpeers.ListPublicNodes(ctx)
 		if err != nil {
-			return nil, handleErr(status.Errorf(codes.Internal, "failed to list peers: %v", err))
+			return netip.Prefix{}, netip.Prefix{}, nil, status.Errorf(codes.Internal, "failed to list peers: %v", err)
 		}
 		for _, peer := range allPeers {
 			if peer.ID != req.GetId() && peer.PrimaryEndpoint != "" {
 				log.Debug("adding edge from public peer to public caller", slog.String("peer", peer.ID))
-				err = s.peers.PutEdge(ctx, peers.Edge{
+				err = txpeers.PutEdge(ctx, peers.Edge{
 					From:   peer.ID,
 					To:     req.GetId(),
 					Weight: 99,
 				})
 				if err != nil {
-					return nil, handleErr(status.Errorf(codes.Internal, "failed to add edge: %v", err))
+					return netip.Prefix{}, netip.Prefix{}, nil, status.Errorf(codes.Internal, "failed to add edge: %v", err)
 				}
 			}
 		}
@@ -252,9 +439,9 @@ func (s *Server) Join(ctx context.Context, req *v1.JoinRequest) (*v1.JoinRespons
 		// Add an edge between the caller and all other nodes in the same zone
 		// with public endpoints.
 		// TODO: Same as above - this should be done according to network policy and batched
-		zonePeers, err := s.peers.ListByZoneID(ctx, req.GetZoneAwarenessId())
+		zonePeers, err := txpeers.ListByZoneID(ctx, req.GetZoneAwarenessId())
 		if err != nil {
-			return nil, handleErr(status.Errorf(codes.Internal, "failed to list peers: %v", err))
+			return netip.Prefix{}, netip.Prefix{}, nil, status.Errorf(codes.Internal, "failed to list peers: %v", err)
 		}
 		for _, peer := range zonePeers {
 			if peer.ID == req.GetId() || peer.PrimaryEndpoint == "" {
@@ -262,13 +449,13 @@ func (s *Server) Join(ctx context.Context, req *v1.JoinRequest) (*v1.JoinRespons
 			}
 			log.Debug("Adding edges to peer in the same zone", slog.String("peer", peer.ID))
 			if peer.ID != req.GetId() {
-				err = s.peers.PutEdge(ctx, peers.Edge{
+				err = txpeers.PutEdge(ctx, peers.Edge{
 					From:   peer.ID,
 					To:     req.GetId(),
 					Weight: 1,
 				})
 				if err != nil {
-					return nil, handleErr(status.Errorf(codes.Internal, "failed to add edge: %v", err))
+					return netip.Prefix{}, netip.Prefix{}, nil, status.Errorf(codes.Internal, "failed to add edge: %v", err)
 				}
 			}
 		}
@@ -278,22 +465,22 @@ func (s *Server) Join(ctx context.Context, req *v1.JoinRequest) (*v1.JoinRespons
 		// Put an ICE edge between the caller and all direct peers
 		for _, peer := range req.GetDirectPeers() {
 			// Check if the peer exists
-			_, err := s.peers.Get(ctx, peer)
+			_, err := txpeers.Get(ctx, peer)
 			if err != nil {
 				if err != peers.ErrNodeNotFound {
-					return nil, handleErr(status.Errorf(codes.Internal, "failed to get peer: %v", err))
+					return netip.Prefix{}, netip.Prefix{}, nil, status.Errorf(codes.Internal, "failed to get peer: %v", err)
 				}
 				// The peer doesn't exist, so create a placeholder for it
 				log.Debug("Registering empty peer", slog.String("peer", peer))
-				err = s.peers.Put(ctx, peers.Node{
+				err = txpeers.Put(ctx, peers.Node{
 					ID: peer,
 				})
 				if err != nil {
-					return nil, handleErr(status.Errorf(codes.Internal, "failed to register peer: %v", err))
+					return netip.Prefix{}, netip.Prefix{}, nil, status.Errorf(codes.Internal, "failed to register peer: %v", err)
 				}
 			}
 			log.Debug("Adding ICE edge to peer", slog.String("peer", peer))
-			err = s.peers.PutEdge(ctx, peers.Edge{
+			err = txpeers.PutEdge(ctx, peers.Edge{
 				From:   peer,
 				To:     req.GetId(),
 				Weight: 1,
@@ -302,108 +489,45 @@ func (s *Server) Join(ctx context.Context, req *v1.JoinRequest) (*v1.JoinRespons
 				},
 			})
 			if err != nil {
-				return nil, handleErr(status.Errorf(codes.Internal, "failed to add edge: %v", err))
+				return netip.Prefix{}, netip.Prefix{}, nil, status.Errorf(codes.Internal, "failed to add edge: %v", err)
 			}
 		}
 	}
 
-	// Add peer to the raft cluster
-	var raftAddress string
-	if req.GetAssignIpv4() && !req.GetPreferRaftIpv6() {
-		// Prefer IPv4 for raft
-		raftAddress = net.JoinHostPort(leasev4.Addr().String(), strconv.Itoa(int(req.GetRaftPort())))
-	} else {
-		// Use IPv6
-		// TODO: Doesn't work when we are IPv4 only. Need to fix this.
-		// Basically if a single node is IPv4 only, we need to use IPv4 for raft.
-		// We may as well use IPv4 for everything in that case. Leave it for now,
-		// but need to document these requirements fully for dual-stack setups.
-		// Another option is to create another role of being neither an observer or voter,
-		// in which case another subscription method needs to be exposed for receiving updates.
-		raftAddress = net.JoinHostPort(leasev6.Addr().String(), strconv.Itoa(int(req.GetRaftPort())))
-	}
-	if req.GetAsVoter() {
-		log.Info("Adding candidate to cluster", slog.String("raft_address", raftAddress))
-		if err := s.store.Raft().AddVoter(ctx, req.GetId(), raftAddress); err != nil {
-			return nil, handleErr(status.Errorf(codes.Internal, "failed to add voter: %v", err))
-		}
-	} else {
-		log.Info("Adding non-voter to cluster", slog.String("raft_address", raftAddress))
-		if err := s.store.Raft().AddNonVoter(ctx, req.GetId(), raftAddress); err != nil {
-			return nil, handleErr(status.Errorf(codes.Internal, "failed to add non-voter: %v", err))
-		}
+	// Attach the registration to the caller's lease if they provided one.
+	// A node that rejoins is moved off any lease it registered with before,
+	// so that lease expiring does not remove the new registration.
+	leaseKeys := []string{peers.NodesPrefix + "/" + req.GetId()}
+	if len(req.GetRoutes()) > 0 {
+		leaseKeys = append(leaseKeys, networking.RoutesPrefix+"/"+nodeAutoRoute(req.GetId()))
 	}
-	cleanFuncs = append(cleanFuncs, func() {
-		err := s.store.Raft().RemoveServer(ctx, req.GetId(), false)
+	if leaseID, ok := leaderproxy.Lease(ctx); ok {
+		log.Debug("Attaching registration to lease", slog.String("lease", leaseID))
+		err = leasesdb.New(tx).Attach(ctx, leaseID, leaseKeys...)
 		if err != nil {
-			log.Warn("Failed to remove voter", slog.String("error", err.Error()))
-		}
-	})
-	// Start building the response
-	resp := &v1.JoinResponse{
-		MeshDomain:  s.meshDomain,
-		NetworkIpv4: s.ipv4Prefix.String(),
-		NetworkIpv6: s.ipv6Prefix.String(),
-		AddressIpv6: leasev6.String(),
-		AddressIpv4: func() string {
-			if leasev4.IsValid() {
-				return leasev4.String()
-			}
-			return ""
-		}(),
-	}
-	dnsServers, err := s.peers.ListByFeature(ctx, v1.Feature_MESH_DNS)
-	if err != nil {
-		log.Warn("could not lookup DNS servers", slog.String("error", err.Error()))
-	} else {
-		for _, peer := range dnsServers {
-			if peer.ID == req.GetId() {
-				continue
-			}
-			switch {
-			// Prefer the IPv4 address
-			case peer.PrivateDNSAddrV4().IsValid():
-				resp.DnsServers = append(resp.DnsServers, peer.PrivateDNSAddrV4().String())
-			case peer.PrivateDNSAddrV6().IsValid():
-				resp.DnsServers = append(resp.DnsServers, peer.PrivateDNSAddrV6().String())
+			if errors.Is(err, leasesdb.ErrLeaseNotFound) {
+				return netip.Prefix{}, netip.Prefix{}, nil, status.Errorf(codes.NotFound, "lease %q not found", leaseID)
 			}
+			return netip.Prefix{}, netip.Prefix{}, nil, status.Errorf(codes.Internal, "failed to attach lease: %v", err)
 		}
-	}
-	peers, err := mesh.WireGuardPeersFor(ctx, s.store.Storage(), req.GetId())
-	if err != nil {
-		return nil, handleErr(status.Errorf(codes.Internal, "failed to get wireguard peers: %v", err))
-	}
-	var requiresICE bool
-	for _, peer := range peers {
-		if peer.PrimaryEndpoint == "" || peer.Ice {
-			requiresICE = true
-			break
+	} else {
+		err = leasesdb.New(tx).Detach(ctx, peers.NodesPrefix+"/"+req.GetId(), networking.RoutesPrefix+"/"+nodeAutoRoute(req.GetId()))
+		if err != nil {
+			return netip.Prefix{}, netip.Prefix{}, nil, status.Errorf(codes.Internal, "failed to detach previous lease: %v", err)
 		}
 	}
-	resp.Peers = peers
 
-	// If the caller needs ICE servers, find all the eligible peers and return them
-	if requiresICE {
-		peers, err := s.peers.ListByFeature(ctx, v1.Feature_ICE_NEGOTIATION)
-		if err != nil {
-			return nil, handleErr(status.Errorf(codes.Internal, "failed to list peers by ICE feature: %v", err))
-		}
-		for _, peer := range peers {
-			if peer.ID == req.GetId() {
-				continue
-			}
-			// We only return peers that are publicly accessible for now.
-			// This should be configurable in the future.
-			publicAddr := peer.PublicRPCAddr()
-			if publicAddr.IsValid() {
-				resp.IceServers = append(resp.IceServers, publicAddr.String())
-			}
-		}
-		if len(resp.IceServers) == 0 {
-			log.Warn("no peers with ICE negotiation feature found, node is on its own")
+	// Commit the node, its edges and its routes in a single raft entry, along
+	// with how to restore every key they write, so that a failure later on only
+	// reverts what this join changed. Keys that existed before, such as the
+	// registration of a rejoining node, are put back as they were.
+	log.Debug("Committing peer registration", slog.Int("ops", len(tx.Ops())))
+	undo, err := tx.CommitUndo(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrRevisionMismatch) {
+			return netip.Prefix{}, netip.Prefix{}, nil, err
 		}
+		return netip.Prefix{}, netip.Prefix{}, nil, status.Errorf(codes.Internal, "failed to persist peer details to raft log: %v", err)
 	}
-
-	log.Debug("Sending join response", slog.Any("response", resp))
-	return resp, nil
+	return leasev4, leasev6, undo, nil
 }
@@ -0,0 +1,158 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "github.com/webmeshproj/api/v1"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/webmeshproj/webmesh/pkg/mesh"
+	leasesdb "github.com/webmeshproj/webmesh/pkg/meshdb/leases"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
+	"github.com/webmeshproj/webmesh/pkg/services/leaderproxy"
+)
+
+func TestRejoinWithinLeaseTTL(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := mesh.NewTestMesh(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	srv := NewServer(store, nil, true)
+	leases := leasesdb.New(store.Storage())
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	join := func(leaseID string) {
+		t.Helper()
+		ctx := ctx
+		if leaseID != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(leaderproxy.LeaseMeta, leaseID))
+		}
+		_, err := srv.Join(ctx, &v1.JoinRequest{
+			Id:        "node-a",
+			PublicKey: key.PublicKey().String(),
+			RaftPort:  9443,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	nodeKey := peers.NodesPrefix + "/node-a"
+
+	first, err := leases.Grant(ctx, time.Minute, "node-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	join(first.ID)
+
+	// Rejoining with a new lease moves the registration to it.
+	second, err := leases.Grant(ctx, time.Minute, "node-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	join(second.ID)
+	if keys, err := leases.Keys(ctx, first.ID); err != nil || len(keys) != 0 {
+		t.Fatalf("expected no keys on the first lease, got %v: %v", keys, err)
+	}
+	if keys, err := leases.Keys(ctx, second.ID); err != nil || len(keys) != 1 || keys[0] != nodeKey {
+		t.Fatalf("expected the node on the second lease, got %v: %v", keys, err)
+	}
+
+	// Rejoining without a lease detaches it, so revoking the lease
+	// before it expires leaves the node in place.
+	join("")
+	if keys, err := leases.Keys(ctx, second.ID); err != nil || len(keys) != 0 {
+		t.Fatalf("expected no keys on the second lease, got %v: %v", keys, err)
+	}
+	if _, err := leases.Revoke(ctx, second.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := peers.New(store.Storage()).Get(ctx, "node-a"); err != nil {
+		if errors.Is(err, peers.ErrNodeNotFound) {
+			t.Fatal("expected the node to survive revoking its previous lease")
+		}
+		t.Fatal(err)
+	}
+}
+
+func TestRejoinKeepsAdminLabels(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := mesh.NewTestMesh(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	srv := NewServer(store, nil, true)
+	p := peers.New(store.Storage())
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	join := func(labels string) peers.Node {
+		t.Helper()
+		ctx := metadata.NewIncomingContext(ctx, metadata.Pairs(leaderproxy.LabelsMeta, labels))
+		_, err := srv.Join(ctx, &v1.JoinRequest{
+			Id:        "node-a",
+			PublicKey: key.PublicKey().String(),
+			RaftPort:  9443,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		node, err := p.Get(ctx, "node-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return node
+	}
+
+	node := join("tier=web,env=dev")
+	node.Labels["env"] = "prod"
+	node.AdminLabels = map[string]string{"env": "prod"}
+	if err := p.Put(ctx, node); err != nil {
+		t.Fatal(err)
+	}
+
+	// Labels set by an admin win over the labels the node rejoins with,
+	// and the labels it joined with before are dropped.
+	node = join("env=staging,zone=b")
+	want := map[string]string{"env": "prod", "zone": "b"}
+	if len(node.Labels) != len(want) {
+		t.Fatalf("expected labels %v, got %v", want, node.Labels)
+	}
+	for k, v := range want {
+		if node.Labels[k] != v {
+			t.Fatalf("expected labels %v, got %v", want, node.Labels)
+		}
+	}
+	if len(node.AdminLabels) != 1 || node.AdminLabels["env"] != "prod" {
+		t.Fatalf("expected admin labels to be kept, got %v", node.AdminLabels)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"errors"
+	"log/slog"
+
+	v1 "github.com/webmeshproj/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/webmeshproj/webmesh/pkg/context"
+	rbacdb "github.com/webmeshproj/webmesh/pkg/meshdb/rbac"
+	"github.com/webmeshproj/webmesh/pkg/raft"
+	"github.com/webmeshproj/webmesh/pkg/services/leadership"
+	"github.com/webmeshproj/webmesh/pkg/services/rbac"
+)
+
+var _ leadership.LeadershipServer = (*Server)(nil)
+
+// canTransferLeadershipAction is granted to admins, or to roles that allow
+// transferring leadership on votes.
+var canTransferLeadershipAction = &rbac.Action{
+	Verb:     rbacdb.VerbRaftTransferLeadership,
+	Resource: v1.RuleResource_RESOURCE_VOTES,
+}
+
+// TransferLeadership transfers leadership to the requested voter, or to the most
+// up-to-date voter if none is given.
+func (s *Server) TransferLeadership(ctx context.Context, req *leadership.TransferLeadershipRequest) (*emptypb.Empty, error) {
+	if !s.store.Raft().IsLeader() {
+		return nil, status.Errorf(codes.FailedPrecondition, "not leader")
+	}
+	if !s.insecure {
+		allowed, err := s.rbacEval.Evaluate(ctx, rbac.Actions{canTransferLeadershipAction})
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to evaluate permissions: %v", err)
+		}
+		if !allowed {
+			return nil, status.Error(codes.PermissionDenied, "not allowed")
+		}
+	}
+	s.log.Info("Transferring leadership", slog.String("to", req.GetId()))
+	err := s.store.Raft().TransferLeadership(ctx, req.GetId())
+	if err != nil {
+		switch {
+		case errors.Is(err, raft.ErrNotVoter):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		case errors.Is(err, raft.ErrNotLeader):
+			return nil, status.Errorf(codes.FailedPrecondition, "not leader")
+		default:
+			return nil, status.Errorf(codes.Unavailable, "failed to transfer leadership: %v", err)
+		}
+	}
+	return &emptypb.Empty{}, nil
+}
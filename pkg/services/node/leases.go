@@ -0,0 +1,105 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/webmeshproj/webmesh/pkg/context"
+	leasesdb "github.com/webmeshproj/webmesh/pkg/meshdb/leases"
+	"github.com/webmeshproj/webmesh/pkg/services/leaderproxy"
+	"github.com/webmeshproj/webmesh/pkg/services/leases"
+)
+
+var _ leases.LeasesServer = (*Server)(nil)
+
+// Grant grants a new lease with the requested TTL to the caller.
+func (s *Server) Grant(ctx context.Context, req *leases.GrantLeaseRequest) (*leases.Lease, error) {
+	if !s.store.Raft().IsLeader() {
+		return nil, status.Errorf(codes.FailedPrecondition, "not leader")
+	}
+	ttl := req.GetTtl().AsDuration()
+	if ttl < leasesdb.MinTTL {
+		return nil, status.Errorf(codes.InvalidArgument, "lease TTL must be at least %s", leasesdb.MinTTL)
+	}
+	lease, err := leasesdb.New(s.store.Storage()).Grant(ctx, ttl, leaseOwner(ctx))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to grant lease: %v", err)
+	}
+	s.log.Debug("Granted lease", slog.String("lease", lease.ID), slog.String("owner", lease.Owner))
+	return &leases.Lease{
+		Id:  lease.ID,
+		Ttl: durationpb.New(lease.TTL),
+	}, nil
+}
+
+// KeepAlive renews the leases sent on the stream. The TTL of the lease is
+// sent back after each renewal.
+func (s *Server) KeepAlive(stream leases.Leases_KeepAliveServer) error {
+	ctx := stream.Context()
+	owner := leaseOwner(ctx)
+	db := leasesdb.New(s.store.Storage())
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if !s.store.Raft().IsLeader() {
+			return status.Errorf(codes.FailedPrecondition, "not leader")
+		}
+		lease, err := db.Get(ctx, req.GetId())
+		if err != nil {
+			if errors.Is(err, leasesdb.ErrLeaseNotFound) {
+				return status.Errorf(codes.NotFound, "lease %q not found", req.GetId())
+			}
+			return status.Errorf(codes.Internal, "failed to get lease: %v", err)
+		}
+		if !s.insecure && lease.Owner != "" && lease.Owner != owner {
+			return status.Errorf(codes.PermissionDenied, "lease %q is not owned by the caller", lease.ID)
+		}
+		lease, err = db.KeepAlive(ctx, lease.ID)
+		if err != nil {
+			if errors.Is(err, leasesdb.ErrLeaseNotFound) {
+				return status.Errorf(codes.NotFound, "lease %q not found", req.GetId())
+			}
+			return status.Errorf(codes.Internal, "failed to renew lease: %v", err)
+		}
+		if err := stream.Send(&leases.KeepAliveResponse{Ttl: durationpb.New(lease.TTL)}); err != nil {
+			return err
+		}
+	}
+}
+
+// leaseOwner returns the ID of the caller that owns leases granted in the context.
+func leaseOwner(ctx context.Context) string {
+	if proxiedFor, ok := leaderproxy.ProxiedFor(ctx); ok {
+		return proxiedFor
+	}
+	if peer, ok := context.AuthenticatedCallerFrom(ctx); ok {
+		return peer
+	}
+	return ""
+}
@@ -35,8 +35,6 @@ func (s *Server) Leave(ctx context.Context, req *v1.LeaveRequest) (*emptypb.Empt
 	if !s.store.Raft().IsLeader() {
 		return nil, status.Errorf(codes.FailedPrecondition, "not leader")
 	}
-	s.mu.Lock()
-	defer s.mu.Unlock()
 	// Check that the node is indeed who they say they are
 	if !s.insecure {
 		if proxiedFor, ok := leaderproxy.ProxiedFor(ctx); ok {
@@ -31,12 +31,18 @@ import (
 	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
 	rbacdb "github.com/webmeshproj/webmesh/pkg/meshdb/rbac"
 	"github.com/webmeshproj/webmesh/pkg/meshdb/state"
+	"github.com/webmeshproj/webmesh/pkg/services/edgemetrics"
+	"github.com/webmeshproj/webmesh/pkg/services/leadership"
+	"github.com/webmeshproj/webmesh/pkg/services/leases"
 	"github.com/webmeshproj/webmesh/pkg/services/rbac"
 )
 
 // Server is the webmesh node service.
 type Server struct {
 	v1.UnimplementedNodeServer
+	leases.UnimplementedLeasesServer
+	leadership.UnimplementedLeadershipServer
+	edgemetrics.UnimplementedEdgeMetricsServer
 
 	store      meshdb.Store
 	peers      peers.Peers
@@ -53,8 +59,9 @@ type Server struct {
 	log        *slog.Logger
 	// insecure flags that no authentication plugins are enabled.
 	insecure bool
-	// lock taken during the join/update process to prevent concurrent node changes.
-	mu sync.Mutex
+	// statemu guards loading the mesh state into the fields above. Node
+	// changes themselves are not serialized, they use revision-checked writes.
+	statemu sync.Mutex
 }
 
 // NewServer returns a new Server. Features are used for returning what features are enabled.
@@ -31,16 +31,16 @@ import (
 	"google.golang.org/grpc/status"
 
 	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/meshdb/networking"
 	"github.com/webmeshproj/webmesh/pkg/meshdb/peers"
 	"github.com/webmeshproj/webmesh/pkg/services/rbac"
+	"github.com/webmeshproj/webmesh/pkg/storage"
 )
 
 func (s *Server) Update(ctx context.Context, req *v1.UpdateRequest) (*v1.UpdateResponse, error) {
 	if !s.store.Raft().IsLeader() {
 		return nil, status.Errorf(codes.FailedPrecondition, "not leader")
 	}
-	s.mu.Lock()
-	defer s.mu.Unlock()
 	log := s.log.With("op", "update", "id", req.GetId())
 	ctx = context.WithLogger(ctx, log)
 
@@ -142,8 +142,11 @@ func (s *Server) Update(ctx context.Context, req *v1.UpdateRequest) (*v1.UpdateR
 		// We weren't able to determine their suffrage...strange
 		return nil, status.Errorf(codes.Internal, "failed to determine peer suffrage")
 	}
+	// Route and node changes are committed together in a single transaction.
+	tx := storage.NewTxn(s.store.Storage())
+	defer tx.Rollback()
 	// Ensure any new routes
-	_, err = s.ensurePeerRoutes(ctx, peer.ID, req.GetRoutes())
+	_, err = s.ensurePeerRoutes(ctx, networking.New(tx), peer.ID, req.GetRoutes())
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to ensure peer routes: %v", err)
 	}
@@ -195,11 +198,17 @@ func (s *Server) Update(ctx context.Context, req *v1.UpdateRequest) (*v1.UpdateR
 	// Apply any node changes
 	if hasChanges {
 		log.Debug("updating peer", slog.Any("peer", toUpdate))
-		err = s.peers.Put(ctx, *toUpdate)
+		err = peers.New(tx).Put(ctx, *toUpdate)
 		if err != nil {
 			return nil, status.Errorf(codes.Internal, "failed to update peer: %v", err)
 		}
 	}
+	if err := tx.Commit(ctx); err != nil {
+		if errors.Is(err, storage.ErrRevisionMismatch) {
+			return nil, status.Errorf(codes.Aborted, "peer was changed concurrently: %v", err)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update peer: %v", err)
+	}
 
 	// Change to voter if requested and not already
 	if req.GetAsVoter() && currentSuffrage != raft.Voter {
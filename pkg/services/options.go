@@ -164,21 +164,11 @@ func (o *Options) ListenPort() (int, error) {
 
 // ServerOptions converts the options to gRPC server options.
 func (o *Options) ServerOptions(store mesh.Mesh, log *slog.Logger) (srvrOptions []grpc.ServerOption, err error) {
-	var opts []grpc.ServerOption
-	if !o.Insecure {
-		tlsConfig, err := o.TLSConfig()
-		if err != nil {
-			return nil, err
-		}
-		// Bit of a hack, but if we are using the mTLS plugin, we need to make sure
-		// the server requests a client certificate.
-		if _, ok := store.Plugins().Get("mtls"); ok {
-			tlsConfig.ClientAuth = tls.RequestClientCert
-		}
-		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
-	} else {
-		opts = append(opts, grpc.Creds(insecure.NewCredentials()))
+	creds, err := o.ServerCredentials(store)
+	if err != nil {
+		return nil, err
 	}
+	opts := []grpc.ServerOption{creds}
 	unarymiddlewares := []grpc.UnaryServerInterceptor{
 		context.LogInjectUnaryServerInterceptor(log),
 		logging.UnaryServerInterceptor(InterceptorLogger(), logging.WithLogOnEvents(logging.StartCall, logging.FinishCall)),
@@ -212,6 +202,23 @@ func (o *Options) ServerOptions(store mesh.Mesh, log *slog.Logger) (srvrOptions
 	return opts, nil
 }
 
+// ServerCredentials returns the transport credentials for the gRPC server.
+func (o *Options) ServerCredentials(store mesh.Mesh) (grpc.ServerOption, error) {
+	if o.Insecure {
+		return grpc.Creds(insecure.NewCredentials()), nil
+	}
+	tlsConfig, err := o.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	// Bit of a hack, but if we are using the mTLS plugin, we need to make sure
+	// the server requests a client certificate.
+	if _, ok := store.Plugins().Get("mtls"); ok {
+		tlsConfig.ClientAuth = tls.RequestClientCert
+	}
+	return grpc.Creds(credentials.NewTLS(tlsConfig)), nil
+}
+
 // TLSConfig returns the TLS configuration.
 func (o *Options) TLSConfig() (*tls.Config, error) {
 	if o.Insecure {
@@ -0,0 +1,173 @@
+//
+//Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.31.0
+// 	protoc        (unknown)
+// source: services/rafttransport/rafttransport.proto
+
+package rafttransport
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// StreamData is a chunk of the data written on a raft connection.
+type StreamData struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// data are the bytes written on the connection.
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *StreamData) Reset() {
+	*x = StreamData{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_rafttransport_rafttransport_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamData) ProtoMessage() {}
+
+func (x *StreamData) ProtoReflect() protoreflect.Message {
+	mi := &file_services_rafttransport_rafttransport_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamData.ProtoReflect.Descriptor instead.
+func (*StreamData) Descriptor() ([]byte, []int) {
+	return file_services_rafttransport_rafttransport_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StreamData) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+var File_services_rafttransport_rafttransport_proto protoreflect.FileDescriptor
+
+var file_services_rafttransport_rafttransport_proto_rawDesc = []byte{
+	0x0a, 0x2a, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x73, 0x2f, 0x72, 0x61, 0x66, 0x74, 0x74,
+	0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2f, 0x72, 0x61, 0x66, 0x74, 0x74, 0x72, 0x61,
+	0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x18, 0x77, 0x65,
+	0x62, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x72, 0x61, 0x66, 0x74, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70,
+	0x6f, 0x72, 0x74, 0x2e, 0x76, 0x31, 0x22, 0x20, 0x0a, 0x0a, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x44, 0x61, 0x74, 0x61, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x32, 0x69, 0x0a, 0x0d, 0x52, 0x61, 0x66, 0x74,
+	0x54, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x58, 0x0a, 0x06, 0x53, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x12, 0x24, 0x2e, 0x77, 0x65, 0x62, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x72, 0x61,
+	0x66, 0x74, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x53,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x44, 0x61, 0x74, 0x61, 0x1a, 0x24, 0x2e, 0x77, 0x65, 0x62, 0x6d,
+	0x65, 0x73, 0x68, 0x2e, 0x72, 0x61, 0x66, 0x74, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72,
+	0x74, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x44, 0x61, 0x74, 0x61, 0x28,
+	0x01, 0x30, 0x01, 0x42, 0x3b, 0x5a, 0x39, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f,
+	0x6d, 0x2f, 0x77, 0x65, 0x62, 0x6d, 0x65, 0x73, 0x68, 0x70, 0x72, 0x6f, 0x6a, 0x2f, 0x77, 0x65,
+	0x62, 0x6d, 0x65, 0x73, 0x68, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x73, 0x2f, 0x72, 0x61, 0x66, 0x74, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_services_rafttransport_rafttransport_proto_rawDescOnce sync.Once
+	file_services_rafttransport_rafttransport_proto_rawDescData = file_services_rafttransport_rafttransport_proto_rawDesc
+)
+
+func file_services_rafttransport_rafttransport_proto_rawDescGZIP() []byte {
+	file_services_rafttransport_rafttransport_proto_rawDescOnce.Do(func() {
+		file_services_rafttransport_rafttransport_proto_rawDescData = protoimpl.X.CompressGZIP(file_services_rafttransport_rafttransport_proto_rawDescData)
+	})
+	return file_services_rafttransport_rafttransport_proto_rawDescData
+}
+
+var file_services_rafttransport_rafttransport_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_services_rafttransport_rafttransport_proto_goTypes = []interface{}{
+	(*StreamData)(nil), // 0: webmesh.rafttransport.v1.StreamData
+}
+var file_services_rafttransport_rafttransport_proto_depIdxs = []int32{
+	0, // 0: webmesh.rafttransport.v1.RaftTransport.Stream:input_type -> webmesh.rafttransport.v1.StreamData
+	0, // 1: webmesh.rafttransport.v1.RaftTransport.Stream:output_type -> webmesh.rafttransport.v1.StreamData
+	1, // [1:2] is the sub-list for method output_type
+	0, // [0:1] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_services_rafttransport_rafttransport_proto_init() }
+func file_services_rafttransport_rafttransport_proto_init() {
+	if File_services_rafttransport_rafttransport_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_services_rafttransport_rafttransport_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamData); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_services_rafttransport_rafttransport_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_services_rafttransport_rafttransport_proto_goTypes,
+		DependencyIndexes: file_services_rafttransport_rafttransport_proto_depIdxs,
+		MessageInfos:      file_services_rafttransport_rafttransport_proto_msgTypes,
+	}.Build()
+	File_services_rafttransport_rafttransport_proto = out.File
+	file_services_rafttransport_rafttransport_proto_rawDesc = nil
+	file_services_rafttransport_rafttransport_proto_goTypes = nil
+	file_services_rafttransport_rafttransport_proto_depIdxs = nil
+}
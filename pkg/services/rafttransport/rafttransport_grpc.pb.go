@@ -0,0 +1,160 @@
+//
+//Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: services/rafttransport/rafttransport.proto
+
+package rafttransport
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	RaftTransport_Stream_FullMethodName = "/webmesh.rafttransport.v1.RaftTransport/Stream"
+)
+
+// RaftTransportClient is the client API for RaftTransport service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RaftTransportClient interface {
+	// Stream carries a single raft connection. Each message holds the next
+	// bytes written on the connection in either direction.
+	Stream(ctx context.Context, opts ...grpc.CallOption) (RaftTransport_StreamClient, error)
+}
+
+type raftTransportClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRaftTransportClient(cc grpc.ClientConnInterface) RaftTransportClient {
+	return &raftTransportClient{cc}
+}
+
+func (c *raftTransportClient) Stream(ctx context.Context, opts ...grpc.CallOption) (RaftTransport_StreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &RaftTransport_ServiceDesc.Streams[0], RaftTransport_Stream_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &raftTransportStreamClient{stream}
+	return x, nil
+}
+
+type RaftTransport_StreamClient interface {
+	Send(*StreamData) error
+	Recv() (*StreamData, error)
+	grpc.ClientStream
+}
+
+type raftTransportStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *raftTransportStreamClient) Send(m *StreamData) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *raftTransportStreamClient) Recv() (*StreamData, error) {
+	m := new(StreamData)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RaftTransportServer is the server API for RaftTransport service.
+// All implementations must embed UnimplementedRaftTransportServer
+// for forward compatibility
+type RaftTransportServer interface {
+	// Stream carries a single raft connection. Each message holds the next
+	// bytes written on the connection in either direction.
+	Stream(RaftTransport_StreamServer) error
+	mustEmbedUnimplementedRaftTransportServer()
+}
+
+// UnimplementedRaftTransportServer must be embedded to have forward compatible implementations.
+type UnimplementedRaftTransportServer struct {
+}
+
+func (UnimplementedRaftTransportServer) Stream(RaftTransport_StreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method Stream not implemented")
+}
+func (UnimplementedRaftTransportServer) mustEmbedUnimplementedRaftTransportServer() {}
+
+// UnsafeRaftTransportServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RaftTransportServer will
+// result in compilation errors.
+type UnsafeRaftTransportServer interface {
+	mustEmbedUnimplementedRaftTransportServer()
+}
+
+func RegisterRaftTransportServer(s grpc.ServiceRegistrar, srv RaftTransportServer) {
+	s.RegisterService(&RaftTransport_ServiceDesc, srv)
+}
+
+func _RaftTransport_Stream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RaftTransportServer).Stream(&raftTransportStreamServer{stream})
+}
+
+type RaftTransport_StreamServer interface {
+	Send(*StreamData) error
+	Recv() (*StreamData, error)
+	grpc.ServerStream
+}
+
+type raftTransportStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *raftTransportStreamServer) Send(m *StreamData) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *raftTransportStreamServer) Recv() (*StreamData, error) {
+	m := new(StreamData)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RaftTransport_ServiceDesc is the grpc.ServiceDesc for RaftTransport service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RaftTransport_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "webmesh.rafttransport.v1.RaftTransport",
+	HandlerType: (*RaftTransportServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       _RaftTransport_Stream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "services/rafttransport/rafttransport.proto",
+}
@@ -34,13 +34,22 @@ import (
 
 	"github.com/webmeshproj/webmesh/pkg/context"
 	"github.com/webmeshproj/webmesh/pkg/mesh"
+	"github.com/webmeshproj/webmesh/pkg/raft"
 	"github.com/webmeshproj/webmesh/pkg/services/admin"
 	"github.com/webmeshproj/webmesh/pkg/services/campfire"
 	"github.com/webmeshproj/webmesh/pkg/services/dashboard"
+	"github.com/webmeshproj/webmesh/pkg/services/edgemetrics"
+	"github.com/webmeshproj/webmesh/pkg/services/labels"
+	"github.com/webmeshproj/webmesh/pkg/services/leadership"
+	"github.com/webmeshproj/webmesh/pkg/services/leases"
+	"github.com/webmeshproj/webmesh/pkg/services/maintenance"
+	"github.com/webmeshproj/webmesh/pkg/services/membership"
 	"github.com/webmeshproj/webmesh/pkg/services/meshapi"
 	"github.com/webmeshproj/webmesh/pkg/services/meshdns"
 	"github.com/webmeshproj/webmesh/pkg/services/node"
 	"github.com/webmeshproj/webmesh/pkg/services/peerdiscovery"
+	"github.com/webmeshproj/webmesh/pkg/services/rafttransport"
+	"github.com/webmeshproj/webmesh/pkg/services/topology"
 	"github.com/webmeshproj/webmesh/pkg/services/turn"
 	"github.com/webmeshproj/webmesh/pkg/services/webrtc"
 )
@@ -81,7 +90,12 @@ func NewServer(store mesh.Mesh, o *Options) (*Server, error) {
 	if o.API != nil {
 		if o.API.Admin {
 			log.Debug("registering admin api")
-			v1.RegisterAdminServer(server, admin.New(store, insecureServices))
+			adminServer := admin.New(store, insecureServices)
+			v1.RegisterAdminServer(server, adminServer)
+			maintenance.RegisterMaintenanceServer(server, adminServer)
+			labels.RegisterLabelsServer(server, adminServer)
+			membership.RegisterMembershipServer(server, adminServer)
+			topology.RegisterTopologyServer(server, adminServer)
 		}
 		if o.API.Mesh {
 			log.Debug("registering mesh api")
@@ -130,7 +144,16 @@ func NewServer(store mesh.Mesh, o *Options) (*Server, error) {
 	}
 	// Always register the node server
 	log.Debug("registering node server")
-	v1.RegisterNodeServer(server, node.NewServer(store, o.ToFeatureSet(), insecureServices))
+	nodeServer := node.NewServer(store, o.ToFeatureSet(), insecureServices)
+	v1.RegisterNodeServer(server, nodeServer)
+	leases.RegisterLeasesServer(server, nodeServer)
+	edgemetrics.RegisterEdgeMetricsServer(server, nodeServer)
+	leadership.RegisterLeadershipServer(server, nodeServer)
+	// Register the raft transport if raft is tunneled over gRPC
+	if sl, ok := store.Raft().StreamLayer().(*raft.GRPCStreamLayer); ok {
+		log.Debug("registering raft transport service")
+		rafttransport.RegisterRaftTransportServer(server, sl)
+	}
 	// Register the health service
 	log.Debug("registering health service")
 	healthpb.RegisterHealthServer(server, server)
@@ -0,0 +1,60 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package topology contains the Topology gRPC service. It manages the
+// topology policies the leader generates edges from and is served alongside
+// the Admin API.
+package topology
+
+import (
+	topologydb "github.com/webmeshproj/webmesh/pkg/meshdb/topology"
+)
+
+var policyTypes = map[PolicyType]topologydb.Type{
+	PolicyType_POLICY_TYPE_FULL_MESH:     topologydb.TypeFullMesh,
+	PolicyType_POLICY_TYPE_HUB_AND_SPOKE: topologydb.TypeHubAndSpoke,
+	PolicyType_POLICY_TYPE_ZONE_MESH:     topologydb.TypeZoneMesh,
+}
+
+// NewPolicy returns the message for the given topology policy.
+func NewPolicy(p topologydb.Policy) *Policy {
+	out := &Policy{
+		Name:     p.Name,
+		Selector: p.Selector,
+		Hubs:     p.Hubs,
+		Gateways: p.Gateways,
+		Weight:   int32(p.Weight),
+	}
+	for typ, name := range policyTypes {
+		if name == p.Type {
+			out.Type = typ
+		}
+	}
+	return out
+}
+
+// ParsePolicy returns the topology policy for the given message. An unknown
+// policy type is left empty. The policy is not validated.
+func ParsePolicy(in *Policy) topologydb.Policy {
+	return topologydb.Policy{
+		Name:     in.GetName(),
+		Type:     policyTypes[in.GetType()],
+		Selector: in.GetSelector(),
+		Hubs:     in.GetHubs(),
+		Gateways: in.GetGateways(),
+		Weight:   int(in.GetWeight()),
+	}
+}
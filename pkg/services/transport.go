@@ -0,0 +1,88 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+
+	v1 "github.com/webmeshproj/api/v1"
+	"google.golang.org/grpc"
+
+	"github.com/webmeshproj/webmesh/pkg/context"
+	"github.com/webmeshproj/webmesh/pkg/mesh"
+	"github.com/webmeshproj/webmesh/pkg/raft"
+	"github.com/webmeshproj/webmesh/pkg/services/rafttransport"
+)
+
+// OpenMesh opens the mesh created with the given options. When raft is
+// tunneled over gRPC, other servers can only reach this node's raft through
+// the services port. The raft transport is then served on the services
+// listen address until Open returns, so that bootstrapping and joining do
+// not wait on a server that is not running yet. Call NewServer once OpenMesh
+// returns.
+func OpenMesh(ctx context.Context, store mesh.Mesh, meshOpts *mesh.Options, o *Options, features []v1.Feature) error {
+	if meshOpts.Raft == nil || meshOpts.Raft.Transport != raft.TransportGRPC {
+		return store.Open(ctx, features)
+	}
+	var transport *grpc.Server
+	meshOpts.Raft.OnStarted = func(ctx context.Context) error {
+		var err error
+		transport, err = serveRaftTransport(store, o)
+		return err
+	}
+	defer func() {
+		meshOpts.Raft.OnStarted = nil
+		if transport != nil {
+			// Stop closes the listener, so the services server can take it over.
+			transport.Stop()
+		}
+	}()
+	return store.Open(ctx, features)
+}
+
+// serveRaftTransport serves only the raft transport on the services listen
+// address.
+func serveRaftTransport(store mesh.Mesh, o *Options) (*grpc.Server, error) {
+	log := slog.Default().With("component", "raft-transport-server")
+	sl, ok := store.Raft().StreamLayer().(*raft.GRPCStreamLayer)
+	if !ok {
+		return nil, fmt.Errorf("raft is not using the gRPC stream layer")
+	}
+	creds, err := o.ServerCredentials(store)
+	if err != nil {
+		return nil, err
+	}
+	serveOpts := []grpc.ServerOption{creds}
+	if store.Plugins().HasAuth() {
+		serveOpts = append(serveOpts, grpc.ChainStreamInterceptor(store.Plugins().AuthStreamInterceptor()))
+	}
+	lis, err := net.Listen("tcp", o.ListenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("start TCP listener: %w", err)
+	}
+	srv := grpc.NewServer(serveOpts...)
+	rafttransport.RegisterRaftTransportServer(srv, sl)
+	log.Info(fmt.Sprintf("Serving the raft transport on %s until the mesh is open", o.ListenAddress))
+	go func() {
+		if err := srv.Serve(lis); err != nil {
+			log.Error("raft transport server failed", slog.String("error", err.Error()))
+		}
+	}()
+	return srv, nil
+}
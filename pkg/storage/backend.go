@@ -0,0 +1,90 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Backend is the name of a storage backend.
+type Backend string
+
+const (
+	// BackendBadger stores data in badger. It is the default for on-disk
+	// storage and the only backend that supports encryption.
+	BackendBadger Backend = "badger"
+	// BackendMemory stores data in an in-memory B-tree. It is the default
+	// for in-memory storage.
+	BackendMemory Backend = "memory"
+	// BackendBolt stores data in a bbolt database file.
+	BackendBolt Backend = "bolt"
+)
+
+// BackendFactory creates a Storage from the given options.
+type BackendFactory func(opts *Options) (Storage, error)
+
+var (
+	backends   = make(map[Backend]BackendFactory)
+	backendsMu sync.RWMutex
+)
+
+func init() {
+	RegisterBackend(BackendBadger, newBadgerStorage)
+}
+
+// RegisterBackend registers a storage backend with the given name. It
+// replaces any backend previously registered with the same name.
+func RegisterBackend(name Backend, factory BackendFactory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	backends[name] = factory
+}
+
+// Backends returns the names of all registered backends in sorted order.
+func Backends() []Backend {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	names := make([]Backend, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// backendFor returns the factory for the backend selected by the options.
+func backendFor(opts *Options) (BackendFactory, error) {
+	name := opts.Backend
+	if name == "" {
+		name = BackendBadger
+		if opts.InMemory {
+			name = BackendMemory
+		}
+	}
+	if opts.Encryption != nil && name != BackendBadger {
+		return nil, fmt.Errorf("backend %q does not support encryption", name)
+	}
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	factory, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown storage backend %q", name)
+	}
+	return factory, nil
+}
@@ -18,6 +18,7 @@ package storage
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -27,14 +28,30 @@ import (
 	"time"
 
 	"github.com/dgraph-io/badger/v4"
-	"github.com/dgraph-io/badger/v4/pb"
 
 	"github.com/webmeshproj/webmesh/pkg/context"
 )
 
+const (
+	// metaRevision is set in the user metadata of entries whose values are
+	// prefixed with the revision they were written at. Entries written before
+	// revisions were tracked do not have it set.
+	metaRevision byte = 1 << 0
+	// internalPrefix is the prefix for keys used internally by the storage.
+	// They are never returned from List, IterPrefix, or Subscribe.
+	internalPrefix = "/.storage/"
+	// revisionKey is where the current revision of the storage is persisted.
+	revisionKey = internalPrefix + "revision"
+	// initialRevision is the revision of an empty storage. It is also reported
+	// for entries that were written before revisions were tracked.
+	initialRevision uint64 = 1
+)
+
 type badgerStorage struct {
-	db *badger.DB
-	mu sync.RWMutex
+	db  *badger.DB
+	mu  sync.RWMutex
+	rev uint64
+	hub *watchHub
 }
 
 func newBadgerStorage(opts *Options) (Storage, error) {
@@ -44,25 +61,39 @@ func newBadgerStorage(opts *Options) (Storage, error) {
 	} else {
 		badgeropts = badger.DefaultOptions(opts.DiskPath)
 	}
+	badgeropts.ReadOnly = opts.ReadOnly && !opts.InMemory
 	badgeropts.Logger = nil
 	if !opts.Silent {
 		badgeropts.Logger = &logger{slog.Default().With("component", "badger")}
 	}
-	db, err := badger.Open(badgeropts)
+	db, err := OpenBadger(badgeropts, opts.Encryption)
 	if err != nil {
 		return nil, fmt.Errorf("badger open: %w", err)
 	}
-	return &badgerStorage{db: db}, nil
+	b := &badgerStorage{db: db}
+	if err := b.loadRevision(); err != nil {
+		defer db.Close()
+		return nil, fmt.Errorf("badger load revision: %w", err)
+	}
+	b.hub = newWatchHub(opts.WatchHistory, b.rev)
+	return b, nil
 }
 
 // Get returns the value of a key.
 func (b *badgerStorage) Get(ctx context.Context, key string) (string, error) {
+	value, _, err := b.GetRevision(ctx, key)
+	return value, err
+}
+
+// GetRevision returns the value of a key along with its revision.
+func (b *badgerStorage) GetRevision(ctx context.Context, key string) (string, uint64, error) {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 	if key == "" {
-		return "", errors.New("badger get: key is empty")
+		return "", 0, errors.New("badger get: key is empty")
 	}
 	var value string
+	var revision uint64
 	err := b.db.View(func(txn *badger.Txn) error {
 		item, err := txn.Get([]byte(key))
 		if err != nil {
@@ -71,16 +102,37 @@ func (b *badgerStorage) Get(ctx context.Context, key string) (string, error) {
 			}
 			return fmt.Errorf("badger get: %w", err)
 		}
-		err = item.Value(func(val []byte) error {
-			value = string(val)
-			return nil
-		})
+		value, revision, err = decodeItem(item)
 		if err != nil {
 			return fmt.Errorf("badger get: %w", err)
 		}
 		return nil
 	})
-	return value, err
+	return value, revision, err
+}
+
+// GetTTL returns the time left until a key expires.
+func (b *badgerStorage) GetTTL(ctx context.Context, key string) (time.Duration, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if key == "" {
+		return 0, errors.New("badger get ttl: key is empty")
+	}
+	var ttl time.Duration
+	err := b.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return ErrKeyNotFound
+			}
+			return fmt.Errorf("badger get ttl: %w", err)
+		}
+		if expiresAt := item.ExpiresAt(); expiresAt > 0 {
+			ttl = time.Until(time.Unix(int64(expiresAt), 0))
+		}
+		return nil
+	})
+	return ttl, err
 }
 
 // Put sets the value of a key.
@@ -90,20 +142,28 @@ func (b *badgerStorage) Put(ctx context.Context, key, value string, ttl time.Dur
 	if key == "" {
 		return errors.New("badger put: key is empty")
 	}
-	err := b.db.Update(func(txn *badger.Txn) error {
-		e := badger.NewEntry([]byte(key), []byte(value))
-		if ttl > 0 {
-			e = e.WithTTL(ttl)
-		}
-		err := txn.SetEntry(e)
+	err := b.update(func(txn *badger.Txn, rev uint64) ([]Event, error) {
+		err := txn.SetEntry(newEntry(key, value, rev, ttl))
 		if err != nil {
-			return fmt.Errorf("badger put: %w", err)
+			return nil, fmt.Errorf("badger put: %w", err)
 		}
-		return nil
+		return []Event{{Type: EventPut, Key: key, Value: value, Revision: rev}}, nil
 	})
 	return err
 }
 
+// PutIfRevision sets the value of a key only if its current revision matches
+// the given revision.
+func (b *badgerStorage) PutIfRevision(ctx context.Context, key, value string, revision uint64, ttl time.Duration) error {
+	return b.Batch(ctx, []Op{{
+		Type:     OpPutIfRevision,
+		Key:      key,
+		Value:    value,
+		TTL:      ttl,
+		Revision: revision,
+	}})
+}
+
 // Delete removes a key.
 func (b *badgerStorage) Delete(ctx context.Context, key string) error {
 	b.mu.Lock()
@@ -111,16 +171,65 @@ func (b *badgerStorage) Delete(ctx context.Context, key string) error {
 	if key == "" {
 		return errors.New("badger delete: key is empty")
 	}
-	err := b.db.Update(func(txn *badger.Txn) error {
+	err := b.update(func(txn *badger.Txn, rev uint64) ([]Event, error) {
 		err := txn.Delete([]byte(key))
 		if err != nil {
 			if errors.Is(err, badger.ErrKeyNotFound) {
-				return ErrKeyNotFound
+				return nil, ErrKeyNotFound
 			}
-			return fmt.Errorf("badger delete: %w", err)
+			return nil, fmt.Errorf("badger delete: %w", err)
 		}
-		return nil
+		return []Event{{Type: EventDelete, Key: key, Revision: rev}}, nil
+	})
+	return err
+}
+
+// Batch applies the given operations atomically in a single transaction.
+// All operations in the batch are written at the same revision.
+func (b *badgerStorage) Batch(ctx context.Context, ops []Op) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, op := range ops {
+		if op.Key == "" {
+			return errors.New("badger batch: key is empty")
+		}
+	}
+	err := b.update(func(txn *badger.Txn, rev uint64) ([]Event, error) {
+		// Check all conditions before making any changes.
+		for _, op := range ops {
+			if op.Type != OpPutIfRevision && op.Type != OpDeleteIfRevision {
+				continue
+			}
+			current, err := currentRevision(txn, op.Key)
+			if err != nil {
+				return nil, fmt.Errorf("badger batch get %q: %w", op.Key, err)
+			}
+			if current != op.Revision {
+				return nil, fmt.Errorf("%w: key %q is at revision %d, expected %d", ErrRevisionMismatch, op.Key, current, op.Revision)
+			}
+		}
+		events := make([]Event, 0, len(ops))
+		for _, op := range ops {
+			switch op.Type {
+			case OpPut, OpPutIfRevision:
+				if err := txn.SetEntry(newEntry(op.Key, op.Value, rev, op.TTL)); err != nil {
+					return nil, fmt.Errorf("badger batch put %q: %w", op.Key, err)
+				}
+				events = append(events, Event{Type: EventPut, Key: op.Key, Value: op.Value, Revision: rev})
+			case OpDelete, OpDeleteIfRevision:
+				if err := txn.Delete([]byte(op.Key)); err != nil {
+					return nil, fmt.Errorf("badger batch delete %q: %w", op.Key, err)
+				}
+				events = append(events, Event{Type: EventDelete, Key: op.Key, Revision: rev})
+			default:
+				return nil, fmt.Errorf("badger batch: unknown operation type %v", op.Type)
+			}
+		}
+		return events, nil
 	})
+	if errors.Is(err, badger.ErrTxnTooBig) {
+		return fmt.Errorf("badger batch of %d operations: %w", len(ops), ErrBatchTooLarge)
+	}
 	return err
 }
 
@@ -135,6 +244,9 @@ func (b *badgerStorage) List(ctx context.Context, prefix string) ([]string, erro
 		for it.Seek([]byte(prefix)); it.ValidForPrefix([]byte(prefix)); it.Next() {
 			item := it.Item()
 			k := item.Key()
+			if isInternalKey(k) {
+				continue
+			}
 			keys = append(keys, string(k))
 		}
 		return nil
@@ -144,18 +256,32 @@ func (b *badgerStorage) List(ctx context.Context, prefix string) ([]string, erro
 
 // IterPrefix iterates over all keys with a given prefix.
 func (b *badgerStorage) IterPrefix(ctx context.Context, prefix string, fn PrefixIterator) error {
+	return b.IterPrefixAfter(ctx, prefix, "", fn)
+}
+
+// IterPrefixAfter iterates over all keys with a given prefix that sort after
+// the given key.
+func (b *badgerStorage) IterPrefixAfter(ctx context.Context, prefix, after string, fn PrefixIterator) error {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
+	start := prefix
+	if after > start {
+		start = after
+	}
 	err := b.db.View(func(txn *badger.Txn) error {
 		it := txn.NewIterator(badger.DefaultIteratorOptions)
 		defer it.Close()
-		for it.Seek([]byte(prefix)); it.ValidForPrefix([]byte(prefix)); it.Next() {
+		for it.Seek([]byte(start)); it.ValidForPrefix([]byte(prefix)); it.Next() {
 			item := it.Item()
 			k := item.Key()
-			err := item.Value(func(v []byte) error {
-				return fn(string(k), string(v))
-			})
+			if isInternalKey(k) || string(k) == after {
+				continue
+			}
+			v, _, err := decodeItem(item)
 			if err != nil {
+				return fmt.Errorf("badger iter %q: %w", string(k), err)
+			}
+			if err := fn(string(k), v); err != nil {
 				return err
 			}
 		}
@@ -195,36 +321,149 @@ func (b *badgerStorage) Restore(ctx context.Context, r io.Reader) error {
 	if err != nil {
 		return fmt.Errorf("badger restore: %w", err)
 	}
+	err = b.loadRevision()
+	if err != nil {
+		return fmt.Errorf("badger restore: %w", err)
+	}
+	b.hub.reset(b.rev)
 	return nil
 }
 
+// Revision returns the current revision of the storage.
+func (b *badgerStorage) Revision(ctx context.Context) (uint64, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.rev, nil
+}
+
+// Watch calls fn for every change to a key with the given prefix made after
+// the given revision. Expiration of keys with a TTL does not produce events.
+func (b *badgerStorage) Watch(ctx context.Context, prefix string, revision uint64, fn WatchFunc) (func(), error) {
+	return b.hub.watch(ctx, prefix, revision, fn)
+}
+
 // Subscribe will call the given function whenever a key with the given prefix is changed.
 // The returned function can be called to unsubscribe. If the given context is cancelled,
 // the subscription will be automatically unsubscribed.
 func (b *badgerStorage) Subscribe(ctx context.Context, prefix string, fn SubscribeFunc) (func(), error) {
-	ctx, cancel := context.WithCancel(ctx)
-	go func() {
-		sub := func(kv *badger.KVList) error {
-			for _, keyval := range kv.GetKv() {
-				fn(string(keyval.Key), string(keyval.Value))
-			}
-			return nil
-		}
-		err := b.db.Subscribe(ctx, sub, []pb.Match{{Prefix: []byte(prefix)}})
-		if err != nil && err != context.Canceled {
-			context.LoggerFrom(ctx).Error(fmt.Sprintf("badger subscribe: %v", err))
-		}
-	}()
-	return cancel, nil
+	return subscribe(ctx, b, prefix, fn)
 }
 
 // Close closes the storage.
 func (b *badgerStorage) Close() error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
+	b.hub.close()
 	return b.db.Close()
 }
 
+// update runs fn in a read-write transaction at the next revision of the
+// storage and persists the new revision with it. The events returned by fn
+// are published to watchers once the transaction commits. The caller must
+// hold the write lock.
+func (b *badgerStorage) update(fn func(txn *badger.Txn, rev uint64) ([]Event, error)) error {
+	rev := b.rev + 1
+	var events []Event
+	err := b.db.Update(func(txn *badger.Txn) error {
+		var err error
+		events, err = fn(txn, rev)
+		if err != nil {
+			return err
+		}
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], rev)
+		return txn.Set([]byte(revisionKey), buf[:])
+	})
+	if err != nil {
+		return err
+	}
+	b.rev = rev
+	b.hub.publish(rev, events)
+	return nil
+}
+
+// loadRevision reads the current revision of the storage from disk.
+func (b *badgerStorage) loadRevision() error {
+	rev := initialRevision
+	err := b.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(revisionKey))
+		if err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return nil
+			}
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			if len(val) != 8 {
+				return fmt.Errorf("invalid revision length %d", len(val))
+			}
+			rev = binary.BigEndian.Uint64(val)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	b.rev = rev
+	return nil
+}
+
+// currentRevision returns the revision of the given key in the transaction,
+// or 0 if it does not exist.
+func currentRevision(txn *badger.Txn, key string) (uint64, error) {
+	item, err := txn.Get([]byte(key))
+	if err != nil {
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	_, rev, err := decodeItem(item)
+	return rev, err
+}
+
+func newEntry(key, value string, rev uint64, ttl time.Duration) *badger.Entry {
+	e := badger.NewEntry([]byte(key), encodeValue(value, rev)).WithMeta(metaRevision)
+	if ttl > 0 {
+		e = e.WithTTL(ttl)
+	}
+	return e
+}
+
+func decodeItem(item *badger.Item) (value string, rev uint64, err error) {
+	err = item.Value(func(val []byte) error {
+		value, rev, err = decodeValue(item.UserMeta(), val)
+		return err
+	})
+	return
+}
+
+func encodeValue(value string, rev uint64) []byte {
+	buf := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(buf, rev)
+	copy(buf[8:], value)
+	return buf
+}
+
+func decodeValue(meta byte, val []byte) (string, uint64, error) {
+	if meta&metaRevision == 0 {
+		return string(val), initialRevision, nil
+	}
+	if len(val) < 8 {
+		return "", 0, errors.New("value is too short to contain a revision")
+	}
+	return string(val[8:]), binary.BigEndian.Uint64(val[:8]), nil
+}
+
+func isInternalKey(key []byte) bool {
+	return bytes.HasPrefix(key, []byte(internalPrefix))
+}
+
+// NewBadgerLogger returns a badger.Logger that writes to the given slog.Logger.
+func NewBadgerLogger(log *slog.Logger) badger.Logger {
+	return &logger{log}
+}
+
 // logger wraps the default slog.Logger to satisfy the badger.Logger interface.
 type logger struct {
 	*slog.Logger
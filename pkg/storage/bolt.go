@@ -0,0 +1,169 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// BoltFileName is the name of the database file the bolt backend creates
+	// in the disk path.
+	BoltFileName = "webmesh.db"
+	// boltHeaderSize is the size of the revision and expiry that prefix
+	// every value stored by the bolt backend.
+	boltHeaderSize = 16
+)
+
+var boltBucket = []byte("webmesh")
+
+func init() {
+	RegisterBackend(BackendBolt, newBoltStorage)
+}
+
+func newBoltStorage(opts *Options) (Storage, error) {
+	if opts.InMemory {
+		return nil, errors.New("bolt backend does not support in-memory storage")
+	}
+	if opts.DiskPath == "" {
+		return nil, errors.New("bolt backend requires a disk path")
+	}
+	if !opts.ReadOnly {
+		if err := os.MkdirAll(opts.DiskPath, 0700); err != nil {
+			return nil, fmt.Errorf("bolt create directory: %w", err)
+		}
+	}
+	db, err := bolt.Open(filepath.Join(opts.DiskPath, BoltFileName), 0600, &bolt.Options{
+		Timeout:  time.Second,
+		ReadOnly: opts.ReadOnly,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("bolt open: %w", err)
+	}
+	if !opts.ReadOnly {
+		err = db.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(boltBucket)
+			return err
+		})
+		if err != nil {
+			defer db.Close()
+			return nil, fmt.Errorf("bolt create bucket: %w", err)
+		}
+	}
+	st, err := newEngineStorage(string(BackendBolt), &boltEngine{db: db}, opts)
+	if err != nil {
+		defer db.Close()
+		return nil, err
+	}
+	return st, nil
+}
+
+// boltEngine is an engine backed by a bbolt database.
+type boltEngine struct {
+	db *bolt.DB
+}
+
+func (b *boltEngine) view(fn func(tx engineTx) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return fn(&boltTx{tx: tx})
+	})
+}
+
+func (b *boltEngine) update(fn func(tx engineTx) error) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return fn(&boltTx{tx: tx})
+	})
+}
+
+func (b *boltEngine) close() error {
+	return b.db.Close()
+}
+
+type boltTx struct {
+	tx *bolt.Tx
+}
+
+func (b *boltTx) get(key string) (record, bool, error) {
+	val := b.tx.Bucket(boltBucket).Get([]byte(key))
+	if val == nil {
+		return record{}, false, nil
+	}
+	rec, err := decodeBoltRecord(val)
+	return rec, err == nil, err
+}
+
+func (b *boltTx) set(key string, rec record) error {
+	return b.tx.Bucket(boltBucket).Put([]byte(key), encodeBoltRecord(rec))
+}
+
+func (b *boltTx) delete(key string) error {
+	return b.tx.Bucket(boltBucket).Delete([]byte(key))
+}
+
+func (b *boltTx) ascend(prefix, start string, fn func(key string, rec record) (bool, error)) error {
+	c := b.tx.Bucket(boltBucket).Cursor()
+	p := []byte(prefix)
+	if start < prefix {
+		start = prefix
+	}
+	for k, v := c.Seek([]byte(start)); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+		rec, err := decodeBoltRecord(v)
+		if err != nil {
+			return fmt.Errorf("decode %q: %w", string(k), err)
+		}
+		ok, err := fn(string(k), rec)
+		if err != nil || !ok {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *boltTx) clear() error {
+	if err := b.tx.DeleteBucket(boltBucket); err != nil {
+		return err
+	}
+	_, err := b.tx.CreateBucket(boltBucket)
+	return err
+}
+
+func encodeBoltRecord(rec record) []byte {
+	buf := make([]byte, boltHeaderSize+len(rec.value))
+	binary.BigEndian.PutUint64(buf[0:8], rec.rev)
+	binary.BigEndian.PutUint64(buf[8:16], uint64(rec.expiresAt))
+	copy(buf[boltHeaderSize:], rec.value)
+	return buf
+}
+
+func decodeBoltRecord(val []byte) (record, error) {
+	if len(val) < boltHeaderSize {
+		return record{}, errors.New("value is too short to contain a header")
+	}
+	return record{
+		rev:       binary.BigEndian.Uint64(val[0:8]),
+		expiresAt: int64(binary.BigEndian.Uint64(val[8:16])),
+		value:     string(val[boltHeaderSize:]),
+	}, nil
+}
@@ -0,0 +1,364 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestBackendConformance runs the same behavior checks against every
+// registered backend.
+func TestBackendConformance(t *testing.T) {
+	t.Parallel()
+
+	tc := []struct {
+		name string
+		test func(t *testing.T, newStorage func(t *testing.T) Storage)
+	}{
+		{"PrefixIteration", testPrefixIteration},
+		{"Pagination", testPagination},
+		{"TTL", testTTL},
+		{"Batch", testBatch},
+		{"SnapshotRestore", testSnapshotRestore},
+		{"Subscribe", testSubscribe},
+	}
+	for _, backend := range Backends() {
+		backend := backend
+		newStorage := func(t *testing.T) Storage {
+			t.Helper()
+			return newTestBackend(t, backend)
+		}
+		t.Run(string(backend), func(t *testing.T) {
+			t.Parallel()
+			for _, c := range tc {
+				c := c
+				t.Run(c.name, func(t *testing.T) {
+					t.Parallel()
+					c.test(t, newStorage)
+				})
+			}
+		})
+	}
+}
+
+// TestSnapshotPortability checks that snapshots taken by each backend can
+// be restored by every other backend.
+func TestSnapshotPortability(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	for _, from := range Backends() {
+		for _, to := range Backends() {
+			from, to := from, to
+			t.Run(string(from)+"-to-"+string(to), func(t *testing.T) {
+				t.Parallel()
+				src := newTestBackend(t, from)
+				if err := src.Put(ctx, "/test/a", "a", 0); err != nil {
+					t.Fatal(err)
+				}
+				if err := src.Put(ctx, "/test/b", "b", time.Hour); err != nil {
+					t.Fatal(err)
+				}
+				_, rev, err := src.GetRevision(ctx, "/test/a")
+				if err != nil {
+					t.Fatal(err)
+				}
+				snapshot, err := src.Snapshot(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				dst := newTestBackend(t, to)
+				if err := dst.Restore(ctx, snapshot); err != nil {
+					t.Fatal(err)
+				}
+				expectKeys(t, dst, "/test/", map[string]string{"/test/a": "a", "/test/b": "b"})
+				if _, got, _ := dst.GetRevision(ctx, "/test/a"); got != rev {
+					t.Fatalf("expected revision %d after restore, got %d", rev, got)
+				}
+			})
+		}
+	}
+}
+
+func newTestBackend(t *testing.T, backend Backend) Storage {
+	t.Helper()
+	st, err := New(&Options{
+		Backend:  backend,
+		InMemory: backend == BackendMemory,
+		DiskPath: t.TempDir(),
+		Silent:   true,
+	})
+	if err != nil {
+		t.Fatalf("create %s storage: %v", backend, err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+func testPrefixIteration(t *testing.T, newStorage func(t *testing.T) Storage) {
+	ctx := context.Background()
+	st := newStorage(t)
+	for key, value := range map[string]string{
+		"/a/2":  "a2",
+		"/a/1":  "a1",
+		"/ab/1": "ab1",
+		"/b/1":  "b1",
+	} {
+		if err := st.Put(ctx, key, value, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	keys, err := st.List(ctx, "/a/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"/a/1", "/a/2"}) {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+	expectKeys(t, st, "/a", map[string]string{"/a/1": "a1", "/a/2": "a2", "/ab/1": "ab1"})
+	// Internal keys should never be visible.
+	all, err := st.List(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 {
+		t.Fatalf("expected 4 keys, got %v", all)
+	}
+	// Iteration should stop at the first error.
+	stop := errors.New("stop")
+	var seen int
+	err = st.IterPrefix(ctx, "/", func(_, _ string) error {
+		seen++
+		return stop
+	})
+	if !errors.Is(err, stop) || seen != 1 {
+		t.Fatalf("expected iteration to stop after one key, got %d keys and %v", seen, err)
+	}
+	if err := st.Delete(ctx, "/a/1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Get(ctx, "/a/1"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func testPagination(t *testing.T, newStorage func(t *testing.T) Storage) {
+	ctx := context.Background()
+	st := newStorage(t)
+	for _, key := range []string{"/p/5", "/p/1", "/p/3", "/p/2", "/p/4", "/q/1"} {
+		if err := st.Put(ctx, key, key, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got [][]string
+	var page Page
+	page.Limit = 2
+	for {
+		keys, next, err := ListPage(ctx, st, "/p/", page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, keys)
+		if next == "" {
+			break
+		}
+		page.Continue = next
+		// Writes behind the cursor should not be revisited.
+		if len(got) == 1 {
+			if err := st.Put(ctx, "/p/0", "/p/0", 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	want := [][]string{{"/p/1", "/p/2"}, {"/p/3", "/p/4"}, {"/p/5"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected pages %v, got %v", want, got)
+	}
+	// An exact final page should not produce a continue token.
+	keys, next, err := ListPage(ctx, st, "/p/", Page{Limit: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 6 || next != "" {
+		t.Fatalf("expected 6 keys and no token, got %v %q", keys, next)
+	}
+	// Tokens are only valid for the prefix they were issued for.
+	_, _, err = ListPage(ctx, st, "/q/", Page{Limit: 1, Continue: EncodeContinueToken("/p/1")})
+	if !errors.Is(err, ErrInvalidContinueToken) {
+		t.Fatalf("expected ErrInvalidContinueToken, got %v", err)
+	}
+}
+
+func testTTL(t *testing.T, newStorage func(t *testing.T) Storage) {
+	ctx := context.Background()
+	st := newStorage(t)
+	if err := st.Put(ctx, "/test/ttl", "value", 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Put(ctx, "/test/keep", "value", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Get(ctx, "/test/ttl"); err != nil {
+		t.Fatalf("expected key before expiry, got %v", err)
+	}
+	time.Sleep(3 * time.Second)
+	if _, err := st.Get(ctx, "/test/ttl"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound after expiry, got %v", err)
+	}
+	expectKeys(t, st, "/test/", map[string]string{"/test/keep": "value"})
+	// An expired key should be treated as absent by conditional puts.
+	if err := st.PutIfRevision(ctx, "/test/ttl", "new", 0, 0); err != nil {
+		t.Fatalf("expected create over expired key to succeed, got %v", err)
+	}
+}
+
+func testBatch(t *testing.T, newStorage func(t *testing.T) Storage) {
+	ctx := context.Background()
+	st := newStorage(t)
+	if err := st.Put(ctx, "/test/a", "a", 0); err != nil {
+		t.Fatal(err)
+	}
+	_, rev, err := st.GetRevision(ctx, "/test/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.Batch(ctx, []Op{
+		{Type: OpPut, Key: "/test/b", Value: "b"},
+		{Type: OpDelete, Key: "/test/a"},
+		{Type: OpPutIfRevision, Key: "/test/a", Value: "a2", Revision: rev + 1},
+	})
+	if !errors.Is(err, ErrRevisionMismatch) {
+		t.Fatalf("expected ErrRevisionMismatch, got %v", err)
+	}
+	expectKeys(t, st, "/test/", map[string]string{"/test/a": "a"})
+	err = st.Batch(ctx, []Op{
+		{Type: OpPut, Key: "/test/b", Value: "b"},
+		{Type: OpPutIfRevision, Key: "/test/a", Value: "a2", Revision: rev},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectKeys(t, st, "/test/", map[string]string{"/test/a": "a2", "/test/b": "b"})
+	_, revA, _ := st.GetRevision(ctx, "/test/a")
+	_, revB, _ := st.GetRevision(ctx, "/test/b")
+	if revA != revB || revA <= rev {
+		t.Fatalf("expected batch to share a new revision, got %d and %d", revA, revB)
+	}
+}
+
+func testSnapshotRestore(t *testing.T, newStorage func(t *testing.T) Storage) {
+	ctx := context.Background()
+	st := newStorage(t)
+	if err := st.Put(ctx, "/test/a", "a", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Put(ctx, "/test/b", "b", 0); err != nil {
+		t.Fatal(err)
+	}
+	rev, err := st.Revision(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := st.Snapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Put(ctx, "/test/c", "c", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Delete(ctx, "/test/a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Restore(ctx, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	expectKeys(t, st, "/test/", map[string]string{"/test/a": "a", "/test/b": "b"})
+	restored, err := st.Revision(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored != rev {
+		t.Fatalf("expected revision %d after restore, got %d", rev, restored)
+	}
+}
+
+func testSubscribe(t *testing.T, newStorage func(t *testing.T) Storage) {
+	ctx := context.Background()
+	st := newStorage(t)
+	type change struct{ key, value string }
+	changes := make(chan change, 10)
+	cancel, err := st.Subscribe(ctx, "/test/", func(key, value string) {
+		changes <- change{key, value}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+	if err := st.Put(ctx, "/other/a", "a", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Put(ctx, "/test/a", "a", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Delete(ctx, "/test/a"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []change{{"/test/a", "a"}, {"/test/a", ""}} {
+		select {
+		case got := <-changes:
+			if got != want {
+				t.Fatalf("expected change %v, got %v", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for change %v", want)
+		}
+	}
+	cancel()
+	if err := st.Put(ctx, "/test/b", "b", 0); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-changes:
+		t.Fatalf("unexpected change after unsubscribe: %v", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func expectKeys(t *testing.T, st Storage, prefix string, want map[string]string) {
+	t.Helper()
+	got := make(map[string]string)
+	err := st.IterPrefix(context.Background(), prefix, func(key, value string) error {
+		got[key] = value
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
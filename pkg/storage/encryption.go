@@ -0,0 +1,190 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// DefaultIndexCacheSize is the size of the block index cache used when
+// encryption is enabled. Badger requires a cache to avoid decrypting table
+// indexes on every read.
+const DefaultIndexCacheSize = 100 << 20
+
+var (
+	// ErrEncryptionKeyMismatch is returned when the storage was encrypted with a
+	// different key than the one given.
+	ErrEncryptionKeyMismatch = errors.New("encryption key does not match the key the data was written with")
+	// ErrNotEncrypted is returned when an encryption key is given for storage
+	// that was written without encryption. Badger cannot encrypt existing data
+	// in place, so the data must be restored from a snapshot into a new
+	// encrypted store.
+	ErrNotEncrypted = errors.New("data was written without encryption and cannot be opened with an encryption key")
+	// ErrEncryptionKeyRequired is returned when storage that was written with
+	// encryption is opened without a key.
+	ErrEncryptionKeyRequired = errors.New("data was written with encryption and requires an encryption key")
+)
+
+// EncryptionOptions are options for encrypting data at rest.
+type EncryptionOptions struct {
+	// Key is the AES key used to encrypt data. It must be 16, 24, or 32 bytes.
+	Key []byte
+	// PreviousKey is the key the data is currently encrypted with when
+	// rotating to a new key. The data is re-keyed to Key before it is opened.
+	// It is safe to leave this set after the rotation has completed.
+	PreviousKey []byte
+	// RotationInterval is how often the internal data keys are rotated.
+	// Defaults to the badger default of 10 days.
+	RotationInterval time.Duration
+}
+
+// ParseEncryptionKey decodes an encryption key. The key must name its encoding
+// with one of the prefixes "hex:", "base64:", or "raw:", and must decode to 16,
+// 24, or 32 bytes.
+func ParseEncryptionKey(data string) ([]byte, error) {
+	encoding, value, ok := strings.Cut(strings.TrimSpace(data), ":")
+	if !ok {
+		return nil, errors.New(`encryption key must be prefixed with its encoding, one of "hex:", "base64:", or "raw:"`)
+	}
+	var key []byte
+	var err error
+	switch encoding {
+	case "hex":
+		key, err = hex.DecodeString(value)
+	case "base64":
+		key, err = base64.StdEncoding.DecodeString(value)
+	case "raw":
+		key = []byte(value)
+	default:
+		return nil, fmt.Errorf("unsupported encryption key encoding %q", encoding)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode %s encryption key: %w", encoding, err)
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	}
+	return nil, fmt.Errorf("encryption key must be 16, 24, or 32 bytes, got %d", len(key))
+}
+
+// ReadEncryptionKey reads and decodes an encryption key from the given file.
+func ReadEncryptionKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read encryption key: %w", err)
+	}
+	key, err := ParseEncryptionKey(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("parse encryption key from %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// OpenBadger opens a badger database with the given encryption options.
+// If enc is nil the database is opened without encryption. If a previous key
+// is set, the database is re-keyed before it is opened unless it is opened
+// read-only. A wrong key is reported as ErrEncryptionKeyMismatch, a key for
+// data written without encryption as ErrNotEncrypted, and a missing key for
+// encrypted data as ErrEncryptionKeyRequired.
+func OpenBadger(opts badger.Options, enc *EncryptionOptions) (*badger.DB, error) {
+	if enc != nil && len(enc.Key) > 0 {
+		opts = opts.WithEncryptionKey(enc.Key)
+		if enc.RotationInterval > 0 {
+			opts = opts.WithEncryptionKeyRotationDuration(enc.RotationInterval)
+		}
+		if opts.IndexCacheSize == 0 {
+			opts = opts.WithIndexCacheSize(DefaultIndexCacheSize)
+		}
+		if len(enc.PreviousKey) > 0 && !opts.InMemory && !opts.ReadOnly {
+			if err := rotateEncryptionKey(opts.Dir, enc.PreviousKey, enc.Key, opts.EncryptionKeyRotationDuration); err != nil {
+				return nil, fmt.Errorf("rotate encryption key: %w", err)
+			}
+		}
+	}
+	db, err := badger.Open(opts)
+	if err != nil {
+		if errors.Is(err, badger.ErrEncryptionKeyMismatch) {
+			return nil, fmt.Errorf("open %s: %w", opts.Dir, encryptionError(opts.Dir, len(opts.EncryptionKey) > 0))
+		}
+		return nil, err
+	}
+	return db, nil
+}
+
+// encryptionError returns the error to report when badger rejects the
+// encryption key for the key registry in dir. Badger reports the same error
+// whether the key is wrong or the data was never encrypted, so the registry
+// is opened without a key to tell them apart.
+func encryptionError(dir string, withKey bool) error {
+	if !withKey {
+		return ErrEncryptionKeyRequired
+	}
+	kr, err := badger.OpenKeyRegistry(badger.KeyRegistryOptions{
+		Dir:      dir,
+		ReadOnly: true,
+	})
+	if err != nil {
+		return ErrEncryptionKeyMismatch
+	}
+	kr.Close()
+	return ErrNotEncrypted
+}
+
+// rotateEncryptionKey re-encrypts the key registry in dir from the previous
+// key to the new key. The table and value log data is encrypted with data keys
+// held in the registry, so only the registry needs to be rewritten. It is a
+// no-op if the registry does not exist or is already using the new key.
+func rotateEncryptionKey(dir string, previous, key []byte, rotation time.Duration) error {
+	if string(previous) == string(key) {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, badger.KeyRegistryFileName)); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	opts := badger.KeyRegistryOptions{
+		Dir:                           dir,
+		ReadOnly:                      true,
+		EncryptionKey:                 previous,
+		EncryptionKeyRotationDuration: rotation,
+	}
+	kr, err := badger.OpenKeyRegistry(opts)
+	if err != nil {
+		if errors.Is(err, badger.ErrEncryptionKeyMismatch) {
+			// Either the rotation already happened or the previous key is
+			// wrong. Opening with the new key will tell us which.
+			return nil
+		}
+		return err
+	}
+	defer kr.Close()
+	opts.ReadOnly = false
+	opts.EncryptionKey = key
+	return badger.WriteKeyRegistry(kr, opts)
+}
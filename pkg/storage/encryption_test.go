@@ -0,0 +1,145 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryption(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+	open := func(enc *EncryptionOptions) (Storage, error) {
+		return New(&Options{DiskPath: dir, Silent: true, Encryption: enc})
+	}
+
+	db, err := open(&EncryptionOptions{Key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put(ctx, "/test/secret", "plaintext-value", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The value should not be readable from the files on disk.
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte("plaintext-value")) {
+			t.Fatalf("found plaintext value in %s", f)
+		}
+	}
+
+	// Opening without the key or with the wrong key should fail clearly.
+	if _, err := open(nil); !errors.Is(err, ErrEncryptionKeyRequired) {
+		t.Fatalf("expected ErrEncryptionKeyRequired, got %v", err)
+	}
+	if _, err := open(&EncryptionOptions{Key: newKey}); !errors.Is(err, ErrEncryptionKeyMismatch) {
+		t.Fatalf("expected ErrEncryptionKeyMismatch, got %v", err)
+	}
+
+	// Rotating to the new key should keep the data readable, and the
+	// previous key can be left in place afterwards.
+	for i := 0; i < 2; i++ {
+		db, err = open(&EncryptionOptions{Key: newKey, PreviousKey: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		val, err := db.Get(ctx, "/test/secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if val != "plaintext-value" {
+			t.Fatalf("unexpected value %q", val)
+		}
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := open(&EncryptionOptions{Key: key}); !errors.Is(err, ErrEncryptionKeyMismatch) {
+		t.Fatalf("expected ErrEncryptionKeyMismatch after rotation, got %v", err)
+	}
+}
+
+func TestParseEncryptionKey(t *testing.T) {
+	t.Parallel()
+
+	key := bytes.Repeat([]byte{0xab}, 16)
+	for _, in := range []string{
+		"hex:" + hex.EncodeToString(key),
+		"base64:" + base64.StdEncoding.EncodeToString(key),
+		"raw:" + string(key) + "\n",
+	} {
+		got, err := ParseEncryptionKey(in)
+		if err != nil {
+			t.Fatalf("parse %q: %v", in, err)
+		}
+		if !bytes.Equal(got, key) {
+			t.Fatalf("parse %q: got %x", in, got)
+		}
+	}
+	for _, in := range []string{
+		"raw:too-short",
+		// The encoding must be given explicitly.
+		hex.EncodeToString(key),
+		"base32:" + hex.EncodeToString(key),
+	} {
+		if _, err := ParseEncryptionKey(in); err == nil {
+			t.Fatalf("expected error parsing %q", in)
+		}
+	}
+}
+
+func TestEncryptionPlaintextStore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	db, err := New(&Options{DiskPath: dir, Silent: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put(context.Background(), "/test/key", "value", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = New(&Options{DiskPath: dir, Silent: true, Encryption: &EncryptionOptions{Key: bytes.Repeat([]byte{1}, 32)}})
+	if !errors.Is(err, ErrNotEncrypted) {
+		t.Fatalf("expected ErrNotEncrypted, got %v", err)
+	}
+}
@@ -0,0 +1,425 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/webmeshproj/webmesh/pkg/context"
+)
+
+// sweepInterval is how often expired keys are removed from engines that do
+// not support TTLs natively.
+const sweepInterval = time.Minute
+
+// record is a value stored by an engine.
+type record struct {
+	value string
+	rev   uint64
+	// expiresAt is the unix time in nanoseconds the record expires, or 0.
+	expiresAt int64
+}
+
+func (r record) expired(now int64) bool {
+	return r.expiresAt > 0 && r.expiresAt <= now
+}
+
+// engine is a minimal ordered key-value store. engineStorage implements
+// Storage on top of it for backends that only need to provide storage of
+// raw records.
+type engine interface {
+	// view runs fn in a read-only transaction.
+	view(fn func(tx engineTx) error) error
+	// update runs fn in a read-write transaction. If fn returns an error
+	// none of its changes are applied.
+	update(fn func(tx engineTx) error) error
+	// close closes the engine.
+	close() error
+}
+
+// engineTx is a transaction against an engine. Records returned by get and
+// ascend may be expired, engineStorage is responsible for hiding them.
+type engineTx interface {
+	// get returns the record for the key.
+	get(key string) (record, bool, error)
+	// set sets the record for the key.
+	set(key string, rec record) error
+	// delete removes the key if it exists.
+	delete(key string) error
+	// ascend calls fn in order for every key with the given prefix that is
+	// greater than or equal to start until fn returns false or an error.
+	ascend(prefix, start string, fn func(key string, rec record) (bool, error)) error
+	// clear removes all keys.
+	clear() error
+}
+
+// engineStorage implements Storage on top of an engine.
+type engineStorage struct {
+	name string
+	eng  engine
+	mu   sync.RWMutex
+	rev  uint64
+	hub  *watchHub
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func newEngineStorage(name string, eng engine, opts *Options) (*engineStorage, error) {
+	s := &engineStorage{
+		name: name,
+		eng:  eng,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if err := s.loadRevision(); err != nil {
+		return nil, fmt.Errorf("%s load revision: %w", name, err)
+	}
+	s.hub = newWatchHub(opts.WatchHistory, s.rev)
+	if opts.ReadOnly {
+		// Expired keys cannot be removed from a read-only storage.
+		close(s.done)
+		return s, nil
+	}
+	go s.sweep()
+	return s, nil
+}
+
+// Get returns the value of a key.
+func (s *engineStorage) Get(ctx context.Context, key string) (string, error) {
+	value, _, err := s.GetRevision(ctx, key)
+	return value, err
+}
+
+// GetRevision returns the value of a key along with its revision.
+func (s *engineStorage) GetRevision(ctx context.Context, key string) (string, uint64, error) {
+	rec, err := s.getRecord(key)
+	if err != nil {
+		return "", 0, err
+	}
+	return rec.value, rec.rev, nil
+}
+
+// GetTTL returns the time left until a key expires.
+func (s *engineStorage) GetTTL(ctx context.Context, key string) (time.Duration, error) {
+	rec, err := s.getRecord(key)
+	if err != nil || rec.expiresAt == 0 {
+		return 0, err
+	}
+	return time.Until(time.Unix(0, rec.expiresAt)), nil
+}
+
+// getRecord returns the record of a key that has not expired.
+func (s *engineStorage) getRecord(key string) (record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if key == "" {
+		return record{}, fmt.Errorf("%s get: key is empty", s.name)
+	}
+	var rec record
+	err := s.eng.view(func(tx engineTx) error {
+		var ok bool
+		var err error
+		rec, ok, err = tx.get(key)
+		if err != nil {
+			return fmt.Errorf("%s get: %w", s.name, err)
+		}
+		if !ok || rec.expired(time.Now().UnixNano()) {
+			return ErrKeyNotFound
+		}
+		return nil
+	})
+	return rec, err
+}
+
+// Put sets the value of a key.
+func (s *engineStorage) Put(ctx context.Context, key, value string, ttl time.Duration) error {
+	return s.Batch(ctx, []Op{{Type: OpPut, Key: key, Value: value, TTL: ttl}})
+}
+
+// PutIfRevision sets the value of a key if its revision matches.
+func (s *engineStorage) PutIfRevision(ctx context.Context, key, value string, revision uint64, ttl time.Duration) error {
+	return s.Batch(ctx, []Op{{Type: OpPutIfRevision, Key: key, Value: value, Revision: revision, TTL: ttl}})
+}
+
+// Delete removes a key.
+func (s *engineStorage) Delete(ctx context.Context, key string) error {
+	return s.Batch(ctx, []Op{{Type: OpDelete, Key: key}})
+}
+
+// Batch applies the given operations atomically.
+func (s *engineStorage) Batch(ctx context.Context, ops []Op) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, op := range ops {
+		if op.Key == "" {
+			return fmt.Errorf("%s batch: key is empty", s.name)
+		}
+	}
+	rev := s.rev + 1
+	now := time.Now()
+	events := make([]Event, 0, len(ops))
+	err := s.eng.update(func(tx engineTx) error {
+		// Check all conditions before making any changes.
+		for _, op := range ops {
+			if op.Type != OpPutIfRevision && op.Type != OpDeleteIfRevision {
+				continue
+			}
+			rec, ok, err := tx.get(op.Key)
+			if err != nil {
+				return fmt.Errorf("%s batch get %q: %w", s.name, op.Key, err)
+			}
+			var current uint64
+			if ok && !rec.expired(now.UnixNano()) {
+				current = rec.rev
+			}
+			if current != op.Revision {
+				return fmt.Errorf("%w: key %q is at revision %d, expected %d", ErrRevisionMismatch, op.Key, current, op.Revision)
+			}
+		}
+		for _, op := range ops {
+			switch op.Type {
+			case OpPut, OpPutIfRevision:
+				rec := record{value: op.Value, rev: rev}
+				if op.TTL > 0 {
+					rec.expiresAt = now.Add(op.TTL).UnixNano()
+				}
+				if err := tx.set(op.Key, rec); err != nil {
+					return fmt.Errorf("%s batch put %q: %w", s.name, op.Key, err)
+				}
+				events = append(events, Event{Type: EventPut, Key: op.Key, Value: op.Value, Revision: rev})
+			case OpDelete, OpDeleteIfRevision:
+				if err := tx.delete(op.Key); err != nil {
+					return fmt.Errorf("%s batch delete %q: %w", s.name, op.Key, err)
+				}
+				events = append(events, Event{Type: EventDelete, Key: op.Key, Revision: rev})
+			default:
+				return fmt.Errorf("%s batch: unknown operation type %v", s.name, op.Type)
+			}
+		}
+		return tx.set(revisionKey, record{value: encodeRevision(rev)})
+	})
+	if err != nil {
+		return err
+	}
+	s.rev = rev
+	s.hub.publish(rev, events)
+	return nil
+}
+
+// List returns all keys with a given prefix.
+func (s *engineStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := s.IterPrefix(ctx, prefix, func(key, _ string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	return keys, err
+}
+
+// IterPrefix iterates over all keys with a given prefix.
+func (s *engineStorage) IterPrefix(ctx context.Context, prefix string, fn PrefixIterator) error {
+	return s.IterPrefixAfter(ctx, prefix, "", fn)
+}
+
+// IterPrefixAfter iterates over all keys with a given prefix that sort after
+// the given key.
+func (s *engineStorage) IterPrefixAfter(ctx context.Context, prefix, after string, fn PrefixIterator) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := time.Now().UnixNano()
+	return s.eng.view(func(tx engineTx) error {
+		return tx.ascend(prefix, after, func(key string, rec record) (bool, error) {
+			if key == after || isInternalKey([]byte(key)) || rec.expired(now) {
+				return true, nil
+			}
+			return true, fn(key, rec.value)
+		})
+	})
+}
+
+// Snapshot returns a snapshot of the storage.
+func (s *engineStorage) Snapshot(ctx context.Context) (io.Reader, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UnixNano()
+	var entries []snapshotEntry
+	err := s.eng.view(func(tx engineTx) error {
+		return tx.ascend("", "", func(key string, rec record) (bool, error) {
+			if rec.expired(now) {
+				return true, nil
+			}
+			entries = append(entries, snapshotEntry{
+				key:       key,
+				value:     rec.value,
+				rev:       rec.rev,
+				expiresAt: rec.expiresAt,
+			})
+			return true, nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s snapshot: %w", s.name, err)
+	}
+	var buf bytes.Buffer
+	if err := writeSnapshot(&buf, entries); err != nil {
+		return nil, fmt.Errorf("%s snapshot: %w", s.name, err)
+	}
+	return &buf, nil
+}
+
+// Restore restores a snapshot of the storage.
+func (s *engineStorage) Restore(ctx context.Context, r io.Reader) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := readSnapshot(r)
+	if err != nil {
+		return fmt.Errorf("%s restore: %w", s.name, err)
+	}
+	err = s.eng.update(func(tx engineTx) error {
+		if err := tx.clear(); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			err := tx.set(e.key, record{value: e.value, rev: e.rev, expiresAt: e.expiresAt})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s restore: %w", s.name, err)
+	}
+	if err := s.loadRevision(); err != nil {
+		return fmt.Errorf("%s restore: %w", s.name, err)
+	}
+	s.hub.reset(s.rev)
+	return nil
+}
+
+// Revision returns the current revision of the storage.
+func (s *engineStorage) Revision(ctx context.Context) (uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rev, nil
+}
+
+// Watch calls fn for every change to a key with the given prefix made after
+// the given revision. Expiration of keys with a TTL does not produce events.
+func (s *engineStorage) Watch(ctx context.Context, prefix string, revision uint64, fn WatchFunc) (func(), error) {
+	return s.hub.watch(ctx, prefix, revision, fn)
+}
+
+// Subscribe will call the given function whenever a key with the given prefix is changed.
+func (s *engineStorage) Subscribe(ctx context.Context, prefix string, fn SubscribeFunc) (func(), error) {
+	return subscribe(ctx, s, prefix, fn)
+}
+
+// Close closes the storage.
+func (s *engineStorage) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.stop)
+		<-s.done
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.hub.close()
+		err = s.eng.close()
+	})
+	return err
+}
+
+// loadRevision reads the current revision of the storage. The caller must
+// hold the write lock or be the only user of the storage.
+func (s *engineStorage) loadRevision() error {
+	rev := initialRevision
+	err := s.eng.view(func(tx engineTx) error {
+		rec, ok, err := tx.get(revisionKey)
+		if err != nil || !ok {
+			return err
+		}
+		rev, err = decodeRevision(rec.value)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.rev = rev
+	return nil
+}
+
+// sweep periodically removes expired keys until the storage is closed.
+func (s *engineStorage) sweep() {
+	defer close(s.done)
+	t := time.NewTicker(sweepInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-t.C:
+			if err := s.removeExpired(); err != nil {
+				context.LoggerFrom(context.Background()).Error("remove expired keys", "backend", s.name, "error", err.Error())
+			}
+		}
+	}
+}
+
+func (s *engineStorage) removeExpired() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UnixNano()
+	var expired []string
+	err := s.eng.view(func(tx engineTx) error {
+		return tx.ascend("", "", func(key string, rec record) (bool, error) {
+			if rec.expired(now) {
+				expired = append(expired, key)
+			}
+			return true, nil
+		})
+	})
+	if err != nil || len(expired) == 0 {
+		return err
+	}
+	return s.eng.update(func(tx engineTx) error {
+		for _, key := range expired {
+			if err := tx.delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func encodeRevision(rev uint64) string {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], rev)
+	return string(buf[:])
+}
+
+func decodeRevision(val string) (uint64, error) {
+	if len(val) != 8 {
+		return 0, fmt.Errorf("invalid revision length %d", len(val))
+	}
+	return binary.BigEndian.Uint64([]byte(val)), nil
+}
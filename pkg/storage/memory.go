@@ -0,0 +1,117 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/google/btree"
+)
+
+// memoryDegree is the degree of the B-tree used by the memory backend.
+const memoryDegree = 32
+
+func init() {
+	RegisterBackend(BackendMemory, newMemoryStorage)
+}
+
+func newMemoryStorage(opts *Options) (Storage, error) {
+	return newEngineStorage(string(BackendMemory), &memoryEngine{tree: newMemoryTree()}, opts)
+}
+
+type memoryItem struct {
+	key string
+	rec record
+}
+
+func newMemoryTree() *btree.BTreeG[memoryItem] {
+	return btree.NewG(memoryDegree, func(a, b memoryItem) bool {
+		return a.key < b.key
+	})
+}
+
+// memoryEngine is an engine backed by an in-memory B-tree. Writes are made to
+// a copy-on-write clone of the tree that replaces it when the update succeeds.
+type memoryEngine struct {
+	mu   sync.RWMutex
+	tree *btree.BTreeG[memoryItem]
+}
+
+func (m *memoryEngine) view(fn func(tx engineTx) error) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return fn(&memoryTx{tree: m.tree})
+}
+
+func (m *memoryEngine) update(fn func(tx engineTx) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tx := &memoryTx{tree: m.tree.Clone()}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	m.tree = tx.tree
+	return nil
+}
+
+func (m *memoryEngine) close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tree.Clear(false)
+	return nil
+}
+
+type memoryTx struct {
+	tree *btree.BTreeG[memoryItem]
+}
+
+func (tx *memoryTx) get(key string) (record, bool, error) {
+	item, ok := tx.tree.Get(memoryItem{key: key})
+	return item.rec, ok, nil
+}
+
+func (tx *memoryTx) set(key string, rec record) error {
+	tx.tree.ReplaceOrInsert(memoryItem{key: key, rec: rec})
+	return nil
+}
+
+func (tx *memoryTx) delete(key string) error {
+	tx.tree.Delete(memoryItem{key: key})
+	return nil
+}
+
+func (tx *memoryTx) ascend(prefix, start string, fn func(key string, rec record) (bool, error)) error {
+	var err error
+	if start < prefix {
+		start = prefix
+	}
+	tx.tree.AscendGreaterOrEqual(memoryItem{key: start}, func(item memoryItem) bool {
+		if !strings.HasPrefix(item.key, prefix) {
+			return false
+		}
+		var ok bool
+		ok, err = fn(item.key, item.rec)
+		return ok && err == nil
+	})
+	return err
+}
+
+func (tx *memoryTx) clear() error {
+	tx.tree = newMemoryTree()
+	return nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Storage Metrics
+var (
+	// KeysTotal tracks the number of keys in the database by prefix.
+	KeysTotal = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "webmesh",
+		Name:      "storage_keys",
+		Help:      "The current number of keys in the database by prefix.",
+	}, []string{"node_id", "prefix"})
+
+	// BytesTotal tracks the size of the keys and values in the database by prefix.
+	BytesTotal = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "webmesh",
+		Name:      "storage_bytes",
+		Help:      "The current size of the keys and values in the database by prefix.",
+	}, []string{"node_id", "prefix"})
+
+	// BadgerLSMSize tracks the size of the badger LSM tree.
+	BadgerLSMSize = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "webmesh",
+		Name:      "storage_badger_lsm_bytes",
+		Help:      "The current size of the badger LSM tree.",
+	}, []string{"node_id"})
+
+	// BadgerVlogSize tracks the size of the badger value log.
+	BadgerVlogSize = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "webmesh",
+		Name:      "storage_badger_vlog_bytes",
+		Help:      "The current size of the badger value log.",
+	}, []string{"node_id"})
+)
+
+// MetricsRecorder records usage metrics for a storage.
+type MetricsRecorder struct {
+	st       Storage
+	nodeID   string
+	prefixes map[string]struct{}
+	mux      sync.Mutex
+	log      *slog.Logger
+}
+
+// NewMetricsRecorder returns a new MetricsRecorder for the storage of the given node.
+func NewMetricsRecorder(nodeID string, st Storage) *MetricsRecorder {
+	return &MetricsRecorder{
+		st:       st,
+		nodeID:   nodeID,
+		prefixes: make(map[string]struct{}),
+		log:      slog.Default().With("component", "storage-metrics"),
+	}
+}
+
+// Run starts the metrics recorder.
+func (m *MetricsRecorder) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := m.updateMetrics(ctx); err != nil {
+			m.log.Error("update metrics", slog.String("error", err.Error()))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// updateMetrics updates the prometheus metrics for the storage.
+func (m *MetricsRecorder) updateMetrics(ctx context.Context) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	stats, err := Stats(ctx, m.st)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]struct{}, len(stats))
+	for _, s := range stats {
+		seen[s.Prefix] = struct{}{}
+		m.prefixes[s.Prefix] = struct{}{}
+		KeysTotal.WithLabelValues(m.nodeID, s.Prefix).Set(float64(s.Keys))
+		BytesTotal.WithLabelValues(m.nodeID, s.Prefix).Set(float64(s.Bytes))
+	}
+	// Drop prefixes that no longer have any keys.
+	for prefix := range m.prefixes {
+		if _, ok := seen[prefix]; !ok {
+			KeysTotal.DeleteLabelValues(m.nodeID, prefix)
+			BytesTotal.DeleteLabelValues(m.nodeID, prefix)
+			delete(m.prefixes, prefix)
+		}
+	}
+	if b, ok := m.st.(*badgerStorage); ok {
+		lsm, vlog := b.db.Size()
+		BadgerLSMSize.WithLabelValues(m.nodeID).Set(float64(lsm))
+		BadgerVlogSize.WithLabelValues(m.nodeID).Set(float64(vlog))
+	}
+	return nil
+}
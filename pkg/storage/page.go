@@ -0,0 +1,109 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidContinueToken is returned when a continue token is malformed or
+// was issued for a different prefix.
+var ErrInvalidContinueToken = errors.New("invalid continue token")
+
+// Page selects a window of keys when listing a prefix. Pages are ordered
+// lexicographically by key, so a listing resumed from a continue token is
+// stable across writes: keys added or removed before the token are not
+// revisited, and keys after it are seen at most once.
+type Page struct {
+	// Limit is the maximum number of keys to return. Zero means no limit.
+	Limit int
+	// Continue is the token returned by a previous page to resume from.
+	// Empty starts from the beginning of the prefix.
+	Continue string
+}
+
+// errPageFull is used internally to stop iteration once a page is full.
+var errPageFull = errors.New("page full")
+
+// IterPage calls fn for each key with the given prefix in the requested page.
+// It returns a token for the next page, or an empty string when there are no
+// more keys.
+func IterPage(ctx context.Context, st Storage, prefix string, page Page, fn PrefixIterator) (string, error) {
+	after, err := DecodeContinueToken(prefix, page.Continue)
+	if err != nil {
+		return "", err
+	}
+	var seen int
+	var last string
+	var more bool
+	err = st.IterPrefixAfter(ctx, prefix, after, func(key, value string) error {
+		if page.Limit > 0 && seen == page.Limit {
+			more = true
+			return errPageFull
+		}
+		if err := fn(key, value); err != nil {
+			return err
+		}
+		seen++
+		last = key
+		return nil
+	})
+	if err != nil && !errors.Is(err, errPageFull) {
+		return "", err
+	}
+	if !more {
+		return "", nil
+	}
+	return EncodeContinueToken(last), nil
+}
+
+// ListPage returns the keys with the given prefix in the requested page and
+// a token for the next page.
+func ListPage(ctx context.Context, st Storage, prefix string, page Page) ([]string, string, error) {
+	var keys []string
+	next, err := IterPage(ctx, st, prefix, page, func(key, _ string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	return keys, next, err
+}
+
+// EncodeContinueToken returns an opaque continue token resuming after the
+// given key.
+func EncodeContinueToken(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// DecodeContinueToken returns the key a continue token resumes after. The
+// key must be within the given prefix. An empty token decodes to an empty
+// key.
+func DecodeContinueToken(prefix, token string) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+	key, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", ErrInvalidContinueToken
+	}
+	if !strings.HasPrefix(string(key), prefix) {
+		return "", ErrInvalidContinueToken
+	}
+	return string(key), nil
+}
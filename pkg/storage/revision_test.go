@@ -0,0 +1,116 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRevisions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, err := NewTestStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Creating a key with a revision of zero should only succeed once.
+	if err := db.PutIfRevision(ctx, "/test/a", "a", 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PutIfRevision(ctx, "/test/a", "a", 0, 0); !errors.Is(err, ErrRevisionMismatch) {
+		t.Fatalf("expected ErrRevisionMismatch, got %v", err)
+	}
+	val, rev, err := db.GetRevision(ctx, "/test/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != "a" || rev == 0 {
+		t.Fatalf("unexpected value %q at revision %d", val, rev)
+	}
+
+	// Unrelated writes should not change the revision of the key.
+	if err := db.Put(ctx, "/test/b", "b", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, got, _ := db.GetRevision(ctx, "/test/a"); got != rev {
+		t.Fatalf("expected revision %d, got %d", rev, got)
+	}
+
+	// Writes should move the key to a new revision.
+	if err := db.PutIfRevision(ctx, "/test/a", "a2", rev, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PutIfRevision(ctx, "/test/a", "a3", rev, 0); !errors.Is(err, ErrRevisionMismatch) {
+		t.Fatalf("expected ErrRevisionMismatch, got %v", err)
+	}
+	val, newRev, err := db.GetRevision(ctx, "/test/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != "a2" || newRev <= rev {
+		t.Fatalf("unexpected value %q at revision %d", val, newRev)
+	}
+
+	// A failed condition should fail the whole batch.
+	err = db.Batch(ctx, []Op{
+		{Type: OpPut, Key: "/test/c", Value: "c"},
+		{Type: OpPutIfRevision, Key: "/test/a", Value: "a4", Revision: rev},
+	})
+	if !errors.Is(err, ErrRevisionMismatch) {
+		t.Fatalf("expected ErrRevisionMismatch, got %v", err)
+	}
+	if _, err := db.Get(ctx, "/test/c"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected no writes from failed batch, got %v", err)
+	}
+
+	// Internal keys should never be listed.
+	keys, err := db.List(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 keys, got %v", keys)
+	}
+
+	// Revisions should survive a snapshot and restore.
+	snapshot, err := db.Snapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := NewTestStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	if err := restored.Restore(ctx, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if _, got, _ := restored.GetRevision(ctx, "/test/a"); got != newRev {
+		t.Fatalf("expected revision %d after restore, got %d", newRev, got)
+	}
+	if err := restored.Put(ctx, "/test/d", "d", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, got, _ := restored.GetRevision(ctx, "/test/d"); got <= newRev {
+		t.Fatalf("expected revision after %d for new write, got %d", newRev, got)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dgraph-io/badger/v4/pb"
+)
+
+// Snapshots are written in the badger backup format so that a snapshot taken
+// by one backend can be restored by any other. This keeps raft snapshots
+// portable between nodes running different backends.
+
+const (
+	// snapshotBatchSize is the number of entries written per list.
+	snapshotBatchSize = 1000
+	// badgerBitDelete is set in the meta of badger backup entries that mark
+	// a key as deleted.
+	badgerBitDelete byte = 1 << 0
+)
+
+// snapshotEntry is a single key in a snapshot.
+type snapshotEntry struct {
+	key   string
+	value string
+	rev   uint64
+	// expiresAt is the unix time in nanoseconds the key expires, or 0.
+	expiresAt int64
+}
+
+// writeSnapshot writes the entries to w in the badger backup format.
+func writeSnapshot(w io.Writer, entries []snapshotEntry) error {
+	for len(entries) > 0 {
+		n := min(len(entries), snapshotBatchSize)
+		list := &pb.KVList{Kv: make([]*pb.KV, 0, n)}
+		for _, e := range entries[:n] {
+			kv := &pb.KV{
+				Key:     []byte(e.key),
+				Version: 1,
+			}
+			if isInternalKey(kv.Key) {
+				kv.Value = []byte(e.value)
+			} else {
+				kv.Value = encodeValue(e.value, e.rev)
+				kv.UserMeta = []byte{metaRevision}
+			}
+			if e.expiresAt > 0 {
+				// Badger expiry is in seconds, round up so keys never
+				// expire early.
+				kv.ExpiresAt = uint64((e.expiresAt + int64(time.Second) - 1) / int64(time.Second))
+			}
+			list.Kv = append(list.Kv, kv)
+		}
+		data, err := list.Marshal()
+		if err != nil {
+			return fmt.Errorf("marshal snapshot list: %w", err)
+		}
+		if err := binary.Write(w, binary.LittleEndian, uint64(len(data))); err != nil {
+			return fmt.Errorf("write snapshot list size: %w", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("write snapshot list: %w", err)
+		}
+		entries = entries[n:]
+	}
+	return nil
+}
+
+// readSnapshot reads the entries from a snapshot in the badger backup format.
+// Only the latest version of each key is returned, and keys that were deleted
+// or have expired are omitted.
+func readSnapshot(r io.Reader) ([]snapshotEntry, error) {
+	latest := make(map[string]*pb.KV)
+	br := bufio.NewReader(r)
+	for {
+		var size uint64
+		err := binary.Read(br, binary.LittleEndian, &size)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("read snapshot list size: %w", err)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, fmt.Errorf("read snapshot list: %w", err)
+		}
+		var list pb.KVList
+		if err := list.Unmarshal(data); err != nil {
+			return nil, fmt.Errorf("unmarshal snapshot list: %w", err)
+		}
+		for _, kv := range list.Kv {
+			if kv.StreamDone {
+				continue
+			}
+			if current, ok := latest[string(kv.Key)]; !ok || kv.Version > current.Version {
+				latest[string(kv.Key)] = kv
+			}
+		}
+	}
+	now := uint64(time.Now().Unix())
+	entries := make([]snapshotEntry, 0, len(latest))
+	for key, kv := range latest {
+		if len(kv.Meta) > 0 && kv.Meta[0]&badgerBitDelete != 0 {
+			continue
+		}
+		if kv.ExpiresAt > 0 && kv.ExpiresAt <= now {
+			continue
+		}
+		var meta byte
+		if len(kv.UserMeta) > 0 {
+			meta = kv.UserMeta[0]
+		}
+		value, rev, err := decodeValue(meta, kv.Value)
+		if err != nil {
+			return nil, fmt.Errorf("decode snapshot key %q: %w", key, err)
+		}
+		entries = append(entries, snapshotEntry{
+			key:       key,
+			value:     value,
+			rev:       rev,
+			expiresAt: int64(kv.ExpiresAt) * int64(time.Second),
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// RegistryPrefix is the prefix under which all mesh resources are stored.
+const RegistryPrefix = "/registry"
+
+// PrefixStats is the usage of the keys under a single prefix.
+type PrefixStats struct {
+	// Prefix is the prefix the keys are grouped under.
+	Prefix string `json:"prefix"`
+	// Keys is the number of keys under the prefix.
+	Keys int `json:"keys"`
+	// Bytes is the combined size of the keys and values under the prefix.
+	Bytes int64 `json:"bytes"`
+}
+
+// StatsPrefix returns the prefix a key is accounted under. Keys under
+// /registry are grouped by resource type, e.g. /registry/nodes, and all
+// other keys by their first path segment.
+func StatsPrefix(key string) string {
+	depth := 1
+	if strings.HasPrefix(key, RegistryPrefix+"/") {
+		depth = 2
+	}
+	parts := strings.SplitN(strings.TrimPrefix(key, "/"), "/", depth+1)
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// Stats walks the storage and returns the key counts and sizes for each
+// prefix, sorted by prefix.
+func Stats(ctx context.Context, st Storage) ([]PrefixStats, error) {
+	byPrefix := make(map[string]*PrefixStats)
+	err := st.IterPrefix(ctx, "", func(key, value string) error {
+		prefix := StatsPrefix(key)
+		stats, ok := byPrefix[prefix]
+		if !ok {
+			stats = &PrefixStats{Prefix: prefix}
+			byPrefix[prefix] = stats
+		}
+		stats.Keys++
+		stats.Bytes += int64(len(key) + len(value))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]PrefixStats, 0, len(byPrefix))
+	for _, stats := range byPrefix {
+		out = append(out, *stats)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Prefix < out[j].Prefix })
+	return out, nil
+}
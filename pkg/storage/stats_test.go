@@ -0,0 +1,58 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	st, err := NewTestStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	for key, value := range map[string]string{
+		"/registry/nodes/a":           "aa",
+		"/registry/nodes/b":           "bbb",
+		"/registry/routes/a-auto":     "r",
+		"/registry/indexes/nodes/z/a": "",
+		"/other":                      "x",
+	} {
+		if err := st.Put(ctx, key, value, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats, err := Stats(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []PrefixStats{
+		{Prefix: "/other", Keys: 1, Bytes: int64(len("/other") + 1)},
+		{Prefix: "/registry/indexes", Keys: 1, Bytes: int64(len("/registry/indexes/nodes/z/a"))},
+		{Prefix: "/registry/nodes", Keys: 2, Bytes: int64(2*len("/registry/nodes/a") + 5)},
+		{Prefix: "/registry/routes", Keys: 1, Bytes: int64(len("/registry/routes/a-auto") + 1)},
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, stats)
+	}
+}
@@ -29,49 +29,149 @@ import (
 type Storage interface {
 	// Get returns the value of a key.
 	Get(ctx context.Context, key string) (string, error)
+	// GetTTL returns the time left until a key expires. It returns 0 for
+	// keys that do not expire.
+	GetTTL(ctx context.Context, key string) (time.Duration, error)
+	// GetRevision returns the value of a key along with the revision at which
+	// it was last modified.
+	GetRevision(ctx context.Context, key string) (value string, revision uint64, err error)
 	// Put sets the value of a key. TTL is optional and can be set to 0.
 	Put(ctx context.Context, key, value string, ttl time.Duration) error
+	// PutIfRevision sets the value of a key only if its current revision matches
+	// the given revision. A revision of 0 requires that the key does not exist.
+	// ErrRevisionMismatch is returned if the revision does not match.
+	PutIfRevision(ctx context.Context, key, value string, revision uint64, ttl time.Duration) error
 	// Delete removes a key.
 	Delete(ctx context.Context, key string) error
+	// Batch applies the given operations atomically. Either all of the
+	// operations are applied or none of them are.
+	Batch(ctx context.Context, ops []Op) error
 	// List returns all keys with a given prefix.
 	List(ctx context.Context, prefix string) ([]string, error)
 	// IterPrefix iterates over all keys with a given prefix. It is important
 	// that the iterator not attempt any write operations as this will cause
 	// a deadlock.
 	IterPrefix(ctx context.Context, prefix string, fn PrefixIterator) error
+	// IterPrefixAfter is like IterPrefix but only visits keys that sort
+	// lexicographically after the given key. An empty key visits the whole
+	// prefix.
+	IterPrefixAfter(ctx context.Context, prefix, after string, fn PrefixIterator) error
 	// Snapshot returns a snapshot of the storage.
 	Snapshot(ctx context.Context) (io.Reader, error)
 	// Restore restores a snapshot of the storage.
 	Restore(ctx context.Context, r io.Reader) error
+	// Revision returns the current revision of the storage.
+	Revision(ctx context.Context) (uint64, error)
+	// Watch calls fn for every change to a key with the given prefix made after
+	// the given revision. A revision of 0 watches from the current revision. If
+	// the changes after the revision are no longer retained, an EventCompacted
+	// is delivered first and the caller should relist. The returned function can
+	// be called to stop watching.
+	Watch(ctx context.Context, prefix string, revision uint64, fn WatchFunc) (func(), error)
 	// Subscribe will call the given function whenever a key with the given prefix is changed.
-	// The returned function can be called to unsubscribe.
+	// Deletes are delivered with an empty value. The returned function can be called to
+	// unsubscribe.
 	Subscribe(ctx context.Context, prefix string, fn SubscribeFunc) (func(), error)
 	// Close closes the storage.
 	Close() error
 }
 
+// OpType is the type of an operation in a batch.
+type OpType int
+
+const (
+	// OpPut sets the value of a key.
+	OpPut OpType = iota
+	// OpDelete removes a key.
+	OpDelete
+	// OpPutIfRevision sets the value of a key only if its current revision
+	// matches the revision of the operation. If any conditional operation in
+	// a batch fails, none of the operations are applied.
+	OpPutIfRevision
+	// OpDeleteIfRevision removes a key only if its current revision matches
+	// the revision of the operation. It fails like OpPutIfRevision.
+	OpDeleteIfRevision
+)
+
+// String returns the string representation of the operation type.
+func (o OpType) String() string {
+	switch o {
+	case OpPut:
+		return "PUT"
+	case OpDelete:
+		return "DELETE"
+	case OpPutIfRevision:
+		return "PUT_IF_REVISION"
+	case OpDeleteIfRevision:
+		return "DELETE_IF_REVISION"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Op is a single operation in a batch.
+type Op struct {
+	// Type is the type of the operation.
+	Type OpType
+	// Key is the key to operate on.
+	Key string
+	// Value is the value to set. It is ignored for deletes.
+	Value string
+	// TTL is the optional time to live for puts.
+	TTL time.Duration
+	// Revision is the expected revision of the key for conditional puts
+	// and deletes.
+	Revision uint64
+}
+
 // SubscribeFunc is the function signature for subscribing to changes to a key.
 type SubscribeFunc func(key, value string)
 
 // PrefixIterator is the function signature for iterating over all keys with a given prefix.
 type PrefixIterator func(key, value string) error
 
-// ErrKeyNotFound is the error returned when a key is not found.
-var ErrKeyNotFound = errors.New("key not found")
+var (
+	// ErrKeyNotFound is the error returned when a key is not found.
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrBatchTooLarge is the error returned when a batch is too large to
+	// be applied atomically by the storage backend. Callers writing many
+	// keys should split their writes into smaller batches.
+	ErrBatchTooLarge = errors.New("batch is too large to apply atomically")
+	// ErrRevisionMismatch is the error returned when a conditional write
+	// fails because the key was modified since it was read.
+	ErrRevisionMismatch = errors.New("revision mismatch")
+)
 
 // Options are the options for creating a new Storage.
 type Options struct {
+	// Backend is the storage backend to use. Defaults to BackendMemory for
+	// in-memory storage and BackendBadger otherwise.
+	Backend Backend
 	// InMemory specifies whether to use an in-memory storage.
 	InMemory bool
 	// DiskPath is the path to the disk storage.
 	DiskPath string
 	// Silent specifies whether to suppress log output.
 	Silent bool
+	// WatchHistory is the number of recent events retained for resuming
+	// watches. Defaults to DefaultWatchHistory.
+	WatchHistory int
+	// Encryption are the options for encrypting data at rest. If nil,
+	// data is stored unencrypted.
+	Encryption *EncryptionOptions
+	// ReadOnly opens an existing on-disk storage without modifying it.
+	// Writes to a read-only storage fail. It is used for inspecting the
+	// data of a node that is not running.
+	ReadOnly bool
 }
 
 // New returns a new Storage.
 func New(opts *Options) (Storage, error) {
-	return newBadgerStorage(opts)
+	factory, err := backendFor(opts)
+	if err != nil {
+		return nil, err
+	}
+	return factory(opts)
 }
 
 // NewTestStorage is a helper for creating an in-memory storage suitable
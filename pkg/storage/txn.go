@@ -0,0 +1,342 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Txn buffers write operations against a Storage and applies them with a
+// single call to Batch when committed. Reads made through the Txn observe
+// the buffered writes. A Txn is not safe to share between goroutines that
+// expect isolation from each other.
+type Txn struct {
+	Storage
+	ops []Op
+	mu  sync.Mutex
+}
+
+// NewTxn returns a new transaction against the given storage.
+func NewTxn(st Storage) *Txn {
+	return &Txn{Storage: st}
+}
+
+// Get returns the value of a key. Buffered writes take precedence over
+// the underlying storage.
+func (t *Txn) Get(ctx context.Context, key string) (string, error) {
+	t.mu.Lock()
+	op, ok := t.lastOp(key)
+	t.mu.Unlock()
+	if ok {
+		if op.Type == OpDelete || op.Type == OpDeleteIfRevision {
+			return "", ErrKeyNotFound
+		}
+		return op.Value, nil
+	}
+	return t.Storage.Get(ctx, key)
+}
+
+// GetTTL returns the time left until a key expires. Buffered writes take
+// precedence over the underlying storage.
+func (t *Txn) GetTTL(ctx context.Context, key string) (time.Duration, error) {
+	t.mu.Lock()
+	op, ok := t.lastOp(key)
+	t.mu.Unlock()
+	if ok {
+		if op.Type == OpDelete || op.Type == OpDeleteIfRevision {
+			return 0, ErrKeyNotFound
+		}
+		return op.TTL, nil
+	}
+	return t.Storage.GetTTL(ctx, key)
+}
+
+// GetRevision returns the value and revision of a key. Keys with buffered
+// writes report their buffered value along with their revision in the
+// underlying storage, which is what conditional puts in the transaction are
+// checked against when it is committed.
+func (t *Txn) GetRevision(ctx context.Context, key string) (string, uint64, error) {
+	t.mu.Lock()
+	op, ok := t.lastOp(key)
+	t.mu.Unlock()
+	if !ok {
+		return t.Storage.GetRevision(ctx, key)
+	}
+	_, rev, err := t.Storage.GetRevision(ctx, key)
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return "", 0, err
+	}
+	if op.Type == OpDelete || op.Type == OpDeleteIfRevision {
+		return "", 0, ErrKeyNotFound
+	}
+	return op.Value, rev, nil
+}
+
+// Put buffers setting the value of a key.
+func (t *Txn) Put(ctx context.Context, key, value string, ttl time.Duration) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ops = append(t.ops, Op{Type: OpPut, Key: key, Value: value, TTL: ttl})
+	return nil
+}
+
+// PutIfRevision buffers a conditional put. The condition is checked against
+// the underlying storage when the transaction is committed, and the whole
+// transaction fails with ErrRevisionMismatch if it does not hold.
+func (t *Txn) PutIfRevision(ctx context.Context, key, value string, revision uint64, ttl time.Duration) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ops = append(t.ops, Op{Type: OpPutIfRevision, Key: key, Value: value, TTL: ttl, Revision: revision})
+	return nil
+}
+
+// Delete buffers removing a key.
+func (t *Txn) Delete(ctx context.Context, key string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ops = append(t.ops, Op{Type: OpDelete, Key: key})
+	return nil
+}
+
+// Batch buffers the given operations.
+func (t *Txn) Batch(ctx context.Context, ops []Op) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ops = append(t.ops, ops...)
+	return nil
+}
+
+// List returns all keys with a given prefix, including buffered puts and
+// excluding buffered deletes.
+func (t *Txn) List(ctx context.Context, prefix string) ([]string, error) {
+	keys, err := t.Storage.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.ops) == 0 {
+		return keys, nil
+	}
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		seen[key] = struct{}{}
+	}
+	for _, op := range t.ops {
+		if !strings.HasPrefix(op.Key, prefix) {
+			continue
+		}
+		switch op.Type {
+		case OpPut, OpPutIfRevision:
+			seen[op.Key] = struct{}{}
+		case OpDelete, OpDeleteIfRevision:
+			delete(seen, op.Key)
+		}
+	}
+	out := make([]string, 0, len(seen))
+	for key := range seen {
+		out = append(out, key)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// IterPrefix iterates over all keys with a given prefix, including buffered
+// puts and excluding buffered deletes. Unlike the underlying storage, it is
+// safe to write to the Txn from the iterator.
+func (t *Txn) IterPrefix(ctx context.Context, prefix string, fn PrefixIterator) error {
+	values := make(map[string]string)
+	err := t.Storage.IterPrefix(ctx, prefix, func(key, value string) error {
+		values[key] = value
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	for _, op := range t.ops {
+		if !strings.HasPrefix(op.Key, prefix) {
+			continue
+		}
+		switch op.Type {
+		case OpPut, OpPutIfRevision:
+			values[op.Key] = op.Value
+		case OpDelete, OpDeleteIfRevision:
+			delete(values, op.Key)
+		}
+	}
+	t.mu.Unlock()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := fn(key, values[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IterPrefixAfter is like IterPrefix but only visits keys that sort after
+// the given key.
+func (t *Txn) IterPrefixAfter(ctx context.Context, prefix, after string, fn PrefixIterator) error {
+	return t.IterPrefix(ctx, prefix, func(key, value string) error {
+		if key <= after {
+			return nil
+		}
+		return fn(key, value)
+	})
+}
+
+// Ops returns a copy of the currently buffered operations.
+func (t *Txn) Ops() []Op {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]Op, len(t.ops))
+	copy(out, t.ops)
+	return out
+}
+
+// Commit applies all buffered operations to the underlying storage in a
+// single batch. The Txn is empty after a successful commit and may be reused.
+func (t *Txn) Commit(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.ops) == 0 {
+		return nil
+	}
+	if err := t.Storage.Batch(ctx, t.ops); err != nil {
+		return err
+	}
+	t.ops = nil
+	return nil
+}
+
+// CommitUndo applies all buffered operations like Commit and returns the
+// operations that revert them. Every key written by the transaction is put
+// back with the value and TTL it had before, or removed if it did not exist.
+// The operations are conditional on the revision the commit wrote the keys
+// at, so that applying them fails with ErrRevisionMismatch rather than
+// reverting a key that was changed since. If the revisions cannot be looked
+// up after a successful commit, the error is returned without any operations.
+func (t *Txn) CommitUndo(ctx context.Context) ([]Op, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.ops) == 0 {
+		return nil, nil
+	}
+	type prior struct {
+		key     string
+		value   string
+		ttl     time.Duration
+		existed bool
+		deleted bool
+	}
+	var priors []prior
+	seen := make(map[string]struct{}, len(t.ops))
+	for _, op := range t.ops {
+		if _, ok := seen[op.Key]; ok {
+			continue
+		}
+		seen[op.Key] = struct{}{}
+		last, _ := t.lastOp(op.Key)
+		p := prior{
+			key:     op.Key,
+			deleted: last.Type == OpDelete || last.Type == OpDeleteIfRevision,
+		}
+		value, err := t.Storage.Get(ctx, op.Key)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			ttl, err := t.Storage.GetTTL(ctx, op.Key)
+			if err != nil && !errors.Is(err, ErrKeyNotFound) {
+				return nil, err
+			}
+			// A key that expired since it was read did not exist.
+			p.value, p.ttl, p.existed = value, ttl, err == nil
+		}
+		priors = append(priors, p)
+	}
+	if err := t.Storage.Batch(ctx, t.ops); err != nil {
+		return nil, err
+	}
+	t.ops = nil
+	// Every key is written at the same revision. Keys that were changed
+	// again since are at a later one, so the lowest revision is the
+	// revision of the commit.
+	var revision uint64
+	for _, p := range priors {
+		if p.deleted {
+			continue
+		}
+		_, rev, err := t.Storage.GetRevision(ctx, p.key)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("lookup revision of %q: %w", p.key, err)
+		}
+		if revision == 0 || rev < revision {
+			revision = rev
+		}
+	}
+	undo := make([]Op, 0, len(priors))
+	for _, p := range priors {
+		switch {
+		case p.existed && p.deleted:
+			undo = append(undo, Op{Type: OpPutIfRevision, Key: p.key, Value: p.value, TTL: p.ttl, Revision: 0})
+		case p.existed:
+			undo = append(undo, Op{Type: OpPutIfRevision, Key: p.key, Value: p.value, TTL: p.ttl, Revision: revision})
+		case !p.deleted:
+			undo = append(undo, Op{Type: OpDeleteIfRevision, Key: p.key, Revision: revision})
+		}
+	}
+	return undo, nil
+}
+
+// Rollback discards all buffered operations.
+func (t *Txn) Rollback() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ops = nil
+}
+
+// Close discards all buffered operations. It does not close the
+// underlying storage.
+func (t *Txn) Close() error {
+	t.Rollback()
+	return nil
+}
+
+// lastOp returns the last buffered operation for the given key.
+func (t *Txn) lastOp(key string) (Op, bool) {
+	for i := len(t.ops) - 1; i >= 0; i-- {
+		if t.ops[i].Key == key {
+			return t.ops[i], true
+		}
+	}
+	return Op{}, false
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTxn(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, err := NewTestStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.Put(ctx, "/test/a", "a", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put(ctx, "/test/b", "b", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := NewTxn(db)
+	if err := tx.Put(ctx, "/test/c", "c", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Delete(ctx, "/test/a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reads through the transaction should see the buffered writes.
+	if _, err := tx.Get(ctx, "/test/a"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+	if val, err := tx.Get(ctx, "/test/c"); err != nil || val != "c" {
+		t.Fatalf("expected c, got %q (%v)", val, err)
+	}
+	keys, err := tx.List(ctx, "/test/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] != "/test/b" || keys[1] != "/test/c" {
+		t.Fatalf("unexpected keys in txn: %v", keys)
+	}
+
+	// The underlying storage should be untouched until commit.
+	if _, err := db.Get(ctx, "/test/c"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound before commit, got %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.Ops()) != 0 {
+		t.Fatalf("expected no buffered ops after commit, got %d", len(tx.Ops()))
+	}
+	if _, err := db.Get(ctx, "/test/a"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound after commit, got %v", err)
+	}
+	if val, err := db.Get(ctx, "/test/c"); err != nil || val != "c" {
+		t.Fatalf("expected c after commit, got %q (%v)", val, err)
+	}
+
+	// A rolled back transaction should leave the storage untouched.
+	if err := tx.Put(ctx, "/test/d", "d", 0); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Get(ctx, "/test/d"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound after rollback, got %v", err)
+	}
+}
+
+func TestBatchIsAtomic(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, err := NewTestStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Batch(ctx, []Op{
+		{Type: OpPut, Key: "/test/a", Value: "a"},
+		{Type: OpPut, Key: "", Value: "invalid"},
+	})
+	if err == nil {
+		t.Fatal("expected error for batch with empty key")
+	}
+	if _, err := db.Get(ctx, "/test/a"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected no writes from failed batch, got %v", err)
+	}
+}
+
+func TestTxnCommitUndo(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	setup := func(t *testing.T) (Storage, []Op) {
+		t.Helper()
+		db, err := NewTestStorage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		if err := db.Put(ctx, "/test/a", "a", time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Put(ctx, "/test/c", "c", 0); err != nil {
+			t.Fatal(err)
+		}
+		tx := NewTxn(db)
+		if err := tx.Put(ctx, "/test/a", "changed", 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Put(ctx, "/test/b", "b", 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Delete(ctx, "/test/c"); err != nil {
+			t.Fatal(err)
+		}
+		undo, err := tx.CommitUndo(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if val, err := db.Get(ctx, "/test/a"); err != nil || val != "changed" {
+			t.Fatalf("expected the transaction to be committed, got %q (%v)", val, err)
+		}
+		return db, undo
+	}
+
+	t.Run("Reverted", func(t *testing.T) {
+		db, undo := setup(t)
+		if err := db.Batch(ctx, undo); err != nil {
+			t.Fatal(err)
+		}
+		if val, err := db.Get(ctx, "/test/a"); err != nil || val != "a" {
+			t.Fatalf("expected a to be restored, got %q (%v)", val, err)
+		}
+		if ttl, err := db.GetTTL(ctx, "/test/a"); err != nil || ttl <= 0 || ttl > time.Hour {
+			t.Fatalf("expected the TTL of a to be restored, got %v (%v)", ttl, err)
+		}
+		if _, err := db.Get(ctx, "/test/b"); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("expected b to be removed, got %v", err)
+		}
+		if val, err := db.Get(ctx, "/test/c"); err != nil || val != "c" {
+			t.Fatalf("expected c to be restored, got %q (%v)", val, err)
+		}
+	})
+
+	t.Run("ChangedSinceCommit", func(t *testing.T) {
+		db, undo := setup(t)
+		if err := db.Put(ctx, "/test/b", "newer", 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Batch(ctx, undo); !errors.Is(err, ErrRevisionMismatch) {
+			t.Fatalf("expected the undo to conflict with the newer write, got %v", err)
+		}
+		if val, err := db.Get(ctx, "/test/b"); err != nil || val != "newer" {
+			t.Fatalf("expected b to keep the newer write, got %q (%v)", val, err)
+		}
+		if val, err := db.Get(ctx, "/test/a"); err != nil || val != "changed" {
+			t.Fatalf("expected a to be left alone, got %q (%v)", val, err)
+		}
+	})
+}
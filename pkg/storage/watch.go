@@ -0,0 +1,222 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/webmeshproj/webmesh/pkg/context"
+)
+
+// DefaultWatchHistory is the default number of events retained for resuming watches.
+const DefaultWatchHistory = 1024
+
+// EventType is the type of a watch event.
+type EventType int
+
+const (
+	// EventPut is sent when a key is created or updated.
+	EventPut EventType = iota
+	// EventDelete is sent when a key is deleted.
+	EventDelete
+	// EventCompacted is sent when events the watcher needed are no longer in
+	// the history, either because it resumed from a revision that is too old or
+	// because it fell too far behind. The watcher should relist the keys it is
+	// interested in. The watch continues with events after the revision of the
+	// compaction event.
+	EventCompacted
+)
+
+// String returns the string representation of the event type.
+func (e EventType) String() string {
+	switch e {
+	case EventPut:
+		return "PUT"
+	case EventDelete:
+		return "DELETE"
+	case EventCompacted:
+		return "COMPACTED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Event is a change to a key in the storage.
+type Event struct {
+	// Type is the type of the event.
+	Type EventType
+	// Key is the key that changed. It is empty for compaction events.
+	Key string
+	// Value is the new value of the key. It is empty for deletes.
+	Value string
+	// Revision is the revision of the storage at which the change was made.
+	Revision uint64
+}
+
+// WatchFunc is the function signature for receiving watch events. Events are
+// delivered in revision order from a single goroutine per watch.
+type WatchFunc func(ev Event)
+
+// watchHub retains a bounded history of events and delivers them to watchers.
+// It is used by storage implementations to implement Watch.
+type watchHub struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	history   []Event
+	size      int
+	rev       uint64
+	compacted uint64
+	closed    bool
+}
+
+func newWatchHub(size int, rev uint64) *watchHub {
+	if size <= 0 {
+		size = DefaultWatchHistory
+	}
+	h := &watchHub{size: size, rev: rev, compacted: rev}
+	h.cond = sync.NewCond(&h.mu)
+	return h
+}
+
+// publish records the events that were written at the given revision.
+func (h *watchHub) publish(rev uint64, events []Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.history = append(h.history, events...)
+	if len(h.history) > h.size {
+		// Drop whole revisions so that watchers never see a partial batch.
+		drop := len(h.history) - h.size
+		h.compacted = h.history[drop-1].Revision
+		for drop < len(h.history) && h.history[drop].Revision == h.compacted {
+			drop++
+		}
+		h.history = append([]Event(nil), h.history[drop:]...)
+	}
+	h.rev = rev
+	h.cond.Broadcast()
+}
+
+// reset discards all history and moves the hub to the given revision. Any
+// watchers behind the new revision receive a compaction event.
+func (h *watchHub) reset(rev uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.history = nil
+	h.rev = rev
+	h.compacted = rev
+	h.cond.Broadcast()
+}
+
+// close stops all watchers.
+func (h *watchHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	h.cond.Broadcast()
+}
+
+// watch starts a watcher for the given prefix from the given revision.
+func (h *watchHub) watch(ctx context.Context, prefix string, rev uint64, fn WatchFunc) (func(), error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(ctx, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.cond.Broadcast()
+	})
+	h.mu.Lock()
+	cursor := rev
+	if cursor == 0 {
+		cursor = h.rev
+	}
+	h.mu.Unlock()
+	go func() {
+		defer stop()
+		for {
+			events, next, ok := h.next(ctx, prefix, cursor)
+			if !ok {
+				return
+			}
+			for _, ev := range events {
+				fn(ev)
+			}
+			cursor = next
+		}
+	}()
+	return cancel, nil
+}
+
+// next blocks until there are events after the cursor and returns the ones
+// matching the prefix along with the new cursor.
+func (h *watchHub) next(ctx context.Context, prefix string, cursor uint64) ([]Event, uint64, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for !h.closed && ctx.Err() == nil && h.rev == cursor {
+		h.cond.Wait()
+	}
+	if h.closed || ctx.Err() != nil {
+		return nil, cursor, false
+	}
+	if cursor < h.compacted || cursor > h.rev {
+		// The events we need are gone, or the storage was restored to an
+		// earlier revision.
+		return []Event{{Type: EventCompacted, Revision: h.rev}}, h.rev, true
+	}
+	start := sort.Search(len(h.history), func(i int) bool {
+		return h.history[i].Revision > cursor
+	})
+	var events []Event
+	for _, ev := range h.history[start:] {
+		if strings.HasPrefix(ev.Key, prefix) {
+			events = append(events, ev)
+		}
+	}
+	return events, h.rev, true
+}
+
+// subscribe implements Subscribe on top of Watch. After a compaction, the
+// current value of every key with the prefix is delivered again.
+func subscribe(ctx context.Context, st Storage, prefix string, fn SubscribeFunc) (func(), error) {
+	ctx, cancel := context.WithCancel(ctx)
+	_, err := st.Watch(ctx, prefix, 0, func(ev Event) {
+		switch ev.Type {
+		case EventPut, EventDelete:
+			fn(ev.Key, ev.Value)
+		case EventCompacted:
+			type kv struct{ key, value string }
+			var kvs []kv
+			err := st.IterPrefix(ctx, prefix, func(key, value string) error {
+				kvs = append(kvs, kv{key, value})
+				return nil
+			})
+			if err != nil {
+				context.LoggerFrom(ctx).Error("relist after compaction", slog.String("prefix", prefix), slog.String("error", err.Error()))
+				return
+			}
+			for _, kv := range kvs {
+				fn(kv.key, kv.value)
+			}
+		}
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return cancel, nil
+}
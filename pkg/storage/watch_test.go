@@ -0,0 +1,96 @@
+/*
+Copyright 2023 Avi Zimmerman <avi.zimmerman@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, err := New(&Options{InMemory: true, Silent: true, WatchHistory: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	start, err := db.Revision(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan Event, 10)
+	cancel, err := db.Watch(ctx, "/test/", 0, func(ev Event) { events <- ev })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	if err := db.Put(ctx, "/other/a", "a", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put(ctx, "/test/a", "a", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(ctx, "/test/a"); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, events, Event{Type: EventPut, Key: "/test/a", Value: "a", Revision: start + 2})
+	expectEvent(t, events, Event{Type: EventDelete, Key: "/test/a", Revision: start + 3})
+
+	// Resuming from a retained revision should replay the events after it.
+	resumed := make(chan Event, 10)
+	cancelResume, err := db.Watch(ctx, "/test/", start+2, func(ev Event) { resumed <- ev })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancelResume()
+	expectEvent(t, resumed, Event{Type: EventDelete, Key: "/test/a", Revision: start + 3})
+
+	// Resuming from a revision that fell out of the history should compact.
+	for i := 0; i < 5; i++ {
+		if err := db.Put(ctx, "/test/b", "b", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	current, err := db.Revision(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compacted := make(chan Event, 10)
+	cancelCompacted, err := db.Watch(ctx, "/test/", start+1, func(ev Event) { compacted <- ev })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancelCompacted()
+	expectEvent(t, compacted, Event{Type: EventCompacted, Revision: current})
+}
+
+func expectEvent(t *testing.T, ch <-chan Event, want Event) {
+	t.Helper()
+	select {
+	case got := <-ch:
+		if got != want {
+			t.Fatalf("expected event %+v, got %+v", want, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for event %+v", want)
+	}
+}
@@ -54,3 +54,34 @@ func Ping(ctx context.Context, addr netip.Addr) error {
 	}
 	return nil
 }
+
+// PingStats sends count ICMP echo requests to the given address and returns
+// the average round trip time of the replies and the fraction of requests
+// that went unanswered. The context must have a timeout set. Unlike Ping,
+// receiving no replies is not an error and is reported as a loss of 1.
+func PingStats(ctx context.Context, addr netip.Addr, count int) (rtt time.Duration, loss float64, err error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, 0, fmt.Errorf("no deadline set")
+	}
+	pinger, err := ping.NewPinger(addr.String())
+	if err != nil {
+		return 0, 0, fmt.Errorf("create pinger: %w", err)
+	}
+	pinger.Count = count
+	pinger.Timeout = time.Until(deadline)
+	pinger.Interval = 200 * time.Millisecond
+	if os.Geteuid() == 0 {
+		pinger.SetPrivileged(true)
+	}
+	pinger.SetLogger(ping.NoopLogger{})
+	err = pinger.Run()
+	if err != nil {
+		return 0, 0, fmt.Errorf("run pinger: %w", err)
+	}
+	stats := pinger.Statistics()
+	if stats.PacketsSent == 0 {
+		return 0, 0, fmt.Errorf("no requests sent")
+	}
+	return stats.AvgRtt, stats.PacketLoss / 100, nil
+}